@@ -0,0 +1,104 @@
+// adminui.go
+// Elevation-aware UI - the UAC shield marks controls that ask for administrator approval, or that stay disabled until the app is elevated
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// shieldIcon is the four-colored UAC shield Windows shows on controls that need elevation
+var shieldIcon = fyne.NewStaticResource("shield.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24">
+<path d="M12 1 L3 5 V11 H12 Z" fill="#1f6fd1"/>
+<path d="M12 1 L21 5 V11 H12 Z" fill="#f5c518"/>
+<path d="M3 11 C3 16.5 6.84 21.74 12 23 V11 Z" fill="#f5c518"/>
+<path d="M21 11 C21 16.5 17.16 21.74 12 23 V11 Z" fill="#1f6fd1"/>
+</svg>`))
+
+// relaunchElevated restarts the application as administrator through UAC and quits this instance
+// The command line is kept, extra arguments such as the selected config are appended when missing
+func relaunchElevated(parent fyne.Window, extra ...string) {
+	args := os.Args[1:]
+	for _, arg := range extra {
+		if arg != "" && !contains(args, arg) {
+			args = append(args, arg)
+		}
+	}
+	if err := elevateAsAdmin(args...); err != nil {
+		dialog.ShowError(fmt.Errorf("failed to relaunch as admin: %v", err), parent)
+		return
+	}
+	fyne.CurrentApp().Quit()
+}
+
+// elevationNotice is added to confirmations of changes that ask for administrator approval
+const elevationNotice = "System variables are written after administrator approval (UAC)."
+
+// elevates reports whether applying changes goes through the elevated helper
+func elevates(changes []ScopedVariable, isAdmin bool) bool {
+	if isAdmin {
+		return false
+	}
+	for _, c := range changes {
+		if c.Scope == ScopeSystem {
+			return true
+		}
+	}
+	return false
+}
+
+// markElevates shows the shield on a button whose action writes system variables through the elevated helper
+// elevates is false when the current selection only touches the user environment
+func markElevates(button *widget.Button, isAdmin, elevates bool) {
+	if elevates && !isAdmin {
+		button.SetIcon(shieldIcon)
+		return
+	}
+	button.SetIcon(nil)
+}
+
+// requireAdmin disables a button and shows the shield on it while it needs an elevated application
+// It is used for actions the elevated helper cannot perform, needed is false when the current selection does not need elevation
+func requireAdmin(button *widget.Button, isAdmin, needed bool) {
+	if needed && !isAdmin {
+		button.SetIcon(shieldIcon)
+		button.Disable()
+		return
+	}
+	button.SetIcon(nil)
+	button.Enable()
+}
+
+// elevateBar is an inline notice with the shield and an Elevate button, shown next to controls that need elevation
+type elevateBar struct {
+	*fyne.Container
+	label *widget.Label
+}
+
+// newElevateBar builds a hidden elevate bar, its button relaunches the application as administrator
+func newElevateBar(parent fyne.Window) *elevateBar {
+	label := widget.NewLabel("")
+	label.Wrapping = fyne.TextWrapWord
+	elevateButton := widget.NewButtonWithIcon("Elevate", shieldIcon, func() { relaunchElevated(parent) })
+	bar := &elevateBar{
+		Container: container.NewBorder(nil, nil, widget.NewIcon(shieldIcon), elevateButton, label),
+		label:     label,
+	}
+	bar.Hide()
+	return bar
+}
+
+// update shows the bar with reason while elevation is needed, and hides it when the app is elevated or read-only
+func (b *elevateBar) update(isAdmin, needed bool, reason string) {
+	if !needed || isAdmin || readOnlyMode {
+		b.Hide()
+		return
+	}
+	b.label.SetText(reason)
+	b.Show()
+}
@@ -0,0 +1,397 @@
+// apply.go
+// Apply engine - writes configured variables to the registry and handles per-variable failures according to policy
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Error policies controlling what happens when a single variable cannot be written
+const (
+	ErrorPolicyStop     = "stop"     // Abort the apply at the first failing variable
+	ErrorPolicyContinue = "continue" // Keep going and report all failures at the end
+	ErrorPolicyPrompt   = "prompt"   // Ask the user whether to continue after each failure
+)
+
+// readOnlyMode disables every code path that modifies the environment, it is set once at startup
+var readOnlyMode bool
+
+// errReadOnly is returned by write operations while read-only mode is active
+var errReadOnly = errors.New("read-only mode is active: changes to the environment are disabled")
+
+// VariableError describes a failure to apply one variable
+type VariableError struct {
+	Name      string // Variable name
+	Operation string // Operation that failed
+	Err       error  // Underlying error
+}
+
+func (e VariableError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Name, e.Operation, e.Err)
+}
+
+// ApplyErrors aggregates the per-variable failures of an apply run
+type ApplyErrors struct {
+	Failures []VariableError // Every variable that could not be applied
+	Aborted  bool            // True when the error policy stopped processing early
+}
+
+func (e *ApplyErrors) Error() string {
+	messages := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		messages = append(messages, f.Error())
+	}
+	summary := fmt.Sprintf("%d variable(s) failed", len(e.Failures))
+	if e.Aborted {
+		summary += " (apply aborted)"
+	}
+	return fmt.Sprintf("%s: %s", summary, strings.Join(messages, "; "))
+}
+
+// errorPromptFunc asks the user whether to continue after a variable failed; it returns true to continue
+type errorPromptFunc func(failure VariableError) bool
+
+// applyOptions carries the settings that influence how variables are written
+type applyOptions struct {
+	ErrorPolicy       string          // One of the ErrorPolicy constants
+	Prompt            errorPromptFunc // Callback used by ErrorPolicyPrompt
+	SensitivePatterns []string        // Name globs whose values are masked in console output
+	Protected         []string        // Variables that may only be deleted or overwritten with force: true
+	Source            string          // Config path or UI action recorded for variables this apply creates
+	FromConfig        bool            // Source is a config file, see managed.go
+}
+
+// registryHiveName returns a human-readable hive name for error messages
+func registryHiveName(hive registry.Key) string {
+	switch hive {
+	case registry.CURRENT_USER:
+		return "HKEY_CURRENT_USER"
+	case registry.LOCAL_MACHINE:
+		return "HKEY_LOCAL_MACHINE"
+	default:
+		return fmt.Sprintf("UnknownHive(%d)", hive)
+	}
+}
+
+// registryScope maps an environment hive to the scope name used throughout the UI
+func registryScope(hive registry.Key) string {
+	if hive == registry.LOCAL_MACHINE {
+		return ScopeSystem
+	}
+	return ScopeUser
+}
+
+// applyVariables processes a list of environment variables and applies them to the Windows registry
+// Per-variable failures are handled according to policy and returned as *ApplyErrors
+func applyVariables(variables []Variable, hive registry.Key, subkeyPath string, options applyOptions) error {
+	if readOnlyMode {
+		return errReadOnly
+	}
+	hiveName := registryHiveName(hive)
+
+	// Open registry key with write permissions, reading is needed to keep deleted values in the trash
+	key, err := registry.OpenKey(hive, subkeyPath, registry.SET_VALUE|registry.QUERY_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open registry key %s\\%s: %w", hiveName, subkeyPath, err)
+	}
+	defer key.Close()
+
+	result := &ApplyErrors{}
+
+	// Expiry changes are collected and written once at the end; one file rewrite per variable stalled large applies
+	// Created and deleted variables update the managed variable records the same way
+	expiries := map[string]*time.Time{}
+	var created, modified, deleted []string
+	var tagged []VariableTag
+	defer func() {
+		if err := setExpiries(registryScope(hive), expiries); err != nil {
+			fmt.Printf("  Warning: Could not record expiries: %v\n", err)
+		}
+		if err := recordManagedChanges(registryScope(hive), options.Source, options.FromConfig, created, modified, deleted); err != nil {
+			fmt.Printf("  Warning: Could not record managed variables: %v\n", err)
+		}
+		if err := updateVariableTags(registryScope(hive), tagged, deleted); err != nil {
+			fmt.Printf("  Warning: Could not record tags: %v\n", err)
+		}
+	}()
+
+	// Process each variable according to its operation type
+	for _, v := range variables {
+		var opErr error
+		// Protected variables are only deleted or overwritten when the entry sets force: true
+		currentValue, currentType, readErr := key.GetStringValue(v.Name)
+		if err := protectionError(v, currentValue, readErr == nil, options.Protected); err != nil {
+			opErr = err
+			fmt.Printf("  Refusing to change %s: %v\n", v.Name, err)
+		}
+
+		if opErr == nil {
+			switch v.Operation {
+			case "set":
+				// Work out the expiry before writing so an invalid expires value leaves the variable untouched
+				var expiresAt *time.Time
+				if v.Expires != "" {
+					at, err := parseExpiry(v.Expires, time.Now())
+					if err != nil {
+						opErr = err
+						fmt.Printf("  Failed to set %s: %v\n", v.Name, err)
+						break
+					}
+					expiresAt = &at
+				}
+
+				// Preserve REG_EXPAND_SZ so %VAR% references keep expanding
+				wantType := uint32(registry.SZ)
+				if v.isExpandable() {
+					wantType = registry.EXPAND_SZ
+				}
+				// Rewriting an identical value costs a registry write and wakes every change listener for nothing
+				unchanged := readErr == nil && currentValue == v.Value && currentType == wantType
+				switch {
+				case unchanged:
+				case v.isExpandable():
+					opErr = key.SetExpandStringValue(v.Name, v.Value)
+				default:
+					opErr = key.SetStringValue(v.Name, v.Value)
+				}
+				if opErr != nil {
+					fmt.Printf("  Failed to set %s=%s: %v\n", v.Name, v.displayValue(options.SensitivePatterns), opErr)
+				} else {
+					if unchanged {
+						fmt.Printf("  %s=%s is already up to date\n", v.Name, v.displayValue(options.SensitivePatterns))
+					} else {
+						fmt.Printf("  Successfully set %s=%s\n", v.Name, v.displayValue(options.SensitivePatterns))
+					}
+					switch {
+					case readErr != nil:
+						created = append(created, v.Name)
+					case !unchanged:
+						modified = append(modified, v.Name)
+					}
+					// Tags and owner from the config replace the recorded ones, entries without them keep tags set in the Variables tab
+					if len(v.Tags) > 0 || v.Owner != "" {
+						tagged = append(tagged, VariableTag{Name: v.Name, Tags: v.Tags, Owner: v.Owner})
+					}
+					// Track temporary variables, and forget an earlier expiry when a variable is set permanently
+					expiries[v.Name] = expiresAt
+					if expiresAt != nil {
+						fmt.Printf("  %s expires at %s\n", v.Name, expiresAt.Format(time.RFC3339))
+					}
+				}
+			case "delete":
+				// The value read above goes to the trash so the deletion can be undone from the Trash tab
+				if err := key.DeleteValue(v.Name); err != nil {
+					if os.IsNotExist(err) {
+						fmt.Printf("  Variable %s already deleted or did not exist.\n", v.Name)
+						expiries[v.Name] = nil // Nothing is left to expire
+					} else {
+						opErr = err
+						fmt.Printf("  Failed to delete %s: %v\n", v.Name, err)
+					}
+				} else {
+					fmt.Printf("  Successfully deleted %s\n", v.Name)
+					expiries[v.Name] = nil
+					deleted = append(deleted, v.Name)
+					if readErr == nil {
+						trashed := Variable{Name: v.Name, Value: currentValue, Type: TypeString, Sensitive: v.Sensitive}
+						if currentType == registry.EXPAND_SZ {
+							trashed.Type = TypeExpand
+						}
+						sessionTrash.add(registryScope(hive), trashed)
+					}
+				}
+			default:
+				opErr = fmt.Errorf("unknown operation %q", v.Operation)
+				fmt.Printf("  Unknown operation '%s' for variable %s. Skipping.\n", v.Operation, v.Name)
+			}
+		}
+
+		if opErr == nil {
+			continue
+		}
+
+		failure := VariableError{Name: v.Name, Operation: v.Operation, Err: opErr}
+		result.Failures = append(result.Failures, failure)
+
+		// Decide whether to keep processing the remaining variables
+		switch options.ErrorPolicy {
+		case ErrorPolicyStop:
+			result.Aborted = true
+		case ErrorPolicyPrompt:
+			result.Aborted = options.Prompt == nil || !options.Prompt(failure)
+		}
+		if result.Aborted {
+			fmt.Println("  Aborting apply due to error policy.")
+			return result
+		}
+	}
+
+	if len(result.Failures) > 0 {
+		return result
+	}
+	return nil
+}
+
+// collectApplyFailures appends the per-variable failures in err to failures
+// It returns false when the apply must not continue, either because the policy aborted it or the error was fatal
+func collectApplyFailures(err error, failures *[]VariableError) bool {
+	var applyErrs *ApplyErrors
+	if !errors.As(err, &applyErrs) {
+		return false
+	}
+	*failures = append(*failures, applyErrs.Failures...)
+	return !applyErrs.Aborted
+}
+
+// applyDirectChanges writes variables edited in the UI straight to the registry, broadcasts the change and records it in the audit log
+// source describes where the change came from and is logged in place of a config path
+// Without administrator privileges the system variables are written by the elevated helper, which asks for UAC approval
+func applyDirectChanges(source string, changes []ScopedVariable, isAdmin bool, settings Settings) error {
+	config := scopedConfig(changes)
+	timer := newPhaseTimer("Apply")
+
+	err := func() error {
+		timer.begin("validate")
+		if err := preflightConfig(config).blocked(!isAdmin); err != nil {
+			return err
+		}
+		options := applyOptions{ErrorPolicy: ErrorPolicyContinue, SensitivePatterns: settings.SensitivePatterns, Protected: settings.ProtectedVariables, Source: source}
+		var failures []VariableError
+		timer.begin("registry writes")
+		if len(config.UserVariables) > 0 {
+			if err := applyVariables(config.UserVariables, registry.CURRENT_USER, userEnvironmentPath, options); err != nil && !collectApplyFailures(err, &failures) {
+				return err
+			}
+		}
+		if len(config.SystemVariables) > 0 {
+			var err error
+			if isAdmin {
+				err = applyVariables(config.SystemVariables, registry.LOCAL_MACHINE, systemEnvironmentPath, options)
+			} else {
+				timer.begin("elevated writes")
+				err = applySystemVariablesElevated(config.SystemVariables, options)
+			}
+			if err != nil && !collectApplyFailures(err, &failures) {
+				return err
+			}
+		}
+		timer.begin("broadcast")
+		if err := broadcastSettingChange(settings.Broadcast, settings.Retry); err != nil {
+			return fmt.Errorf("error broadcasting WM_SETTINGCHANGE: %w", err)
+		}
+		if len(failures) > 0 {
+			return &ApplyErrors{Failures: failures}
+		}
+		return nil
+	}()
+
+	recordApplyHistory(source, config, err, timer)
+	return err
+}
+
+// loadConfigForMachine loads a config with its parents merged, entries for other machines dropped and value scripts evaluated
+func loadConfigForMachine(filePath string) (Config, error) {
+	config, err := loadConfig(filePath)
+	if err != nil {
+		return config, err
+	}
+	if config, err = resolveExtends(config, filePath); err != nil {
+		return config, err
+	}
+	if config, err = selectApplicableVariables(config); err != nil {
+		return config, err
+	}
+	return evaluateConfigScripts(config)
+}
+
+// applyConfigUnattended applies a config file without any user interaction, for triggers that run without the UI
+// Prompt placeholders and parameters without a default fail because nobody can answer them, and the "prompt" error policy continues instead of asking
+func applyConfigUnattended(source string, isAdmin bool, settings Settings) error {
+	_, err := applyConfigUnattendedWith(source, isAdmin, false, settings)
+	return err
+}
+
+// applyConfigUnattendedWith is applyConfigUnattended returning the applied config
+// With elevate the system variables are written through the elevated helper when not running as administrator, for callers with a user at the desktop
+func applyConfigUnattendedWith(source string, isAdmin, elevate bool, settings Settings) (Config, error) {
+	timer := newPhaseTimer("Apply")
+	timer.begin("parse")
+	config, err := loadConfigForMachine(source)
+	if err == nil {
+		timer.begin("validate")
+		config, err = resolveWithParamDefaults(config)
+		config = normalizeConfigPaths(config, settings)
+	}
+	if err == nil {
+		config, err = enforcePathOrder(config, settings)
+	}
+	if err == nil {
+		config, err = expandConfigPatterns(config)
+	}
+	if err == nil {
+		config, err = expandSyncMode(config, source, settings.ProtectedVariables)
+	}
+	if err == nil {
+		err = checkPSModulePathUnattended(config)
+	}
+	if err != nil {
+		recordApplyHistory(source, config, err, timer)
+		return config, &ValidationError{Err: err}
+	}
+
+	err = func() error {
+		if len(config.SystemVariables) > 0 && !isAdmin && !elevate {
+			return errAdminRequired
+		}
+		if err := preflightConfig(config).blocked(!isAdmin && elevate); err != nil {
+			return err
+		}
+		policy := settings.ErrorPolicy
+		if policy == ErrorPolicyPrompt {
+			policy = ErrorPolicyContinue
+		}
+		options := applyOptions{
+			ErrorPolicy:       policy,
+			SensitivePatterns: settings.SensitivePatterns,
+			Protected:         settings.ProtectedVariables,
+			Source:            source,
+			FromConfig:        true,
+		}
+		var failures []VariableError
+		timer.begin("registry writes")
+		if len(config.UserVariables) > 0 {
+			if err := applyVariables(config.UserVariables, registry.CURRENT_USER, userEnvironmentPath, options); err != nil && !collectApplyFailures(err, &failures) {
+				return err
+			}
+		}
+		if len(config.SystemVariables) > 0 {
+			var err error
+			if isAdmin {
+				err = applyVariables(config.SystemVariables, registry.LOCAL_MACHINE, systemEnvironmentPath, options)
+			} else {
+				timer.begin("elevated writes")
+				err = applySystemVariablesElevated(config.SystemVariables, options)
+			}
+			if err != nil && !collectApplyFailures(err, &failures) {
+				return err
+			}
+		}
+		timer.begin("broadcast")
+		if err := broadcastSettingChange(settings.Broadcast, settings.Retry); err != nil {
+			return fmt.Errorf("error broadcasting WM_SETTINGCHANGE: %w", err)
+		}
+		if len(failures) > 0 {
+			return &ApplyErrors{Failures: failures}
+		}
+		return nil
+	}()
+
+	recordApplyHistory(source, config, err, timer)
+	return config, err
+}
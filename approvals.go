@@ -0,0 +1,413 @@
+// approvals.go
+// Approval workflow - profiles pushed to the listener or corrected by enforcement wait as pending approvals until the signed-in user decides
+// The decision is kept for the sender to poll and posted to its callback URL when the request named one
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// approvalsFileName keeps pending and recently decided approval requests across restarts
+const approvalsFileName = "approvals.json"
+
+// Statuses of an approval request
+const (
+	approvalPending  = "pending"
+	approvalApplying = "applying" // Approved, the apply is queued or running
+	approvalRejected = "rejected"
+	approvalApplied  = "applied"
+	approvalFailed   = "failed" // Approved, but the apply failed
+)
+
+// Origins of an approval request
+const (
+	approvalFromListener    = "listener"
+	approvalFromEnforcement = "enforcement"
+)
+
+// Approval request limits
+const (
+	maxDecidedApprovals = 100 // Decided requests kept for senders polling their outcome
+	callbackTimeout     = 10 * time.Second
+)
+
+// ApprovalRequest is a profile apply waiting for, or decided by, the interactive user
+type ApprovalRequest struct {
+	ID          string    `json:"id"`
+	Profile     string    `json:"profile"`
+	Origin      string    `json:"origin"`                 // approvalFromListener or approvalFromEnforcement
+	Requester   string    `json:"requester,omitempty"`    // Client address of a listener request
+	Reason      string    `json:"reason,omitempty"`       // Why the request was made, e.g. the drift enforcement found
+	CallbackURL string    `json:"callback_url,omitempty"` // Where the outcome is posted
+	Received    time.Time `json:"received"`
+	Status      string    `json:"status"`
+	Decided     time.Time `json:"decided,omitempty"`
+	DecidedBy   string    `json:"decided_by,omitempty"`
+	Error       string    `json:"error,omitempty"` // Rejection reason or apply error
+}
+
+// label describes the request for the approvals window
+func (r ApprovalRequest) label() string {
+	label := fmt.Sprintf("%s  profile %s from %s", r.Received.Local().Format("2006-01-02 15:04:05"), r.Profile, r.Origin)
+	if r.Requester != "" {
+		label += " (" + r.Requester + ")"
+	}
+	if r.Reason != "" {
+		label += "\n    " + r.Reason
+	}
+	return label
+}
+
+// approvalQueue holds the approval requests, the notifier tells the user about new ones
+type approvalQueue struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries []ApprovalRequest
+	notify  func(title, content string)
+}
+
+// approvalRequests is the approval queue of the running application
+var approvalRequests = &approvalQueue{}
+
+// load reads the persisted requests once, the caller must hold mu
+func (q *approvalQueue) load() {
+	if q.loaded {
+		return
+	}
+	q.loaded = true
+	path, err := appDataPath(approvalsFileName)
+	if err != nil {
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Warning: Could not read approval requests: %v\n", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &q.entries); err != nil {
+		fmt.Printf("Warning: Could not parse approval requests file %s: %v\n", path, err)
+	}
+	// An apply interrupted by exiting the application is pending again
+	for i := range q.entries {
+		if q.entries[i].Status == approvalApplying {
+			q.entries[i].Status = approvalPending
+		}
+	}
+}
+
+// save writes the requests, keeping every pending one and the most recent decided ones; the caller must hold mu
+func (q *approvalQueue) save() {
+	decided := 0
+	kept := make([]ApprovalRequest, 0, len(q.entries))
+	for i := len(q.entries) - 1; i >= 0; i-- {
+		if q.entries[i].Status != approvalPending && q.entries[i].Status != approvalApplying {
+			if decided++; decided > maxDecidedApprovals {
+				continue
+			}
+		}
+		kept = append([]ApprovalRequest{q.entries[i]}, kept...)
+	}
+	q.entries = kept
+
+	path, err := appDataPath(approvalsFileName)
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(q.entries, "", "  "); err == nil {
+			err = ioutil.WriteFile(path, data, 0644)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Warning: Could not save approval requests: %v\n", err)
+	}
+}
+
+// submit queues a request as pending and notifies the user, it returns the request ID
+// A pending request of the same origin and profile is reused, so repeated pushes or drift checks don't pile up
+func (q *approvalQueue) submit(r ApprovalRequest) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.load()
+	for _, e := range q.entries {
+		if e.Status == approvalPending && e.Origin == r.Origin && strings.EqualFold(e.Profile, r.Profile) && e.CallbackURL == r.CallbackURL {
+			return e.ID, nil
+		}
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("failed to generate request id: %w", err)
+	}
+	r.ID, r.Received, r.Status = hex.EncodeToString(id[:]), time.Now(), approvalPending
+	q.entries = append(q.entries, r)
+	q.save()
+	fmt.Printf("Approval requested: profile %s from %s (request %s)\n", r.Profile, r.Origin, r.ID)
+	if q.notify != nil {
+		q.notify("Approval Required", fmt.Sprintf("Profile %s was requested by %s. Open Pending Approvals to review and approve or reject it.", r.Profile, r.Origin))
+	}
+	return r.ID, nil
+}
+
+// lookup returns a request by ID
+func (q *approvalQueue) lookup(id string) (ApprovalRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.load()
+	for _, e := range q.entries {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return ApprovalRequest{}, false
+}
+
+// pending lists the requests waiting for a decision, oldest first
+func (q *approvalQueue) pending() []ApprovalRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.load()
+	var result []ApprovalRequest
+	for _, e := range q.entries {
+		if e.Status == approvalPending {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// rejected reports whether the latest decided request of the origin and profile was rejected for the same reason
+func (q *approvalQueue) rejected(origin, profile, reason string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.load()
+	for i := len(q.entries) - 1; i >= 0; i-- {
+		e := q.entries[i]
+		if e.Origin == origin && strings.EqualFold(e.Profile, profile) && e.Status != approvalPending && e.Status != approvalApplying {
+			return e.Status == approvalRejected && e.Reason == reason
+		}
+	}
+	return false
+}
+
+// claim marks a pending request as being applied, so it cannot be approved or rejected a second time
+func (q *approvalQueue) claim(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.load()
+	for i, e := range q.entries {
+		if e.ID == id && e.Status == approvalPending {
+			q.entries[i].Status = approvalApplying
+			q.save()
+			return nil
+		}
+	}
+	return fmt.Errorf("request %s is no longer pending", id)
+}
+
+// decide records the outcome of a pending or claimed request and reports it to the sender's callback URL
+// Rejections need a pending request, apply outcomes a claimed one
+func (q *approvalQueue) decide(id, status, message string) (ApprovalRequest, error) {
+	q.mu.Lock()
+	var decided ApprovalRequest
+	found := false
+	from := approvalPending
+	if status != approvalRejected {
+		from = approvalApplying
+	}
+	for i, e := range q.entries {
+		if e.ID == id && e.Status == from {
+			e.Status, e.Error, e.Decided, e.DecidedBy = status, message, time.Now(), os.Getenv("USERNAME")
+			q.entries[i], decided, found = e, e, true
+		}
+	}
+	if found {
+		q.save()
+	}
+	q.mu.Unlock()
+	if !found {
+		return decided, fmt.Errorf("request %s is no longer pending", id)
+	}
+
+	outcome := status
+	if message != "" {
+		outcome += ": " + message
+	}
+	if err := appendHistory(HistoryEntry{Action: "approval", ConfigPath: decided.Profile, Success: status == approvalApplied || status == approvalRejected,
+		Message: fmt.Sprintf("%s request %s %s by %s", decided.Origin, decided.ID, outcome, decided.DecidedBy)}); err != nil {
+		fmt.Printf("Warning: Could not write audit log: %v\n", err)
+	}
+	if decided.CallbackURL != "" {
+		go postApprovalOutcome(decided)
+	}
+	return decided, nil
+}
+
+// postApprovalOutcome posts the decided request as JSON to its callback URL
+func postApprovalOutcome(r ApprovalRequest) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	client := &http.Client{Timeout: callbackTimeout}
+	resp, err := client.Post(r.CallbackURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		fmt.Printf("Warning: Could not report request %s to %s: %v\n", r.ID, r.CallbackURL, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Printf("Warning: Reporting request %s to %s returned %s\n", r.ID, r.CallbackURL, resp.Status)
+	}
+}
+
+// validateCallbackURL accepts http and https URLs
+func validateCallbackURL(raw string) error {
+	if raw != "" && !isRemoteConfig(raw) {
+		return fmt.Errorf("callback_url must be an http:// or https:// URL")
+	}
+	return nil
+}
+
+// showApprovalsWindow lists the pending approval requests, the diff of each can be reviewed before approving or rejecting it
+// Approved profiles are applied with the interactive user present, so system variables may ask for administrator approval
+func showApprovalsWindow(app fyne.App, settings *Settings, isAdmin bool) {
+	window := app.NewWindow("Pending Approvals")
+	window.Resize(fyne.NewSize(760, 460))
+
+	resultLabel := widget.NewLabel("")
+	resultLabel.Wrapping = fyne.TextWrapWord
+	var requests []ApprovalRequest
+	selected := -1
+
+	list := widget.NewList(
+		func() int { return len(requests) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) { item.(*widget.Label).SetText(requests[id].label()) },
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	reload := func() {
+		requests = approvalRequests.pending()
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+		if len(requests) == 0 {
+			resultLabel.SetText("No requests are waiting for approval.")
+		}
+	}
+
+	// current returns the selected request and the path of its profile
+	current := func() (ApprovalRequest, string, bool) {
+		if selected < 0 || selected >= len(requests) {
+			dialog.ShowInformation("Pending Approvals", "Select a request first.", window)
+			return ApprovalRequest{}, "", false
+		}
+		r := requests[selected]
+		path, err := profilePath(r.Profile)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return r, "", false
+		}
+		return r, path, true
+	}
+
+	reviewButton := widget.NewButton("Review Diff", func() {
+		_, path, ok := current()
+		if !ok {
+			return
+		}
+		go func() {
+			config, err := loadConfigForMachine(path)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error loading profile: %v", err), window)
+				return
+			}
+			showPreviewWindow(app, normalizeConfigPaths(config, *settings), path, isAdmin, *settings)
+		}()
+	})
+	approveButton := widget.NewButton("Approve and Apply", func() {
+		r, path, ok := current()
+		if !ok {
+			return
+		}
+		dialog.ShowConfirm("Approve Request", fmt.Sprintf("Apply profile %s requested by %s?", r.Profile, r.Origin), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			// Claim the request before queueing the apply, so a second click or a rejection cannot race it
+			if err := approvalRequests.claim(r.ID); err != nil {
+				dialog.ShowError(err, window)
+				reload()
+				return
+			}
+			reload()
+			resultLabel.SetText(fmt.Sprintf("Applying profile %s...", r.Profile))
+			backgroundJobs.submit(jobKindApply, "Apply approved profile "+r.Profile, func(ctx context.Context) error {
+				listenerApplies.Lock()
+				_, applyErr := applyConfigUnattendedWith(path, isAdmin, true, *settings)
+				listenerApplies.Unlock()
+				status, message := approvalApplied, ""
+				if applyErr != nil {
+					status, message = approvalFailed, applyErr.Error()
+				}
+				if _, err := approvalRequests.decide(r.ID, status, message); err != nil {
+					dialog.ShowError(err, window)
+				}
+				if status == approvalApplied {
+					resultLabel.SetText(fmt.Sprintf("Profile %s applied, the sender is told so.", r.Profile))
+				} else {
+					resultLabel.SetText(fmt.Sprintf("Profile %s could not be applied: %s", r.Profile, message))
+				}
+				reload()
+				return applyErr
+			})
+		}, window)
+	})
+	rejectButton := widget.NewButton("Reject", func() {
+		r, _, ok := current()
+		if !ok {
+			return
+		}
+		reasonEntry := widget.NewEntry()
+		reasonEntry.SetPlaceHolder("Optional reason reported to the sender")
+		dialog.ShowForm("Reject Request", "Reject", "Cancel", []*widget.FormItem{widget.NewFormItem("Reason", reasonEntry)}, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if _, err := approvalRequests.decide(r.ID, approvalRejected, strings.TrimSpace(reasonEntry.Text)); err != nil {
+				dialog.ShowError(err, window)
+			}
+			resultLabel.SetText(fmt.Sprintf("Request for profile %s rejected.", r.Profile))
+			reload()
+		}, window)
+	})
+	hideInReadOnly(approveButton, rejectButton)
+
+	reload()
+	window.SetContent(container.NewBorder(
+		widget.NewLabel("Profiles pushed to the listener or corrected by enforcement wait here until they are approved:"),
+		container.NewVBox(
+			widget.NewSeparator(),
+			resultLabel,
+			container.NewHBox(reviewButton, approveButton, rejectButton, widget.NewButton("Refresh", reload), widget.NewButton("Close", window.Close)),
+		),
+		nil, nil,
+		list,
+	))
+	window.Show()
+}
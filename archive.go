@@ -0,0 +1,135 @@
+// archive.go
+// Backup archives - bundles a user+system snapshot and its metadata into a single zip based .evmbackup file
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// backupArchiveExtension is the file extension of environment backup archives
+const backupArchiveExtension = ".evmbackup"
+
+// Entry names inside a backup archive
+const (
+	archiveMetadataEntry = "metadata.yaml"
+	archiveUserEntry     = "user.yaml"
+	archiveSystemEntry   = "system.yaml"
+)
+
+// archiveManifest is stored as metadata.yaml and describes the snapshot set in the archive
+type archiveManifest struct {
+	Version  int             `yaml:"version"`            // Config schema version of the contained snapshots
+	Metadata *ConfigMetadata `yaml:"metadata,omitempty"` // Metadata of the exported environment
+}
+
+// isBackupArchive checks if the provided file path has the backup archive extension
+func isBackupArchive(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), backupArchiveExtension)
+}
+
+// saveBackupArchive writes a config as a snapshot set (metadata, user and system YAML) into a zip archive
+func saveBackupArchive(config Config, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create backup archive %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	entries := []struct {
+		name    string
+		content interface{}
+	}{
+		{archiveMetadataEntry, archiveManifest{Version: CurrentConfigVersion, Metadata: config.Metadata}},
+		{archiveUserEntry, Config{Version: CurrentConfigVersion, UserVariables: config.UserVariables}},
+		{archiveSystemEntry, Config{Version: CurrentConfigVersion, SystemVariables: config.SystemVariables}},
+	}
+
+	for _, entry := range entries {
+		data, err := yaml.Marshal(entry.content)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", entry.name, err)
+		}
+		w, err := writer.Create(entry.name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to backup archive: %w", entry.name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s to backup archive: %w", entry.name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finalize backup archive %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// loadBackupArchive reads a snapshot set from a backup archive and combines it into a single config
+// Each contained YAML file goes through the regular schema migration
+func loadBackupArchive(filePath string) (Config, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to open backup archive %s: %w", filePath, err)
+	}
+	defer reader.Close()
+
+	config := Config{Version: CurrentConfigVersion}
+	found := false
+	for _, f := range reader.File {
+		data, err := readZipEntry(f)
+		if err != nil {
+			return Config{}, err
+		}
+
+		switch f.Name {
+		case archiveMetadataEntry:
+			var manifest archiveManifest
+			if err := yaml.Unmarshal(data, &manifest); err != nil {
+				return Config{}, fmt.Errorf("invalid %s in backup archive: %w", archiveMetadataEntry, err)
+			}
+			config.Metadata = manifest.Metadata
+		case archiveUserEntry:
+			part, err := parseConfig(data)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s in backup archive: %w", archiveUserEntry, err)
+			}
+			config.UserVariables = part.UserVariables
+			found = true
+		case archiveSystemEntry:
+			part, err := parseConfig(data)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s in backup archive: %w", archiveSystemEntry, err)
+			}
+			config.SystemVariables = part.SystemVariables
+			found = true
+		}
+	}
+
+	if !found {
+		return Config{}, fmt.Errorf("backup archive %s contains no snapshots", filePath)
+	}
+	return config, nil
+}
+
+// readZipEntry returns the uncompressed contents of a single archive entry
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s in archive: %w", f.Name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,176 @@
+// backup.go
+// Scheduled backups - periodically exports a full environment snapshot to a backup directory with retention
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup schedules selectable in Settings
+const (
+	BackupScheduleOff    = "off"
+	BackupScheduleDaily  = "daily"
+	BackupScheduleWeekly = "weekly"
+)
+
+const (
+	backupFilePrefix     = "snapshot-"       // Prefix of snapshot files written to the backup directory
+	backupFileTimeLayout = "20060102-150405" // Timestamp embedded in snapshot file names
+	backupCheckInterval  = 30 * time.Minute  // How often the running application checks whether a backup is due
+	backupFolderName     = "backups"         // Default backup directory inside the application data directory
+	defaultBackupKeep    = 10                // Default number of snapshots kept by retention
+)
+
+// BackupSettings controls automatic environment snapshots
+type BackupSettings struct {
+	Schedule  string `yaml:"schedule"`  // One of the BackupSchedule constants
+	Directory string `yaml:"directory"` // Destination folder, defaults to the application data backups folder
+	Keep      int    `yaml:"keep"`      // Number of snapshots to retain, older ones are deleted
+}
+
+// interval returns how much time must pass between two scheduled backups, or zero if disabled
+func (b BackupSettings) interval() time.Duration {
+	switch b.Schedule {
+	case BackupScheduleDaily:
+		return 24 * time.Hour
+	case BackupScheduleWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// backupDirectory returns the configured backup folder or the default one, creating it if necessary
+func (b BackupSettings) backupDirectory() (string, error) {
+	dir := b.Directory
+	if dir == "" {
+		base, err := appDataDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(base, backupFolderName)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// listSnapshots returns the snapshot files in dir sorted from oldest to newest
+func listSnapshots(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backup directory %s: %w", dir, err)
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), backupFilePrefix) && isValidYAMLFile(entry.Name()) {
+			snapshots = append(snapshots, filepath.Join(dir, entry.Name()))
+		}
+	}
+	// Timestamped names sort chronologically
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
+
+// lastBackupTime returns when the newest snapshot in dir was taken, or the zero time if there is none
+func lastBackupTime(dir string) (time.Time, error) {
+	snapshots, err := listSnapshots(dir)
+	if err != nil || len(snapshots) == 0 {
+		return time.Time{}, err
+	}
+	newest := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(snapshots[len(snapshots)-1]), backupFilePrefix), filepath.Ext(snapshots[len(snapshots)-1]))
+	return time.ParseInLocation(backupFileTimeLayout, newest, time.Local)
+}
+
+// createBackup exports the full environment into a new snapshot file and applies retention
+// Backups are never redacted so they can serve as a complete restore point
+func createBackup(backup BackupSettings, isAdmin bool) (string, error) {
+	dir, err := backup.backupDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	config, err := exportEnvironmentVariables(isAdmin)
+	if err != nil {
+		return "", fmt.Errorf("failed to export environment for backup: %w", err)
+	}
+	config.Metadata.Description = "Automatic environment backup"
+
+	path := filepath.Join(dir, backupFilePrefix+time.Now().Format(backupFileTimeLayout)+".yaml")
+	if err := saveConfigToFile(config, path); err != nil {
+		return "", err
+	}
+
+	if err := pruneBackups(dir, backup.Keep); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// pruneBackups deletes the oldest snapshots so that at most keep remain
+func pruneBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		return err
+	}
+	for len(snapshots) > keep {
+		if err := os.Remove(snapshots[0]); err != nil {
+			return fmt.Errorf("failed to delete old backup %s: %w", snapshots[0], err)
+		}
+		fmt.Printf("Deleted old backup %s\n", snapshots[0])
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// runScheduledBackup takes a backup if the schedule says one is due
+func runScheduledBackup(backup BackupSettings, isAdmin bool) {
+	interval := backup.interval()
+	if interval == 0 {
+		return
+	}
+
+	dir, err := backup.backupDirectory()
+	if err != nil {
+		fmt.Printf("Warning: Scheduled backup skipped: %v\n", err)
+		return
+	}
+	last, err := lastBackupTime(dir)
+	if err != nil {
+		fmt.Printf("Warning: Could not determine last backup time: %v\n", err)
+	}
+	if !last.IsZero() && time.Since(last) < interval {
+		return
+	}
+
+	path, err := createBackup(backup, isAdmin)
+	if err != nil {
+		fmt.Printf("Warning: Scheduled backup failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Scheduled backup written to %s\n", path)
+}
+
+// startBackupScheduler checks for due backups at startup and then periodically while the application runs
+// The settings are read on every check so schedule changes take effect without a restart
+func startBackupScheduler(settings *Settings, isAdmin bool) {
+	go func() {
+		runScheduledBackup(settings.Backup, isAdmin)
+		ticker := time.NewTicker(backupCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runScheduledBackup(settings.Backup, isAdmin)
+		}
+	}()
+}
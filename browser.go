@@ -0,0 +1,374 @@
+// browser.go
+// Variable browser - the Variables tab listing the current user and system environment from the registry
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows/registry"
+)
+
+// ScopedVariable is a variable together with the scope it was read from
+type ScopedVariable struct {
+	Scope string // ScopeUser or ScopeSystem
+	Variable
+}
+
+// readAllVariables reads the user and system environment from the registry, sorted by scope and name
+// Reading the system environment does not require administrator privileges
+func readAllVariables() ([]ScopedVariable, error) {
+	var all []ScopedVariable
+
+	userVars, err := readVariablesFromRegistry(registry.CURRENT_USER, userEnvironmentPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user environment variables: %w", err)
+	}
+	for _, v := range userVars {
+		all = append(all, ScopedVariable{Scope: ScopeUser, Variable: v})
+	}
+
+	systemVars, err := readVariablesFromRegistry(registry.LOCAL_MACHINE, systemEnvironmentPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system environment variables: %w", err)
+	}
+	for _, v := range systemVars {
+		all = append(all, ScopedVariable{Scope: ScopeSystem, Variable: v})
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Scope != all[j].Scope {
+			return all[i].Scope == ScopeUser
+		}
+		return strings.ToUpper(all[i].Name) < strings.ToUpper(all[j].Name)
+	})
+	return all, nil
+}
+
+// typeLabel returns the display name of a variable's registry type
+func (v Variable) typeLabel() string {
+	if v.isExpandable() {
+		return TypeExpand
+	}
+	return TypeString
+}
+
+// formatValueDetails renders a value for the details pane, listing ';'-separated entries one per line
+func formatValueDetails(v Variable, sensitivePatterns []string) string {
+	value := v.displayValue(sensitivePatterns)
+	if value != maskedValue && strings.Contains(value, ";") {
+		parts := strings.Split(value, ";")
+		lines := make([]string, 0, len(parts))
+		for i, part := range parts {
+			lines = append(lines, fmt.Sprintf("%3d  %s", i+1, part))
+		}
+		return strings.Join(lines, "\n")
+	}
+	return value
+}
+
+// newVariablesTab builds the Variables tab, a filterable and sortable table of all current environment variables
+// Selecting a row shows the full value in a details pane below the table, clicking the mark column marks it for bulk actions
+// The returned search function sets the filter, it is used by the command palette
+func newVariablesTab(parent fyne.Window, settings *Settings, isAdmin bool) (fyne.CanvasObject, func(query string)) {
+	var all, shown []ScopedVariable
+	var pending pendingIndex
+	var managed managedIndex
+	marked := map[string]bool{} // Keys of variables marked for bulk actions
+	markKey := func(v ScopedVariable) string { return v.Scope + "/" + strings.ToUpper(v.Name) }
+
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("Filter by name or value, tag:java or owner:name...")
+
+	detailsLabel := widget.NewLabel("Select a variable to see its full value.")
+	detailsLabel.Wrapping = fyne.TextWrapBreak
+	detailsScroll := container.NewVScroll(detailsLabel)
+	detailsScroll.SetMinSize(fyne.NewSize(0, 140))
+
+	var reload func()
+	favoritesStrip, refreshFavorites := newFavoritesStrip(parent, settings, isAdmin, func() { reload() })
+
+	markedLabel := widget.NewLabel("")
+	pendingLabel := widget.NewLabel("")
+	updateAdminState := func() {} // Set once the action buttons exist
+	updateMarked := func() {
+		count := 0
+		for _, v := range all {
+			if marked[markKey(v)] {
+				count++
+			}
+		}
+		markedLabel.SetText(fmt.Sprintf("%d marked", count))
+		updateAdminState()
+	}
+
+	table := newSortableTable([]tableColumn{
+		{Title: "Mark", Width: 60,
+			Cell: func(row int) string {
+				if marked[markKey(shown[row])] {
+					return "[x]"
+				}
+				return "[ ]"
+			},
+			OnTapped: func(row int) {
+				key := markKey(shown[row])
+				marked[key] = !marked[key]
+				updateMarked()
+			}},
+		{Title: "Name", Width: 220, Cell: func(row int) string { return shown[row].Name }},
+		{Title: "Owner", Width: 110, Cell: func(row int) string {
+			_, ok := managed.lookup(shown[row].Scope, shown[row].Name)
+			switch {
+			case ok && shown[row].Owner != "":
+				return "● " + shown[row].Owner
+			case ok:
+				return "● managed"
+			}
+			return shown[row].Owner
+		}},
+		{Title: "Tags", Width: 130, Cell: func(row int) string { return strings.Join(shown[row].Tags, ", ") }},
+		{Title: "Scope", Width: 80, Cell: func(row int) string { return shown[row].Scope }},
+		{Title: "Type", Width: 80, Cell: func(row int) string { return shown[row].typeLabel() }},
+		{Title: "Value", Width: 380, Cell: func(row int) string { return shown[row].displayValue(settings.SensitivePatterns) }},
+		{Title: "Modified", Width: 130, Cell: func(row int) string {
+			if m, ok := managed.lookup(shown[row].Scope, shown[row].Name); ok {
+				modified, _ := m.lastModified()
+				return modified.Local().Format(managedTimeLayout)
+			}
+			return ""
+		}},
+		{Title: "Modified By", Width: 160, Cell: func(row int) string {
+			if m, ok := managed.lookup(shown[row].Scope, shown[row].Name); ok {
+				_, by := m.lastModified()
+				return filepath.Base(by)
+			}
+			return ""
+		}},
+		{Title: "Session", Width: 150, Cell: func(row int) string { return pending.status(shown[row].Name) }},
+	}, func() int { return len(shown) })
+
+	table.OnSelected = func(row int) {
+		v := shown[row]
+		details := fmt.Sprintf("%s (%s, %s)\n\n%s", v.Name, v.Scope, v.typeLabel(), formatValueDetails(v.Variable, settings.SensitivePatterns))
+		if m, ok := managed.lookup(v.Scope, v.Name); ok {
+			details += "\n\n● " + m.describe()
+		} else {
+			details += "\n\nUnmanaged: not created by this tool."
+		}
+		if len(v.Tags) > 0 {
+			details += "\nTags: " + strings.Join(v.Tags, ", ")
+		}
+		if v.Owner != "" {
+			details += "\nOwner: " + v.Owner
+		}
+		if pending.restartRequired(v.Name) {
+			details += "\n\n⟳ This value changed after running programs were started. Restart them (or use 'Refresh Running Consoles') to pick it up."
+		}
+		detailsLabel.SetText(details)
+		updateAdminState()
+	}
+
+	applyFilter := func() {
+		needle := strings.ToUpper(strings.TrimSpace(filterEntry.Text))
+		shown = nil
+		for _, v := range all {
+			if matches, ok := v.matchesTagFilter(needle); ok {
+				if matches {
+					shown = append(shown, v)
+				}
+				continue
+			}
+			if needle == "" || strings.Contains(strings.ToUpper(v.Name), needle) ||
+				(!v.isSensitive(settings.SensitivePatterns) && strings.Contains(strings.ToUpper(v.Value), needle)) {
+				shown = append(shown, v)
+			}
+		}
+		table.clearSelection()
+		detailsLabel.SetText("Select a variable to see its full value.")
+		updateAdminState()
+		table.Refresh()
+	}
+	filterEntry.OnChanged = func(string) { applyFilter() }
+
+	reload = func() {
+		loaded, err := readAllVariables()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error reading environment variables: %v", err), parent)
+		}
+		all = loaded
+		if pending, err = loadPendingIndex(); err != nil {
+			fmt.Printf("Warning: Could not compare with the inherited environment: %v\n", err)
+		}
+		if managed, err = loadManagedIndex(); err != nil {
+			fmt.Printf("Warning: Could not load managed variables: %v\n", err)
+		}
+		tags, err := loadTagIndex()
+		if err != nil {
+			fmt.Printf("Warning: Could not load tags: %v\n", err)
+		}
+		for i := range all {
+			if t, ok := tags.lookup(all[i].Scope, all[i].Name); ok {
+				all[i].Tags, all[i].Owner = t.Tags, t.Owner
+			}
+		}
+		names := make([]string, 0, len(all))
+		for _, v := range all {
+			names = append(names, v.Name)
+		}
+		if n := pending.count(names); n > 0 {
+			pendingLabel.SetText(fmt.Sprintf("⟳ %d variable(s) changed since running programs were started, restart them to pick up the new values.", n))
+		} else {
+			pendingLabel.SetText("")
+		}
+		// Drop marks of variables that no longer exist
+		existing := map[string]bool{}
+		for _, v := range all {
+			existing[markKey(v)] = true
+		}
+		for key := range marked {
+			if !existing[key] {
+				delete(marked, key)
+			}
+		}
+		updateMarked()
+		refreshFavorites(all)
+		applyFilter()
+	}
+	reload()
+
+	refreshButton := widget.NewButton("Refresh", reload)
+	newButton := widget.NewButton("New Variable", func() {
+		showNewVariableDialog(parent, settings, isAdmin, reload)
+	})
+	findReplaceButton := widget.NewButton("Find & Replace", func() {
+		showFindReplaceWindow(settings, isAdmin, reload)
+	})
+	orphanedButton := widget.NewButton("Orphaned Managed...", func() {
+		showManagedReportWindow(settings, isAdmin, reload)
+	})
+
+	// targets returns the marked variables, or the selected one when nothing is marked
+	targets := func() []ScopedVariable {
+		var result []ScopedVariable
+		for _, v := range all {
+			if marked[markKey(v)] {
+				result = append(result, v)
+			}
+		}
+		if len(result) == 0 && table.selected >= 0 {
+			result = append(result, shown[table.selected])
+		}
+		return result
+	}
+
+	// Deleted variables are kept in the session trash and can be restored from the Trash tab
+	deleteButton := widget.NewButton("Delete", func() {
+		variables := targets()
+		if len(variables) == 0 {
+			dialog.ShowInformation("Error", "Please select or mark a variable first.", parent)
+			return
+		}
+		runBulkAction(bulkActionDelete, variables, parent, settings, isAdmin, reload)
+	})
+
+	bulkSelect := widget.NewSelect(availableBulkActions(), nil)
+	bulkSelect.PlaceHolder = "Bulk action..."
+	bulkSelect.OnChanged = func(action string) {
+		if action == "" {
+			return
+		}
+		bulkSelect.ClearSelected()
+		variables := targets()
+		if len(variables) == 0 {
+			dialog.ShowInformation("Error", "Please select or mark a variable first.", parent)
+			return
+		}
+		runBulkAction(action, variables, parent, settings, isAdmin, reload)
+	}
+
+	// Deleting system variables asks for administrator approval, the shield shows while the selection includes one
+	updateAdminState = func() { markElevates(deleteButton, isAdmin, elevates(targets(), isAdmin)) }
+	updateAdminState()
+
+	// Right-clicking a row offers to move the variable to the other scope, to edit list values entry by entry and to pin it to the favorites
+	table.OnSecondaryTapped = func(row int, pos fyne.Position) {
+		v := shown[row]
+		target := ScopeSystem
+		if v.Scope == ScopeSystem {
+			target = ScopeUser
+		}
+		pinLabel := "Pin to Favorites"
+		if settings.isFavorite(v.Name) {
+			pinLabel = "Unpin from Favorites"
+		}
+		var items []*fyne.MenuItem
+		if !readOnlyMode {
+			// Moving always writes the system environment, deleting only for system variables
+			moveItem := fyne.NewMenuItem(fmt.Sprintf("Move to %s scope", target), func() {
+				moveVariableScope(v, parent, settings, isAdmin, reload)
+			})
+			deleteItem := fyne.NewMenuItem("Delete", func() {
+				runBulkAction(bulkActionDelete, []ScopedVariable{v}, parent, settings, isAdmin, reload)
+			})
+			if !isAdmin {
+				moveItem.Icon = shieldIcon
+				if v.Scope == ScopeSystem {
+					deleteItem.Icon = shieldIcon
+				}
+			}
+			items = append(items, moveItem, deleteItem)
+		}
+		if rule, ok := settings.listRuleFor(v.Name); ok {
+			items = append(items, fyne.NewMenuItem("Edit List...", func() {
+				showListEditorWindow(settings, isAdmin, v, rule, reload)
+			}))
+		}
+		items = append(items, fyne.NewMenuItem("Edit Tags...", func() {
+			showTagDialog([]ScopedVariable{v}, parent, reload)
+		}))
+		items = append(items, fyne.NewMenuItem(pinLabel, func() {
+			if err := setFavorite(settings, v.Name, !settings.isFavorite(v.Name)); err != nil {
+				dialog.ShowError(fmt.Errorf("error saving settings: %v", err), parent)
+			}
+			refreshFavorites(all)
+		}))
+		widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), parent.Canvas(), pos)
+	}
+
+	markAllButton := widget.NewButton("Mark Shown", func() {
+		for _, v := range shown {
+			marked[markKey(v)] = true
+		}
+		updateMarked()
+		table.Refresh()
+	})
+	clearMarksButton := widget.NewButton("Clear Marks", func() {
+		marked = map[string]bool{}
+		updateMarked()
+		table.Refresh()
+	})
+
+	hideInReadOnly(newButton, deleteButton)
+
+	search := func(query string) {
+		filterEntry.SetText(query)
+		parent.Canvas().Focus(filterEntry)
+	}
+
+	return container.NewBorder(
+		container.NewVBox(
+			favoritesStrip,
+			container.NewBorder(nil, nil, nil, container.NewHBox(newButton, deleteButton, refreshButton), filterEntry),
+			container.NewHBox(markAllButton, clearMarksButton, bulkSelect, markedLabel, findReplaceButton, orphanedButton),
+		),
+		container.NewVBox(pendingLabel, widget.NewSeparator(), detailsScroll),
+		nil, nil,
+		table.table,
+	), search
+}
@@ -0,0 +1,282 @@
+// bulk.go
+// Bulk edit - actions applied to several variables marked in the Variables tab at once
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	sqweekdialog "github.com/sqweek/dialog"
+)
+
+// Bulk actions offered in the Variables tab
+const (
+	bulkActionDelete      = "Delete"
+	bulkActionExport      = "Export Selection..."
+	bulkActionChangeScope = "Move to Other Scope"
+	bulkActionAddToConfig = "Add to Config..."
+	bulkActionAffix       = "Add Prefix/Suffix to Values..."
+	bulkActionTag         = "Set Tags and Owner..."
+)
+
+// bulkActions lists the bulk actions in menu order
+var bulkActions = []string{bulkActionDelete, bulkActionExport, bulkActionChangeScope, bulkActionAddToConfig, bulkActionAffix, bulkActionTag}
+
+// availableBulkActions returns the bulk actions allowed in the current mode, read-only mode only offers those that don't modify the environment
+func availableBulkActions() []string {
+	if readOnlyMode {
+		return []string{bulkActionExport, bulkActionAddToConfig, bulkActionTag}
+	}
+	return bulkActions
+}
+
+// scopedConfig groups scoped variables into the user and system sections of a config
+func scopedConfig(variables []ScopedVariable) Config {
+	config := Config{Version: CurrentConfigVersion}
+	for _, v := range variables {
+		if v.Scope == ScopeSystem {
+			config.SystemVariables = append(config.SystemVariables, v.Variable)
+		} else {
+			config.UserVariables = append(config.UserVariables, v.Variable)
+		}
+	}
+	return config
+}
+
+// configChanges flattens the user and system sections of a config into scoped variables
+func configChanges(config Config) []ScopedVariable {
+	changes := make([]ScopedVariable, 0, len(config.UserVariables)+len(config.SystemVariables))
+	for _, v := range config.UserVariables {
+		changes = append(changes, ScopedVariable{Scope: ScopeUser, Variable: v})
+	}
+	for _, v := range config.SystemVariables {
+		changes = append(changes, ScopedVariable{Scope: ScopeSystem, Variable: v})
+	}
+	return changes
+}
+
+// deletionChanges returns delete operations for the given variables
+func deletionChanges(variables []ScopedVariable, sensitivePatterns []string) []ScopedVariable {
+	changes := make([]ScopedVariable, 0, len(variables))
+	for _, v := range variables {
+		changes = append(changes, ScopedVariable{Scope: v.Scope, Variable: Variable{Name: v.Name, Operation: "delete", Sensitive: v.isSensitive(sensitivePatterns)}})
+	}
+	return changes
+}
+
+// moveScopeChanges returns the operations that move each variable to the other scope, keeping value and type
+func moveScopeChanges(variables []ScopedVariable) []ScopedVariable {
+	var changes []ScopedVariable
+	for _, v := range variables {
+		target := ScopeSystem
+		if v.Scope == ScopeSystem {
+			target = ScopeUser
+		}
+		moved := v.Variable
+		moved.Operation = "set"
+		changes = append(changes,
+			ScopedVariable{Scope: target, Variable: moved},
+			ScopedVariable{Scope: v.Scope, Variable: Variable{Name: v.Name, Operation: "delete", Sensitive: v.Sensitive}},
+		)
+	}
+	return changes
+}
+
+// affixChanges returns set operations that wrap each value in prefix and suffix
+func affixChanges(variables []ScopedVariable, prefix, suffix string) []ScopedVariable {
+	changes := make([]ScopedVariable, 0, len(variables))
+	for _, v := range variables {
+		changed := v.Variable
+		changed.Operation = "set"
+		changed.Value = prefix + v.Value + suffix
+		changes = append(changes, ScopedVariable{Scope: v.Scope, Variable: changed})
+	}
+	return changes
+}
+
+// mergeIntoConfig adds or replaces the variables in a config file as set operations
+func mergeIntoConfig(filePath string, variables []ScopedVariable) error {
+	if isEncryptedConfig(filePath) {
+		return fmt.Errorf("cannot add variables to encrypted config %s: decrypt it first", filePath)
+	}
+	config, err := loadConfig(filePath)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range variables {
+		section := &config.UserVariables
+		if v.Scope == ScopeSystem {
+			section = &config.SystemVariables
+		}
+		entry := v.Variable
+		entry.Operation = "set"
+
+		replaced := false
+		for i, existing := range *section {
+			if strings.EqualFold(existing.Name, entry.Name) {
+				(*section)[i] = entry
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			*section = append(*section, entry)
+		}
+	}
+	return saveConfigToFile(config, filePath)
+}
+
+// variableNames lists the names of variables for confirmation messages
+func variableNames(variables []ScopedVariable) string {
+	names := make([]string, 0, len(variables))
+	for _, v := range variables {
+		names = append(names, fmt.Sprintf("%s (%s)", v.Name, v.Scope))
+	}
+	return strings.Join(names, "\n")
+}
+
+// runBulkAction performs a bulk action on the given variables, calling onDone after registry changes
+func runBulkAction(action string, variables []ScopedVariable, parent fyne.Window, settings *Settings, isAdmin bool, onDone func()) {
+	// apply writes changes after confirmation, reporting the outcome
+	apply := func(title, question string, changes []ScopedVariable) {
+		if elevates(changes, isAdmin) {
+			question += "\n\n" + elevationNotice
+		}
+		dialog.ShowConfirm(title, question, func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				if !confirmDangerousChanges(changes, *settings, parent) {
+					return
+				}
+				if err := applyDirectChanges("Variables tab: "+action, changes, isAdmin, *settings); err != nil {
+					dialog.ShowError(fmt.Errorf("error applying bulk action: %v", err), parent)
+				}
+				onDone()
+			}()
+		}, parent)
+	}
+
+	switch action {
+	case bulkActionDelete:
+		apply("Delete Variables", fmt.Sprintf("Delete %d variable(s)?\n\n%s\n\nThe old values are kept in the Trash tab until you purge them.", len(variables), variableNames(variables)),
+			deletionChanges(variables, settings.SensitivePatterns))
+
+	case bulkActionChangeScope:
+		if len(variables) == 1 {
+			moveVariableScope(variables[0], parent, settings, isAdmin, onDone)
+			return
+		}
+		apply("Move Variables", fmt.Sprintf("Move %d variable(s) to the other scope?\n\n%s", len(variables), variableNames(variables)), moveScopeChanges(variables))
+
+	case bulkActionAffix:
+		prefixEntry := widget.NewEntry()
+		suffixEntry := widget.NewEntry()
+		dialog.ShowForm("Add Prefix/Suffix", "Preview", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Prefix", prefixEntry),
+			widget.NewFormItem("Suffix", suffixEntry),
+		}, func(confirmed bool) {
+			if !confirmed || (prefixEntry.Text == "" && suffixEntry.Text == "") {
+				return
+			}
+			changes := affixChanges(variables, prefixEntry.Text, suffixEntry.Text)
+			lines := make([]string, 0, len(changes))
+			for _, c := range changes {
+				lines = append(lines, fmt.Sprintf("%s = %s", c.Name, c.displayValue(settings.SensitivePatterns)))
+			}
+			apply("Change Values", fmt.Sprintf("Set the following value(s)?\n\n%s", strings.Join(lines, "\n")), changes)
+		}, parent)
+
+	case bulkActionExport:
+		go func() {
+			savePath, err := sqweekdialog.File().Filter("YAML Config", "yaml", "yml").Save()
+			if err != nil || savePath == "" {
+				if err != nil && err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error saving file: %v", err), parent)
+				}
+				return
+			}
+			if !isValidYAMLFile(savePath) {
+				savePath += ".yaml"
+			}
+
+			config := scopedConfig(variables)
+			for i := range config.UserVariables {
+				config.UserVariables[i].Operation = "set"
+			}
+			for i := range config.SystemVariables {
+				config.SystemVariables[i].Operation = "set"
+			}
+			if settings.RedactOnExport {
+				config.UserVariables = redactSensitiveVariables(config.UserVariables, settings.SensitivePatterns)
+				config.SystemVariables = redactSensitiveVariables(config.SystemVariables, settings.SensitivePatterns)
+			}
+			if settings.PortableExport {
+				config.UserVariables = portableUserPaths(config.UserVariables)
+			}
+			if err := saveConfigToFile(config, savePath); err != nil {
+				dialog.ShowError(fmt.Errorf("error writing config to file: %v", err), parent)
+				return
+			}
+			dialog.ShowInformation("Export Success", fmt.Sprintf("%d variable(s) exported to:\n%s", len(variables), savePath), parent)
+		}()
+
+	case bulkActionTag:
+		showTagDialog(variables, parent, onDone)
+
+	case bulkActionAddToConfig:
+		go func() {
+			filePath, err := sqweekdialog.File().Filter("YAML Config", "yaml", "yml").Load()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error choosing file: %v", err), parent)
+				}
+				return
+			}
+			if err := mergeIntoConfig(filePath, variables); err != nil {
+				dialog.ShowError(fmt.Errorf("error updating config: %v", err), parent)
+				return
+			}
+			dialog.ShowInformation("Config Updated", fmt.Sprintf("%d variable(s) added to:\n%s", len(variables), filePath), parent)
+		}()
+	}
+}
+
+// moveVariableScope promotes a user variable to the system scope or demotes a system variable, keeping value and type
+// Without administrator privileges the system side of the move is written by the elevated helper
+func moveVariableScope(v ScopedVariable, parent fyne.Window, settings *Settings, isAdmin bool, onDone func()) {
+	target := ScopeSystem
+	if v.Scope == ScopeSystem {
+		target = ScopeUser
+	}
+
+	question := fmt.Sprintf("Move %s from the %s to the %s scope?", v.Name, v.Scope, target)
+	current, err := loadCurrentValueIndex()
+	if existing, exists := current.lookup(target, v.Name); err == nil && exists {
+		question += fmt.Sprintf("\n\nThe %s scope already defines it as:\n%s\nThat value will be replaced.", target, existing.displayValue(settings.SensitivePatterns))
+	}
+	if !isAdmin {
+		question += "\n\n" + elevationNotice
+	}
+
+	dialog.ShowConfirm("Move Variable", question, func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			changes := moveScopeChanges([]ScopedVariable{v})
+			if !confirmDangerousChanges(changes, *settings, parent) {
+				return
+			}
+			if err := applyDirectChanges("Variables tab: move to "+target, changes, isAdmin, *settings); err != nil {
+				dialog.ShowError(fmt.Errorf("error moving %s: %v", v.Name, err), parent)
+			}
+			onDone()
+		}()
+	}, parent)
+}
@@ -0,0 +1,201 @@
+// bundle.go
+// Profile bundles - every profile and environment snapshot packed into one zip based .evmbundle file for moving to another machine
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// bundleExtension is the file extension of profile bundles
+const bundleExtension = ".evmbundle"
+
+// Entry names and folders inside a profile bundle
+const (
+	bundleManifestEntry  = "bundle.yaml"
+	bundleProfilesFolder = "profiles"
+	bundleSnapshotFolder = "snapshots"
+)
+
+// bundleManifest is stored as bundle.yaml and lists what the bundle contains
+type bundleManifest struct {
+	Version   int      `yaml:"version"`             // Config schema version of this build
+	Created   string   `yaml:"created"`             // When the bundle was written
+	Host      string   `yaml:"host,omitempty"`      // Machine the bundle was exported from
+	Author    string   `yaml:"author,omitempty"`    // Account that exported the bundle
+	Profiles  []string `yaml:"profiles"`            // Profile names
+	Snapshots []string `yaml:"snapshots,omitempty"` // Snapshot file names
+}
+
+// bundleImportResult counts what an import wrote and skipped
+type bundleImportResult struct {
+	Profiles  int      // Profiles written
+	Snapshots int      // Snapshots written
+	Skipped   []string // Entries left alone because they already exist
+}
+
+// exportProfileBundle writes all profiles and, if requested, all snapshots of the backup directory into a bundle
+// Profile files are copied as they are, so extends, conditions and scripts survive unchanged
+func exportProfileBundle(filePath string, backup BackupSettings, includeSnapshots bool) (bundleManifest, error) {
+	hostname, _ := os.Hostname()
+	manifest := bundleManifest{
+		Version: CurrentConfigVersion,
+		Created: time.Now().Format(time.RFC3339),
+		Host:    hostname,
+		Author:  os.Getenv("USERNAME"),
+	}
+
+	dir, err := profilesDir()
+	if err != nil {
+		return manifest, err
+	}
+	profiles, err := listProfiles()
+	if err != nil {
+		return manifest, err
+	}
+	files := map[string]string{} // Entry name to source path
+	for _, name := range profiles {
+		manifest.Profiles = append(manifest.Profiles, name)
+		files[path.Join(bundleProfilesFolder, name+".yaml")] = filepath.Join(dir, name+".yaml")
+	}
+
+	if includeSnapshots {
+		backupDir, err := backup.backupDirectory()
+		if err != nil {
+			return manifest, err
+		}
+		snapshots, err := listSnapshots(backupDir)
+		if err != nil {
+			return manifest, err
+		}
+		for _, snapshot := range snapshots {
+			name := filepath.Base(snapshot)
+			manifest.Snapshots = append(manifest.Snapshots, name)
+			files[path.Join(bundleSnapshotFolder, name)] = snapshot
+		}
+	}
+	if len(files) == 0 {
+		return manifest, fmt.Errorf("there are no profiles or snapshots to export")
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to create bundle %s: %w", filePath, err)
+	}
+	defer file.Close()
+	writer := zip.NewWriter(file)
+
+	manifestData, err := yaml.Marshal(manifest)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to marshal %s: %w", bundleManifestEntry, err)
+	}
+	if err := writeZipEntry(writer, bundleManifestEntry, manifestData); err != nil {
+		return manifest, err
+	}
+	for entry, source := range files {
+		data, err := ioutil.ReadFile(source)
+		if err != nil {
+			return manifest, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		if err := writeZipEntry(writer, entry, data); err != nil {
+			return manifest, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return manifest, fmt.Errorf("failed to finalize bundle %s: %w", filePath, err)
+	}
+	return manifest, nil
+}
+
+// writeZipEntry adds a file to a zip archive
+func writeZipEntry(writer *zip.Writer, name string, data []byte) error {
+	w, err := writer.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	return nil
+}
+
+// importProfileBundle copies the profiles and snapshots of a bundle into this machine's profile and backup directories
+// Existing profiles are only replaced with overwrite; existing snapshots are always kept
+func importProfileBundle(filePath string, backup BackupSettings, overwrite bool) (bundleImportResult, error) {
+	var result bundleImportResult
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return result, fmt.Errorf("failed to open bundle %s: %w", filePath, err)
+	}
+	defer reader.Close()
+
+	hasManifest := false
+	for _, f := range reader.File {
+		if f.Name == bundleManifestEntry {
+			hasManifest = true
+		}
+	}
+	if !hasManifest {
+		return result, fmt.Errorf("%s is not a profile bundle: %s is missing", filePath, bundleManifestEntry)
+	}
+
+	for _, f := range reader.File {
+		folder, name := path.Split(f.Name)
+		folder = strings.TrimSuffix(folder, "/")
+		if name == "" || f.Name == bundleManifestEntry {
+			continue
+		}
+		data, err := readZipEntry(f)
+		if err != nil {
+			return result, err
+		}
+
+		switch folder {
+		case bundleProfilesFolder:
+			profile := strings.TrimSuffix(name, filepath.Ext(name))
+			target, err := profilePath(profile)
+			if err != nil {
+				return result, fmt.Errorf("bundle entry %s: %w", f.Name, err)
+			}
+			// Refuse broken profiles instead of importing something that cannot be applied later
+			if _, err := parseConfig(data); err != nil {
+				return result, fmt.Errorf("bundle entry %s: %w", f.Name, err)
+			}
+			if fileExists(target) && !overwrite {
+				result.Skipped = append(result.Skipped, "profile "+profile)
+				continue
+			}
+			if err := ioutil.WriteFile(target, data, 0644); err != nil {
+				return result, fmt.Errorf("failed to write profile %s: %w", profile, err)
+			}
+			result.Profiles++
+		case bundleSnapshotFolder:
+			if !strings.HasPrefix(name, backupFilePrefix) || !isValidYAMLFile(name) || strings.ContainsAny(name, `\:`) {
+				continue
+			}
+			backupDir, err := backup.backupDirectory()
+			if err != nil {
+				return result, err
+			}
+			target := filepath.Join(backupDir, name)
+			if fileExists(target) {
+				result.Skipped = append(result.Skipped, "snapshot "+name)
+				continue
+			}
+			if err := ioutil.WriteFile(target, data, 0644); err != nil {
+				return result, fmt.Errorf("failed to write snapshot %s: %w", name, err)
+			}
+			result.Snapshots++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,177 @@
+// capabilities.go
+// Capability report - elevation, UAC virtualization, remote registry, key write access and the last broadcast result
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// TOKEN_ELEVATION_TYPE values returned for TokenElevationType
+const (
+	tokenElevationTypeDefault = 1 // UAC disabled or not an administrator account
+	tokenElevationTypeFull    = 2 // Elevated administrator token
+	tokenElevationTypeLimited = 3 // Filtered token of an administrator, UAC can elevate it
+)
+
+// uacPolicyPath holds the EnableLUA switch below HKEY_LOCAL_MACHINE
+const uacPolicyPath = `SOFTWARE\Microsoft\Windows\CurrentVersion\Policies\System`
+
+// broadcastResult is the outcome of the most recent WM_SETTINGCHANGE broadcast
+type broadcastResult struct {
+	Time time.Time
+	Mode string
+	Err  error
+}
+
+var (
+	lastBroadcastMu sync.Mutex
+	lastBroadcast   *broadcastResult
+)
+
+// recordBroadcastResult remembers a broadcast outcome for the capability report
+func recordBroadcastResult(mode string, err error) {
+	lastBroadcastMu.Lock()
+	defer lastBroadcastMu.Unlock()
+	lastBroadcast = &broadcastResult{Time: time.Now(), Mode: mode, Err: err}
+}
+
+// tokenUint32 reads a DWORD-sized token information class of the current process token
+func tokenUint32(class uint32) (uint32, error) {
+	var value, returned uint32
+	err := windows.GetTokenInformation(windows.GetCurrentProcessToken(), class, (*byte)(unsafe.Pointer(&value)), uint32(unsafe.Sizeof(value)), &returned)
+	return value, err
+}
+
+// elevationCapability describes how the process runs and whether it could be elevated
+func elevationCapability(isAdmin bool) string {
+	elevationType, err := tokenUint32(windows.TokenElevationType)
+	if err != nil {
+		return fmt.Sprintf("unknown (%v)", err)
+	}
+	switch {
+	case elevationType == tokenElevationTypeFull:
+		return "elevated administrator"
+	case elevationType == tokenElevationTypeLimited:
+		return "unelevated administrator, UAC can elevate"
+	case isAdmin:
+		return "administrator (UAC disabled or built-in account)"
+	default:
+		return "standard user, elevation needs administrator credentials"
+	}
+}
+
+// virtualizationCapability reports whether UAC is enabled and whether registry virtualization applies to this process
+func virtualizationCapability() string {
+	uac := "unknown"
+	if key, err := registry.OpenKey(registry.LOCAL_MACHINE, uacPolicyPath, registry.QUERY_VALUE); err == nil {
+		if enabled, _, err := key.GetIntegerValue("EnableLUA"); err == nil {
+			uac = "off"
+			if enabled != 0 {
+				uac = "on"
+			}
+		}
+		key.Close()
+	}
+
+	virtualization := "unknown"
+	if enabled, err := tokenUint32(windows.TokenVirtualizationEnabled); err == nil {
+		virtualization = "off"
+		if enabled != 0 {
+			virtualization = "ON - writes may be redirected to the VirtualStore"
+		}
+	}
+	return fmt.Sprintf("UAC %s, virtualization %s", uac, virtualization)
+}
+
+// remoteRegistryCapability reports the state of the Remote Registry service
+func remoteRegistryCapability() string {
+	manager, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return fmt.Sprintf("unknown (%v)", err)
+	}
+	defer windows.CloseServiceHandle(manager)
+
+	name, _ := windows.UTF16PtrFromString("RemoteRegistry")
+	service, err := windows.OpenService(manager, name, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return fmt.Sprintf("not available (%v)", err)
+	}
+	defer windows.CloseServiceHandle(service)
+
+	var status windows.SERVICE_STATUS
+	if err := windows.QueryServiceStatus(service, &status); err != nil {
+		return fmt.Sprintf("unknown (%v)", err)
+	}
+	switch status.CurrentState {
+	case windows.SERVICE_RUNNING:
+		return "running"
+	case windows.SERVICE_STOPPED:
+		return "stopped (starts on demand if not disabled)"
+	default:
+		return fmt.Sprintf("service state %d", status.CurrentState)
+	}
+}
+
+// keyAccessCapability reports whether an Environment key can be written
+func keyAccessCapability(hive registry.Key, subkeyPath string) string {
+	if readOnlyMode {
+		return "disabled by read-only audit mode"
+	}
+	if err := checkScopeAccess(hive, subkeyPath); err != nil {
+		return fmt.Sprintf("not writable (%v)", err)
+	}
+	return "writable"
+}
+
+// broadcastCapability describes the last WM_SETTINGCHANGE broadcast
+func broadcastCapability() string {
+	lastBroadcastMu.Lock()
+	defer lastBroadcastMu.Unlock()
+	switch {
+	case lastBroadcast == nil:
+		return "not sent yet this session"
+	case lastBroadcast.Err != nil:
+		return fmt.Sprintf("FAILED at %s (%s): %v", lastBroadcast.Time.Format("15:04:05"), lastBroadcast.Mode, lastBroadcast.Err)
+	default:
+		return fmt.Sprintf("succeeded at %s (%s)", lastBroadcast.Time.Format("15:04:05"), lastBroadcast.Mode)
+	}
+}
+
+// capabilityReport returns the report lines shown in the capability panel
+func capabilityReport(isAdmin bool) []string {
+	return []string{
+		fmt.Sprintf("Elevation: %s", elevationCapability(isAdmin)),
+		fmt.Sprintf("UAC: %s", virtualizationCapability()),
+		fmt.Sprintf("Remote Registry: %s", remoteRegistryCapability()),
+		fmt.Sprintf("HKCU\\%s: %s", userEnvironmentPath, keyAccessCapability(registry.CURRENT_USER, userEnvironmentPath)),
+		fmt.Sprintf("HKLM\\%s: %s", systemEnvironmentPath, keyAccessCapability(registry.LOCAL_MACHINE, systemEnvironmentPath)),
+		fmt.Sprintf("Last WM_SETTINGCHANGE broadcast: %s", broadcastCapability()),
+	}
+}
+
+// newCapabilityPanel builds the collapsible capability report below the privilege level
+func newCapabilityPanel(adminStatus string, isAdmin bool) fyne.CanvasObject {
+	reportLabel := widget.NewLabel("")
+	refresh := func() {
+		go func() {
+			reportLabel.SetText(strings.Join(capabilityReport(isAdmin), "\n"))
+		}()
+	}
+	refresh()
+
+	item := widget.NewAccordionItem(fmt.Sprintf("Privilege Level: %s", adminStatus), container.NewVBox(
+		reportLabel,
+		container.NewHBox(widget.NewButton("Refresh", refresh)),
+	))
+	return widget.NewAccordion(item)
+}
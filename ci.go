@@ -0,0 +1,88 @@
+// ci.go
+// CI exports - GitHub Actions env blocks and GitLab CI variables blocks from a config
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// invalidSecretNameChars matches characters not allowed in CI secret names
+var invalidSecretNameChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// gitHubActionsExportFormat renders a workflow env: block
+var gitHubActionsExportFormat = exportFormat{
+	Name:        "GitHub Actions env:",
+	Description: "Workflow env: block. Sensitive variables reference ${{ secrets.NAME }} instead of carrying their value; create those repository secrets separately.",
+	Extension:   ".yml",
+	Render:      renderGitHubActionsEnv,
+}
+
+// gitLabCIExportFormat renders a .gitlab-ci.yml variables: block
+var gitLabCIExportFormat = exportFormat{
+	Name:        "GitLab CI variables:",
+	Description: "variables: block for .gitlab-ci.yml. Sensitive variables are left out with a comment, define them as masked CI/CD variables in the project settings.",
+	Extension:   ".yml",
+	Render:      renderGitLabCIVariables,
+}
+
+// ciSecretName converts a variable name into a valid CI secret name
+func ciSecretName(name string) string {
+	return invalidSecretNameChars.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+// yamlQuote returns value as a double-quoted YAML scalar, JSON strings are valid YAML
+func yamlQuote(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
+// ciExportHeader returns the comment lines written above CI blocks
+func ciExportHeader(config Config) string {
+	header := "# Generated by System Variable Manager"
+	if config.Metadata != nil && config.Metadata.Name != "" {
+		header += " from " + config.Metadata.Name
+	}
+	return header + "\n"
+}
+
+// renderGitHubActionsEnv renders config as a GitHub Actions env: block
+// Both scopes are merged and every name is written once, the runner treats names case-insensitively on Windows
+func renderGitHubActionsEnv(config Config) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString(ciExportHeader(config))
+	sb.WriteString("env:\n")
+	for _, v := range uniqueExportKeys(exportVariables(config), strings.ToUpper) {
+		if v.Sensitive {
+			secret := ciSecretName(v.Name)
+			fmt.Fprintf(&sb, "  %s: ${{ secrets.%s }} # Create repository secret %s\n", v.Name, secret, secret)
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s: %s\n", v.Name, yamlQuote(v.Value))
+	}
+	return []byte(sb.String()), nil
+}
+
+// renderGitLabCIVariables renders config as a GitLab CI variables: block, every name once since GitLab rejects duplicate keys
+// %VAR% references of expandable values become $VAR, which GitLab expands
+func renderGitLabCIVariables(config Config) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString(ciExportHeader(config))
+	sb.WriteString("variables:\n")
+	for _, v := range uniqueExportKeys(exportVariables(config), strings.ToUpper) {
+		if v.Sensitive {
+			fmt.Fprintf(&sb, "  # %s is sensitive: define it as a masked (and protected) variable under Settings > CI/CD > Variables\n", v.Name)
+			continue
+		}
+		value := v.Value
+		if v.isExpandable() {
+			value = expansionReference.ReplaceAllString(value, "$${$1}")
+		} else {
+			value = strings.ReplaceAll(value, "$", "$$") // Keep literal dollar signs from being expanded
+		}
+		fmt.Fprintf(&sb, "  %s: %s\n", v.Name, yamlQuote(value))
+	}
+	return []byte(sb.String()), nil
+}
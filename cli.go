@@ -0,0 +1,70 @@
+// cli.go
+// Command line parsing - flags and the optional config file argument
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// commandLineOptions holds the parsed command line
+type commandLineOptions struct {
+	ConfigPath    string // Config file to pre-select, also passed through UAC elevation
+	ReadOnly      bool   // --read-only: start in read-only audit mode
+	Portable      bool   // --portable: keep settings and state next to the executable
+	ApplySystem   string // --apply-system=<request>: run as the elevated helper writing system variables
+	ApplyProfile  string // --apply-profile=<name>: apply a profile unattended without a window, used by the logon task
+	Doctor        bool   // --doctor: print the doctor report and exit
+	Compliance    string // --compliance=<baseline>: print or write a compliance report against the baseline config and exit
+	ReportPath    string // --report=<file>: where --compliance writes its report, CSV or JSON by extension
+	QuietApply    bool   // --quiet-apply <file>: apply the config without a window and report the outcome in a toast
+	JSONErrors    bool   // --json-errors: report failures of the command line modes as JSON on stderr
+	Installer     string // --installer-apply=<file>: apply the config for an installer custom action without any dialog
+	LogPath       string // --log=<file>: where --installer-apply appends its log
+	Timeout       int    // --timeout=<seconds>: how long --installer-apply waits for the apply
+	ExportHistory string // --export-history=<file>: write the audit log as CSV or JSON by extension and exit
+}
+
+// parseCommandLine parses the arguments after the program name
+// Flags start with "--"; the first other argument is the config file
+func parseCommandLine(args []string) commandLineOptions {
+	var options commandLineOptions
+	for _, arg := range args {
+		switch {
+		case strings.EqualFold(arg, "--read-only"):
+			options.ReadOnly = true
+		case strings.EqualFold(arg, portableFlag):
+			options.Portable = true
+		case strings.HasPrefix(arg, elevatedApplyFlag):
+			options.ApplySystem = strings.TrimPrefix(arg, elevatedApplyFlag)
+		case strings.EqualFold(arg, doctorFlag):
+			options.Doctor = true
+		case strings.HasPrefix(arg, complianceFlag):
+			options.Compliance = strings.TrimPrefix(arg, complianceFlag)
+		case strings.HasPrefix(arg, reportFlag):
+			options.ReportPath = strings.TrimPrefix(arg, reportFlag)
+		case strings.EqualFold(arg, quietApplyFlag):
+			options.QuietApply = true
+		case strings.HasPrefix(arg, quietApplyFlag+"="):
+			options.QuietApply = true
+			options.ConfigPath = strings.TrimPrefix(arg, quietApplyFlag+"=")
+		case strings.EqualFold(arg, jsonErrorsFlag):
+			options.JSONErrors = true
+		case strings.HasPrefix(arg, installerApplyFlag):
+			options.Installer = strings.TrimPrefix(arg, installerApplyFlag)
+		case strings.HasPrefix(arg, logFlag):
+			options.LogPath = strings.TrimPrefix(arg, logFlag)
+		case strings.HasPrefix(arg, timeoutFlag):
+			options.Timeout, _ = strconv.Atoi(strings.TrimPrefix(arg, timeoutFlag)) // Invalid values fall back to the default
+		case strings.HasPrefix(arg, exportHistoryFlag):
+			options.ExportHistory = strings.TrimPrefix(arg, exportHistoryFlag)
+		case strings.HasPrefix(arg, logonApplyFlag):
+			options.ApplyProfile = strings.TrimPrefix(arg, logonApplyFlag)
+		case strings.HasPrefix(arg, "--"):
+			// Unknown flags are ignored so newer shortcuts keep working with older builds
+		case options.ConfigPath == "":
+			options.ConfigPath = arg
+		}
+	}
+	return options
+}
@@ -0,0 +1,370 @@
+// comparison.go
+// Environment comparison - compares two exports, or an export with this machine, and reports what only one side has or has differently
+// The differences can be written as a reconciliation config that makes one side match the other
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	sqweekdialog "github.com/sqweek/dialog"
+)
+
+// Categories of a comparison entry
+const (
+	comparisonOnlyOnA   = "only_on_a"
+	comparisonOnlyOnB   = "only_on_b"
+	comparisonDifferent = "different"
+)
+
+// comparisonEntry is one variable found on only one side or with different values
+type comparisonEntry struct {
+	Category string `json:"category"`
+	Scope    string `json:"scope"`
+	Name     string `json:"name"`
+	ValueA   string `json:"value_a,omitempty"` // Masked when sensitive
+	ValueB   string `json:"value_b,omitempty"`
+}
+
+// comparisonReport is the categorized comparison of two environments
+type comparisonReport struct {
+	SideA     string            `json:"side_a"`
+	SideB     string            `json:"side_b"`
+	Generated string            `json:"generated"`
+	Skipped   []string          `json:"skipped_scopes,omitempty"` // Scopes only one side contains, which are not compared
+	Entries   []comparisonEntry `json:"entries"`
+}
+
+// comparisonSide is an export file or, with an empty path, the live environment of this machine
+type comparisonSide struct {
+	Path string
+}
+
+// label names the side for the report
+func (s comparisonSide) label() string {
+	if s.Path != "" {
+		return s.Path
+	}
+	host, _ := os.Hostname()
+	return host + " (this machine)"
+}
+
+// load reads the environment of the side
+func (s comparisonSide) load(isAdmin bool) (Config, error) {
+	if s.Path == "" {
+		return exportEnvironmentVariables(isAdmin)
+	}
+	return loadConfig(s.Path)
+}
+
+// comparedScopes drops the scopes only one side contains, so a section missing from an export
+// (e.g. system variables exported without administrator rights) isn't reported as missing variables
+func comparedScopes(a, b Config) (Config, Config, []string) {
+	var skipped []string
+	if (len(a.UserVariables) == 0) != (len(b.UserVariables) == 0) {
+		a.UserVariables, b.UserVariables = nil, nil
+		skipped = append(skipped, ScopeUser)
+	}
+	if (len(a.SystemVariables) == 0) != (len(b.SystemVariables) == 0) {
+		a.SystemVariables, b.SystemVariables = nil, nil
+		skipped = append(skipped, ScopeSystem)
+	}
+	return a, b, skipped
+}
+
+// buildComparison compares the set entries of two environments, masking sensitive values
+func buildComparison(a, b Config, sideA, sideB string, patterns []string) comparisonReport {
+	a, b, skipped := comparedScopes(a, b)
+	report := comparisonReport{SideA: sideA, SideB: sideB, Generated: time.Now().Format(time.RFC3339), Skipped: skipped}
+	for _, c := range diffConfigs(a, b) {
+		mask := func(value string) string { return Variable{Name: c.Name, Value: value}.displayValue(patterns) }
+		entry := comparisonEntry{Scope: c.Scope, Name: c.Name, ValueA: mask(c.OldValue), ValueB: mask(c.NewValue)}
+		switch c.Kind {
+		case ChangeRemoved:
+			entry.Category = comparisonOnlyOnA
+		case ChangeAdded:
+			entry.Category = comparisonOnlyOnB
+		default:
+			entry.Category = comparisonDifferent
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	return report
+}
+
+// count returns the number of entries of a category
+func (r comparisonReport) count(category string) int {
+	n := 0
+	for _, e := range r.Entries {
+		if e.Category == category {
+			n++
+		}
+	}
+	return n
+}
+
+// describe summarizes the report in one line
+func (r comparisonReport) describe() string {
+	if len(r.Entries) == 0 {
+		return fmt.Sprintf("%s and %s have the same variables.", r.SideA, r.SideB)
+	}
+	return fmt.Sprintf("%d only on A, %d only on B, %d with different values",
+		r.count(comparisonOnlyOnA), r.count(comparisonOnlyOnB), r.count(comparisonDifferent))
+}
+
+// details lists the entries grouped by category
+func (r comparisonReport) details() string {
+	var lines []string
+	for _, s := range r.Skipped {
+		lines = append(lines, fmt.Sprintf("The %s variables are not compared because only one side contains them.", s))
+	}
+	sections := []struct{ category, title string }{
+		{comparisonOnlyOnA, "Only on A: " + r.SideA},
+		{comparisonOnlyOnB, "Only on B: " + r.SideB},
+		{comparisonDifferent, "Different values"},
+	}
+	for _, section := range sections {
+		if r.count(section.category) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("%s (%d)", section.title, r.count(section.category)))
+		for _, e := range r.Entries {
+			if e.Category != section.category {
+				continue
+			}
+			scope := strings.ToUpper(e.Scope)
+			switch e.Category {
+			case comparisonOnlyOnA:
+				lines = append(lines, fmt.Sprintf("  [%s] %s = %s", scope, e.Name, e.ValueA))
+			case comparisonOnlyOnB:
+				lines = append(lines, fmt.Sprintf("  [%s] %s = %s", scope, e.Name, e.ValueB))
+			default:
+				lines = append(lines, fmt.Sprintf("  [%s] %s\n      A: %s\n      B: %s", scope, e.Name, e.ValueA, e.ValueB))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderComparison renders the report as CSV when path ends in .csv and as JSON otherwise
+func renderComparison(r comparisonReport, path string) ([]byte, error) {
+	if !strings.HasSuffix(strings.ToLower(path), ".csv") {
+		return marshalExportJSON(r)
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true // Excel on Windows expects CRLF line endings
+	rows := [][]string{{"side_a", "side_b", "category", "scope", "name", "value_a", "value_b"}}
+	for _, e := range r.Entries {
+		rows = append(rows, []string{r.SideA, r.SideB, e.Category, e.Scope, e.Name, e.ValueA, e.ValueB})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to encode CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// reconciliationConfig builds the config that turns target into desired, keeping the type of the desired variables
+// Machine-specific Windows defaults such as PROCESSOR_* and TEMP are left alone
+func reconciliationConfig(target, desired Config) Config {
+	target, desired, _ = comparedScopes(target, desired)
+	find := func(variables []Variable, name string) Variable {
+		for _, v := range variables {
+			if v.Operation == "set" && strings.EqualFold(v.Name, name) {
+				return v
+			}
+		}
+		return Variable{Name: name}
+	}
+	config := Config{Version: CurrentConfigVersion}
+	for _, c := range diffConfigs(target, desired) {
+		if nameMatchesAny(c.Name, windowsDefaultVariables) {
+			continue
+		}
+		v := Variable{Name: c.Name, Operation: "delete"}
+		if c.Kind != ChangeRemoved {
+			source := desired.UserVariables
+			if c.Scope == ScopeSystem {
+				source = desired.SystemVariables
+			}
+			found := find(source, c.Name)
+			v = Variable{Name: c.Name, Value: c.NewValue, Operation: "set", Type: found.Type, Sensitive: found.Sensitive}
+		}
+		if c.Scope == ScopeSystem {
+			config.SystemVariables = append(config.SystemVariables, v)
+		} else {
+			config.UserVariables = append(config.UserVariables, v)
+		}
+	}
+	return config
+}
+
+// showComparisonWindow walks through choosing the two environments, then shows the categorized comparison
+// The report can be exported and the differences saved as a reconciliation config for either side
+func showComparisonWindow(settings *Settings, isAdmin bool) {
+	window := fyne.CurrentApp().NewWindow("Compare Environments")
+	window.Resize(fyne.NewSize(820, 560))
+
+	var sides [2]*comparisonSide
+	var configs [2]Config
+	var report *comparisonReport
+	sideLabels := [2]*widget.Label{widget.NewLabel("Not chosen."), widget.NewLabel("Not chosen.")}
+	summaryLabel := widget.NewLabel("Step 1: choose the two environments to compare.")
+	summaryLabel.Wrapping = fyne.TextWrapWord
+	detailsLabel := widget.NewLabel("")
+	detailsLabel.Wrapping = fyne.TextWrapBreak
+
+	compare := func() {
+		if sides[0] == nil || sides[1] == nil {
+			return
+		}
+		if sides[0].Path == "" && sides[1].Path == "" {
+			summaryLabel.SetText("⚠️  Choose an export for at least one side, this machine can only be one of them.")
+			return
+		}
+		summaryLabel.SetText("Comparing...")
+		a, b := *sides[0], *sides[1]
+		// Loading an encrypted export asks for its passphrase, which blocks until answered
+		go func() {
+			var loaded [2]Config
+			for i, side := range []comparisonSide{a, b} {
+				config, err := side.load(isAdmin)
+				if err != nil {
+					report = nil
+					summaryLabel.SetText(fmt.Sprintf("⚠️  Side %c: %v", 'A'+i, err))
+					detailsLabel.SetText("")
+					return
+				}
+				loaded[i] = config
+			}
+			r := buildComparison(loaded[0], loaded[1], a.label(), b.label(), settings.SensitivePatterns)
+			configs, report = loaded, &r
+			summaryLabel.SetText("Step 2: review the differences. " + r.describe())
+			detailsLabel.SetText(r.details())
+		}()
+	}
+
+	// choose returns the buttons selecting side i as an export file or this machine
+	choose := func(i int) fyne.CanvasObject {
+		fileButton := widget.NewButton("Choose Export...", func() {
+			go func() {
+				path, err := sqweekdialog.File().Title(fmt.Sprintf("Choose Export %c", 'A'+i)).Filter("YAML Config", "yaml", "yml").Filter("Encrypted Config", "enc").Filter("Environment Backup", "evmbackup").Filter("Registry Export", "reg").Filter("CSV Inventory", "csv").Load()
+				if err != nil {
+					if err.Error() != "cancelled" {
+						dialog.ShowError(fmt.Errorf("error selecting file: %v", err), window)
+					}
+					return
+				}
+				sides[i] = &comparisonSide{Path: path}
+				sideLabels[i].SetText(path)
+				compare()
+			}()
+		})
+		liveButton := widget.NewButton("This Machine", func() {
+			sides[i] = &comparisonSide{}
+			sideLabels[i].SetText(sides[i].label())
+			compare()
+		})
+		return container.NewBorder(nil, nil, nil, container.NewHBox(fileButton, liveButton), sideLabels[i])
+	}
+
+	exportButton := widget.NewButton("Export Report...", func() {
+		if report == nil {
+			dialog.ShowInformation("Compare Environments", "Choose both environments and let the comparison finish first.", window)
+			return
+		}
+		r := *report
+		go func() {
+			savePath, err := sqweekdialog.File().Title("Export Comparison").Filter("CSV Report", "csv").Filter("JSON Report", "json").Save()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error saving file: %v", err), window)
+				}
+				return
+			}
+			if !strings.HasSuffix(strings.ToLower(savePath), ".csv") && !strings.HasSuffix(strings.ToLower(savePath), ".json") {
+				savePath += ".csv"
+			}
+			data, err := renderComparison(r, savePath)
+			if err == nil {
+				err = ioutil.WriteFile(savePath, data, 0644)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error writing report: %v", err), window)
+				return
+			}
+			summaryLabel.SetText(fmt.Sprintf("%s\nReport written to: %s", r.describe(), savePath))
+		}()
+	})
+
+	// reconcile saves the config making side target match the other side
+	directionSelect := widget.NewSelect([]string{"Make B match A", "Make A match B"}, nil)
+	directionSelect.SetSelectedIndex(0)
+	reconcileButton := widget.NewButton("Generate Reconciliation Config...", func() {
+		if report == nil {
+			dialog.ShowInformation("Compare Environments", "Choose both environments and let the comparison finish first.", window)
+			return
+		}
+		target, desired := 1, 0
+		if directionSelect.SelectedIndex() == 1 {
+			target, desired = 0, 1
+		}
+		config := reconciliationConfig(configs[target], configs[desired])
+		if len(config.UserVariables) == 0 && len(config.SystemVariables) == 0 {
+			dialog.ShowInformation("Compare Environments", "There is nothing to reconcile in this direction.", window)
+			return
+		}
+		r := *report
+		sideNames := [2]string{r.SideA, r.SideB}
+		config.Metadata = &ConfigMetadata{
+			Name:        fmt.Sprintf("Reconcile %s with %s", sideNames[target], sideNames[desired]),
+			Description: fmt.Sprintf("Makes %s match %s, generated from an environment comparison", sideNames[target], sideNames[desired]),
+			Author:      os.Getenv("USERNAME"),
+			Created:     time.Now().Format(time.RFC3339),
+		}
+		go func() {
+			savePath, err := sqweekdialog.File().Title("Save Reconciliation Config").Filter("YAML Config", "yaml", "yml").Save()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error saving file: %v", err), window)
+				}
+				return
+			}
+			if !strings.HasSuffix(strings.ToLower(savePath), ".yaml") && !strings.HasSuffix(strings.ToLower(savePath), ".yml") {
+				savePath += ".yaml"
+			}
+			if err := saveConfigToFile(config, savePath); err != nil {
+				dialog.ShowError(fmt.Errorf("error writing config to file: %v", err), window)
+				return
+			}
+			summaryLabel.SetText(fmt.Sprintf("%s\nReconciliation config with %d change(s) written to: %s", r.describe(), len(configChanges(config)), savePath))
+		}()
+	})
+
+	window.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Compares two exports, or an export with this machine. Values are compared as stored, before expansion."),
+			widget.NewForm(
+				widget.NewFormItem("A", choose(0)),
+				widget.NewFormItem("B", choose(1)),
+			),
+			summaryLabel,
+		),
+		container.NewHBox(exportButton, directionSelect, reconcileButton, widget.NewButton("Close", window.Close)),
+		nil, nil,
+		container.NewVScroll(detailsLabel),
+	))
+	window.Show()
+}
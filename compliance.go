@@ -0,0 +1,343 @@
+// compliance.go
+// Compliance reports - compares this machine with a baseline config and exports the result as CSV or JSON for audits
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	sqweekdialog "github.com/sqweek/dialog"
+)
+
+// complianceFlag prints a compliance report for the given baseline config and exits
+const complianceFlag = "--compliance="
+
+// reportFlag writes the compliance report to a file instead of the console, CSV for .csv and JSON otherwise
+const reportFlag = "--report="
+
+// Status values of a compliance entry
+const (
+	complianceCompliant = "compliant" // Set as the baseline expects, or absent as it expects
+	complianceDrifted   = "drifted"   // Present with another value or type, or present although the baseline deletes it
+	complianceMissing   = "missing"   // Set by the baseline but absent here
+	complianceExtra     = "extra"     // Present here but not mentioned by the baseline
+)
+
+// windowsDefaultVariables are created by Windows itself and are not reported as extra
+var windowsDefaultVariables = []string{
+	"ComSpec", "DriverData", "NUMBER_OF_PROCESSORS", "OS", "PATHEXT", "PROCESSOR_*", "PSModulePath",
+	"TEMP", "TMP", "USERNAME", "windir", "OneDrive", "OneDriveConsumer", "OneDriveCommercial",
+}
+
+// complianceEntry is one variable of a compliance report
+type complianceEntry struct {
+	Scope    string `json:"scope"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"` // Baseline value, empty for deletes and extras
+	Actual   string `json:"actual,omitempty"`   // Value on this machine, empty when absent
+	Detail   string `json:"detail,omitempty"`   // Why a variable drifted
+}
+
+// complianceReport is the result of comparing this machine with a baseline
+type complianceReport struct {
+	Host      string            `json:"host"`
+	User      string            `json:"user"`
+	Baseline  string            `json:"baseline"`
+	Generated string            `json:"generated"`
+	Compliant bool              `json:"compliant"` // No drifted or missing variables, extras are informational
+	Summary   map[string]int    `json:"summary"`
+	Entries   []complianceEntry `json:"entries"`
+}
+
+// loadComplianceBaseline loads a baseline config the way an unattended apply would, so its values are final
+func loadComplianceBaseline(path string, settings Settings) (Config, error) {
+	config, err := loadConfigForMachine(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if config, err = resolveWithParamDefaults(config); err != nil {
+		return Config{}, err
+	}
+	return normalizeConfigPaths(config, settings), nil
+}
+
+// buildComplianceReport compares the set and delete entries of baseline with the registry
+// Variables the baseline does not mention are reported as extra in the scopes it covers
+func buildComplianceReport(baseline Config, baselineName string, patterns []string) (complianceReport, error) {
+	host, _ := os.Hostname()
+	report := complianceReport{
+		Host:      host,
+		User:      os.Getenv("USERNAME"),
+		Baseline:  baselineName,
+		Generated: time.Now().Format(time.RFC3339),
+		Summary:   map[string]int{complianceCompliant: 0, complianceDrifted: 0, complianceMissing: 0, complianceExtra: 0},
+	}
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		return report, err
+	}
+
+	mentioned := map[string]bool{}
+	covered := map[string]bool{}
+	sensitive := func(v Variable) bool { return v.isSensitive(patterns) }
+	mask := func(value string, secret bool) string {
+		if secret && value != "" {
+			return maskedValue
+		}
+		return value
+	}
+	check := func(scope string, variables []Variable) {
+		for _, v := range variables {
+			if v.Operation != "set" && v.Operation != "delete" {
+				continue
+			}
+			covered[scope] = true
+			mentioned[scope+"/"+strings.ToUpper(v.Name)] = true
+			existing, exists := current.lookup(scope, v.Name)
+			secret := sensitive(v) || (exists && sensitive(existing.Variable))
+			entry := complianceEntry{Scope: scope, Name: v.Name, Status: complianceCompliant}
+			if exists {
+				entry.Actual = mask(existing.Value, secret)
+			}
+			switch {
+			case v.Operation == "delete" && exists:
+				entry.Status, entry.Detail = complianceDrifted, "the baseline deletes this variable"
+			case v.Operation == "delete":
+			case !exists:
+				entry.Status = complianceMissing
+			case existing.Value != v.Value:
+				entry.Status, entry.Detail = complianceDrifted, "value differs"
+			case existing.typeLabel() != v.typeLabel():
+				entry.Status, entry.Detail = complianceDrifted, fmt.Sprintf("stored as %s, the baseline expects %s", existing.typeLabel(), v.typeLabel())
+			}
+			if v.Operation == "set" {
+				entry.Expected = mask(v.Value, secret)
+			}
+			report.Entries = append(report.Entries, entry)
+		}
+	}
+	check(ScopeUser, baseline.UserVariables)
+	check(ScopeSystem, baseline.SystemVariables)
+
+	for _, sv := range current {
+		if !covered[sv.Scope] || mentioned[sv.Scope+"/"+strings.ToUpper(sv.Name)] || nameMatchesAny(sv.Name, windowsDefaultVariables) {
+			continue
+		}
+		report.Entries = append(report.Entries, complianceEntry{Scope: sv.Scope, Name: sv.Name, Status: complianceExtra, Actual: mask(sv.Value, sensitive(sv.Variable))})
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if a.Scope != b.Scope {
+			return a.Scope == ScopeSystem
+		}
+		return strings.ToUpper(a.Name) < strings.ToUpper(b.Name)
+	})
+	for _, e := range report.Entries {
+		report.Summary[e.Status]++
+	}
+	report.Compliant = report.Summary[complianceDrifted] == 0 && report.Summary[complianceMissing] == 0
+	return report, nil
+}
+
+// describe summarizes the report in one line
+func (r complianceReport) describe() string {
+	verdict := "compliant"
+	if !r.Compliant {
+		verdict = "NOT compliant"
+	}
+	return fmt.Sprintf("%s is %s with %s: %d compliant, %d drifted, %d missing, %d extra",
+		r.Host, verdict, r.Baseline, r.Summary[complianceCompliant], r.Summary[complianceDrifted], r.Summary[complianceMissing], r.Summary[complianceExtra])
+}
+
+// renderComplianceCSV renders the report with one row per variable; the host column lets reports of a lab be concatenated
+func renderComplianceCSV(r complianceReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true // Excel on Windows expects CRLF line endings
+	rows := [][]string{{"host", "user", "baseline", "scope", "name", "status", "expected", "actual", "detail"}}
+	for _, e := range r.Entries {
+		rows = append(rows, []string{r.Host, r.User, r.Baseline, e.Scope, e.Name, e.Status, e.Expected, e.Actual, e.Detail})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to encode CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderCompliance renders the report as CSV when path ends in .csv and as JSON otherwise
+func renderCompliance(r complianceReport, path string) ([]byte, error) {
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		return renderComplianceCSV(r)
+	}
+	return marshalExportJSON(r)
+}
+
+// runComplianceCommand writes the compliance report for --compliance and returns the process exit code
+// It exits with 0 when the machine is compliant, 1 when it is not and 2 when the report could not be made
+func runComplianceCommand(baselinePath, reportPath string) int {
+	settings, err := loadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not load settings: %v\n", err)
+	}
+	baseline, err := loadComplianceBaseline(baselinePath, settings)
+	if err != nil {
+		return writeCommandError("Compliance check", err, exitValidation)
+	}
+	report, err := buildComplianceReport(baseline, baselinePath, settings.SensitivePatterns)
+	if err != nil {
+		return writeCommandError("Compliance check", err, exitValidation)
+	}
+
+	if reportPath == "" {
+		data, _ := marshalExportJSON(report)
+		os.Stdout.Write(data)
+	} else {
+		data, err := renderCompliance(report, reportPath)
+		if err == nil {
+			err = ioutil.WriteFile(reportPath, data, 0644)
+		}
+		if err != nil {
+			return writeCommandError("Compliance check", err, exitValidation)
+		}
+		fmt.Println(report.describe())
+		fmt.Printf("Report written to %s\n", reportPath)
+	}
+	if !report.Compliant {
+		return 1
+	}
+	return 0
+}
+
+// showComplianceWindow checks this machine against a baseline config and exports the report; selectedPath is the default baseline
+func showComplianceWindow(settings *Settings, selectedPath string) {
+	window := fyne.CurrentApp().NewWindow("Compliance Report")
+	window.Resize(fyne.NewSize(860, 560))
+
+	baselinePath := selectedPath
+	baselineLabel := widget.NewLabel("No baseline chosen.")
+	summaryLabel := widget.NewLabel("")
+	summaryLabel.Wrapping = fyne.TextWrapWord
+	entriesLabel := widget.NewLabel("")
+	entriesLabel.Wrapping = fyne.TextWrapBreak
+	var report *complianceReport
+
+	check := func() {
+		if baselinePath == "" {
+			return
+		}
+		baselineLabel.SetText("Baseline: " + baselinePath)
+		summaryLabel.SetText("Checking...")
+		// Loading an encrypted baseline asks for its passphrase, which blocks until answered
+		go func() {
+			baseline, err := loadComplianceBaseline(baselinePath, *settings)
+			var r complianceReport
+			if err == nil {
+				r, err = buildComplianceReport(baseline, baselinePath, settings.SensitivePatterns)
+			}
+			if err != nil {
+				report = nil
+				summaryLabel.SetText(fmt.Sprintf("⚠️  %v", err))
+				entriesLabel.SetText("")
+				return
+			}
+			report = &r
+			summaryLabel.SetText(r.describe())
+			var lines []string
+			for _, e := range r.Entries {
+				if e.Status == complianceCompliant {
+					continue
+				}
+				line := fmt.Sprintf("[%s] %s (%s)", e.Status, e.Name, e.Scope)
+				if e.Detail != "" {
+					line += ": " + e.Detail
+				}
+				if e.Expected != "" {
+					line += "\n    expected: " + e.Expected
+				}
+				if e.Actual != "" {
+					line += "\n    actual:   " + e.Actual
+				}
+				lines = append(lines, line)
+			}
+			if len(lines) == 0 {
+				lines = []string{"Every variable of the baseline matches."}
+			}
+			entriesLabel.SetText(strings.Join(lines, "\n"))
+		}()
+	}
+
+	chooseButton := widget.NewButton("Choose Baseline...", func() {
+		go func() {
+			path, err := sqweekdialog.File().Title("Choose Baseline Config").Filter("YAML Config", "yaml", "yml").Load()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error selecting file: %v", err), window)
+				}
+				return
+			}
+			baselinePath = path
+			check()
+		}()
+	})
+
+	export := func(title, extension string) func() {
+		return func() {
+			if report == nil {
+				dialog.ShowInformation("Compliance Report", "Choose a baseline and let the check finish first.", window)
+				return
+			}
+			r := *report
+			go func() {
+				savePath, err := sqweekdialog.File().Title(title).Filter(title, strings.TrimPrefix(extension, ".")).Save()
+				if err != nil {
+					if err.Error() != "cancelled" {
+						dialog.ShowError(fmt.Errorf("error saving file: %v", err), window)
+					}
+					return
+				}
+				if !strings.HasSuffix(strings.ToLower(savePath), extension) {
+					savePath += extension
+				}
+				data, err := renderCompliance(r, savePath)
+				if err == nil {
+					err = ioutil.WriteFile(savePath, data, 0644)
+				}
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("error writing report: %v", err), window)
+					return
+				}
+				summaryLabel.SetText(fmt.Sprintf("%s\nReport written to: %s", r.describe(), savePath))
+			}()
+		}
+	}
+
+	check()
+	window.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Compares this machine with a baseline config. Extra variables are listed in the scopes the baseline covers but do not fail the check."),
+			container.NewBorder(nil, nil, nil, chooseButton, baselineLabel),
+			summaryLabel,
+		),
+		container.NewHBox(
+			widget.NewButton("Recheck", check),
+			widget.NewButton("Export CSV...", export("CSV Report", ".csv")),
+			widget.NewButton("Export JSON...", export("JSON Report", ".json")),
+			widget.NewButton("Close", window.Close),
+		),
+		nil, nil,
+		container.NewVScroll(entriesLabel),
+	))
+	window.Show()
+}
@@ -0,0 +1,290 @@
+// conditions.go
+// Conditional entries - when_* selectors that limit variables and groups to matching machines
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// stringList is a YAML value that may be written as a single string or a list of strings
+type stringList []string
+
+// UnmarshalYAML accepts both `key: value` and `key: [a, b]`
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Conditions limit where a variable or group is applied, every condition that is set must match
+// Each condition lists alternatives of which at least one must match
+type Conditions struct {
+	WhenHost      stringList `yaml:"when_host,omitempty"`       // Hostname globs (or regex: expressions)
+	WhenOSVersion stringList `yaml:"when_os_version,omitempty"` // win10, win11, a version prefix like 10.0.19045 or a comparison like ">= 10.0.22000"
+	WhenUser      stringList `yaml:"when_user,omitempty"`       // User name globs, DOMAIN\name to also match the domain
+	When          string     `yaml:"when,omitempty"`            // Expression over host facts, e.g. "has_nvidia_gpu" or "ram_gb >= 32"
+}
+
+// VariableGroup is a set of variables sharing the same conditions
+type VariableGroup struct {
+	Conditions      `yaml:",inline"`
+	Name            string     `yaml:"name,omitempty"`             // Shown in logs when the group is skipped
+	UserVariables   []Variable `yaml:"user_variables,omitempty"`   // Added to the user variables when the group applies
+	SystemVariables []Variable `yaml:"system_variables,omitempty"` // Added to the system variables when the group applies
+}
+
+// evaluate reports whether the conditions match facts, or explains which condition did not
+func (c Conditions) evaluate(facts map[string]interface{}) (bool, string, error) {
+	if len(c.WhenHost) > 0 {
+		ok, err := matchesAnyPattern(c.WhenHost, scriptString(facts["hostname"]))
+		if err != nil {
+			return false, "", fmt.Errorf("when_host: %w", err)
+		}
+		if !ok {
+			return false, fmt.Sprintf("host %s is not %s", facts["hostname"], strings.Join(c.WhenHost, " or ")), nil
+		}
+	}
+	if len(c.WhenOSVersion) > 0 {
+		ok, err := matchesAnyOSVersion(c.WhenOSVersion, facts)
+		if err != nil {
+			return false, "", fmt.Errorf("when_os_version: %w", err)
+		}
+		if !ok {
+			return false, fmt.Sprintf("Windows %s (%s) is not %s", facts["os_version"], facts["os_name"], strings.Join(c.WhenOSVersion, " or ")), nil
+		}
+	}
+	if len(c.WhenUser) > 0 {
+		ok, err := matchesAnyUser(c.WhenUser, facts)
+		if err != nil {
+			return false, "", fmt.Errorf("when_user: %w", err)
+		}
+		if !ok {
+			return false, fmt.Sprintf("user %s\\%s is not %s", facts["user_domain"], facts["username"], strings.Join(c.WhenUser, " or ")), nil
+		}
+	}
+	if strings.TrimSpace(c.When) != "" {
+		ok, err := evaluateCondition(c.When, facts)
+		if err != nil {
+			return false, "", fmt.Errorf("when: %w", err)
+		}
+		if !ok {
+			return false, fmt.Sprintf("%q is false on this machine", c.When), nil
+		}
+	}
+	return true, "", nil
+}
+
+// matchesAnyUser reports whether the current user matches one of the selectors
+// Selectors containing a backslash are matched against DOMAIN\name, others against the user name alone
+func matchesAnyUser(selectors []string, facts map[string]interface{}) (bool, error) {
+	username := scriptString(facts["username"])
+	qualified := scriptString(facts["user_domain"]) + `\` + username
+	for _, selector := range selectors {
+		selector = strings.TrimSpace(selector)
+		value := username
+		if strings.Contains(selector, `\`) {
+			value = qualified
+			// Backslashes are escape characters in globs, match the domain separator literally
+			if !strings.HasPrefix(selector, regexPatternPrefix) {
+				selector = strings.ReplaceAll(selector, `\`, `\\`)
+			}
+		}
+		ok, err := matchesAnyPattern([]string{selector}, value)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchesAnyPattern reports whether value matches one of the name patterns
+func matchesAnyPattern(patterns []string, value string) (bool, error) {
+	for _, pattern := range patterns {
+		match, err := compileNamePattern(strings.TrimSpace(pattern))
+		if err != nil {
+			return false, err
+		}
+		if match(value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchesAnyOSVersion reports whether the running Windows version matches one of the selectors
+func matchesAnyOSVersion(selectors []string, facts map[string]interface{}) (bool, error) {
+	for _, selector := range selectors {
+		ok, err := matchesOSVersion(strings.TrimSpace(selector), facts)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchesOSVersion checks a single selector: a name like win11, a comparison like ">= 10.0.22000" or a version prefix
+func matchesOSVersion(selector string, facts map[string]interface{}) (bool, error) {
+	version := scriptString(facts["os_version"])
+	if strings.EqualFold(strings.ReplaceAll(selector, " ", ""), scriptString(facts["os_name"])) {
+		return true, nil
+	}
+	for _, op := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+		if strings.HasPrefix(selector, op) {
+			cmp, err := compareVersions(version, strings.TrimSpace(strings.TrimPrefix(selector, op)))
+			if err != nil {
+				return false, err
+			}
+			return matchesComparison(op, cmp), nil
+		}
+	}
+	if _, err := parseVersion(selector); err != nil {
+		return false, fmt.Errorf("unknown OS version selector %q", selector)
+	}
+	return version == selector || strings.HasPrefix(version, selector+"."), nil
+}
+
+// matchesComparison interprets a -1/0/1 comparison result for an operator
+func matchesComparison(op string, cmp int) bool {
+	switch op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+// parseVersion splits a dotted version into its numeric parts
+func parseVersion(version string) ([]int, error) {
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// compareVersions compares two dotted versions, missing parts count as zero
+func compareVersions(a, b string) (int, error) {
+	left, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	right, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var l, r int
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		switch {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// windowsVersion returns the running Windows version as major.minor.build and its marketing name (win10, win11, ...)
+func windowsVersion() (string, string) {
+	info := windows.RtlGetVersion()
+	version := fmt.Sprintf("%d.%d.%d", info.MajorVersion, info.MinorVersion, info.BuildNumber)
+	switch {
+	case info.MajorVersion == 10 && info.BuildNumber >= 22000:
+		return version, "win11"
+	case info.MajorVersion == 10:
+		return version, "win10"
+	case info.MajorVersion == 6 && info.MinorVersion == 3:
+		return version, "win8.1"
+	case info.MajorVersion == 6 && info.MinorVersion == 2:
+		return version, "win8"
+	case info.MajorVersion == 6 && info.MinorVersion == 1:
+		return version, "win7"
+	default:
+		return version, "windows"
+	}
+}
+
+// selectVariables keeps the variables whose conditions match facts, logging the ones that are skipped
+func selectVariables(variables []Variable, facts map[string]interface{}) ([]Variable, error) {
+	var selected []Variable
+	for _, v := range variables {
+		ok, reason, err := v.Conditions.evaluate(facts)
+		if err != nil {
+			return nil, fmt.Errorf("conditions of %s: %w", v.Name, err)
+		}
+		if !ok {
+			fmt.Printf("  Skipping %s: %s\n", v.Name, reason)
+			continue
+		}
+		v.Conditions = Conditions{}
+		selected = append(selected, v)
+	}
+	return selected, nil
+}
+
+// selectApplicableVariables flattens matching groups into config and drops every entry whose conditions do not match this machine
+func selectApplicableVariables(config Config) (Config, error) {
+	return selectApplicableVariablesWith(config, hostFacts())
+}
+
+// selectApplicableVariablesWith flattens matching groups into config and drops every entry whose conditions do not match facts
+func selectApplicableVariablesWith(config Config, facts map[string]interface{}) (Config, error) {
+	userVariables := append([]Variable{}, config.UserVariables...)
+	systemVariables := append([]Variable{}, config.SystemVariables...)
+	for i, group := range config.Groups {
+		name := group.Name
+		if name == "" {
+			name = fmt.Sprintf("group %d", i+1)
+		}
+		ok, reason, err := group.Conditions.evaluate(facts)
+		if err != nil {
+			return Config{}, fmt.Errorf("conditions of %s: %w", name, err)
+		}
+		if !ok {
+			fmt.Printf("  Skipping %s: %s\n", name, reason)
+			continue
+		}
+		userVariables = append(userVariables, group.UserVariables...)
+		systemVariables = append(systemVariables, group.SystemVariables...)
+	}
+
+	var err error
+	if config.UserVariables, err = selectVariables(userVariables, facts); err != nil {
+		return Config{}, err
+	}
+	if config.SystemVariables, err = selectVariables(systemVariables, facts); err != nil {
+		return Config{}, err
+	}
+	config.Groups = nil
+	return config, nil
+}
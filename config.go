@@ -0,0 +1,331 @@
+// config.go
+// Configuration schema - YAML structures, schema versioning and migration of older config files
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CurrentConfigVersion is the schema version written by this build of the application
+// Bump it and register a migration in configMigrations whenever the YAML structure changes
+const CurrentConfigVersion = 2
+
+// Registry value types a variable can be stored as
+const (
+	TypeString = "string" // REG_SZ, the value is stored literally
+	TypeExpand = "expand" // REG_EXPAND_SZ, %VAR% references are expanded when the environment is built
+)
+
+// Variable represents a single environment variable with its operation type
+type Variable struct {
+	Name        string           `yaml:"name"`                   // Environment variable name
+	Value       string           `yaml:"value"`                  // Environment variable value
+	ValueScript string           `yaml:"value_script,omitempty"` // Expression computing the value from host facts, replaces value when set
+	Operation   string           `yaml:"operation"`              // "set" to create/update, "delete" to remove, "delete_matching" to remove by pattern
+	Type        string           `yaml:"type,omitempty"`         // TypeString (default) or TypeExpand
+	Sensitive   bool             `yaml:"sensitive,omitempty"`    // Mask the value in the UI, logs and redacted exports
+	Pattern     string           `yaml:"pattern,omitempty"`      // Name glob (or regex: expression) used by delete_matching
+	Expires     string           `yaml:"expires,omitempty"`      // Duration (8h, 7d) or timestamp after which a set variable is removed again
+	Force       bool             `yaml:"force,omitempty"`        // Allow deleting or overwriting a protected variable
+	MatchedBy   string           `yaml:"-"`                      // Pattern that produced this deletion when expanded from delete_matching
+	Origin      string           `yaml:"-"`                      // Set for entries coming from or replacing an extended parent config
+	Modified    string           `yaml:"modified,omitempty"`     // Informational, written by exports: when this tool last changed a managed variable
+	ModifiedBy  string           `yaml:"modified_by,omitempty"`  // Informational, written by exports: the config or action of that change
+	Tags        []string         `yaml:"tags,omitempty"`         // Free-form group names, e.g. [java, corp-proxy], to filter, export, apply and delete by tag
+	Owner       string           `yaml:"owner,omitempty"`        // Person or team responsible for the variable
+	Conditions  `yaml:",inline"` // Optional when_* selectors limiting the machines the entry applies to
+}
+
+// ConfigMetadata describes a configuration file so applied configs are self-describing
+type ConfigMetadata struct {
+	Name        string   `yaml:"name,omitempty" json:"name,omitempty"`                 // Short human-readable config name
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`   // What the config is for
+	Author      string   `yaml:"author,omitempty" json:"author,omitempty"`             // Who wrote or exported the config
+	Created     string   `yaml:"created,omitempty" json:"created,omitempty"`           // Creation date, free-form
+	TargetHosts []string `yaml:"target_hosts,omitempty" json:"target_hosts,omitempty"` // Hostnames the config is intended for
+}
+
+// Config represents the structure of a YAML configuration file
+type Config struct {
+	Version         int             `yaml:"version"`               // Schema version, files without it are treated as version 1
+	Extends         stringList      `yaml:"extends,omitempty"`     // Parent configs this config inherits from, relative to this file
+	Metadata        *ConfigMetadata `yaml:"metadata,omitempty"`    // Optional description of the config
+	UserVariables   []Variable      `yaml:"user_variables"`        // Variables for current user only
+	SystemVariables []Variable      `yaml:"system_variables"`      // System-wide variables (requires admin)
+	Groups          []VariableGroup `yaml:"groups,omitempty"`      // Variables applied together when their group's conditions match
+	Mode            string          `yaml:"mode,omitempty"`        // ConfigModeMerge (default) or ConfigModeSync for both sections
+	UserMode        string          `yaml:"user_mode,omitempty"`   // Overrides mode for user_variables
+	SystemMode      string          `yaml:"system_mode,omitempty"` // Overrides mode for system_variables
+	Params          []ConfigParam   `yaml:"params,omitempty"`      // Values filled in through a form at apply time, see params.go
+	PathOrder       []PathOrderRule `yaml:"path_order,omitempty"`  // Ordering constraints for Path entries, see pathorder.go
+}
+
+// Config modes deciding what happens to managed variables a section does not list
+const (
+	ConfigModeMerge = "merge" // Additive: variables not listed are left alone
+	ConfigModeSync  = "sync"  // Declarative: managed variables not listed are deleted
+)
+
+// sectionMode returns the effective mode of the user or system section
+func (c Config) sectionMode(scope string) string {
+	mode := c.UserMode
+	if scope == ScopeSystem {
+		mode = c.SystemMode
+	}
+	if mode == "" {
+		mode = c.Mode
+	}
+	if mode == "" {
+		return ConfigModeMerge
+	}
+	return mode
+}
+
+// validateModes rejects unknown mode values, a typo must not silently turn sync into merge
+func (c Config) validateModes() error {
+	for key, mode := range map[string]string{"mode": c.Mode, "user_mode": c.UserMode, "system_mode": c.SystemMode} {
+		if mode != "" && mode != ConfigModeMerge && mode != ConfigModeSync {
+			return fmt.Errorf("invalid %s %q: use %q or %q", key, mode, ConfigModeMerge, ConfigModeSync)
+		}
+	}
+	return nil
+}
+
+// configDocument is the generic form of a YAML config used while migrating between schema versions
+type configDocument map[interface{}]interface{}
+
+// configMigration upgrades a raw config document from one schema version to the next
+type configMigration func(doc configDocument) error
+
+// configMigrations maps a schema version to the migration that upgrades it to the following version
+// Migrations operate on the raw document so structural changes (renamed or regrouped keys) can be handled
+var configMigrations = map[int]configMigration{
+	1: migrateV1ToV2,
+}
+
+// migrateV1ToV2 adds explicit value types, version 1 always wrote plain REG_SZ strings
+func migrateV1ToV2(doc configDocument) error {
+	for _, section := range []string{"user_variables", "system_variables"} {
+		raw, ok := doc[section]
+		if !ok || raw == nil {
+			continue
+		}
+		list, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be a list", section)
+		}
+		for _, item := range list {
+			entry, ok := item.(map[interface{}]interface{})
+			if !ok {
+				return fmt.Errorf("entries of %s must be mappings", section)
+			}
+			if _, hasType := entry["type"]; !hasType {
+				entry["type"] = TypeString
+			}
+		}
+	}
+	return nil
+}
+
+// loadConfig reads a YAML configuration file (plain or encrypted, or a backup archive or .reg export) from disk or a URL and migrates it to the current schema
+// A folder is loaded as the ordered set of the configs it contains
+func loadConfig(filePath string) (Config, error) {
+	if isRemoteConfig(filePath) {
+		cachePath, err := fetchRemoteConfig(filePath)
+		if err != nil {
+			return Config{}, err
+		}
+		filePath = cachePath
+	}
+	if isConfigFolder(filePath) {
+		return loadConfigFolder(filePath)
+	}
+	if isBackupArchive(filePath) {
+		return loadBackupArchive(filePath)
+	}
+	if isRegFile(filePath) {
+		return loadRegFile(filePath)
+	}
+	if isCSVFile(filePath) {
+		return loadCSVFile(filePath)
+	}
+
+	yamlFile, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading YAML file %s: %w", filePath, err)
+	}
+	if isEncryptedConfig(filePath) {
+		if yamlFile, err = decryptConfigFile(filePath, yamlFile); err != nil {
+			return Config{}, err
+		}
+	}
+	return parseConfig(yamlFile)
+}
+
+// parseConfig decodes YAML data into a Config, upgrading older schema versions along the way
+func parseConfig(data []byte) (Config, error) {
+	doc := configDocument{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Config{}, fmt.Errorf("error unmarshaling YAML: %w", err)
+	}
+
+	if err := migrateConfigDocument(doc); err != nil {
+		return Config{}, err
+	}
+
+	// Re-encode the migrated document so it can be decoded into the typed structure
+	migrated, err := yaml.Marshal(doc)
+	if err != nil {
+		return Config{}, fmt.Errorf("error encoding migrated config: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(migrated, &config); err != nil {
+		return Config{}, fmt.Errorf("error unmarshaling YAML: %w", err)
+	}
+	if err := config.validateModes(); err != nil {
+		return Config{}, err
+	}
+	if err := validateParams(config.Params); err != nil {
+		return Config{}, err
+	}
+	if err := validatePathOrder(config.PathOrder); err != nil {
+		return Config{}, err
+	}
+	if err := validateConfigTags(config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// migrateConfigDocument applies all registered migrations needed to bring doc up to CurrentConfigVersion
+func migrateConfigDocument(doc configDocument) error {
+	version, err := configDocumentVersion(doc)
+	if err != nil {
+		return err
+	}
+
+	if version > CurrentConfigVersion {
+		return fmt.Errorf("config schema version %d is newer than the supported version %d, please update the application", version, CurrentConfigVersion)
+	}
+
+	for version < CurrentConfigVersion {
+		migrate, ok := configMigrations[version]
+		if !ok {
+			return fmt.Errorf("no migration available from config schema version %d", version)
+		}
+		if err := migrate(doc); err != nil {
+			return fmt.Errorf("failed to migrate config from schema version %d: %w", version, err)
+		}
+		version++
+	}
+
+	doc["version"] = version
+	return nil
+}
+
+// configDocumentVersion extracts the schema version from a raw config document
+// Files written before versioning was introduced carry no version and are treated as version 1
+func configDocumentVersion(doc configDocument) (int, error) {
+	raw, ok := doc["version"]
+	if !ok || raw == nil {
+		return 1, nil
+	}
+
+	version, ok := raw.(int)
+	if !ok || version < 1 {
+		return 0, fmt.Errorf("invalid config schema version %v: must be a positive integer", raw)
+	}
+	return version, nil
+}
+
+// saveConfigToFile marshals a Config struct to YAML format and saves it to disk
+func saveConfigToFile(config Config, filePath string) error {
+	// Always stamp exported files with the schema version they were written in
+	config.Version = CurrentConfigVersion
+
+	yamlData, err := yaml.Marshal(&config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config to YAML: %w", err)
+	}
+
+	// Write YAML data to file with standard permissions
+	err = ioutil.WriteFile(filePath, yamlData, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write YAML to file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// targetsHost reports whether the metadata allows the config to be applied on the given host
+// Configs without a target host list are considered to target every machine
+func (m *ConfigMetadata) targetsHost(hostname string) bool {
+	if m == nil || len(m.TargetHosts) == 0 {
+		return true
+	}
+	for _, target := range m.TargetHosts {
+		if strings.EqualFold(strings.TrimSpace(target), hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe renders the metadata block as human-readable lines for the preview header
+func (m *ConfigMetadata) describe() []string {
+	if m == nil {
+		return nil
+	}
+
+	var lines []string
+	if m.Name != "" {
+		lines = append(lines, fmt.Sprintf("Config: %s", m.Name))
+	}
+	if m.Description != "" {
+		lines = append(lines, fmt.Sprintf("Description: %s", m.Description))
+	}
+	if m.Author != "" {
+		lines = append(lines, fmt.Sprintf("Author: %s", m.Author))
+	}
+	if m.Created != "" {
+		lines = append(lines, fmt.Sprintf("Created: %s", m.Created))
+	}
+	if len(m.TargetHosts) > 0 {
+		lines = append(lines, fmt.Sprintf("Target Hosts: %s", strings.Join(m.TargetHosts, ", ")))
+		if hostname, err := os.Hostname(); err == nil && !m.targetsHost(hostname) {
+			lines = append(lines, fmt.Sprintf("  ⚠️  WARNING: This machine (%s) is not one of the target hosts", hostname))
+		}
+	}
+	return lines
+}
+
+// isExpandable reports whether the variable is stored as REG_EXPAND_SZ
+func (v Variable) isExpandable() bool {
+	return strings.EqualFold(v.Type, TypeExpand)
+}
+
+// maxVariableValueLength is the largest value Windows accepts for a single environment variable, in characters
+const maxVariableValueLength = 32767
+
+// validateVariable checks that a variable can be stored in the environment
+func validateVariable(v Variable) error {
+	name := strings.TrimSpace(v.Name)
+	switch {
+	case name == "":
+		return fmt.Errorf("variable name must not be empty")
+	case name != v.Name:
+		return fmt.Errorf("variable name %q must not start or end with whitespace", v.Name)
+	case strings.ContainsAny(name, "=\x00"):
+		return fmt.Errorf("variable name %q must not contain '=' or NUL characters", v.Name)
+	case strings.ContainsRune(v.Value, 0):
+		return fmt.Errorf("value of %s must not contain NUL characters", v.Name)
+	case len([]rune(v.Value)) > maxVariableValueLength:
+		return fmt.Errorf("value of %s is longer than %d characters", v.Name, maxVariableValueLength)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// configfolder.go
+// Config folders - every config in a folder applied as one ordered set, later files overriding earlier ones
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isConfigFolder reports whether a config path is a local directory
+func isConfigFolder(path string) bool {
+	if isRemoteConfig(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// configFolderFiles lists the configs of a folder in apply order, subfolders and hidden files are skipped
+func configFolderFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config folder %s: %w", dir, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !isSupportedConfigFile(entry.Name()) {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.SliceStable(names, func(i, j int) bool { return configFolderLess(names[i], names[j]) })
+
+	files := make([]string, len(names))
+	for i, name := range names {
+		files[i] = filepath.Join(dir, name)
+	}
+	return files, nil
+}
+
+// configFolderLess orders file names by their numeric prefix, so 2-dev.yaml comes before 10-team.yaml
+// Names without a prefix follow the numbered ones, ties are broken alphabetically ignoring case
+func configFolderLess(a, b string) bool {
+	prefixA, hasA := fileNumberPrefix(a)
+	prefixB, hasB := fileNumberPrefix(b)
+	switch {
+	case hasA != hasB:
+		return hasA
+	case hasA && prefixA != prefixB:
+		return prefixA < prefixB
+	}
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+// fileNumberPrefix returns the number a file name starts with
+func fileNumberPrefix(name string) (int, bool) {
+	digits := 0
+	for digits < len(name) && name[digits] >= '0' && name[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name[:digits])
+	return n, err == nil
+}
+
+// loadConfigFolder turns a folder into a config extending each of its files in apply order
+// Merging them like parents means entries of later files replace earlier ones of the same name and scope, and the preview shows each entry's file
+func loadConfigFolder(dir string) (Config, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return Config{}, fmt.Errorf("error resolving %s: %w", dir, err)
+	}
+	files, err := configFolderFiles(absDir)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(files) == 0 {
+		return Config{}, fmt.Errorf("config folder %s contains no configs", dir)
+	}
+	return Config{Version: CurrentConfigVersion, Extends: stringList(files)}, nil
+}
+
+// configFolderSummary names the files of a folder in apply order for status messages
+func configFolderSummary(dir string) string {
+	files, err := configFolderFiles(dir)
+	if err != nil {
+		return err.Error()
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+	return fmt.Sprintf("%d config(s) applied in this order: %s", len(names), strings.Join(names, ", "))
+}
+
+// configStat returns the modification time and size of a config, for a folder the newest time of its configs and their total size
+func configStat(path string) (time.Time, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if !info.IsDir() {
+		return info.ModTime(), info.Size(), nil
+	}
+	files, err := configFolderFiles(path)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	newest, total := info.ModTime(), int64(0) // Adding or removing a file changes the folder's own time
+	for _, file := range files {
+		if fileInfo, err := os.Stat(file); err == nil {
+			if fileInfo.ModTime().After(newest) {
+				newest = fileInfo.ModTime()
+			}
+			total += fileInfo.Size()
+		}
+	}
+	return newest, total, nil
+}
@@ -0,0 +1,103 @@
+// confirm.go
+// Typed confirmation - makes the user type the variable name before dangerous changes are written
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultConfirmNames are variables whose deletion or overwrite can break a machine
+var defaultConfirmNames = []string{"PATH", "TEMP", "TMP", "ComSpec", "PATHEXT", "windir"}
+
+// isConfirmName reports whether name is one of names, compared case-insensitively like Windows does
+func isConfirmName(name string, names []string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dangerousChanges returns the changes that require a typed confirmation:
+// deleting or overwriting a listed variable with a different value, and deleting any system variable
+func dangerousChanges(changes []ScopedVariable, current currentValueIndex, names []string) []ScopedVariable {
+	var dangerous []ScopedVariable
+	for _, c := range changes {
+		existing, exists := current.lookup(c.Scope, c.Name)
+		if !exists {
+			continue // Creating a variable or deleting a missing one cannot destroy anything
+		}
+		switch c.Operation {
+		case "delete":
+			if c.Scope == ScopeSystem || isConfirmName(c.Name, names) {
+				dangerous = append(dangerous, c)
+			}
+		case "set":
+			if isConfirmName(c.Name, names) && existing.Value != c.Value {
+				dangerous = append(dangerous, c)
+			}
+		}
+	}
+	return dangerous
+}
+
+// confirmDangerousChanges asks the user to type the name of every dangerous change and blocks until answered
+// It returns true when there is nothing to confirm, typed confirmation is disabled, or every name was typed
+// Confirmed changes are marked with force, so typing the name of a protected variable allows writing it
+// Must not be called on the UI event thread since it waits for the dialog
+func confirmDangerousChanges(changes []ScopedVariable, settings Settings, parent fyne.Window) bool {
+	if !settings.TypedConfirmation {
+		return true
+	}
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		fmt.Printf("Warning: Could not read current values for confirmation: %v\n", err)
+	}
+	dangerous := dangerousChanges(changes, current, settings.ConfirmNames)
+	if len(dangerous) == 0 {
+		return true
+	}
+
+	notice := widget.NewLabel(fmt.Sprintf("%d change(s) affect variables that can break programs or the system.\nType each variable name to proceed.", len(dangerous)))
+	items := []*widget.FormItem{widget.NewFormItem("", notice)}
+	for _, c := range dangerous {
+		action := "Overwrite"
+		if c.Operation == "delete" {
+			action = "Delete"
+		}
+		name := c.Name
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder(name)
+		entry.Validator = func(text string) error {
+			if !strings.EqualFold(strings.TrimSpace(text), name) {
+				return fmt.Errorf("type %s to confirm", name)
+			}
+			return nil
+		}
+		items = append(items, widget.NewFormItem(fmt.Sprintf("%s %s (%s)", action, name, c.Scope), entry))
+	}
+
+	answer := make(chan bool)
+	form := dialog.NewForm("Confirm Dangerous Change", "Confirm", "Cancel", items, func(confirmed bool) {
+		answer <- confirmed
+	}, parent)
+	form.Resize(fyne.NewSize(520, 0))
+	form.Show()
+	if !<-answer {
+		return false
+	}
+	for i, c := range changes {
+		for _, d := range dangerous {
+			if c.Scope == d.Scope && strings.EqualFold(c.Name, d.Name) {
+				changes[i].Force = true
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+// conflicts.go
+// Scope conflicts - reports variables defined in both scopes with different values and offers to resolve them
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// scopeConflicts returns the variables whose user value shadows a different system value
+// PATH-like variables are concatenated rather than shadowed and are never conflicts
+func scopeConflicts(effective []EffectiveVariable) []EffectiveVariable {
+	var conflicts []EffectiveVariable
+	for _, e := range effective {
+		if e.shadowsSystem() && e.User.Value != e.System.Value {
+			conflicts = append(conflicts, e)
+		}
+	}
+	return conflicts
+}
+
+// Resolutions offered for a scope conflict
+const (
+	resolutionDeleteUser   = "Delete User Value"
+	resolutionDeleteSystem = "Delete System Value"
+	resolutionUseUser      = "Copy User Value to System"
+	resolutionUseSystem    = "Copy System Value to User"
+)
+
+// conflictResolutionChanges returns the registry changes that resolve a conflict
+func conflictResolutionChanges(conflict EffectiveVariable, resolution string) []ScopedVariable {
+	user, system := *conflict.User, *conflict.System
+	switch resolution {
+	case resolutionDeleteUser:
+		return []ScopedVariable{{Scope: ScopeUser, Variable: Variable{Name: user.Name, Operation: "delete", Sensitive: user.Sensitive}}}
+	case resolutionDeleteSystem:
+		return []ScopedVariable{{Scope: ScopeSystem, Variable: Variable{Name: system.Name, Operation: "delete", Sensitive: system.Sensitive}}}
+	case resolutionUseUser:
+		aligned := user
+		aligned.Name, aligned.Operation = system.Name, "set"
+		return []ScopedVariable{{Scope: ScopeSystem, Variable: aligned}}
+	case resolutionUseSystem:
+		aligned := system
+		aligned.Name, aligned.Operation = user.Name, "set"
+		return []ScopedVariable{{Scope: ScopeUser, Variable: aligned}}
+	}
+	return nil
+}
+
+// showConflictReportWindow lists all scope conflicts with one-click resolutions, onDone is called after a resolution
+func showConflictReportWindow(settings *Settings, isAdmin bool, onDone func()) {
+	window := fyne.CurrentApp().NewWindow("Scope Conflicts")
+	window.Resize(fyne.NewSize(900, 600))
+
+	rows := container.NewVBox()
+	var render func()
+	render = func() {
+		rows.RemoveAll()
+		effective, err := loadEffectiveEnvironment()
+		if err != nil {
+			rows.Add(widget.NewLabel(fmt.Sprintf("⚠️  Could not read environment variables: %v", err)))
+		}
+		conflicts := scopeConflicts(effective)
+		if len(conflicts) == 0 {
+			rows.Add(widget.NewLabel("No conflicts: no variable is defined in both scopes with different values."))
+		} else {
+			rows.Add(widget.NewLabel(fmt.Sprintf("%d variable(s) are defined in both scopes with different values. The user value always wins for new processes.", len(conflicts))))
+		}
+
+		for _, c := range conflicts {
+			c := c
+			sensitive := c.User.isSensitive(settings.SensitivePatterns) || c.System.isSensitive(settings.SensitivePatterns)
+			systemTokens, userTokens := wordDiff(c.System.Value, c.User.Value)
+
+			buttons := container.NewHBox()
+			for _, resolution := range []string{resolutionDeleteUser, resolutionDeleteSystem, resolutionUseUser, resolutionUseSystem} {
+				resolution := resolution
+				button := widget.NewButton(resolution, func() {
+					changes := conflictResolutionChanges(c, resolution)
+					dialog.ShowConfirm("Resolve Conflict", fmt.Sprintf("%s for %s?", resolution, c.Name), func(ok bool) {
+						if !ok {
+							return
+						}
+						go func() {
+							if !confirmDangerousChanges(changes, *settings, window) {
+								return
+							}
+							if err := applyDirectChanges("Scope conflict report", changes, isAdmin, *settings); err != nil {
+								dialog.ShowError(fmt.Errorf("error resolving %s: %v", c.Name, err), window)
+							}
+							render()
+							if onDone != nil {
+								onDone()
+							}
+						}()
+					}, window)
+				})
+				// Everything except deleting the user value writes to the system environment
+				markElevates(button, isAdmin, elevates(conflictResolutionChanges(c, resolution), isAdmin))
+				hideInReadOnly(button)
+				buttons.Add(button)
+			}
+
+			rows.Add(widget.NewSeparator())
+			rows.Add(widget.NewLabelWithStyle(fmt.Sprintf("%s  (user value wins)", c.Name), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			rows.Add(container.NewGridWithColumns(2,
+				widget.NewLabelWithStyle("System (shadowed)", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+				widget.NewLabelWithStyle("User (effective)", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+			))
+			rows.Add(container.NewGridWithColumns(2,
+				highlightedValueText(systemTokens, theme.ColorNameError, sensitive),
+				highlightedValueText(userTokens, theme.ColorNameSuccess, sensitive),
+			))
+			rows.Add(buttons)
+		}
+
+		if !isAdmin && len(conflicts) > 0 {
+			rows.Add(widget.NewSeparator())
+			rows.Add(widget.NewLabel("Resolutions marked with the shield change the system environment and ask for administrator approval."))
+		}
+		rows.Refresh()
+	}
+	render()
+
+	window.SetContent(container.NewBorder(
+		nil,
+		container.NewHBox(widget.NewButton("Refresh", render), widget.NewButton("Close", window.Close)),
+		nil, nil,
+		container.NewScroll(rows),
+	))
+	window.Show()
+}
+
+// describeConflictCount summarizes the number of conflicts for status text
+func describeConflictCount(effective []EffectiveVariable) string {
+	conflicts := scopeConflicts(effective)
+	if len(conflicts) == 0 {
+		return "No scope conflicts."
+	}
+	names := make([]string, 0, len(conflicts))
+	for _, c := range conflicts {
+		names = append(names, c.Name)
+	}
+	return fmt.Sprintf("%d scope conflict(s): %s", len(conflicts), strings.Join(names, ", "))
+}
@@ -0,0 +1,383 @@
+// consoles.go
+// Console session refresh - pushes updated environment variables into already running cmd/PowerShell processes
+// A small stub calling SetEnvironmentVariableW is executed inside each selected process via CreateRemoteThread
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows"
+)
+
+// consoleProcessNames lists the shell executables offered for a targeted environment refresh
+var consoleProcessNames = []string{"cmd.exe", "powershell.exe", "pwsh.exe"}
+
+const (
+	MEM_COMMIT_RESERVE    = 0x3000               // MEM_COMMIT | MEM_RESERVE allocation type
+	MEM_RELEASE           = 0x8000               // Free the whole remote allocation
+	remoteThreadTimeoutMs = 5000                 // Maximum time to wait for an injected call to finish
+	stillActive           = 259                  // GetExitCodeThread value for threads that are still running
+	injectAccessRights    = 0x043A               // PROCESS_CREATE_THREAD | VM_OPERATION | VM_READ | VM_WRITE | QUERY_INFORMATION
+	processQueryLimitInfo = 0x1000               // PROCESS_QUERY_LIMITED_INFORMATION, used when listing processes
+	maxUTF16PathLength    = 32767                // Upper bound for QueryFullProcessImageNameW buffers
+	stubHeaderSize        = 0x28                 // Shadow space plus alignment reserved by the injected stub
+	injectedStubLength    = 4 + 3*10 + 2 + 4 + 1 // sub rsp + three mov imm64 + call rax + add rsp + ret
+)
+
+var (
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procVirtualAllocEx     = kernel32.NewProc("VirtualAllocEx")
+	procVirtualFreeEx      = kernel32.NewProc("VirtualFreeEx")
+	procCreateRemoteThread = kernel32.NewProc("CreateRemoteThread")
+	procGetExitCodeThread  = kernel32.NewProc("GetExitCodeThread")
+	procSetEnvironmentVarW = kernel32.NewProc("SetEnvironmentVariableW")
+)
+
+// ConsoleProcess describes a running shell that can receive refreshed variables
+type ConsoleProcess struct {
+	PID  uint32 // Process identifier
+	Name string // Executable name, e.g. "powershell.exe"
+	Path string // Full executable path if it could be queried
+}
+
+// listConsoleProcesses enumerates running cmd/PowerShell processes owned by the current session
+func listConsoleProcesses() ([]ConsoleProcess, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot processes: %w", err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	// Shells of other sessions belong to other users, even an elevated instance must leave them alone
+	var session uint32
+	if err := windows.ProcessIdToSessionId(windows.GetCurrentProcessId(), &session); err != nil {
+		return nil, fmt.Errorf("failed to query the current session: %w", err)
+	}
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+
+	var processes []ConsoleProcess
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		name := windows.UTF16ToString(entry.ExeFile[:])
+		if !isConsoleProcessName(name) {
+			continue
+		}
+		var processSession uint32
+		if err := windows.ProcessIdToSessionId(entry.ProcessID, &processSession); err != nil || processSession != session {
+			continue
+		}
+		processes = append(processes, ConsoleProcess{
+			PID:  entry.ProcessID,
+			Name: name,
+			Path: processImagePath(entry.ProcessID),
+		})
+	}
+
+	sort.Slice(processes, func(i, j int) bool {
+		if processes[i].Name != processes[j].Name {
+			return processes[i].Name < processes[j].Name
+		}
+		return processes[i].PID < processes[j].PID
+	})
+	return processes, nil
+}
+
+// isConsoleProcessName checks whether an executable name is one of the supported shells
+func isConsoleProcessName(name string) bool {
+	for _, shell := range consoleProcessNames {
+		if strings.EqualFold(name, shell) {
+			return true
+		}
+	}
+	return false
+}
+
+// processImagePath returns the full executable path of a process, or an empty string if it is not accessible
+func processImagePath(pid uint32) string {
+	handle, err := windows.OpenProcess(processQueryLimitInfo, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(handle)
+
+	buf := make([]uint16, maxUTF16PathLength)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(handle, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return windows.UTF16ToString(buf[:size])
+}
+
+// refreshedValuesForConfig computes the value every config variable has in a freshly created environment
+// A nil value means the variable no longer exists and must be removed from the running process
+func refreshedValuesForConfig(config Config) (map[string]*string, error) {
+	current, err := freshEnvironmentMap()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]*string)
+	for _, v := range append(append([]Variable{}, config.UserVariables...), config.SystemVariables...) {
+		if v.isPatternOperation() {
+			continue // Pattern deletions have no single name to refresh
+		}
+		if value, ok := current[strings.ToUpper(v.Name)]; ok {
+			value := value
+			values[v.Name] = &value
+		} else {
+			values[v.Name] = nil
+		}
+	}
+	return values, nil
+}
+
+// injectEnvironment sets or removes each variable inside a running process
+func injectEnvironment(pid uint32, values map[string]*string) error {
+	if readOnlyMode {
+		return errReadOnly
+	}
+	if runtime.GOARCH != "amd64" {
+		return fmt.Errorf("console refresh is only supported on 64-bit builds")
+	}
+	if err := procSetEnvironmentVarW.Find(); err != nil {
+		return fmt.Errorf("cannot locate SetEnvironmentVariableW: %w", err)
+	}
+
+	process, err := windows.OpenProcess(injectAccessRights, false, pid)
+	if err != nil {
+		return fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(process)
+
+	// kernel32 is mapped at the same address in every process of the same architecture only
+	var isWow64 bool
+	if err := windows.IsWow64Process(process, &isWow64); err != nil {
+		return fmt.Errorf("failed to query architecture of process %d: %w", pid, err)
+	}
+	if isWow64 {
+		return fmt.Errorf("process %d is a 32-bit process and cannot be refreshed", pid)
+	}
+
+	// Apply variables in a stable order so failures are reported predictably
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failures []string
+	for _, name := range names {
+		if err := remoteSetEnvironmentVariable(process, name, values[name]); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to refresh %d variable(s) in process %d: %s", len(failures), pid, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// remoteSetEnvironmentVariable calls SetEnvironmentVariableW(name, value) inside another process
+func remoteSetEnvironmentVariable(process windows.Handle, name string, value *string) error {
+	nameUTF16, err := windows.UTF16FromString(name)
+	if err != nil {
+		return err
+	}
+	var valueUTF16 []uint16
+	if value != nil {
+		if valueUTF16, err = windows.UTF16FromString(*value); err != nil {
+			return err
+		}
+	}
+
+	// Remote layout: [stub][name][value]
+	size := uintptr(injectedStubLength + 2*len(nameUTF16) + 2*len(valueUTF16))
+	remote, _, callErr := procVirtualAllocEx.Call(uintptr(process), 0, size, MEM_COMMIT_RESERVE, windows.PAGE_READWRITE)
+	if remote == 0 {
+		return fmt.Errorf("VirtualAllocEx failed: %w", callErr)
+	}
+	// The allocation is leaked on purpose if the remote thread does not finish, freeing it would crash the target
+	release := true
+	defer func() {
+		if release {
+			procVirtualFreeEx.Call(uintptr(process), remote, 0, MEM_RELEASE)
+		}
+	}()
+
+	nameAddr := remote + injectedStubLength
+	valueAddr := uintptr(0)
+	if value != nil {
+		valueAddr = nameAddr + uintptr(2*len(nameUTF16))
+	}
+
+	payload := buildSetEnvironmentStub(nameAddr, valueAddr, procSetEnvironmentVarW.Addr())
+	payload = append(payload, utf16Bytes(nameUTF16)...)
+	payload = append(payload, utf16Bytes(valueUTF16)...)
+
+	if err := windows.WriteProcessMemory(process, remote, &payload[0], uintptr(len(payload)), nil); err != nil {
+		return fmt.Errorf("WriteProcessMemory failed: %w", err)
+	}
+
+	var oldProtect uint32
+	if err := windows.VirtualProtectEx(process, remote, size, windows.PAGE_EXECUTE_READ, &oldProtect); err != nil {
+		return fmt.Errorf("VirtualProtectEx failed: %w", err)
+	}
+
+	thread, _, callErr := procCreateRemoteThread.Call(uintptr(process), 0, 0, remote, 0, 0, 0)
+	if thread == 0 {
+		return fmt.Errorf("CreateRemoteThread failed: %w", callErr)
+	}
+	defer windows.CloseHandle(windows.Handle(thread))
+
+	if event, err := windows.WaitForSingleObject(windows.Handle(thread), remoteThreadTimeoutMs); err != nil || event != windows.WAIT_OBJECT_0 {
+		release = false
+		return fmt.Errorf("timed out waiting for remote call")
+	}
+
+	var exitCode uint32
+	if ret, _, callErr := procGetExitCodeThread.Call(thread, uintptr(unsafe.Pointer(&exitCode))); ret == 0 {
+		return fmt.Errorf("GetExitCodeThread failed: %w", callErr)
+	}
+	if exitCode == 0 || exitCode == stillActive {
+		return fmt.Errorf("SetEnvironmentVariableW reported failure")
+	}
+	return nil
+}
+
+// buildSetEnvironmentStub assembles the x64 thread routine that calls SetEnvironmentVariableW
+//
+//	sub rsp, 0x28 / mov rcx, name / mov rdx, value / mov rax, fn / call rax / add rsp, 0x28 / ret
+func buildSetEnvironmentStub(nameAddr, valueAddr, fnAddr uintptr) []byte {
+	stub := make([]byte, 0, injectedStubLength)
+	stub = append(stub, 0x48, 0x83, 0xEC, stubHeaderSize)
+	stub = appendMovImm64(stub, 0xB9, nameAddr)
+	stub = appendMovImm64(stub, 0xBA, valueAddr)
+	stub = appendMovImm64(stub, 0xB8, fnAddr)
+	stub = append(stub, 0xFF, 0xD0)
+	stub = append(stub, 0x48, 0x83, 0xC4, stubHeaderSize)
+	stub = append(stub, 0xC3)
+	return stub
+}
+
+// appendMovImm64 encodes "mov <reg>, imm64" where opcode selects the destination register
+func appendMovImm64(code []byte, opcode byte, value uintptr) []byte {
+	code = append(code, 0x48, opcode)
+	var imm [8]byte
+	binary.LittleEndian.PutUint64(imm[:], uint64(value))
+	return append(code, imm[:]...)
+}
+
+// utf16Bytes converts a UTF-16 slice into its little-endian byte representation
+func utf16Bytes(s []uint16) []byte {
+	b := make([]byte, 2*len(s))
+	for i, c := range s {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+// showConsoleRefreshWindow lets the user pick running shells and pushes the config's variables into them
+func showConsoleRefreshWindow(app fyne.App, config Config) {
+	refreshWindow := app.NewWindow("Refresh Running Consoles")
+	refreshWindow.Resize(fyne.NewSize(600, 400))
+
+	processes, err := listConsoleProcesses()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("error listing console processes: %v", err), refreshWindow)
+	}
+
+	selected := make(map[uint32]bool)
+	checks := container.NewVBox()
+	for _, p := range processes {
+		p := p
+		label := fmt.Sprintf("%s (PID %d)", p.Name, p.PID)
+		if p.Path != "" {
+			label = fmt.Sprintf("%s  -  %s", label, p.Path)
+		}
+		checks.Add(widget.NewCheck(label, func(checked bool) {
+			selected[p.PID] = checked
+		}))
+	}
+	if len(processes) == 0 {
+		checks.Add(widget.NewLabel("No running cmd.exe, powershell.exe or pwsh.exe processes were found."))
+	}
+
+	resultLabel := widget.NewLabel("")
+	resultLabel.Wrapping = fyne.TextWrapWord
+
+	refreshButton := widget.NewButton("Refresh Selected", func() {
+		values, err := refreshedValuesForConfig(config)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error building refreshed environment: %v", err), refreshWindow)
+			return
+		}
+
+		var targets []ConsoleProcess
+		for _, p := range processes {
+			if selected[p.PID] {
+				targets = append(targets, p)
+			}
+		}
+		if len(targets) == 0 {
+			resultLabel.SetText("No processes selected.")
+			return
+		}
+
+		// Run as a job, a target that does not respond keeps its remote call waiting for the timeout
+		resultLabel.SetText(fmt.Sprintf("Refreshing %d process(es)...", len(targets)))
+		backgroundJobs.submit(jobKindApply, fmt.Sprintf("Refresh %d running console(s)", len(targets)), func(ctx context.Context) error {
+			var results []string
+			failed := 0
+			for _, p := range targets {
+				if jobCancelled(ctx) {
+					resultLabel.SetText(strings.Join(append(results, "Cancelled."), "\n"))
+					return errJobCancelled
+				}
+				if err := injectEnvironment(p.PID, values); err != nil {
+					results = append(results, fmt.Sprintf("%s (PID %d): %v", p.Name, p.PID, err))
+					failed++
+				} else {
+					results = append(results, fmt.Sprintf("%s (PID %d): refreshed %d variable(s)", p.Name, p.PID, len(values)))
+				}
+			}
+			resultLabel.SetText(strings.Join(results, "\n"))
+			if failed > 0 {
+				return fmt.Errorf("%d of %d console(s) could not be refreshed", failed, len(targets))
+			}
+			return nil
+		})
+	})
+
+	closeButton := widget.NewButton("Close", func() {
+		refreshWindow.Close()
+	})
+
+	windowContent := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Select running consoles to receive the variables from the selected config:"),
+			widget.NewSeparator(),
+		),
+		container.NewVBox(
+			widget.NewSeparator(),
+			resultLabel,
+			container.NewHBox(refreshButton, closeButton),
+		),
+		nil, nil,
+		container.NewScroll(checks),
+	)
+
+	refreshWindow.SetContent(windowContent)
+	refreshWindow.Show()
+}
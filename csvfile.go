@@ -0,0 +1,151 @@
+// csvfile.go
+// CSV inventories - imports and exports variables as name,value,scope,operation rows for spreadsheets
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// csvFileExtension is the file extension of CSV inventories
+const csvFileExtension = ".csv"
+
+// csvColumns are the columns of an inventory in the order they are exported; type is optional on import
+var csvColumns = []string{"name", "value", "scope", "operation", "type"}
+
+// csvExportFormat renders a CSV inventory that can be chosen as a config again
+var csvExportFormat = exportFormat{
+	Name:        "CSV inventory",
+	Description: "One row per variable with name, value, scope, operation and type columns, for inventories kept in spreadsheets. The file can be chosen as a config again.",
+	Extension:   csvFileExtension,
+	Render:      renderCSVInventory,
+}
+
+// isCSVFile checks if the provided file path has the .csv extension
+func isCSVFile(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), csvFileExtension)
+}
+
+// loadCSVFile reads a CSV inventory from disk and converts its rows into a Config
+func loadCSVFile(filePath string) (Config, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading CSV file %s: %w", filePath, err)
+	}
+	return parseCSVInventory(data)
+}
+
+// parseCSVInventory converts CSV rows into a Config
+// A header row names the columns in any order, without one they are name, value, scope, operation, type
+// Spreadsheets saved with a ';' list separator are detected from the first line
+func parseCSVInventory(data []byte) (Config, error) {
+	text := string(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})) // Excel writes a UTF-8 BOM
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	firstLine := strings.SplitN(text, "\n", 2)[0]
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	config := Config{Version: CurrentConfigVersion}
+	columns := map[string]int{}
+	for i, name := range csvColumns {
+		columns[name] = i
+	}
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("error parsing CSV: %w", err)
+		}
+		row, _ := reader.FieldPos(0)
+		if first && isCSVHeader(record) {
+			columns = map[string]int{}
+			for i, field := range record {
+				columns[strings.ToLower(strings.TrimSpace(field))] = i
+			}
+			if _, ok := columns["name"]; !ok {
+				return Config{}, fmt.Errorf("CSV header has no name column")
+			}
+			continue
+		}
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue // Spreadsheets often end with empty rows
+		}
+
+		v := Variable{
+			Name:      strings.TrimSpace(field("name")),
+			Value:     field("value"),
+			Operation: strings.ToLower(strings.TrimSpace(field("operation"))),
+			Type:      strings.ToLower(strings.TrimSpace(field("type"))),
+		}
+		if v.Name == "" {
+			return Config{}, fmt.Errorf("line %d: name is empty", row)
+		}
+		switch v.Operation {
+		case "":
+			v.Operation = "set"
+		case "set", "delete":
+		default:
+			return Config{}, fmt.Errorf("line %d: operation %q is not set or delete", row, v.Operation)
+		}
+		switch v.Type {
+		case "", TypeString, TypeExpand:
+		default:
+			return Config{}, fmt.Errorf("line %d: type %q is not %s or %s", row, v.Type, TypeString, TypeExpand)
+		}
+		switch scope := strings.ToLower(strings.TrimSpace(field("scope"))); scope {
+		case ScopeUser, "":
+			config.UserVariables = append(config.UserVariables, v)
+		case ScopeSystem, "machine":
+			config.SystemVariables = append(config.SystemVariables, v)
+		default:
+			return Config{}, fmt.Errorf("line %d: scope %q is not %s or %s", row, scope, ScopeUser, ScopeSystem)
+		}
+	}
+	return config, nil
+}
+
+// isCSVHeader reports whether a row names columns instead of holding a variable
+func isCSVHeader(record []string) bool {
+	for _, field := range record {
+		if strings.EqualFold(strings.TrimSpace(field), "name") {
+			return true
+		}
+	}
+	return false
+}
+
+// renderCSVInventory renders the set and delete entries of config with a header row, system variables first
+func renderCSVInventory(config Config) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true // Excel on Windows expects CRLF line endings
+	rows := [][]string{csvColumns}
+	section := func(scope string, variables []Variable) {
+		for _, v := range variables {
+			if v.Operation == "set" || v.Operation == "delete" {
+				rows = append(rows, []string{v.Name, v.Value, scope, v.Operation, v.typeLabel()})
+			}
+		}
+	}
+	section(ScopeSystem, config.SystemVariables)
+	section(ScopeUser, config.UserVariables)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to encode CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,221 @@
+// dashboard.go
+// Health dashboard - variable counts, PATH length and quality, secret-looking values and backup age at a glance
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows/registry"
+)
+
+// legacyPathLimit is the PATH length older tools such as setx and the classic System Properties dialog truncate to
+const legacyPathLimit = 2047
+
+// backupWarningAge is how old the newest snapshot may get before the dashboard flags it
+const backupWarningAge = 7 * 24 * time.Hour
+
+// pathEntries splits a PATH-style value into its non-empty entries
+func pathEntries(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ";") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// normalizePathEntry returns the comparison key of a PATH entry: expanded, without quotes or trailing separators, upper case
+func normalizePathEntry(entry string) string {
+	entry = strings.Trim(strings.TrimSpace(entry), `"`)
+	if expanded, err := registry.ExpandString(entry); err == nil {
+		entry = expanded
+	}
+	entry = strings.TrimRight(strings.ReplaceAll(entry, "/", `\`), `\`)
+	return strings.ToUpper(entry)
+}
+
+// pathHealth summarizes the PATH entries of both scopes
+type pathHealth struct {
+	UserLength   int      // Characters of the user Path value
+	SystemLength int      // Characters of the system Path value
+	Entries      int      // Entries in both scopes together
+	Dead         []string // Entries whose directory does not exist
+	Duplicates   []string // Entries listed more than once, across both scopes
+}
+
+// checkPathHealth measures and inspects the user and system Path values
+func checkPathHealth(all []ScopedVariable) pathHealth {
+	var health pathHealth
+	seen := map[string]bool{}
+	for _, scope := range []string{ScopeSystem, ScopeUser} {
+		for _, v := range all {
+			if v.Scope != scope || !strings.EqualFold(v.Name, "Path") {
+				continue
+			}
+			if scope == ScopeUser {
+				health.UserLength = len(v.Value)
+			} else {
+				health.SystemLength = len(v.Value)
+			}
+			for _, entry := range pathEntries(v.Value) {
+				health.Entries++
+				key := normalizePathEntry(entry)
+				if seen[key] {
+					health.Duplicates = append(health.Duplicates, fmt.Sprintf("%s (%s)", entry, scope))
+					continue
+				}
+				seen[key] = true
+				if info, err := os.Stat(key); err != nil || !info.IsDir() {
+					health.Dead = append(health.Dead, fmt.Sprintf("%s (%s)", entry, scope))
+				}
+			}
+		}
+	}
+	return health
+}
+
+// dashboardSection is one titled card of the dashboard
+type dashboardSection struct {
+	Title string
+	Text  string
+}
+
+// dashboardReport computes the dashboard sections
+func dashboardReport(settings Settings) []dashboardSection {
+	all, err := readAllVariables()
+	if err != nil {
+		return []dashboardSection{{"Error", fmt.Sprintf("Could not read environment variables: %v", err)}}
+	}
+
+	// Variable counts per scope, and names defined in both scopes
+	counts := map[string]int{}
+	names := map[string]int{}
+	for _, v := range all {
+		counts[v.Scope]++
+		names[strings.ToUpper(v.Name)]++
+	}
+	shadowed := 0
+	for _, n := range names {
+		if n > 1 {
+			shadowed++
+		}
+	}
+	var sections []dashboardSection
+	sections = append(sections, dashboardSection{"Variables", fmt.Sprintf("User: %d\nSystem: %d\nDefined in both scopes: %d", counts[ScopeUser], counts[ScopeSystem], shadowed)})
+
+	// PATH length against the limits and the quality of its entries
+	health := checkPathHealth(all)
+	lengthLine := func(scope string, length int) string {
+		line := fmt.Sprintf("%s Path: %d characters (%.0f%% of the %d limit)", scope, length, 100*float64(length)/maxVariableValueLength, maxVariableValueLength)
+		if length > legacyPathLimit {
+			line += fmt.Sprintf("  ⚠️  above %d, older tools truncate it", legacyPathLimit)
+		}
+		return line
+	}
+	pathLines := []string{
+		lengthLine("User", health.UserLength),
+		lengthLine("System", health.SystemLength),
+		fmt.Sprintf("Entries: %d, dead: %d, duplicates: %d", health.Entries, len(health.Dead), len(health.Duplicates)),
+	}
+	for _, entry := range health.Dead {
+		pathLines = append(pathLines, "  dead: "+entry)
+	}
+	for _, entry := range health.Duplicates {
+		pathLines = append(pathLines, "  duplicate: "+entry)
+	}
+	sections = append(sections, dashboardSection{"PATH", strings.Join(pathLines, "\n")})
+
+	// Size of the environment block new processes get, against the limit
+	sizeText := ""
+	if size, entries, err := freshEnvironmentSize(); err != nil {
+		sizeText = fmt.Sprintf("Could not measure the environment block: %v", err)
+	} else {
+		sizeText = fmt.Sprintf("Environment block: %s", describeEnvironmentSize(size))
+		if size > environmentBlockLimit {
+			sizeText += "  ⚠️  above the limit, some programs fail to start child processes"
+		} else if overBudget(size) {
+			sizeText += "  ⚠️  close to the limit"
+		}
+		sizeText += "\nLargest: " + describeOffenders(entries)
+	}
+	sections = append(sections, dashboardSection{"Environment Size", sizeText})
+
+	// Secret-looking values, split by whether they are masked already
+	var secretLines []string
+	unmasked := 0
+	sensitive := map[string]bool{}
+	for _, v := range all {
+		if v.isSensitive(settings.SensitivePatterns) {
+			sensitive[v.Scope+"/"+strings.ToUpper(v.Name)] = true
+		}
+	}
+	for _, f := range scanVariablesForSecrets(all) {
+		kind := f.Kind
+		if f.Obfuscated != "" {
+			kind += " in " + f.Obfuscated
+		}
+		status := "masked"
+		if !sensitive[f.Scope+"/"+strings.ToUpper(f.Name)] {
+			status = "NOT masked, add it to the sensitive patterns"
+			unmasked++
+		}
+		secretLines = append(secretLines, fmt.Sprintf("  %s (%s): %s, %s", f.Name, f.Scope, kind, status))
+	}
+	secretSummary := fmt.Sprintf("%d variable(s) with secret-looking values, %d not masked", len(secretLines), unmasked)
+	if len(secretLines) > 0 {
+		secretSummary += "\n" + secretSuggestion
+	}
+	sections = append(sections, dashboardSection{"Secrets", strings.Join(append([]string{secretSummary}, secretLines...), "\n")})
+
+	// Age of the newest environment snapshot
+	backupText := "No snapshot has been taken yet."
+	if dir, err := settings.Backup.backupDirectory(); err != nil {
+		backupText = fmt.Sprintf("Could not open the backup directory: %v", err)
+	} else if last, err := lastBackupTime(dir); err != nil {
+		backupText = fmt.Sprintf("Could not read the snapshots: %v", err)
+	} else if !last.IsZero() {
+		backupText = fmt.Sprintf("Last snapshot: %s (%s ago)", last.Format("2006-01-02 15:04"), time.Since(last).Round(time.Minute))
+		if time.Since(last) > backupWarningAge {
+			backupText += "  ⚠️  older than a week"
+		}
+	}
+	if settings.Backup.Schedule == BackupScheduleOff {
+		backupText += "\nScheduled snapshots are off."
+	}
+	sections = append(sections, dashboardSection{"Backups", backupText})
+	return sections
+}
+
+// newDashboardTab builds the Dashboard tab, computed when it is created and on Refresh
+func newDashboardTab(settings *Settings, isAdmin bool) fyne.CanvasObject {
+	cards := container.NewVBox()
+	refresh := func() {
+		go func() {
+			sections := dashboardReport(*settings)
+			cards.RemoveAll()
+			for _, section := range sections {
+				label := widget.NewLabel(section.Text)
+				label.Wrapping = fyne.TextWrapWord
+				cards.Add(widget.NewCard(section.Title, "", label))
+			}
+			cards.Refresh()
+		}()
+	}
+	refresh()
+
+	return container.NewBorder(
+		container.NewHBox(
+			widget.NewButton("Refresh", refresh),
+			widget.NewButton("Run Doctor...", func() { showDoctorWindow(settings, isAdmin, refresh) }),
+		),
+		nil, nil, nil,
+		container.NewVScroll(cards),
+	)
+}
@@ -0,0 +1,172 @@
+// diff.go
+// Config comparison - computes added, removed and changed variables between two configs
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Scope names used when reporting changes
+const (
+	ScopeUser   = "user"
+	ScopeSystem = "system"
+)
+
+// Kinds of differences between two variable sets
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// VariableChange describes how a single variable differs between an old and a new config
+type VariableChange struct {
+	Scope    string // ScopeUser or ScopeSystem
+	Name     string // Variable name as written in the new config (or the old one for removals)
+	Kind     string // One of the Change constants
+	OldValue string // Value in the old config, empty for additions
+	NewValue string // Value in the new config, empty for removals
+}
+
+// diffVariables compares two variable lists of the same scope, matching names case-insensitively
+// Only "set" entries are considered, since they describe the resulting state
+func diffVariables(scope string, oldVars, newVars []Variable) []VariableChange {
+	oldByName := make(map[string]Variable)
+	for _, v := range oldVars {
+		if v.Operation == "set" {
+			oldByName[strings.ToUpper(v.Name)] = v
+		}
+	}
+	newByName := make(map[string]Variable)
+	for _, v := range newVars {
+		if v.Operation == "set" {
+			newByName[strings.ToUpper(v.Name)] = v
+		}
+	}
+
+	var changes []VariableChange
+	for key, nv := range newByName {
+		ov, existed := oldByName[key]
+		switch {
+		case !existed:
+			changes = append(changes, VariableChange{Scope: scope, Name: nv.Name, Kind: ChangeAdded, NewValue: nv.Value})
+		case ov.Value != nv.Value:
+			changes = append(changes, VariableChange{Scope: scope, Name: nv.Name, Kind: ChangeChanged, OldValue: ov.Value, NewValue: nv.Value})
+		}
+	}
+	for key, ov := range oldByName {
+		if _, exists := newByName[key]; !exists {
+			changes = append(changes, VariableChange{Scope: scope, Name: ov.Name, Kind: ChangeRemoved, OldValue: ov.Value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return strings.ToUpper(changes[i].Name) < strings.ToUpper(changes[j].Name)
+	})
+	return changes
+}
+
+// diffConfigs compares the user and system sections of two configs
+func diffConfigs(oldConfig, newConfig Config) []VariableChange {
+	changes := diffVariables(ScopeUser, oldConfig.UserVariables, newConfig.UserVariables)
+	return append(changes, diffVariables(ScopeSystem, oldConfig.SystemVariables, newConfig.SystemVariables)...)
+}
+
+// changesToConfig builds a delta config that turns the old state into the new one when applied
+func changesToConfig(changes []VariableChange) Config {
+	config := Config{Version: CurrentConfigVersion}
+	for _, c := range changes {
+		v := Variable{Name: c.Name, Value: c.NewValue, Operation: "set"}
+		if c.Kind == ChangeRemoved {
+			v = Variable{Name: c.Name, Operation: "delete"}
+		}
+		if c.Scope == ScopeSystem {
+			config.SystemVariables = append(config.SystemVariables, v)
+		} else {
+			config.UserVariables = append(config.UserVariables, v)
+		}
+	}
+	return config
+}
+
+// describeChanges renders changes as human-readable lines, masking sensitive values
+func describeChanges(changes []VariableChange, sensitivePatterns []string) []string {
+	mask := func(name, value string) string {
+		return Variable{Name: name, Value: value}.displayValue(sensitivePatterns)
+	}
+
+	lines := make([]string, 0, len(changes))
+	for _, c := range changes {
+		scope := strings.ToUpper(c.Scope)
+		switch c.Kind {
+		case ChangeAdded:
+			lines = append(lines, fmt.Sprintf("+ [%s] %s = %s", scope, c.Name, mask(c.Name, c.NewValue)))
+		case ChangeRemoved:
+			lines = append(lines, fmt.Sprintf("- [%s] %s (was %s)", scope, c.Name, mask(c.Name, c.OldValue)))
+		case ChangeChanged:
+			lines = append(lines, fmt.Sprintf("~ [%s] %s: %s -> %s", scope, c.Name, mask(c.Name, c.OldValue), mask(c.Name, c.NewValue)))
+		}
+	}
+	return lines
+}
+
+// valueTokenPattern splits values into words and the separators between them (';' for PATH-like lists, whitespace otherwise)
+var valueTokenPattern = regexp.MustCompile(`[^;\s]+|[;\s]+`)
+
+// diffToken is a fragment of a value marked as unchanged or changed by wordDiff
+type diffToken struct {
+	Text    string // Token text including separators
+	Changed bool   // True when the token does not appear in the other value at this position
+}
+
+// wordDiff compares two values word by word using the longest common subsequence of their tokens
+// It returns the tokens of the old value (changed = removed) and of the new value (changed = added)
+func wordDiff(oldValue, newValue string) ([]diffToken, []diffToken) {
+	a := valueTokenPattern.FindAllString(oldValue, -1)
+	b := valueTokenPattern.FindAllString(newValue, -1)
+
+	// lcs[i][j] holds the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if strings.EqualFold(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var oldTokens, newTokens []diffToken
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case strings.EqualFold(a[i], b[j]):
+			oldTokens = append(oldTokens, diffToken{Text: a[i]})
+			newTokens = append(newTokens, diffToken{Text: b[j]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			oldTokens = append(oldTokens, diffToken{Text: a[i], Changed: true})
+			i++
+		default:
+			newTokens = append(newTokens, diffToken{Text: b[j], Changed: true})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		oldTokens = append(oldTokens, diffToken{Text: a[i], Changed: true})
+	}
+	for ; j < len(b); j++ {
+		newTokens = append(newTokens, diffToken{Text: b[j], Changed: true})
+	}
+	return oldTokens, newTokens
+}
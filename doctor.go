@@ -0,0 +1,416 @@
+// doctor.go
+// Doctor - checks the environment for common problems and lists them by priority, with one-click fixes where possible
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// doctorFlag prints the doctor report to the console and exits
+const doctorFlag = "--doctor"
+
+// Finding severities, in the order the fix list is sorted by
+const (
+	doctorCritical = "critical" // Breaks programs or Windows tools right now
+	doctorWarning  = "warning"  // Likely wrong, worth fixing soon
+	doctorInfo     = "info"     // Worth knowing, often intended
+)
+
+// doctorSeverityRank orders severities for the prioritized list
+var doctorSeverityRank = map[string]int{doctorCritical: 0, doctorWarning: 1, doctorInfo: 2}
+
+// scopeConflictExceptions are names Windows expects in both scopes: Path is merged, TEMP and TMP are overridden per user
+var scopeConflictExceptions = map[string]bool{"PATH": true, "TEMP": true, "TMP": true}
+
+// doctorFinding is one problem found by the doctor
+type doctorFinding struct {
+	Severity string
+	Check    string                         // Area that was checked, e.g. "PATH"
+	Problem  string                         // What is wrong
+	FixLabel string                         // Describes the fix, empty when it has to be fixed by hand
+	Changes  []ScopedVariable               // Variable writes that fix the problem
+	Action   func(settings *Settings) error // Fix that is not a variable write
+}
+
+// canFix reports whether the finding offers a one-click fix
+func (f doctorFinding) canFix() bool {
+	return len(f.Changes) > 0 || f.Action != nil
+}
+
+// line renders the finding for the console and the audit log
+func (f doctorFinding) line() string {
+	line := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(f.Severity), f.Check, f.Problem)
+	if f.FixLabel != "" {
+		line += " - Fix: " + f.FixLabel
+	}
+	return line
+}
+
+// runDoctor runs every check and returns the findings, most severe first
+func runDoctor(settings *Settings) ([]doctorFinding, error) {
+	all, err := readAllVariables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read environment variables: %w", err)
+	}
+
+	var findings []doctorFinding
+	findings = append(findings, doctorPathChecks(all)...)
+	findings = append(findings, doctorPathLimitChecks(all, *settings)...)
+	findings = append(findings, doctorTypeChecks(all)...)
+	findings = append(findings, doctorReferenceChecks(all)...)
+	findings = append(findings, doctorScopeChecks(all)...)
+	findings = append(findings, doctorPSModulePathChecks(all)...)
+	findings = append(findings, doctorBroadcastChecks(settings)...)
+	findings = append(findings, doctorEnvironmentSizeChecks()...)
+	findings = append(findings, doctorSecretChecks(all)...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		return doctorSeverityRank[findings[i].Severity] < doctorSeverityRank[findings[j].Severity]
+	})
+	return findings, nil
+}
+
+// doctorPathChecks flags Path values above the length limits and entries that are dead or listed twice
+// System entries are checked first, so an entry in both scopes is removed from the user Path
+func doctorPathChecks(all []ScopedVariable) []doctorFinding {
+	var findings []doctorFinding
+	seen := map[string]bool{}
+	for _, scope := range []string{ScopeSystem, ScopeUser} {
+		for _, v := range all {
+			if v.Scope != scope || !strings.EqualFold(v.Name, "Path") {
+				continue
+			}
+			switch {
+			case len(v.Value) > maxVariableValueLength:
+				findings = append(findings, doctorFinding{Severity: doctorCritical, Check: "PATH",
+					Problem: fmt.Sprintf("%s Path has %d characters, more than Windows accepts (%d)", scope, len(v.Value), maxVariableValueLength)})
+			case len(v.Value) > legacyPathLimit:
+				findings = append(findings, doctorFinding{Severity: doctorWarning, Check: "PATH",
+					Problem: fmt.Sprintf("%s Path has %d characters, older tools truncate it at %d", scope, len(v.Value), legacyPathLimit)})
+			}
+
+			var kept, dead, duplicates []string
+			for _, entry := range pathEntries(v.Value) {
+				key := normalizePathEntry(entry)
+				if seen[key] {
+					duplicates = append(duplicates, entry)
+					continue
+				}
+				seen[key] = true
+				if info, err := os.Stat(key); err != nil || !info.IsDir() {
+					dead = append(dead, entry)
+					continue
+				}
+				kept = append(kept, entry)
+			}
+			if len(dead)+len(duplicates) == 0 {
+				continue
+			}
+
+			var problems []string
+			if len(dead) > 0 {
+				problems = append(problems, fmt.Sprintf("%d missing director%s (%s)", len(dead), plural(len(dead), "y", "ies"), strings.Join(dead, "; ")))
+			}
+			if len(duplicates) > 0 {
+				problems = append(problems, fmt.Sprintf("%d duplicate entr%s (%s)", len(duplicates), plural(len(duplicates), "y", "ies"), strings.Join(duplicates, "; ")))
+			}
+			fixed := v
+			fixed.Value = strings.Join(kept, ";")
+			fixed.Operation = "set"
+			findings = append(findings, doctorFinding{
+				Severity: doctorWarning,
+				Check:    "PATH",
+				Problem:  fmt.Sprintf("%s Path lists %s", scope, strings.Join(problems, " and ")),
+				FixLabel: fmt.Sprintf("remove %d entr%s from the %s Path", len(dead)+len(duplicates), plural(len(dead)+len(duplicates), "y", "ies"), scope),
+				Changes:  []ScopedVariable{fixed},
+			})
+		}
+	}
+	return findings
+}
+
+// doctorTypeChecks flags %VAR% references stored as REG_SZ, which Windows never expands
+func doctorTypeChecks(all []ScopedVariable) []doctorFinding {
+	var findings []doctorFinding
+	for _, v := range all {
+		if v.isExpandable() || !referencePattern.MatchString(v.Value) {
+			continue
+		}
+		fixed := v
+		fixed.Type = TypeExpand
+		fixed.Operation = "set"
+		findings = append(findings, doctorFinding{
+			Severity: doctorWarning,
+			Check:    "Types",
+			Problem:  fmt.Sprintf("%s (%s) contains %%VAR%% references but is stored as REG_SZ, so they are passed on literally", v.Name, v.Scope),
+			FixLabel: "store it as REG_EXPAND_SZ",
+			Changes:  []ScopedVariable{fixed},
+		})
+	}
+	return findings
+}
+
+// doctorPathLimitChecks flags path-type values beyond MAX_PATH and network shares in PATH
+func doctorPathLimitChecks(all []ScopedVariable, settings Settings) []doctorFinding {
+	var findings []doctorFinding
+	for _, v := range all {
+		rule, ok := pathValueRule(v.Name, settings)
+		if !ok {
+			continue
+		}
+		for _, warning := range pathLimitWarnings(v.Name, v.Value, rule) {
+			findings = append(findings, doctorFinding{Severity: doctorWarning, Check: "Path limits", Problem: fmt.Sprintf("%s (%s)", warning, v.Scope)})
+		}
+	}
+	return findings
+}
+
+// doctorReferenceChecks flags %VAR% references to variables that are not defined anywhere
+func doctorReferenceChecks(all []ScopedVariable) []doctorFinding {
+	// Built-in variables such as USERPROFILE are not in the Environment keys, the environment block of a new process has them
+	defined := map[string]bool{}
+	for _, v := range all {
+		defined[strings.ToUpper(v.Name)] = true
+	}
+	if fresh, err := freshEnvironmentMap(); err == nil {
+		for name := range fresh {
+			defined[name] = true
+		}
+	}
+	for _, entry := range os.Environ() {
+		if name, _, ok := strings.Cut(entry, "="); ok {
+			defined[strings.ToUpper(name)] = true
+		}
+	}
+
+	var findings []doctorFinding
+	for _, v := range all {
+		var broken []string
+		for _, match := range referencePattern.FindAllStringSubmatch(v.Value, -1) {
+			if !defined[strings.ToUpper(match[1])] {
+				broken = append(broken, match[0])
+			}
+		}
+		if len(broken) > 0 {
+			findings = append(findings, doctorFinding{
+				Severity: doctorWarning,
+				Check:    "References",
+				Problem:  fmt.Sprintf("%s (%s) references undefined %s", v.Name, v.Scope, strings.Join(broken, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+// doctorScopeChecks lists variables defined in both scopes, where the user value silently wins
+func doctorScopeChecks(all []ScopedVariable) []doctorFinding {
+	system := map[string]ScopedVariable{}
+	for _, v := range all {
+		if v.Scope == ScopeSystem {
+			system[strings.ToUpper(v.Name)] = v
+		}
+	}
+
+	var findings []doctorFinding
+	for _, v := range all {
+		key := strings.ToUpper(v.Name)
+		s, ok := system[key]
+		if v.Scope != ScopeUser || !ok || scopeConflictExceptions[key] {
+			continue
+		}
+		if s.Value == v.Value {
+			redundant := v
+			redundant.Operation = "delete"
+			findings = append(findings, doctorFinding{
+				Severity: doctorInfo,
+				Check:    "Scopes",
+				Problem:  fmt.Sprintf("%s is set to the same value in both scopes", v.Name),
+				FixLabel: "delete the redundant user variable",
+				Changes:  []ScopedVariable{redundant},
+			})
+			continue
+		}
+		findings = append(findings, doctorFinding{
+			Severity: doctorInfo,
+			Check:    "Scopes",
+			Problem:  fmt.Sprintf("%s is defined in both scopes with different values, the user value shadows the system value", v.Name),
+		})
+	}
+	return findings
+}
+
+// doctorPSModulePathChecks flags default PowerShell module paths missing from both scopes
+// The fix adds them back to the front of the system value
+func doctorPSModulePathChecks(all []ScopedVariable) []doctorFinding {
+	values := map[string]ScopedVariable{}
+	for _, v := range all {
+		if strings.EqualFold(v.Name, psModulePathName) {
+			values[v.Scope] = v
+		}
+	}
+	missing := missingDefaultModulePaths(values[ScopeSystem].Value, values[ScopeUser].Value)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	fixed, ok := values[ScopeSystem]
+	if !ok {
+		fixed = ScopedVariable{Scope: ScopeSystem, Variable: Variable{Name: psModulePathName}}
+	}
+	fixed.Value = joinPathValue(strings.Join(missing, ";"), fixed.Value)
+	fixed.Type, fixed.Operation = TypeExpand, "set"
+	return []doctorFinding{{
+		Severity: doctorCritical,
+		Check:    "PSModulePath",
+		Problem:  fmt.Sprintf("the default module path(s) %s are missing, built-in PowerShell modules do not load", strings.Join(missing, "; ")),
+		FixLabel: "add them to the system PSModulePath",
+		Changes:  []ScopedVariable{fixed},
+	}}
+}
+
+// doctorBroadcastChecks flags settings and results that keep running programs from seeing changes
+func doctorBroadcastChecks(settings *Settings) []doctorFinding {
+	var findings []doctorFinding
+	if settings.Broadcast.Mode == BroadcastModeSkip {
+		findings = append(findings, doctorFinding{
+			Severity: doctorWarning,
+			Check:    "Broadcast",
+			Problem:  "WM_SETTINGCHANGE broadcasts are turned off, Explorer and other programs do not pick up changes until you sign out",
+			FixLabel: "switch the broadcast mode to \"Wait for windows\"",
+			Action: func(settings *Settings) error {
+				updated := *settings
+				updated.Broadcast.Mode = BroadcastModeTimeout
+				if err := saveSettings(updated); err != nil {
+					return err
+				}
+				*settings = updated
+				return nil
+			},
+		})
+	}
+
+	lastBroadcastMu.Lock()
+	last := lastBroadcast
+	lastBroadcastMu.Unlock()
+	if last != nil && last.Err != nil {
+		findings = append(findings, doctorFinding{
+			Severity: doctorCritical,
+			Check:    "Broadcast",
+			Problem:  fmt.Sprintf("the last WM_SETTINGCHANGE broadcast at %s failed: %v", last.Time.Format("15:04:05"), last.Err),
+			FixLabel: "broadcast again",
+			Action: func(settings *Settings) error {
+				return broadcastSettingChange(settings.Broadcast, settings.Retry)
+			},
+		})
+	}
+	return findings
+}
+
+// plural returns one or many depending on n
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// runDoctorCommand prints the doctor report for --doctor
+// It exits with 1 when there are critical findings or warnings, so scripts can act on it
+func runDoctorCommand() int {
+	settings, err := loadSettings()
+	if err != nil {
+		fmt.Printf("Warning: Could not load settings: %v\n", err)
+	}
+	findings, err := runDoctor(&settings)
+	if err != nil {
+		return writeCommandError("Doctor", err, exitFailed)
+	}
+	if len(findings) == 0 {
+		fmt.Println("Doctor: no problems found")
+		return 0
+	}
+
+	status := 0
+	fmt.Printf("Doctor: %d finding(s)\n", len(findings))
+	for i, f := range findings {
+		fmt.Printf("%d. %s\n", i+1, f.line())
+		if f.Severity != doctorInfo {
+			status = 1
+		}
+	}
+	return status
+}
+
+// showDoctorWindow runs the doctor and lists its findings with a Fix button for each fixable one
+func showDoctorWindow(settings *Settings, isAdmin bool, onDone func()) {
+	window := fyne.CurrentApp().NewWindow("Doctor")
+	window.Resize(fyne.NewSize(900, 600))
+
+	rows := container.NewVBox()
+	var render func()
+	render = func() {
+		rows.RemoveAll()
+		findings, err := runDoctor(settings)
+		if err != nil {
+			rows.Add(widget.NewLabel(fmt.Sprintf("⚠️  %v", err)))
+		} else if len(findings) == 0 {
+			rows.Add(widget.NewLabel("No problems found."))
+		} else {
+			rows.Add(widget.NewLabel(fmt.Sprintf("%d finding(s), most severe first.", len(findings))))
+		}
+
+		for _, f := range findings {
+			f := f
+			problem := widget.NewLabel(f.Problem)
+			problem.Wrapping = fyne.TextWrapWord
+			rows.Add(widget.NewSeparator())
+			rows.Add(widget.NewLabelWithStyle(fmt.Sprintf("%s  %s", strings.ToUpper(f.Severity), f.Check), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			rows.Add(problem)
+			if !f.canFix() {
+				continue
+			}
+
+			fixButton := widget.NewButton("Fix: "+f.FixLabel, func() {
+				go func() {
+					if len(f.Changes) > 0 {
+						if !confirmDangerousChanges(f.Changes, *settings, window) {
+							return
+						}
+						if err := applyDirectChanges("Doctor", f.Changes, isAdmin, *settings); err != nil {
+							dialog.ShowError(fmt.Errorf("error applying fix: %v", err), window)
+						}
+					}
+					if f.Action != nil {
+						if err := f.Action(settings); err != nil {
+							dialog.ShowError(fmt.Errorf("error applying fix: %v", err), window)
+						}
+					}
+					render()
+					if onDone != nil {
+						onDone()
+					}
+				}()
+			})
+			markElevates(fixButton, isAdmin, elevates(f.Changes, isAdmin))
+			hideInReadOnly(fixButton)
+			rows.Add(container.NewHBox(fixButton))
+		}
+		rows.Refresh()
+	}
+	go render()
+
+	window.SetContent(container.NewBorder(
+		nil,
+		container.NewHBox(widget.NewButton("Run Again", func() { go render() }), widget.NewButton("Close", window.Close)),
+		nil, nil,
+		container.NewScroll(rows),
+	))
+	window.Show()
+}
@@ -0,0 +1,255 @@
+// effective.go
+// Effective environment - merges the system and user scopes the way Windows builds a new process environment
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// concatenatedVariables are appended (system first, then user) instead of the user value replacing the system value
+var concatenatedVariables = map[string]bool{"PATH": true, "LIBPATH": true, "OS2LIBPATH": true}
+
+// Sources of an effective value
+const (
+	SourceUser     = "user"           // Only defined in the user scope
+	SourceSystem   = "system"         // Only defined in the system scope
+	SourceShadowed = "user overrides" // Defined in both scopes, the user value wins
+	SourceCombined = "system + user"  // Defined in both scopes and concatenated
+)
+
+// EffectiveVariable is a variable as seen by a newly started process
+type EffectiveVariable struct {
+	Name   string    // Variable name as stored in the winning scope
+	Value  string    // Effective value after merging and %VAR% expansion
+	Source string    // One of the Source* constants
+	User   *Variable // Definition in the user scope, if any
+	System *Variable // Definition in the system scope, if any
+}
+
+// shadowsSystem reports whether a user definition replaces a system definition
+func (e EffectiveVariable) shadowsSystem() bool {
+	return e.Source == SourceShadowed
+}
+
+// mergeEnvironment combines user and system variables following the Windows rules:
+// user values replace system values, except for PATH-like variables which are concatenated
+// %VAR% references in expandable values are expanded against the merged environment
+func mergeEnvironment(all []ScopedVariable) []EffectiveVariable {
+	byName := map[string]*EffectiveVariable{}
+	var order []string
+	for _, sv := range all {
+		key := strings.ToUpper(sv.Name)
+		e, ok := byName[key]
+		if !ok {
+			e = &EffectiveVariable{Name: sv.Name}
+			byName[key] = e
+			order = append(order, key)
+		}
+		v := sv.Variable
+		if sv.Scope == ScopeSystem {
+			e.System = &v
+		} else {
+			e.User = &v
+			e.Name = sv.Name
+		}
+	}
+
+	// Raw merged values, used for expanding references
+	raw := map[string]string{}
+	for key, e := range byName {
+		switch {
+		case e.User != nil && e.System != nil && concatenatedVariables[key]:
+			e.Source = SourceCombined
+			raw[key] = joinPathValue(e.System.Value, e.User.Value)
+		case e.User != nil && e.System != nil:
+			e.Source = SourceShadowed
+			raw[key] = e.User.Value
+		case e.User != nil:
+			e.Source = SourceUser
+			raw[key] = e.User.Value
+		default:
+			e.Source = SourceSystem
+			raw[key] = e.System.Value
+		}
+	}
+
+	result := make([]EffectiveVariable, 0, len(order))
+	for _, key := range order {
+		e := byName[key]
+		e.Value = raw[key]
+		if (e.User != nil && e.User.isExpandable()) || (e.System != nil && e.System.isExpandable()) {
+			e.Value = expandReferences(e.Value, raw)
+		}
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToUpper(result[i].Name) < strings.ToUpper(result[j].Name)
+	})
+	return result
+}
+
+// joinPathValue concatenates two ';'-separated lists without doubling the separator
+func joinPathValue(first, second string) string {
+	first = strings.TrimRight(first, ";")
+	if first == "" {
+		return second
+	}
+	if second == "" {
+		return first
+	}
+	return first + ";" + second
+}
+
+// expansionReference finds %NAME% references to expand
+var expansionReference = regexp.MustCompile(`%([^%=]+)%`)
+
+// expandReferences replaces %NAME% with the value from values, leaving unknown references untouched like Windows does
+func expandReferences(value string, values map[string]string) string {
+	return expansionReference.ReplaceAllStringFunc(value, func(ref string) string {
+		if v, ok := values[strings.ToUpper(ref[1:len(ref)-1])]; ok {
+			return v
+		}
+		return ref
+	})
+}
+
+// loadEffectiveEnvironment reads the registry and merges it, preferring the environment block Windows
+// would build for a new process when it is available since it also contains the built-in variables
+func loadEffectiveEnvironment() ([]EffectiveVariable, error) {
+	all, err := readAllVariables()
+	if err != nil {
+		return nil, err
+	}
+	merged := mergeEnvironment(all)
+
+	if fresh, err := freshEnvironmentMap(); err == nil {
+		for i := range merged {
+			if value, ok := fresh[strings.ToUpper(merged[i].Name)]; ok {
+				merged[i].Value = value
+			}
+		}
+	}
+	return merged, nil
+}
+
+// effectiveDetails describes where an effective value comes from for the details pane
+func effectiveDetails(e EffectiveVariable, sensitivePatterns []string) string {
+	sensitive := (e.User != nil && e.User.isSensitive(sensitivePatterns)) || (e.System != nil && e.System.isSensitive(sensitivePatterns))
+	show := func(value string) string {
+		if sensitive && value != "" {
+			return maskedValue
+		}
+		return formatValueDetails(Variable{Name: e.Name, Value: value}, nil)
+	}
+
+	lines := []string{fmt.Sprintf("%s (%s)", e.Name, e.Source), "", "Effective value:", show(e.Value)}
+	if e.System != nil {
+		lines = append(lines, "", "System value:", show(e.System.Value))
+	}
+	if e.User != nil {
+		lines = append(lines, "", "User value:", show(e.User.Value))
+	}
+	switch e.Source {
+	case SourceShadowed:
+		lines = append(lines, "", "⚠️  The user value shadows the system value, new processes only see the user value.")
+	case SourceCombined:
+		lines = append(lines, "", "The system and user values are concatenated, system entries come first.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newEffectiveTab builds the Effective tab, showing the value each variable has in a newly started process
+func newEffectiveTab(parent fyne.Window, settings *Settings, isAdmin bool, openSandbox func()) fyne.CanvasObject {
+	var all, shown []EffectiveVariable
+	var pending pendingIndex
+
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("Filter by name...")
+	shadowedOnly := widget.NewCheck("Only shadowed", nil)
+	conflictLabel := widget.NewLabel("")
+
+	detailsLabel := widget.NewLabel("Select a variable to see how its effective value is built.")
+	detailsLabel.Wrapping = fyne.TextWrapBreak
+	detailsScroll := container.NewVScroll(detailsLabel)
+	detailsScroll.SetMinSize(fyne.NewSize(0, 160))
+
+	// effectiveValue masks the effective value when either definition is sensitive
+	effectiveValue := func(e EffectiveVariable) string {
+		if (e.User != nil && e.User.isSensitive(settings.SensitivePatterns)) || (e.System != nil && e.System.isSensitive(settings.SensitivePatterns)) {
+			return maskedValue
+		}
+		return e.Value
+	}
+
+	table := newSortableTable([]tableColumn{
+		{Title: "Name", Width: 220, Cell: func(row int) string { return shown[row].Name }},
+		{Title: "Source", Width: 130, Cell: func(row int) string { return shown[row].Source }},
+		{Title: "Shadowed", Width: 90, Cell: func(row int) string {
+			if shown[row].shadowsSystem() {
+				return "⚠️ yes"
+			}
+			return ""
+		}},
+		{Title: "Effective Value", Width: 360, Cell: func(row int) string { return effectiveValue(shown[row]) }},
+		{Title: "Session", Width: 150, Cell: func(row int) string { return pending.status(shown[row].Name) }},
+	}, func() int { return len(shown) })
+	table.OnSelected = func(row int) {
+		details := effectiveDetails(shown[row], settings.SensitivePatterns)
+		if pending.restartRequired(shown[row].Name) {
+			details += "\n\n⟳ Programs started before the last change still see the old value."
+		}
+		detailsLabel.SetText(details)
+	}
+
+	applyFilter := func() {
+		needle := strings.ToUpper(strings.TrimSpace(filterEntry.Text))
+		shown = nil
+		for _, e := range all {
+			if (needle == "" || strings.Contains(strings.ToUpper(e.Name), needle)) && (!shadowedOnly.Checked || e.shadowsSystem()) {
+				shown = append(shown, e)
+			}
+		}
+		table.clearSelection()
+		detailsLabel.SetText("Select a variable to see how its effective value is built.")
+		table.Refresh()
+	}
+	filterEntry.OnChanged = func(string) { applyFilter() }
+	shadowedOnly.OnChanged = func(bool) { applyFilter() }
+
+	reload := func() {
+		loaded, err := loadEffectiveEnvironment()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error reading environment variables: %v", err), parent)
+		}
+		all = loaded
+		if pending, err = loadPendingIndex(); err != nil {
+			fmt.Printf("Warning: Could not compare with the inherited environment: %v\n", err)
+		}
+		conflictLabel.SetText(describeConflictCount(all))
+		applyFilter()
+	}
+	reload()
+
+	conflictsButton := widget.NewButton("Conflict Report", func() {
+		showConflictReportWindow(settings, isAdmin, reload)
+	})
+
+	return container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Values as a newly started process sees them: user values override system values, PATH is system followed by user."),
+			container.NewBorder(nil, nil, nil, container.NewHBox(shadowedOnly, widget.NewButton("Refresh", reload)), filterEntry),
+			container.NewBorder(nil, nil, nil, container.NewHBox(widget.NewButton("Expansion Sandbox...", openSandbox), conflictsButton), conflictLabel),
+		),
+		container.NewVBox(widget.NewSeparator(), detailsScroll),
+		nil, nil,
+		table.table,
+	)
+}
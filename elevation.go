@@ -0,0 +1,258 @@
+// elevation.go
+// Per-action elevation - system variables are written by a short-lived elevated helper so the main window stays unelevated
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// elevatedApplyFlag starts the application as the elevated helper, followed by the request file path
+const elevatedApplyFlag = "--apply-system="
+
+// ShellExecuteEx flags and the error returned when the user declines the UAC prompt
+const (
+	seeMaskNoCloseProcess = 0x00000040 // Return a handle to the started process
+	seeMaskNoAsync        = 0x00000100 // Wait for the launch to finish before returning
+	errorCancelled        = 1223       // ERROR_CANCELLED
+)
+
+// errElevationCancelled is returned when the UAC prompt was dismissed
+var errElevationCancelled = errors.New("administrator approval was cancelled")
+
+// shellExecuteInfo mirrors the Win32 SHELLEXECUTEINFOW structure
+type shellExecuteInfo struct {
+	cbSize       uint32
+	fMask        uint32
+	hwnd         uintptr
+	lpVerb       *uint16
+	lpFile       *uint16
+	lpParameters *uint16
+	lpDirectory  *uint16
+	nShow        int32
+	hInstApp     uintptr
+	lpIDList     uintptr
+	lpClass      *uint16
+	hkeyClass    uintptr
+	dwHotKey     uint32
+	hIcon        uintptr
+	hProcess     windows.Handle
+}
+
+// elevatedApplyRequest is the work handed to the elevated helper
+type elevatedApplyRequest struct {
+	Variables         []Variable `json:"variables"`
+	ErrorPolicy       string     `json:"error_policy"`
+	SensitivePatterns []string   `json:"sensitive_patterns,omitempty"`
+	Protected         []string   `json:"protected,omitempty"`
+	Source            string     `json:"source,omitempty"`
+	FromConfig        bool       `json:"from_config,omitempty"`
+}
+
+// elevatedApplyFailure is one variable the helper could not write
+type elevatedApplyFailure struct {
+	Name      string `json:"name"`
+	Operation string `json:"operation"`
+	Error     string `json:"error"`
+}
+
+// elevatedApplyResult is written back by the helper next to the request file
+type elevatedApplyResult struct {
+	Failures []elevatedApplyFailure `json:"failures,omitempty"`
+	Aborted  bool                   `json:"aborted,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Trashed  []Variable             `json:"trashed,omitempty"` // Deleted variables with their old values, for the caller's trash
+}
+
+// elevatedResultPath returns where the helper writes the result for a request file
+func elevatedResultPath(requestPath string) string {
+	return requestPath + ".result"
+}
+
+// applySystemVariablesElevated writes system variables through an elevated helper process
+// Only this write triggers a UAC prompt; the helper exits as soon as it is done
+func applySystemVariablesElevated(variables []Variable, options applyOptions) error {
+	if readOnlyMode {
+		return errReadOnly
+	}
+
+	dir, err := ioutil.TempDir("", "svm-elevated-")
+	if err != nil {
+		return fmt.Errorf("failed to create elevation request directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The helper has no UI, so failures can only be collected, not prompted for
+	policy := options.ErrorPolicy
+	if policy == ErrorPolicyPrompt {
+		policy = ErrorPolicyContinue
+	}
+	request := elevatedApplyRequest{
+		Variables:         variables,
+		ErrorPolicy:       policy,
+		SensitivePatterns: options.SensitivePatterns,
+		Protected:         options.Protected,
+		Source:            options.Source,
+		FromConfig:        options.FromConfig,
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode elevation request: %w", err)
+	}
+	requestPath := filepath.Join(dir, "request.json")
+	if err := ioutil.WriteFile(requestPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write elevation request: %w", err)
+	}
+
+	parameters := quoteCommandArgument(elevatedApplyFlag + requestPath)
+	if portableMode {
+		parameters += " " + portableFlag // The helper records expirations in the same place
+	}
+	exitCode, err := runElevatedAndWait(parameters)
+	if err != nil {
+		return err
+	}
+
+	resultData, err := ioutil.ReadFile(elevatedResultPath(requestPath))
+	if err != nil {
+		return fmt.Errorf("elevated helper exited with code %d without a result: %w", exitCode, err)
+	}
+	var result elevatedApplyResult
+	if err := json.Unmarshal(resultData, &result); err != nil {
+		return fmt.Errorf("failed to decode elevated helper result: %w", err)
+	}
+	// The helper's trash ends with its process, so deletions go to the trash of this instance
+	for _, v := range result.Trashed {
+		sessionTrash.add(ScopeSystem, v)
+	}
+	if result.Error != "" {
+		return fmt.Errorf("elevated helper: %s", result.Error)
+	}
+	if len(result.Failures) == 0 {
+		return nil
+	}
+	applyErrs := &ApplyErrors{Aborted: result.Aborted}
+	for _, f := range result.Failures {
+		applyErrs.Failures = append(applyErrs.Failures, VariableError{Name: f.Name, Operation: f.Operation, Err: errors.New(f.Error)})
+	}
+	return applyErrs
+}
+
+// runElevatedAndWait starts this executable elevated with the given parameters and waits for it to exit
+func runElevatedAndWait(parameters string) (uint32, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("cannot find executable path: %w", err)
+	}
+	cwd, _ := os.Getwd()
+
+	verbPtr, _ := syscall.UTF16PtrFromString("runas")
+	exePtr, _ := syscall.UTF16PtrFromString(exePath)
+	paramPtr, _ := syscall.UTF16PtrFromString(parameters)
+	cwdPtr, _ := syscall.UTF16PtrFromString(cwd)
+
+	info := shellExecuteInfo{
+		fMask:        seeMaskNoCloseProcess | seeMaskNoAsync,
+		lpVerb:       verbPtr,
+		lpFile:       exePtr,
+		lpParameters: paramPtr,
+		lpDirectory:  cwdPtr,
+		nShow:        windows.SW_HIDE,
+	}
+	info.cbSize = uint32(unsafe.Sizeof(info))
+
+	r, _, callErr := syscall.NewLazyDLL("shell32.dll").NewProc("ShellExecuteExW").Call(uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		if errno, ok := callErr.(syscall.Errno); ok && errno == errorCancelled {
+			return 0, errElevationCancelled
+		}
+		return 0, fmt.Errorf("ShellExecuteExW failed: %w", callErr)
+	}
+	if info.hProcess == 0 {
+		return 0, fmt.Errorf("elevated helper did not start")
+	}
+	defer windows.CloseHandle(info.hProcess)
+
+	if _, err := windows.WaitForSingleObject(info.hProcess, windows.INFINITE); err != nil {
+		return 0, fmt.Errorf("failed waiting for elevated helper: %w", err)
+	}
+	var exitCode uint32
+	if err := windows.GetExitCodeProcess(info.hProcess, &exitCode); err != nil {
+		return 0, fmt.Errorf("failed to read elevated helper exit code: %w", err)
+	}
+	return exitCode, nil
+}
+
+// runElevatedApply is the entry point of the elevated helper: it writes the requested system variables and records the outcome
+// The exit code is 0 when every variable was applied
+func runElevatedApply(requestPath string) int {
+	result := elevatedApply(requestPath)
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = ioutil.WriteFile(elevatedResultPath(requestPath), data, 0600)
+	}
+	if err != nil {
+		fmt.Printf("Failed to write elevated apply result: %v\n", err)
+		return exitFailed
+	}
+	switch {
+	case result.Error != "":
+		return exitFailed
+	case len(result.Failures) > 0:
+		return exitPartialFailure
+	}
+	return exitOK
+}
+
+// elevatedApply performs the helper's write and converts errors into a result document
+func elevatedApply(requestPath string) elevatedApplyResult {
+	if isAdmin, err := isRunningAsAdmin(); err != nil || !isAdmin {
+		return elevatedApplyResult{Error: "helper is not running with administrator privileges"}
+	}
+
+	data, err := ioutil.ReadFile(requestPath)
+	if err != nil {
+		return elevatedApplyResult{Error: fmt.Sprintf("error reading request: %v", err)}
+	}
+	var request elevatedApplyRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return elevatedApplyResult{Error: fmt.Sprintf("error decoding request: %v", err)}
+	}
+
+	options := applyOptions{
+		ErrorPolicy:       request.ErrorPolicy,
+		SensitivePatterns: request.SensitivePatterns,
+		Protected:         request.Protected,
+		Source:            request.Source,
+		FromConfig:        request.FromConfig,
+	}
+	err = applyVariables(request.Variables, registry.LOCAL_MACHINE, systemEnvironmentPath, options)
+	// list returns the most recent deletion first, the caller adds them in deletion order
+	var trashed []Variable
+	items := sessionTrash.list()
+	for i := len(items) - 1; i >= 0; i-- {
+		trashed = append(trashed, items[i].Variable)
+	}
+	if err == nil {
+		return elevatedApplyResult{Trashed: trashed}
+	}
+
+	var applyErrs *ApplyErrors
+	if !errors.As(err, &applyErrs) {
+		return elevatedApplyResult{Error: err.Error(), Trashed: trashed}
+	}
+	result := elevatedApplyResult{Aborted: applyErrs.Aborted, Trashed: trashed}
+	for _, f := range applyErrs.Failures {
+		result.Failures = append(result.Failures, elevatedApplyFailure{Name: f.Name, Operation: f.Operation, Error: f.Err.Error()})
+	}
+	return result
+}
@@ -0,0 +1,256 @@
+// encryption.go
+// Encrypted configs - passphrase-protected .yaml.enc files for configs that travel over email or USB
+// Files are encrypted with AES-256-GCM using a key derived from the passphrase with scrypt, files written with PBKDF2 still open
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v2"
+)
+
+// encryptedConfigExtension is the suffix of encrypted configs, the plaintext is always YAML
+const encryptedConfigExtension = ".yaml.enc"
+
+// encryptedConfigMagic starts every encrypted config so other files are recognized early
+// Version 2 derives the key with scrypt, version 1 files derived it with PBKDF2 and can still be opened
+var (
+	encryptedConfigMagic       = []byte("SVMENC2\n")
+	legacyEncryptedConfigMagic = []byte("SVMENC1\n")
+)
+
+// Key derivation parameters, new files carry the scrypt cost and version 1 files their PBKDF2 iteration count
+// The parameters are read before anything is authenticated, so costs beyond the maximums are refused
+const (
+	encryptionScryptLogN    = 17 // N = 2^17, about 128 MB of memory with r = 8
+	encryptionScryptR       = 8
+	encryptionScryptP       = 1
+	maxEncryptionScryptLogN = 20
+	legacyIterations        = 600000
+	maxLegacyIterations     = 4 * legacyIterations
+	encryptionSaltSize      = 16
+	encryptionKeySize       = 32
+)
+
+// errWrongPassphrase is returned when a file cannot be decrypted with the given passphrase
+var errWrongPassphrase = errors.New("wrong passphrase or corrupted file")
+
+// passphrasePrompt asks for a passphrase, confirm requests it twice for new files; set by the UI
+var passphrasePrompt func(title string, confirm bool) (string, bool)
+
+// passphraseCache remembers passphrases per file for the session so reloads do not ask again
+var (
+	passphraseMu    sync.Mutex
+	passphraseCache = map[string]string{}
+)
+
+// isEncryptedConfig checks if the provided file path is an encrypted config
+func isEncryptedConfig(filePath string) bool {
+	return strings.HasSuffix(strings.ToLower(filePath), ".enc")
+}
+
+// encryptConfigData encrypts plaintext with a key derived from passphrase
+func encryptConfigData(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := configCipher(passphrase, salt, encryptionScryptLogN)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	var header bytes.Buffer
+	header.Write(encryptedConfigMagic)
+	header.WriteByte(encryptionScryptLogN)
+	header.Write(salt)
+	header.Write(nonce)
+	// The header is authenticated too, so tampering with the parameters is detected
+	return aead.Seal(header.Bytes(), nonce, plaintext, header.Bytes()), nil
+}
+
+// decryptConfigData decrypts data written by encryptConfigData, or by a version writing PBKDF2 files
+func decryptConfigData(data []byte, passphrase string) ([]byte, error) {
+	var aead cipher.AEAD
+	var offset int
+	var err error
+	switch {
+	case bytes.HasPrefix(data, encryptedConfigMagic):
+		offset = len(encryptedConfigMagic)
+		if len(data) < offset+1+encryptionSaltSize {
+			return nil, fmt.Errorf("encrypted config is truncated")
+		}
+		logN := data[offset]
+		salt := data[offset+1 : offset+1+encryptionSaltSize]
+		offset += 1 + encryptionSaltSize
+		aead, err = configCipher(passphrase, salt, logN)
+	case bytes.HasPrefix(data, legacyEncryptedConfigMagic):
+		offset = len(legacyEncryptedConfigMagic)
+		if len(data) < offset+4+encryptionSaltSize {
+			return nil, fmt.Errorf("encrypted config is truncated")
+		}
+		iterations := binary.BigEndian.Uint32(data[offset:])
+		salt := data[offset+4 : offset+4+encryptionSaltSize]
+		offset += 4 + encryptionSaltSize
+		aead, err = legacyConfigCipher(passphrase, salt, iterations)
+	default:
+		return nil, fmt.Errorf("not an encrypted config")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < offset+aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted config is truncated")
+	}
+	nonce := data[offset : offset+aead.NonceSize()]
+	offset += aead.NonceSize()
+
+	plaintext, err := aead.Open(nil, nonce, data[offset:], data[:offset])
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// configCipher derives the AES-GCM cipher for a passphrase and salt with scrypt at cost 2^logN
+func configCipher(passphrase string, salt []byte, logN byte) (cipher.AEAD, error) {
+	if logN < 10 || logN > maxEncryptionScryptLogN {
+		return nil, fmt.Errorf("invalid key derivation parameters")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<logN, encryptionScryptR, encryptionScryptP, encryptionKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return aesGCM(key)
+}
+
+// legacyConfigCipher derives the AES-GCM cipher of a version 1 file with PBKDF2-HMAC-SHA256
+func legacyConfigCipher(passphrase string, salt []byte, iterations uint32) (cipher.AEAD, error) {
+	if iterations == 0 || iterations > maxLegacyIterations {
+		return nil, fmt.Errorf("invalid key derivation parameters")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, int(iterations), encryptionKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return aesGCM(key)
+}
+
+// aesGCM returns the AES-256-GCM cipher for a derived key
+func aesGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptConfigFile decrypts an encrypted config, asking for the passphrase unless it is cached for the session
+func decryptConfigFile(filePath string, data []byte) ([]byte, error) {
+	passphraseMu.Lock()
+	cached, ok := passphraseCache[filePath]
+	passphraseMu.Unlock()
+	if ok {
+		if plaintext, err := decryptConfigData(data, cached); err == nil {
+			return plaintext, nil
+		}
+	}
+
+	if passphrasePrompt == nil {
+		return nil, fmt.Errorf("%s is encrypted and no passphrase can be asked for", filePath)
+	}
+	passphrase, ok := passphrasePrompt("Passphrase for "+filePath, false)
+	if !ok {
+		return nil, fmt.Errorf("passphrase entry cancelled for %s", filePath)
+	}
+	plaintext, err := decryptConfigData(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %w", filePath, err)
+	}
+
+	passphraseMu.Lock()
+	passphraseCache[filePath] = passphrase
+	passphraseMu.Unlock()
+	return plaintext, nil
+}
+
+// encryptedConfigExportFormat writes the config as YAML encrypted with a new passphrase
+var encryptedConfigExportFormat = exportFormat{
+	Name:        "Encrypted config (.yaml.enc)",
+	Description: "The config encrypted with a passphrase (AES-256-GCM). Values are exported unredacted since the file is protected; opening it asks for the passphrase.",
+	Extension:   encryptedConfigExtension,
+	Render:      renderEncryptedConfig,
+	KeepSecrets: true,
+	Portable:    true,
+}
+
+// renderEncryptedConfig encrypts config with a passphrase asked for twice
+func renderEncryptedConfig(config Config) ([]byte, error) {
+	if passphrasePrompt == nil {
+		return nil, fmt.Errorf("no passphrase can be asked for")
+	}
+	passphrase, ok := passphrasePrompt("Passphrase for the encrypted config", true)
+	if !ok {
+		return nil, fmt.Errorf("passphrase entry cancelled")
+	}
+
+	config.Version = CurrentConfigVersion
+	plaintext, err := yaml.Marshal(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config to YAML: %w", err)
+	}
+	return encryptConfigData(plaintext, passphrase)
+}
+
+// minPassphraseLength is the shortest passphrase accepted for new encrypted configs
+const minPassphraseLength = 8
+
+// showPassphrasePrompt asks for a passphrase and blocks until the dialog is closed, call it from a goroutine
+// When confirm is set the passphrase has to be entered twice and must be at least minPassphraseLength characters
+func showPassphrasePrompt(title string, confirm bool, parent fyne.Window) (string, bool) {
+	entry := widget.NewPasswordEntry()
+	items := []*widget.FormItem{widget.NewFormItem("Passphrase", entry)}
+	if confirm {
+		repeat := widget.NewPasswordEntry()
+		entry.Validator = func(text string) error {
+			if len([]rune(text)) < minPassphraseLength {
+				return fmt.Errorf("use at least %d characters", minPassphraseLength)
+			}
+			return nil
+		}
+		repeat.Validator = func(text string) error {
+			if text != entry.Text {
+				return fmt.Errorf("passphrases do not match")
+			}
+			return nil
+		}
+		items = append(items, widget.NewFormItem("Repeat", repeat))
+	}
+
+	answer := make(chan bool)
+	dialog.ShowForm(title, "OK", "Cancel", items, func(confirmed bool) {
+		answer <- confirmed
+	}, parent)
+	if !<-answer {
+		return "", false
+	}
+	return entry.Text, true
+}
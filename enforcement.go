@@ -0,0 +1,254 @@
+// enforcement.go
+// Enforcement mode - reapplies a profile whenever the environment drifts from it, within a maintenance window
+// Outside the window corrections wait for it or ask the signed-in user first, so background writes don't disrupt active work
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// What enforcement does with drift found outside the maintenance window
+const (
+	EnforcementDefer  = "defer"  // Correct it when the window opens
+	EnforcementPrompt = "prompt" // Ask the user whether to correct it now
+)
+
+// Drift check intervals in minutes
+const (
+	defaultEnforcementInterval = 15
+	maxEnforcementInterval     = 24 * 60
+)
+
+// enforcementStartDelay lets the window open before the first check can ask anything
+const enforcementStartDelay = time.Minute
+
+// EnforcementSettings controls the optional drift correction
+type EnforcementSettings struct {
+	Enabled         bool     `yaml:"enabled"`          // Reapply the profile when the environment drifts from it
+	Profile         string   `yaml:"profile"`          // Profile kept in place
+	IntervalMinutes int      `yaml:"interval_minutes"` // How often drift is checked
+	WindowStart     string   `yaml:"window_start"`     // Start of the maintenance window as HH:MM, empty allows corrections at any time
+	WindowEnd       string   `yaml:"window_end"`       // End of the maintenance window as HH:MM, before the start for windows past midnight
+	WindowDays      []string `yaml:"window_days"`      // Weekdays of the window (Mon, Tue, ...), empty for every day
+	OutsideWindow   string   `yaml:"outside_window"`   // EnforcementDefer or EnforcementPrompt
+	RequireApproval bool     `yaml:"require_approval"` // Queue corrections as pending approvals instead of applying them
+}
+
+// maintenanceWindow is a daily time range on some weekdays, an empty window is always open
+type maintenanceWindow struct {
+	start, end int // Minutes after midnight
+	days       map[time.Weekday]bool
+	always     bool
+}
+
+// weekdayNames maps the accepted weekday names to weekdays
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseClock parses an HH:MM time of day into minutes after midnight
+func parseClock(text string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(text))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM such as 22:00", text)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseMaintenanceWindow validates the window of the enforcement settings
+func parseMaintenanceWindow(s EnforcementSettings) (maintenanceWindow, error) {
+	if strings.TrimSpace(s.WindowStart) == "" && strings.TrimSpace(s.WindowEnd) == "" {
+		return maintenanceWindow{always: true}, nil
+	}
+	var w maintenanceWindow
+	var err error
+	if w.start, err = parseClock(s.WindowStart); err != nil {
+		return w, fmt.Errorf("window start: %w", err)
+	}
+	if w.end, err = parseClock(s.WindowEnd); err != nil {
+		return w, fmt.Errorf("window end: %w", err)
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("the maintenance window must not start and end at the same time")
+	}
+	if len(s.WindowDays) > 0 {
+		w.days = map[time.Weekday]bool{}
+		for _, name := range s.WindowDays {
+			day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+			if !ok && len(name) > 3 {
+				day, ok = weekdayNames[strings.ToLower(strings.TrimSpace(name))[:3]]
+			}
+			if !ok {
+				return w, fmt.Errorf("invalid weekday %q, use Mon, Tue, Wed, Thu, Fri, Sat or Sun", name)
+			}
+			w.days[day] = true
+		}
+	}
+	return w, nil
+}
+
+// contains reports whether the window is open at t
+// A window past midnight belongs to the day it starts on, so "Fri 22:00-06:00" is open early on Saturday
+func (w maintenanceWindow) contains(t time.Time) bool {
+	if w.always {
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case w.start < w.end:
+		return minute >= w.start && minute < w.end && w.onDay(day)
+	case minute >= w.start:
+		return w.onDay(day)
+	case minute < w.end:
+		return w.onDay((day + 6) % 7)
+	}
+	return false
+}
+
+// onDay reports whether the window opens on day
+func (w maintenanceWindow) onDay(day time.Weekday) bool {
+	return w.days == nil || w.days[day]
+}
+
+// next returns when the window opens next after t
+func (w maintenanceWindow) next(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for d := 0; d <= 7; d++ {
+		day := midnight.AddDate(0, 0, d)
+		if opens := day.Add(time.Duration(w.start) * time.Minute); opens.After(t) && w.onDay(day.Weekday()) {
+			return opens
+		}
+	}
+	return t
+}
+
+// enforcer checks the profile for drift and corrects it according to the window
+type enforcer struct {
+	settings *Settings
+	isAdmin  bool
+	ask      func(profile, drift string) bool // Asks the user whether to correct drift outside the window
+	notify   func(title, content string)
+	mu       sync.Mutex
+	declined bool // The user declined a correction outside the window, wait for the window until the drift changes
+	deferred bool // Drift waiting for the window was already reported
+	drift    string
+}
+
+// startEnforcer checks the enforced profile periodically while the application runs
+// window is used to ask the user outside the maintenance window
+func startEnforcer(settings *Settings, isAdmin bool, window fyne.Window, notify func(title, content string)) {
+	if !settings.Enforcement.Enabled || strings.TrimSpace(settings.Enforcement.Profile) == "" {
+		return
+	}
+	e := &enforcer{settings: settings, isAdmin: isAdmin, ask: askToEnforce(window), notify: notify}
+	go func() {
+		time.Sleep(enforcementStartDelay)
+		for {
+			e.check(time.Now())
+			interval := settings.Enforcement.IntervalMinutes
+			if interval <= 0 {
+				interval = defaultEnforcementInterval
+			}
+			time.Sleep(time.Duration(interval) * time.Minute)
+		}
+	}()
+}
+
+// askToEnforce shows a confirmation and waits for the answer
+func askToEnforce(window fyne.Window) func(profile, drift string) bool {
+	return func(profile, drift string) bool {
+		answer := make(chan bool, 1)
+		dialog.ShowConfirm("Correct Environment Drift",
+			fmt.Sprintf("The environment no longer matches the enforced profile %q:\n%s\n\nIt is outside the maintenance window. Correct it now? Running programs may have to be restarted. Otherwise it is corrected in the next window.", profile, drift),
+			func(ok bool) { answer <- ok }, window)
+		return <-answer
+	}
+}
+
+// check corrects drift from the enforced profile, right away inside the window and otherwise as configured
+func (e *enforcer) check(now time.Time) {
+	if readOnlyMode {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	options := e.settings.Enforcement
+	name := strings.TrimSpace(options.Profile)
+	path, err := profilePath(name)
+	if err != nil {
+		fmt.Printf("Enforcement: %v\n", err)
+		return
+	}
+	config, err := loadConfigForMachine(path)
+	if err != nil {
+		fmt.Printf("Enforcement: could not load profile %s: %v\n", name, err)
+		return
+	}
+	report, err := buildComplianceReport(config, name, e.settings.SensitivePatterns)
+	if err != nil {
+		fmt.Printf("Enforcement: could not check profile %s: %v\n", name, err)
+		return
+	}
+	if report.Compliant {
+		e.declined, e.deferred, e.drift = false, false, ""
+		return
+	}
+	drift := report.describe()
+	if drift != e.drift {
+		e.declined, e.deferred, e.drift = false, false, drift
+	}
+
+	window, err := parseMaintenanceWindow(options)
+	if err != nil {
+		fmt.Printf("Enforcement: %v\n", err)
+		return
+	}
+	if !window.contains(now) {
+		if options.OutsideWindow == EnforcementPrompt && !e.declined && e.ask != nil {
+			if e.ask(name, drift) {
+				e.correct(path, name, drift, "confirmed outside the maintenance window")
+				return
+			}
+			e.declined = true
+		}
+		if !e.deferred {
+			e.deferred = true
+			message := fmt.Sprintf("%s. Corrected in the maintenance window at %s.", drift, window.next(now).Format("Mon 15:04"))
+			fmt.Printf("Enforcement: %s\n", message)
+			e.notify("Environment Drift", message)
+		}
+		return
+	}
+	if options.RequireApproval {
+		// Drift the user already rejected is not queued again until it changes
+		if approvalRequests.rejected(approvalFromEnforcement, name, drift) {
+			return
+		}
+		if _, err := approvalRequests.submit(ApprovalRequest{Profile: name, Origin: approvalFromEnforcement, Reason: drift}); err != nil {
+			fmt.Printf("Enforcement: %v\n", err)
+		}
+		return
+	}
+	e.correct(path, name, drift, "maintenance window")
+}
+
+// correct reapplies the enforced profile and reports the outcome
+func (e *enforcer) correct(path, name, drift, reason string) {
+	fmt.Printf("Enforcement: reapplying profile %s (%s): %s\n", name, reason, drift)
+	if err := applyConfigUnattended(path, e.isAdmin, *e.settings); err != nil {
+		fmt.Printf("Enforcement: reapplying profile %s failed: %v\n", name, err)
+		e.notify("Enforcement Failed", fmt.Sprintf("Profile %s could not be reapplied: %v", name, err))
+		return
+	}
+	e.declined, e.deferred, e.drift = false, false, ""
+	e.notify("Environment Corrected", fmt.Sprintf("Profile %s was reapplied: %s", name, drift))
+}
@@ -0,0 +1,133 @@
+// envsize.go
+// Environment size budget - measures the environment block new processes get and warns when it nears the 32 KB limit
+// Above the limit some programs fail to start child processes without a clear error, so the largest variables are named
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Size budget of the environment block, in UTF-16 characters including separators
+const (
+	environmentBlockLimit  = 32767 // Programs that copy the block into a fixed buffer break above this
+	environmentBudgetShare = 0.8   // Warn once the block uses this share of the limit
+	environmentOffenders   = 5     // Largest variables listed in warnings
+)
+
+// environmentEntry is one variable of the environment block and the characters it takes
+type environmentEntry struct {
+	Name string
+	Size int
+}
+
+// environmentBlockSize returns the size of an environment block made of "NAME=value" entries and its largest entries
+// Every entry ends with a NUL and the block with one more
+func environmentBlockSize(env []string) (int, []environmentEntry) {
+	total := 1
+	entries := make([]environmentEntry, 0, len(env))
+	for _, kv := range env {
+		size := len(utf16.Encode([]rune(kv))) + 1
+		total += size
+		if i := strings.Index(kv, "="); i > 0 {
+			entries = append(entries, environmentEntry{Name: kv[:i], Size: size})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Size > entries[j].Size })
+	return total, entries
+}
+
+// freshEnvironmentSize measures the environment block a newly started program gets
+func freshEnvironmentSize() (int, []environmentEntry, error) {
+	env, err := freshEnvironment()
+	if err != nil {
+		return 0, nil, err
+	}
+	total, entries := environmentBlockSize(env)
+	return total, entries, nil
+}
+
+// overBudget reports whether a block size is close to or above the limit
+func overBudget(size int) bool {
+	return float64(size) >= environmentBudgetShare*environmentBlockLimit
+}
+
+// describeEnvironmentSize renders a block size against the limit, e.g. "27,310 of 32,767 characters (83%)"
+func describeEnvironmentSize(size int) string {
+	return fmt.Sprintf("%s of %s characters (%.0f%%)", groupDigits(size), groupDigits(environmentBlockLimit), 100*float64(size)/environmentBlockLimit)
+}
+
+// describeOffenders names the largest entries of the block with their share
+func describeOffenders(entries []environmentEntry) string {
+	if len(entries) > environmentOffenders {
+		entries = entries[:environmentOffenders]
+	}
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("%s %s (%.0f%%)", e.Name, groupDigits(e.Size), 100*float64(e.Size)/environmentBlockLimit)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// groupDigits formats n with thousands separators
+func groupDigits(n int) string {
+	digits := fmt.Sprint(n)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
+
+// projectedEnvironmentSize estimates the block size after changes are applied, from the raw value lengths
+// Expanding %VAR% references changes the real size a little, so it is an estimate
+func projectedEnvironmentSize(size int, changes []ScopedVariable, current currentValueIndex) int {
+	length := func(name, value string) int {
+		return len(utf16.Encode([]rune(name+"="+value))) + 1
+	}
+	for _, c := range changes {
+		if c.isPatternOperation() {
+			continue
+		}
+		existing, exists := current.lookup(c.Scope, c.Name)
+		if exists {
+			size -= length(existing.Name, existing.Value)
+		}
+		if c.Operation == "set" {
+			size += length(c.Name, c.Value)
+		}
+	}
+	return size
+}
+
+// environmentSizeWarnings warns when a change set brings the environment block close to or above the limit
+func environmentSizeWarnings(changes []ScopedVariable, current currentValueIndex) []string {
+	size, entries, err := freshEnvironmentSize()
+	if err != nil {
+		return []string{fmt.Sprintf("could not measure the environment block: %v", err)}
+	}
+	projected := projectedEnvironmentSize(size, changes, current)
+	switch {
+	case projected > environmentBlockLimit:
+		return []string{fmt.Sprintf("the environment block would grow to about %s, above the limit; some programs then fail to start child processes. Largest now: %s", describeEnvironmentSize(projected), describeOffenders(entries))}
+	case overBudget(projected) && projected > size:
+		return []string{fmt.Sprintf("the environment block would grow to about %s, close to the limit. Largest now: %s", describeEnvironmentSize(projected), describeOffenders(entries))}
+	}
+	return nil
+}
+
+// doctorEnvironmentSizeChecks flags an environment block close to or above the limit
+func doctorEnvironmentSizeChecks() []doctorFinding {
+	size, entries, err := freshEnvironmentSize()
+	if err != nil {
+		return []doctorFinding{{Severity: doctorInfo, Check: "Environment size", Problem: fmt.Sprintf("could not measure the environment block: %v", err)}}
+	}
+	switch {
+	case size > environmentBlockLimit:
+		return []doctorFinding{{Severity: doctorCritical, Check: "Environment size", Problem: fmt.Sprintf("the environment block uses %s, above the limit; some programs fail to start child processes. Shorten or remove the largest variables: %s", describeEnvironmentSize(size), describeOffenders(entries))}}
+	case overBudget(size):
+		return []doctorFinding{{Severity: doctorWarning, Check: "Environment size", Problem: fmt.Sprintf("the environment block uses %s, close to the limit. Largest variables: %s", describeEnvironmentSize(size), describeOffenders(entries))}}
+	}
+	return nil
+}
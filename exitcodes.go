@@ -0,0 +1,128 @@
+// exitcodes.go
+// Exit-code contract - stable exit codes of the command line modes and the optional JSON error report on stderr
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// jsonErrorsFlag makes the command line modes report failures as one JSON object on stderr
+const jsonErrorsFlag = "--json-errors"
+
+// jsonErrors is set once at startup from --json-errors
+var jsonErrors bool
+
+// Exit codes of the command line modes, installers and scripts branch on them so they never change meaning
+const (
+	exitOK                 = 0 // Everything was applied
+	exitFailed             = 1 // Any other failure, and findings of --doctor and --compliance
+	exitValidation         = 2 // The config, profile or arguments are invalid, nothing was applied
+	exitPartialFailure     = 3 // Some variables could not be applied
+	exitPermissionDenied   = 4 // A scope is not writable, administrator privileges are missing or read-only mode is active
+	exitElevationCancelled = 5 // The UAC prompt for the system variables was dismissed
+	exitTimedOut           = 6 // --installer-apply did not finish within its timeout
+)
+
+// exitOutcomes names the exit codes in the JSON error report
+var exitOutcomes = map[int]string{
+	exitOK:                 "ok",
+	exitFailed:             "failed",
+	exitValidation:         "validation_error",
+	exitPartialFailure:     "partial_failure",
+	exitPermissionDenied:   "permission_denied",
+	exitElevationCancelled: "elevation_cancelled",
+	exitTimedOut:           "timed_out",
+}
+
+// errPermissionDenied marks the permission preflight failing
+var errPermissionDenied = errors.New("permission check failed")
+
+// errAdminRequired is returned by unattended applies of system variables without administrator privileges
+var errAdminRequired = errors.New("system variables require administrator privileges")
+
+// errTimedOut is returned when the installer mode stops waiting for an apply
+var errTimedOut = errors.New("apply timed out")
+
+// ValidationError wraps a failure to load or validate a config before anything was written
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying load or validation error
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// exitCodeFor maps an error of an apply to its exit code
+func exitCodeFor(err error) int {
+	var validationErr *ValidationError
+	var applyErrs *ApplyErrors
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errElevationCancelled):
+		return exitElevationCancelled
+	case errors.Is(err, errTimedOut):
+		return exitTimedOut
+	case errors.Is(err, errReadOnly), errors.Is(err, errPermissionDenied), errors.Is(err, errAdminRequired), errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		return exitPermissionDenied
+	case errors.As(err, &applyErrs):
+		return exitPartialFailure
+	case errors.As(err, &validationErr):
+		return exitValidation
+	}
+	return exitFailed
+}
+
+// jsonErrorReport is the object --json-errors writes to stderr
+type jsonErrorReport struct {
+	Code     int                `json:"code"`
+	Outcome  string             `json:"outcome"`
+	Context  string             `json:"context"`
+	Error    string             `json:"error"`
+	Failures []jsonErrorFailure `json:"failures,omitempty"`
+}
+
+// jsonErrorFailure is one variable that could not be applied
+type jsonErrorFailure struct {
+	Name      string `json:"name"`
+	Operation string `json:"operation"`
+	Error     string `json:"error"`
+}
+
+// reportCommandError writes a failure of a command line mode to stderr and returns its exit code
+// context says what failed, such as "Logon reapply of profile dev", and prefixes the plain text message
+func reportCommandError(context string, err error) int {
+	return writeCommandError(context, err, exitCodeFor(err))
+}
+
+// writeCommandError writes a failure to stderr with an exit code chosen by the caller, for modes with codes of their own
+func writeCommandError(context string, err error, code int) int {
+	if err == nil || code == exitOK {
+		return code
+	}
+	if !jsonErrors {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", context, err)
+		return code
+	}
+
+	report := jsonErrorReport{Code: code, Outcome: exitOutcomes[code], Context: context, Error: err.Error()}
+	var applyErrs *ApplyErrors
+	if errors.As(err, &applyErrs) {
+		for _, f := range applyErrs.Failures {
+			report.Failures = append(report.Failures, jsonErrorFailure{Name: f.Name, Operation: f.Operation, Error: f.Err.Error()})
+		}
+	}
+	data, _ := json.Marshal(report)
+	fmt.Fprintln(os.Stderr, string(data))
+	return code
+}
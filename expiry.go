@@ -0,0 +1,207 @@
+// expiry.go
+// Temporary variables - tracks variables applied with an expires field and removes them once they expire
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expirationsFileName stores the expiry time of every variable applied with an expires field
+const expirationsFileName = "expirations.json"
+
+// expiryCheckInterval is how often the running application looks for expired variables
+const expiryCheckInterval = time.Minute
+
+// ExpiringVariable is a variable that must be removed at a given time
+type ExpiringVariable struct {
+	Scope     string    `json:"scope"`      // ScopeUser or ScopeSystem
+	Name      string    `json:"name"`       // Variable name
+	ExpiresAt time.Time `json:"expires_at"` // When the variable is removed
+}
+
+// expirationsMu serializes access to the expirations file between applies and the enforcer
+var expirationsMu sync.Mutex
+
+// parseExpiry converts an expires value into an absolute time
+// Durations are relative to from and accept a d suffix for days (e.g. 8h, 30m, 7d);
+// timestamps are RFC 3339 or a plain date (2006-01-02), which expires at local midnight
+func parseExpiry(expires string, from time.Time) (time.Time, error) {
+	expires = strings.TrimSpace(expires)
+	if strings.HasSuffix(expires, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(expires, "d")); err == nil && days > 0 {
+			return from.AddDate(0, 0, days), nil
+		}
+	}
+	if d, err := time.ParseDuration(expires); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("expiry duration %q must be positive", expires)
+		}
+		return from.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, expires); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", expires, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expires value %q: use a duration such as 8h or 7d, or a timestamp such as 2026-12-31T18:00:00Z", expires)
+}
+
+// loadExpirations reads the tracked expiring variables, the caller must hold expirationsMu
+func loadExpirations() ([]ExpiringVariable, error) {
+	path, err := appDataPath(expirationsFileName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expirations file %s: %w", path, err)
+	}
+
+	var entries []ExpiringVariable
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse expirations file %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// saveExpirations writes the tracked expiring variables, the caller must hold expirationsMu
+func saveExpirations(entries []ExpiringVariable) error {
+	path, err := appDataPath(expirationsFileName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode expirations: %w", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write expirations file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setExpiry tracks or, with a nil expiresAt, stops tracking the expiry of a variable
+func setExpiry(scope, name string, expiresAt *time.Time) error {
+	return setExpiries(scope, map[string]*time.Time{name: expiresAt})
+}
+
+// setExpiries applies several setExpiry updates of one scope with a single read and write of the expirations file
+func setExpiries(scope string, updates map[string]*time.Time) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	expirationsMu.Lock()
+	defer expirationsMu.Unlock()
+
+	entries, err := loadExpirations()
+	if err != nil {
+		return err
+	}
+
+	updated := make(map[string]bool, len(updates))
+	changed := false
+	for name, expiresAt := range updates {
+		updated[strings.ToUpper(name)] = true
+		changed = changed || expiresAt != nil
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.Scope == scope && updated[strings.ToUpper(e.Name)] {
+			changed = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if !changed {
+		return nil // Nothing tracked, avoid rewriting the file
+	}
+	for name, expiresAt := range updates {
+		if expiresAt != nil {
+			kept = append(kept, ExpiringVariable{Scope: scope, Name: name, ExpiresAt: *expiresAt})
+		}
+	}
+	return saveExpirations(kept)
+}
+
+// expiredVariables returns the tracked variables whose expiry time has passed
+func expiredVariables(now time.Time) ([]ExpiringVariable, error) {
+	expirationsMu.Lock()
+	defer expirationsMu.Unlock()
+
+	entries, err := loadExpirations()
+	if err != nil {
+		return nil, err
+	}
+	var expired []ExpiringVariable
+	for _, e := range entries {
+		if !now.Before(e.ExpiresAt) {
+			expired = append(expired, e)
+		}
+	}
+	return expired, nil
+}
+
+// removeExpiredVariables deletes every expired variable the current privileges allow
+// Expired system variables are kept until the application runs as administrator,
+// variables that no longer exist are only forgotten so they aren't deleted again at every check
+func removeExpiredVariables(settings Settings, isAdmin bool) {
+	expired, err := expiredVariables(time.Now())
+	if err != nil {
+		fmt.Printf("Warning: Could not check for expired variables: %v\n", err)
+		return
+	}
+	if len(expired) == 0 {
+		return
+	}
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		fmt.Printf("Warning: Could not check for expired variables: %v\n", err)
+		return
+	}
+
+	var deletions []ScopedVariable
+	for _, e := range expired {
+		if e.Scope == ScopeSystem && !isAdmin {
+			continue
+		}
+		if _, exists := current.lookup(e.Scope, e.Name); !exists {
+			fmt.Printf("Variable %s (%s) expired but no longer exists, forgetting its expiry\n", e.Name, e.Scope)
+			if err := setExpiry(e.Scope, e.Name, nil); err != nil {
+				fmt.Printf("Warning: Could not record expiries: %v\n", err)
+			}
+			continue
+		}
+		fmt.Printf("Variable %s (%s) expired at %s, removing it\n", e.Name, e.Scope, e.ExpiresAt.Format(time.RFC3339))
+		deletions = append(deletions, ScopedVariable{Scope: e.Scope, Variable: Variable{Name: e.Name, Operation: "delete"}})
+	}
+	if len(deletions) == 0 {
+		return
+	}
+
+	if err := applyDirectChanges("Expiry", deletions, isAdmin, settings); err != nil {
+		fmt.Printf("Warning: Could not remove expired variables: %v\n", err)
+	}
+}
+
+// startExpiryEnforcer removes expired variables at startup and then periodically while the application runs
+func startExpiryEnforcer(settings *Settings, isAdmin bool) {
+	go func() {
+		removeExpiredVariables(*settings, isAdmin)
+		ticker := time.NewTicker(expiryCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			removeExpiredVariables(*settings, isAdmin)
+		}
+	}()
+}
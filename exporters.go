@@ -0,0 +1,272 @@
+// exporters.go
+// Export formats - renders the current environment or a config into formats other than the native YAML config
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	sqweekdialog "github.com/sqweek/dialog"
+)
+
+// Export sources offered by the export dialog
+const (
+	exportSourceEnvironment = "Current environment"
+	exportSourceConfig      = "Selected config"
+)
+
+// exportFormat converts a config into the contents of a file
+type exportFormat struct {
+	Name        string                              // Shown in the format selector
+	Description string                              // One-line explanation of the output
+	Extension   string                              // Extension added to the chosen file name, including the dot
+	Render      func(config Config) ([]byte, error) // Produces the file contents
+	StartDir    func() string                       // Optional directory the save dialog opens in
+	KeepSecrets bool                                // Export values unredacted, for formats that protect them
+	Portable    bool                                // Config formats applied on Windows, where %USERPROFILE% references resolve
+}
+
+// builtinExportFormats are the formats shipped with the application
+var builtinExportFormats = []exportFormat{
+	windowsTerminalExportFormat,
+	vsCodeSettingsExportFormat,
+	devContainerExportFormat,
+	jetBrainsEnvsExportFormat,
+	envFileExportFormat,
+	csvExportFormat,
+	gitHubActionsExportFormat,
+	gitLabCIExportFormat,
+	kubernetesExportFormat,
+	terraformExportFormat,
+	ansibleExportFormat,
+	encryptedConfigExportFormat,
+	htmlReportExportFormat,
+	markdownReportExportFormat,
+}
+
+// availableExportFormats returns the built-in formats followed by those provided by exporter plugins
+func availableExportFormats() []exportFormat {
+	formats := append([]exportFormat{}, builtinExportFormats...)
+
+	plugins, err := loadPlugins()
+	if err != nil {
+		fmt.Printf("Warning: Could not load plugins: %v\n", err)
+	}
+	for _, p := range plugins {
+		if p.Kind != PluginKindExporter {
+			continue
+		}
+		p := p
+		formats = append(formats, exportFormat{
+			Name:        p.Name + " (plugin)",
+			Description: p.Description,
+			Extension:   p.Extension,
+			Render:      p.export,
+		})
+	}
+	return formats
+}
+
+// showExportFormatDialog lets the user pick an export format and source and writes the export
+// When selectedPath is set, loadSelected offers the selected config as an alternative source to the current environment
+func showExportFormatDialog(parent fyne.Window, settings *Settings, isAdmin bool, selectedPath string, loadSelected func() (Config, error)) {
+	formats := availableExportFormats()
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.Name
+	}
+	descriptionLabel := widget.NewLabel("")
+	descriptionLabel.Wrapping = fyne.TextWrapWord
+	formatSelect := widget.NewSelect(names, func(string) {})
+	formatSelect.OnChanged = func(string) {
+		if i := formatSelect.SelectedIndex(); i >= 0 {
+			descriptionLabel.SetText(formats[i].Description)
+		}
+	}
+	formatSelect.SetSelectedIndex(0)
+
+	sources := []string{exportSourceEnvironment}
+	if selectedPath != "" {
+		sources = append(sources, exportSourceConfig)
+	}
+	sourceRadio := widget.NewRadioGroup(sources, nil)
+	sourceRadio.Horizontal = true
+	sourceRadio.SetSelected(sources[len(sources)-1])
+
+	dir, _ := pluginsDir()
+	pluginHint := widget.NewLabel(fmt.Sprintf("More formats can be added with exporter plugins in %s", dir))
+	pluginHint.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(formatSelect, descriptionLabel, widget.NewLabel("Source:"), sourceRadio, pluginHint)
+	d := dialog.NewCustomConfirm("Export As", "Export", "Cancel", content, func(ok bool) {
+		i := formatSelect.SelectedIndex()
+		if !ok || i < 0 {
+			return
+		}
+		format := formats[i]
+		load := func() (Config, error) {
+			config, err := exportEnvironmentVariables(isAdmin)
+			if err != nil {
+				return Config{}, fmt.Errorf("error exporting variables: %v", err)
+			}
+			return config, nil
+		}
+		if sourceRadio.Selected == exportSourceConfig {
+			load = loadSelected
+		}
+		backgroundJobs.submit(jobKindExport, "Export as "+format.Name, func(ctx context.Context) error {
+			err := exportInFormat(format, load, settings)
+			if err != nil && err != errJobCancelled {
+				dialog.ShowError(err, parent)
+			}
+			return err
+		})
+	}, parent)
+	d.Resize(fyne.NewSize(520, 300))
+	d.Show()
+}
+
+// exportInFormat loads the export source, asks for a file name and writes the rendered output
+// It returns errJobCancelled when the save dialog is cancelled
+func exportInFormat(format exportFormat, load func() (Config, error), settings *Settings) error {
+	config, err := load()
+	if err != nil {
+		return err
+	}
+	// Mark sensitive variables so formats that support secrets can treat them separately
+	config.UserVariables = markSensitiveVariables(config.UserVariables, settings.SensitivePatterns)
+	config.SystemVariables = markSensitiveVariables(config.SystemVariables, settings.SensitivePatterns)
+	if settings.RedactOnExport && !format.KeepSecrets {
+		config.UserVariables = redactSensitiveVariables(config.UserVariables, settings.SensitivePatterns)
+		config.SystemVariables = redactSensitiveVariables(config.SystemVariables, settings.SensitivePatterns)
+	}
+	if settings.PortableExport && format.Portable {
+		config.UserVariables = portableUserPaths(config.UserVariables)
+	}
+
+	data, err := format.Render(config)
+	if err != nil {
+		return fmt.Errorf("error rendering %s export: %v", format.Name, err)
+	}
+
+	builder := sqweekdialog.File().Title("Export As " + format.Name)
+	if format.StartDir != nil {
+		if dir := format.StartDir(); dir != "" {
+			builder = builder.SetStartDir(dir)
+		}
+	}
+	savePath, err := builder.Save()
+	if err != nil {
+		if err = fileDialogOutcome(err); err == errJobCancelled {
+			return err
+		}
+		return fmt.Errorf("error saving file: %v", err)
+	}
+	if savePath == "" {
+		return errJobCancelled
+	}
+	if format.Extension != "" && !strings.HasSuffix(strings.ToLower(savePath), strings.ToLower(format.Extension)) {
+		savePath += format.Extension
+	}
+	if err := ioutil.WriteFile(savePath, data, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %v", savePath, err)
+	}
+	fmt.Printf("Exported %s to %s\n", format.Name, savePath)
+	return nil
+}
+
+// markSensitiveVariables returns a copy of variables with Sensitive set for every variable matching patterns
+func markSensitiveVariables(variables []Variable, patterns []string) []Variable {
+	marked := make([]Variable, len(variables))
+	for i, v := range variables {
+		v.Sensitive = v.isSensitive(patterns)
+		marked[i] = v
+	}
+	return marked
+}
+
+// exportName returns a name for the exported environment, taken from the config metadata when available
+func exportName(config Config, fallback string) string {
+	if config.Metadata != nil && strings.TrimSpace(config.Metadata.Name) != "" {
+		return strings.TrimSpace(config.Metadata.Name)
+	}
+	return fallback
+}
+
+// exportVariables returns the set and delete operations of config merged per name the way Windows builds the environment:
+// PATH-like variables are joined system first, then user, and for other names the user value wins, each name is returned once
+// Within a section the last entry of a name wins, as it does when the section is applied
+func exportVariables(config Config) []Variable {
+	byName := map[string]*Variable{}
+	var order []string
+	for _, v := range append(lastOperations(config.SystemVariables), lastOperations(config.UserVariables)...) {
+		key := strings.ToUpper(v.Name)
+		existing, ok := byName[key]
+		switch {
+		case !ok:
+			byName[key] = &v
+			order = append(order, key)
+		case v.Operation == "delete":
+			// Deleting the user value leaves the system value in place
+		case existing.Operation == "set" && concatenatedVariables[key]:
+			existing.Value = joinPathValue(existing.Value, v.Value)
+			existing.Sensitive = existing.Sensitive || v.Sensitive
+			if v.isExpandable() {
+				existing.Type = TypeExpand
+			}
+		default:
+			*existing = v
+		}
+	}
+
+	variables := make([]Variable, 0, len(order))
+	for _, key := range order {
+		variables = append(variables, *byName[key])
+	}
+	return variables
+}
+
+// lastOperations returns the last set or delete entry of each name in variables, in order of first appearance
+func lastOperations(variables []Variable) []Variable {
+	index := map[string]int{}
+	var last []Variable
+	for _, v := range variables {
+		if v.Operation != "set" && v.Operation != "delete" {
+			continue
+		}
+		key := strings.ToUpper(v.Name)
+		if i, ok := index[key]; ok {
+			last[i] = v
+			continue
+		}
+		index[key] = len(last)
+		last = append(last, v)
+	}
+	return last
+}
+
+// uniqueExportKeys keeps the set entries of variables whose rendered key is not taken yet, for formats that reject duplicate keys
+// Names that map to the same key, e.g. MY-VAR and MY_VAR as identifiers, keep the first and report the others
+func uniqueExportKeys(variables []Variable, key func(name string) string) []Variable {
+	seen := map[string]string{}
+	var unique []Variable
+	for _, v := range variables {
+		if v.Operation != "set" {
+			continue
+		}
+		k := key(v.Name)
+		if first, taken := seen[k]; taken {
+			fmt.Printf("Warning: %s is left out of the export, its key %s is already used by %s\n", v.Name, k, first)
+			continue
+		}
+		seen[k] = v.Name
+		unique = append(unique, v)
+	}
+	return unique
+}
@@ -0,0 +1,131 @@
+// extends.go
+// Config inheritance - configs that extend a parent config and override some of its entries
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// maxExtendsDepth bounds how many parent configs can be chained
+const maxExtendsDepth = 16
+
+// Origin prefixes shown in the preview, followed by the parent file name
+const (
+	originInherited  = "inherited from "
+	originOverridden = "overrides "
+)
+
+// resolveExtends merges the parents named by config.Extends into config
+// Paths (or URLs) are relative to the file that names them; child entries replace parent entries of the same name and scope
+func resolveExtends(config Config, filePath string) (Config, error) {
+	if !isRemoteConfig(filePath) {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return Config{}, fmt.Errorf("error resolving %s: %w", filePath, err)
+		}
+		filePath = absPath
+	}
+	return resolveExtendsChain(config, filePath, []string{filePath})
+}
+
+// resolveExtendsChain resolves config's parents recursively, chain holds the files already being resolved
+func resolveExtendsChain(config Config, filePath string, chain []string) (Config, error) {
+	if len(config.Extends) == 0 {
+		return config, nil
+	}
+	if len(chain) > maxExtendsDepth {
+		return Config{}, fmt.Errorf("extends chain is deeper than %d configs", maxExtendsDepth)
+	}
+
+	merged := Config{Version: config.Version}
+	for _, parentPath := range config.Extends {
+		parentPath = resolveConfigReference(filePath, parentPath)
+		for _, seen := range chain {
+			if strings.EqualFold(seen, parentPath) {
+				return Config{}, fmt.Errorf("extends cycle: %s extends %s again", filepath.Base(filePath), filepath.Base(parentPath))
+			}
+		}
+
+		parent, err := loadConfig(parentPath)
+		if err != nil {
+			return Config{}, fmt.Errorf("error loading parent config of %s: %w", filepath.Base(filePath), err)
+		}
+		parent, err = resolveExtendsChain(parent, parentPath, append(chain, parentPath))
+		if err != nil {
+			return Config{}, err
+		}
+
+		origin := originInherited + filepath.Base(parentPath)
+		merged.Metadata = parent.Metadata
+		merged.UserVariables = overrideVariables(merged.UserVariables, markOrigin(parent.UserVariables, origin))
+		merged.SystemVariables = overrideVariables(merged.SystemVariables, markOrigin(parent.SystemVariables, origin))
+		merged.Groups = append(merged.Groups, parent.Groups...)
+		merged.Params = overrideParams(merged.Params, parent.Params)
+		merged.PathOrder = append(merged.PathOrder, parent.PathOrder...)
+		merged.Mode, merged.UserMode, merged.SystemMode = overrideMode(merged.Mode, parent.Mode), overrideMode(merged.UserMode, parent.UserMode), overrideMode(merged.SystemMode, parent.SystemMode)
+	}
+
+	if config.Metadata != nil {
+		merged.Metadata = config.Metadata
+	}
+	merged.UserVariables = overrideVariables(merged.UserVariables, config.UserVariables)
+	merged.SystemVariables = overrideVariables(merged.SystemVariables, config.SystemVariables)
+	merged.Groups = append(merged.Groups, config.Groups...)
+	merged.Params = overrideParams(merged.Params, config.Params)
+	merged.PathOrder = append(merged.PathOrder, config.PathOrder...)
+	merged.Mode, merged.UserMode, merged.SystemMode = overrideMode(merged.Mode, config.Mode), overrideMode(merged.UserMode, config.UserMode), overrideMode(merged.SystemMode, config.SystemMode)
+	return merged, nil
+}
+
+// overrideMode returns the mode of the later config when it sets one
+func overrideMode(inherited, mode string) string {
+	if mode != "" {
+		return mode
+	}
+	return inherited
+}
+
+// markOrigin returns a copy of variables with Origin set where it is not already set by a deeper parent
+func markOrigin(variables []Variable, origin string) []Variable {
+	marked := make([]Variable, len(variables))
+	for i, v := range variables {
+		if v.Origin == "" {
+			v.Origin = origin
+		}
+		marked[i] = v
+	}
+	return marked
+}
+
+// overrideVariables replaces base entries with child entries of the same name and appends the rest
+// Conditional child entries are appended instead, so the parent's value still applies where the condition does not match
+func overrideVariables(base, child []Variable) []Variable {
+	result := append([]Variable{}, base...)
+	for _, v := range child {
+		replaced := false
+		if v.Name != "" && !v.hasConditions() {
+			for i, existing := range result {
+				if existing.Name != "" && strings.EqualFold(existing.Name, v.Name) {
+					if strings.HasPrefix(existing.Origin, originInherited) {
+						v.Origin = originOverridden + strings.TrimPrefix(existing.Origin, originInherited)
+					}
+					result[i] = v
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// hasConditions reports whether any when_* selector is set on the variable
+func (v Variable) hasConditions() bool {
+	c := v.Conditions
+	return len(c.WhenHost) > 0 || len(c.WhenOSVersion) > 0 || len(c.WhenUser) > 0 || strings.TrimSpace(c.When) != ""
+}
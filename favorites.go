@@ -0,0 +1,178 @@
+// favorites.go
+// Favorites - frequently edited variables pinned to a strip above the Variables table with a quick-edit dialog
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultFavoriteVariables are pinned until the user changes the favorites
+var defaultFavoriteVariables = []string{"Path", "JAVA_HOME", "HTTP_PROXY"}
+
+// isFavorite reports whether a variable name is pinned
+func (s Settings) isFavorite(name string) bool {
+	for _, favorite := range s.FavoriteVariables {
+		if strings.EqualFold(favorite, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFavorite pins or unpins a variable name and saves the settings
+func setFavorite(settings *Settings, name string, pinned bool) error {
+	var favorites []string
+	for _, favorite := range settings.FavoriteVariables {
+		if !strings.EqualFold(favorite, name) {
+			favorites = append(favorites, favorite)
+		}
+	}
+	if pinned {
+		favorites = append(favorites, name)
+	}
+	updated := *settings
+	updated.FavoriteVariables = favorites
+	if err := saveSettings(updated); err != nil {
+		return err
+	}
+	*settings = updated
+	return nil
+}
+
+// favoriteDefinitions returns the definitions of a favorite in all scopes, the user value first
+func favoriteDefinitions(name string, all []ScopedVariable) []ScopedVariable {
+	var found []ScopedVariable
+	for _, v := range all {
+		if strings.EqualFold(v.Name, name) {
+			found = append(found, v)
+		}
+	}
+	return found
+}
+
+// newFavoritesStrip builds the strip of favorite buttons; the returned function rebuilds it from the current variables
+// Clicking a favorite opens the quick-edit dialog, onChanged is called after a favorite was edited or unpinned
+func newFavoritesStrip(parent fyne.Window, settings *Settings, isAdmin bool, onChanged func()) (fyne.CanvasObject, func(all []ScopedVariable)) {
+	buttons := container.NewHBox()
+	strip := container.NewBorder(nil, nil, widget.NewLabel("★ Favorites:"), nil, container.NewHScroll(buttons))
+
+	refresh := func(all []ScopedVariable) {
+		buttons.RemoveAll()
+		if len(settings.FavoriteVariables) == 0 {
+			buttons.Add(widget.NewLabel("Right-click a variable and choose \"Pin to Favorites\"."))
+		}
+		for _, name := range settings.FavoriteVariables {
+			name := name
+			definitions := favoriteDefinitions(name, all)
+			label := name
+			if len(definitions) == 0 {
+				label += " (not set)"
+			}
+			buttons.Add(widget.NewButton(label, func() {
+				showQuickEditDialog(parent, settings, isAdmin, name, definitions, onChanged)
+			}))
+		}
+		buttons.Refresh()
+	}
+	return strip, refresh
+}
+
+// showQuickEditDialog edits the value of a favorite in one of its scopes and writes it immediately
+func showQuickEditDialog(parent fyne.Window, settings *Settings, isAdmin bool, name string, definitions []ScopedVariable, onDone func()) {
+	byScope := map[string]ScopedVariable{}
+	for _, v := range definitions {
+		byScope[v.Scope] = v
+	}
+
+	// Secrets are edited without showing them
+	var valueEntry *widget.Entry
+	if (Variable{Name: name}).isSensitive(settings.SensitivePatterns) {
+		valueEntry = widget.NewPasswordEntry()
+	} else {
+		valueEntry = widget.NewMultiLineEntry()
+		valueEntry.Wrapping = fyne.TextWrapBreak
+	}
+	definedLabel := widget.NewLabel("")
+	errorLabel := widget.NewLabel("")
+	errorLabel.Wrapping = fyne.TextWrapWord
+	pathHintLabel := widget.NewLabel("")
+	pathHintLabel.Wrapping = fyne.TextWrapBreak
+	valueEntry.OnChanged = func(value string) { pathHintLabel.SetText(editorPathHint(name, value, *settings)) }
+
+	// Saving a system value asks for administrator approval
+	saveButton := widget.NewButton("Save", nil)
+	scopeSelect := widget.NewSelect([]string{ScopeUser, ScopeSystem}, func(scope string) {
+		markElevates(saveButton, isAdmin, scope == ScopeSystem)
+		if v, ok := byScope[scope]; ok {
+			valueEntry.SetText(v.Value)
+			definedLabel.SetText(fmt.Sprintf("Currently set in the %s environment (%s).", scope, v.typeLabel()))
+		} else {
+			valueEntry.SetText("")
+			definedLabel.SetText(fmt.Sprintf("Not set in the %s environment, saving creates it.", scope))
+		}
+	})
+	if _, ok := byScope[ScopeUser]; ok || len(definitions) == 0 {
+		scopeSelect.SetSelected(ScopeUser)
+	} else {
+		scopeSelect.SetSelected(ScopeSystem)
+	}
+
+	var d *dialog.CustomDialog
+	save := func() {
+		scope := scopeSelect.Selected
+		v := ScopedVariable{Scope: scope, Variable: Variable{Name: name, Value: valueEntry.Text, Operation: "set", Type: TypeString}}
+		if existing, ok := byScope[scope]; ok {
+			v.Name, v.Type = existing.Name, existing.typeLabel()
+		}
+		if settings.NormalizePaths {
+			v.Variable, _ = normalizeVariable(v.Variable, *settings)
+		}
+		if err := validateVariable(v.Variable); err != nil {
+			errorLabel.SetText(fmt.Sprintf("⚠️  %v", err))
+			return
+		}
+		d.Hide()
+		go func() {
+			changes := []ScopedVariable{v}
+			if !confirmDangerousChanges(changes, *settings, parent) {
+				return
+			}
+			if err := applyDirectChanges("Favorites quick edit", changes, isAdmin, *settings); err != nil {
+				dialog.ShowError(fmt.Errorf("error applying %s: %v", v.Name, err), parent)
+			}
+			if onDone != nil {
+				onDone()
+			}
+		}()
+	}
+	unpin := func() {
+		d.Hide()
+		if err := setFavorite(settings, name, false); err != nil {
+			dialog.ShowError(fmt.Errorf("error saving settings: %v", err), parent)
+		}
+		if onDone != nil {
+			onDone()
+		}
+	}
+
+	saveButton.OnTapped = save
+	hideInReadOnly(saveButton)
+	form := widget.NewForm(
+		widget.NewFormItem("Scope", scopeSelect),
+		widget.NewFormItem("Value", valueEntry),
+	)
+	d = dialog.NewCustomWithoutButtons(name, container.NewVBox(form, definedLabel, pathHintLabel, errorLabel), parent)
+	d.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("Cancel", func() { d.Hide() }),
+		widget.NewButton("Unpin", unpin),
+		saveButton,
+	})
+	d.Resize(fyne.NewSize(600, 360))
+	d.Show()
+}
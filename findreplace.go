@@ -0,0 +1,169 @@
+// findreplace.go
+// Find & replace - substitutes text in the values of all user and system variables after a per-variable review
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// replacementCandidate is a variable whose value changes under a find & replace
+type replacementCandidate struct {
+	ScopedVariable        // The variable with its current value
+	NewValue       string // Value after the substitution
+}
+
+// findReplacements computes the substitution for every variable whose value matches find
+// Literal searches treat find as plain text; regex searches allow $1-style references in replace
+func findReplacements(variables []ScopedVariable, find, replace string, useRegex, ignoreCase bool) ([]replacementCandidate, error) {
+	if find == "" {
+		return nil, fmt.Errorf("search text must not be empty")
+	}
+
+	pattern := find
+	if !useRegex {
+		pattern = regexp.QuoteMeta(find)
+		// Literal replacements must not interpret $ in the replacement text
+		replace = escapeReplacement(replace)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	var candidates []replacementCandidate
+	for _, v := range variables {
+		if !re.MatchString(v.Value) {
+			continue
+		}
+		newValue := re.ReplaceAllString(v.Value, replace)
+		if newValue != v.Value {
+			candidates = append(candidates, replacementCandidate{ScopedVariable: v, NewValue: newValue})
+		}
+	}
+	return candidates, nil
+}
+
+// escapeReplacement escapes $ so a literal replacement text is inserted unchanged by ReplaceAllString
+func escapeReplacement(text string) string {
+	return strings.ReplaceAll(text, "$", "$$")
+}
+
+// showFindReplaceWindow opens the find & replace tool, onDone is called after replacements were applied
+func showFindReplaceWindow(settings *Settings, isAdmin bool, onDone func()) {
+	window := fyne.CurrentApp().NewWindow("Find & Replace in Values")
+	window.Resize(fyne.NewSize(900, 600))
+
+	findEntry := widget.NewEntry()
+	findEntry.SetPlaceHolder(`e.g. C:\old-tools\`)
+	replaceEntry := widget.NewEntry()
+	replaceEntry.SetPlaceHolder(`e.g. D:\tools\`)
+	regexCheck := widget.NewCheck("Regular expression", nil)
+	ignoreCaseCheck := widget.NewCheck("Ignore case", nil)
+	ignoreCaseCheck.SetChecked(true)
+
+	results := container.NewVBox()
+	summaryLabel := widget.NewLabel("Enter the text to find and click 'Find'.")
+	var candidates []replacementCandidate
+	var accepted []*widget.Check
+
+	scan := func() {
+		results.RemoveAll()
+		candidates, accepted = nil, nil
+
+		all, err := readAllVariables()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error reading environment variables: %v", err), window)
+			return
+		}
+		candidates, err = findReplacements(all, findEntry.Text, replaceEntry.Text, regexCheck.Checked, ignoreCaseCheck.Checked)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+
+		for _, c := range candidates {
+			title := fmt.Sprintf("[%s] %s", c.Scope, c.Name)
+			check := widget.NewCheck(title, nil)
+			check.SetChecked(true)
+			if c.Scope == ScopeSystem && !isAdmin {
+				check.SetText(title + "  (asks for administrator approval)")
+			}
+			accepted = append(accepted, check)
+
+			sensitive := c.isSensitive(settings.SensitivePatterns)
+			oldTokens, newTokens := wordDiff(c.Value, c.NewValue)
+			results.Add(container.NewVBox(
+				check,
+				container.NewGridWithColumns(2,
+					highlightedValueText(oldTokens, theme.ColorNameError, sensitive),
+					highlightedValueText(newTokens, theme.ColorNameSuccess, sensitive),
+				),
+			))
+		}
+		summaryLabel.SetText(fmt.Sprintf("%d variable(s) would change. Uncheck the substitutions you don't want.", len(candidates)))
+		results.Refresh()
+	}
+
+	findButton := widget.NewButton("Find", scan)
+	applyButton := widget.NewButton("Apply Accepted", func() {
+		var changes []ScopedVariable
+		for i, c := range candidates {
+			if accepted[i].Checked {
+				changed := c.Variable
+				changed.Operation = "set"
+				changed.Value = c.NewValue
+				changes = append(changes, ScopedVariable{Scope: c.Scope, Variable: changed})
+			}
+		}
+		if len(changes) == 0 {
+			dialog.ShowInformation("Nothing to Apply", "No substitutions are accepted.", window)
+			return
+		}
+		dialog.ShowConfirm("Apply Replacements", fmt.Sprintf("Change %d variable(s)?", len(changes)), func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				if !confirmDangerousChanges(changes, *settings, window) {
+					return
+				}
+				if err := applyDirectChanges("Find & Replace", changes, isAdmin, *settings); err != nil {
+					dialog.ShowError(fmt.Errorf("error applying replacements: %v", err), window)
+				} else {
+					dialog.ShowInformation("Replacements Applied", fmt.Sprintf("%d variable(s) updated.", len(changes)), window)
+				}
+				scan()
+				if onDone != nil {
+					onDone()
+				}
+			}()
+		}, window)
+	})
+
+	hideInReadOnly(applyButton)
+
+	form := widget.NewForm(
+		widget.NewFormItem("Find", findEntry),
+		widget.NewFormItem("Replace with", replaceEntry),
+	)
+	scroll := container.NewScroll(results)
+
+	window.SetContent(container.NewBorder(
+		container.NewVBox(form, container.NewHBox(regexCheck, ignoreCaseCheck, findButton), widget.NewSeparator(), summaryLabel),
+		container.NewHBox(applyButton, widget.NewButton("Close", window.Close)),
+		nil, nil,
+		scroll,
+	))
+	window.Show()
+}
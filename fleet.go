@@ -0,0 +1,399 @@
+// fleet.go
+// Fleet apply - writes a config to many machines and their signed-in users over the remote registry, several targets at a time
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows/registry"
+)
+
+// defaultFleetParallelism is how many targets a fleet apply writes at the same time unless configured otherwise
+const defaultFleetParallelism = 8
+
+// maxFleetParallelism caps the setting, every target holds a remote registry connection while it is written
+const maxFleetParallelism = 64
+
+// fleetTarget is one environment written by a fleet apply: the system environment of a host or a signed-in user's environment there
+type fleetTarget struct {
+	Host    string
+	Scope   string        // Empty for a host whose targets could not be listed
+	Profile remoteProfile // The user of a user target
+}
+
+// label names the target in results and the console log
+func (t fleetTarget) label() string {
+	switch t.Scope {
+	case ScopeUser:
+		return fmt.Sprintf("%s (%s)", t.Host, t.Profile.Name)
+	case ScopeSystem:
+		return fmt.Sprintf("%s (system)", t.Host)
+	}
+	return t.Host
+}
+
+// fleetResult is the outcome of one target, or of a host whose targets could not be listed
+type fleetResult struct {
+	Target   fleetTarget
+	Written  int             // Variables set or deleted, unchanged ones are not counted
+	Failures []VariableError // Variables that could not be written
+	Err      error           // Why the target failed as a whole
+	Duration time.Duration
+}
+
+// ok reports whether every variable of the target was written
+func (r fleetResult) ok() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// parseFleetHosts splits host names typed one per line or separated by commas, dropping duplicates
+func parseFleetHosts(text string) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == '\r' || r == ',' || r == ';' }) {
+		host := remoteHostName(field)
+		if host == "" || seen[strings.ToUpper(host)] {
+			continue
+		}
+		seen[strings.ToUpper(host)] = true
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// runBounded calls fn for every index from 0 to count-1 on at most limit goroutines and waits for all calls
+// Indexes not started when ctx is cancelled are skipped
+func runBounded(ctx context.Context, limit, count int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-slots; wg.Done() }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// targetFacts returns the host facts conditions and value scripts see for a target
+// Only the host and user names are the target's, the other facts still describe this machine
+func targetFacts(t fleetTarget) map[string]interface{} {
+	facts := hostFacts()
+	facts["hostname"] = t.Host
+	if t.Scope == ScopeUser {
+		facts["username"], facts["user_domain"] = t.Profile.Name, ""
+		if i := strings.LastIndex(t.Profile.Name, `\`); i >= 0 {
+			facts["username"], facts["user_domain"] = t.Profile.Name[i+1:], t.Profile.Name[:i]
+		}
+	}
+	return facts
+}
+
+// configForTarget selects and resolves the entries of base for one target
+// Entries that need the target's registry to be expanded, delete_matching and sync mode, are refused
+func configForTarget(base Config, t fleetTarget, settings Settings) ([]Variable, error) {
+	facts := targetFacts(t)
+	config, err := selectApplicableVariablesWith(base, facts)
+	if err != nil {
+		return nil, err
+	}
+	if config, err = evaluateConfigScriptsWith(config, facts); err != nil {
+		return nil, err
+	}
+	if config, err = resolveWithParamDefaults(config); err != nil {
+		return nil, err
+	}
+	config = normalizeConfigPaths(config, settings)
+	if config.sectionMode(t.Scope) == ConfigModeSync {
+		return nil, fmt.Errorf("sync mode is not supported for remote machines")
+	}
+	variables := config.UserVariables
+	if t.Scope == ScopeSystem {
+		variables = config.SystemVariables
+	}
+	for _, v := range variables {
+		if v.Operation != "set" && v.Operation != "delete" {
+			return nil, fmt.Errorf("%s: %s is not supported for remote machines", v.Name, v.Operation)
+		}
+	}
+	return variables, nil
+}
+
+// fleetTargetsFor lists the targets of one host: its system environment and the environment of every signed-in user
+func fleetTargetsFor(host string, base Config, retry RetrySettings) ([]fleetTarget, error) {
+	var targets []fleetTarget
+	if len(base.SystemVariables) > 0 || hasGroupVariables(base, ScopeSystem) {
+		targets = append(targets, fleetTarget{Host: host, Scope: ScopeSystem})
+	}
+	if len(base.UserVariables) > 0 || hasGroupVariables(base, ScopeUser) {
+		profiles, err := listRemoteProfiles(host, retry)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range profiles {
+			targets = append(targets, fleetTarget{Host: host, Scope: ScopeUser, Profile: p})
+		}
+	}
+	return targets, nil
+}
+
+// hasGroupVariables reports whether a group of config has variables of the scope
+func hasGroupVariables(config Config, scope string) bool {
+	for _, g := range config.Groups {
+		if (scope == ScopeUser && len(g.UserVariables) > 0) || (scope == ScopeSystem && len(g.SystemVariables) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRemoteVariables writes variables below root, a key of a remote registry, and returns how many it changed
+// Protected variables are refused like in a local apply; expiries, the trash and managed records only exist for this machine
+func writeRemoteVariables(root registry.Key, subkey string, variables []Variable, settings Settings) (int, []VariableError, error) {
+	key, err := registry.OpenKey(root, subkey, registry.SET_VALUE|registry.QUERY_VALUE)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open the remote environment key: %w", err)
+	}
+	defer key.Close()
+
+	written := 0
+	var failures []VariableError
+	for _, v := range variables {
+		currentValue, currentType, readErr := key.GetStringValue(v.Name)
+		opErr := protectionError(v, currentValue, readErr == nil, settings.ProtectedVariables)
+		if opErr == nil {
+			switch v.Operation {
+			case "set":
+				wantType := uint32(registry.SZ)
+				if v.isExpandable() {
+					wantType = registry.EXPAND_SZ
+				}
+				switch {
+				case readErr == nil && currentValue == v.Value && currentType == wantType:
+					continue
+				case v.isExpandable():
+					opErr = key.SetExpandStringValue(v.Name, v.Value)
+				default:
+					opErr = key.SetStringValue(v.Name, v.Value)
+				}
+			case "delete":
+				if readErr != nil {
+					continue
+				}
+				opErr = key.DeleteValue(v.Name)
+			}
+		}
+		if opErr != nil {
+			failures = append(failures, VariableError{Name: v.Name, Operation: v.Operation, Err: opErr})
+			continue
+		}
+		written++
+	}
+	return written, failures, nil
+}
+
+// applyFleetTarget writes the entries of base that apply to one target
+func applyFleetTarget(base Config, t fleetTarget, settings Settings) (result fleetResult) {
+	start := time.Now()
+	result.Target = t
+	defer func() { result.Duration = time.Since(start) }()
+
+	variables, err := configForTarget(base, t, settings)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if len(variables) == 0 {
+		return result
+	}
+	root, subkey := registry.LOCAL_MACHINE, systemEnvironmentPath
+	if t.Scope == ScopeUser {
+		root, subkey = registry.USERS, t.Profile.SID+`\`+userEnvironmentPath
+	}
+	remote, err := openRemoteKey(t.Host, root, settings.Retry)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to connect to the remote registry: %w", err)
+		return result
+	}
+	defer remote.Close()
+	result.Written, result.Failures, result.Err = writeRemoteVariables(remote, subkey, variables, settings)
+	return result
+}
+
+// applyToFleet applies base to every host, listing the hosts' targets and then writing the targets at most parallelism at a time
+// progress is called after each target with the number of finished and known targets
+func applyToFleet(ctx context.Context, base Config, hosts []string, settings Settings, progress func(done, total int)) []fleetResult {
+	parallelism := settings.FleetParallelism
+	if parallelism < 1 || parallelism > maxFleetParallelism {
+		parallelism = defaultFleetParallelism
+	}
+
+	var mu sync.Mutex
+	var targets []fleetTarget
+	var results []fleetResult
+	runBounded(ctx, parallelism, len(hosts), func(i int) {
+		var found []fleetTarget
+		err := fmt.Errorf("not one of the target hosts in the config's metadata")
+		if base.Metadata.targetsHost(hosts[i]) {
+			found, err = fleetTargetsFor(hosts[i], base, settings.Retry)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			results = append(results, fleetResult{Target: fleetTarget{Host: hosts[i]}, Err: err})
+			return
+		}
+		targets = append(targets, found...)
+	})
+
+	done := len(results)
+	total := len(targets) + len(results)
+	progress(done, total)
+	runBounded(ctx, parallelism, len(targets), func(i int) {
+		result := applyFleetTarget(base, targets[i], settings)
+		if result.ok() {
+			fmt.Printf("Fleet apply: %s: %d variable(s) written in %s\n", result.Target.label(), result.Written, result.Duration.Round(time.Millisecond))
+		} else {
+			fmt.Printf("Fleet apply: %s failed: %s\n", result.Target.label(), result.problem())
+		}
+		mu.Lock()
+		results = append(results, result)
+		done++
+		current := done
+		mu.Unlock()
+		progress(current, total)
+	})
+	return results
+}
+
+// problem describes why a target failed
+func (r fleetResult) problem() string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	parts := make([]string, len(r.Failures))
+	for i, f := range r.Failures {
+		parts[i] = fmt.Sprintf("%s: %v", f.Name, f.Err)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// describeFleetResults summarizes the results in one line and lists the failed targets
+func describeFleetResults(results []fleetResult, hosts int) (string, []string) {
+	failed, written := 0, 0
+	var lines []string
+	for _, r := range results {
+		written += r.Written
+		if !r.ok() {
+			failed++
+			lines = append(lines, fmt.Sprintf("%s: %s", r.Target.label(), r.problem()))
+		}
+	}
+	summary := fmt.Sprintf("%d of %d target(s) on %d machine(s) succeeded, %d variable(s) written", len(results)-failed, len(results), hosts, written)
+	return summary, lines
+}
+
+// showFleetApplyWindow applies the selected config to a list of machines as one job
+// load returns the selected config with its parents merged but its conditions not evaluated yet
+func showFleetApplyWindow(settings *Settings, selectedPath string, load func() (Config, error)) {
+	window := fyne.CurrentApp().NewWindow("Apply to Machines")
+	window.Resize(fyne.NewSize(760, 560))
+
+	hostsEntry := widget.NewMultiLineEntry()
+	hostsEntry.SetPlaceHolder("One host name per line, e.g.\nLAB-PC-01\nLAB-PC-02")
+	hostsEntry.SetMinRowsVisible(6)
+	infoLabel := widget.NewLabel("")
+	infoLabel.Wrapping = fyne.TextWrapWord
+	progressLabel := widget.NewLabel("")
+	resultLabel := widget.NewLabel("")
+	resultLabel.Wrapping = fyne.TextWrapBreak
+
+	parallelism := settings.FleetParallelism
+	if parallelism < 1 || parallelism > maxFleetParallelism {
+		parallelism = defaultFleetParallelism
+	}
+	infoLabel.SetText(fmt.Sprintf("Config: %s\nSystem variables go to each machine, user variables to every user signed in there. Up to %d targets are written at a time (Parallel Machines in Settings). Needs the Remote Registry service and an account that is administrator on the machines.", selectedPath, parallelism))
+
+	var applyButton *widget.Button
+	applyButton = widget.NewButton("Apply", func() {
+		hosts := parseFleetHosts(hostsEntry.Text)
+		if len(hosts) == 0 {
+			dialog.ShowInformation("Apply to Machines", "Enter at least one host name.", window)
+			return
+		}
+		dialog.ShowConfirm("Apply to Machines", fmt.Sprintf("Apply %s to %d machine(s)?", selectedPath, len(hosts)), func(ok bool) {
+			if !ok {
+				return
+			}
+			applyButton.Disable()
+			resultLabel.SetText("")
+			progressLabel.SetText("Waiting for other jobs...")
+			backgroundJobs.submit(jobKindRemote, fmt.Sprintf("Apply %s to %d machine(s)", selectedPath, len(hosts)), func(ctx context.Context) error {
+				defer applyButton.Enable()
+				base, err := load()
+				if err != nil {
+					progressLabel.SetText("")
+					dialog.ShowError(err, window)
+					return err
+				}
+				progressLabel.SetText("Listing the signed-in users...")
+				results := applyToFleet(ctx, base, hosts, *settings, func(done, total int) {
+					progressLabel.SetText(fmt.Sprintf("%d of %d target(s) done", done, total))
+				})
+				summary, failures := describeFleetResults(results, len(hosts))
+				if jobCancelled(ctx) {
+					summary = "Cancelled: " + summary
+				}
+				progressLabel.SetText(summary)
+				resultLabel.SetText(strings.Join(failures, "\n"))
+
+				entry := HistoryEntry{Action: "fleet", ConfigPath: selectedPath, Metadata: base.Metadata, Success: len(failures) == 0, Message: summary}
+				if err := appendHistory(entry); err != nil {
+					fmt.Printf("Warning: Could not write audit log: %v\n", err)
+				}
+				switch {
+				case jobCancelled(ctx):
+					return errJobCancelled
+				case len(failures) > 0:
+					return fmt.Errorf("%d target(s) failed", len(failures))
+				}
+				return nil
+			})
+		}, window)
+	})
+
+	window.SetContent(container.NewBorder(
+		container.NewVBox(infoLabel, hostsEntry, progressLabel),
+		container.NewHBox(applyButton, widget.NewButton("Close", window.Close)),
+		nil, nil,
+		container.NewVScroll(resultLabel),
+	))
+	window.Show()
+}
+
+// loadFleetConfig loads a config with its parents merged; conditions, scripts and placeholders are resolved per target
+func loadFleetConfig(path string) (Config, error) {
+	config, err := loadConfig(path)
+	if err != nil {
+		return config, err
+	}
+	return resolveExtends(config, path)
+}
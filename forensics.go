@@ -0,0 +1,312 @@
+// forensics.go
+// Change-source forensics - audits writes to the Environment keys and reads the Security event log to show who changed a variable
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows"
+)
+
+// registryAuditSubcategory is the GUID of the "Registry" object access audit subcategory, independent of the display language
+const registryAuditSubcategory = "{0CCE921E-69AE-11D9-BED3-505054503030}"
+
+// registryValueModifiedEvent is the Security log event "A registry value was modified"
+const registryValueModifiedEvent = 4657
+
+// maxForensicEvents limits how many recent events are read from the Security log
+const maxForensicEvents = 500
+
+// environmentAuditACE audits successful value writes (KEY_SET_VALUE) by everyone
+const environmentAuditACE = "(AU;SA;0x2;;;WD)"
+
+// environmentAuditACEForms are the renderings of the audit entry when a SACL is converted back to SDDL
+var environmentAuditACEForms = []string{environmentAuditACE, "(AU;SA;DC;;;WD)"}
+
+// auditedEnvironmentKeys are the Environment keys in the object name syntax of the security APIs
+var auditedEnvironmentKeys = []string{
+	`MACHINE\` + systemEnvironmentPath,
+	`CURRENT_USER\` + userEnvironmentPath,
+}
+
+// ChangeSourceEvent is one Security log record of a write to an Environment key
+type ChangeSourceEvent struct {
+	Time      time.Time
+	User      string // DOMAIN\name of the account that made the change
+	Process   string // Executable that made the change
+	Key       string // Registry key as logged, e.g. \REGISTRY\MACHINE\SYSTEM\...\Environment
+	Name      string // Variable name
+	Operation string // "created", "modified" or "deleted"
+	OldValue  string
+	NewValue  string
+}
+
+// scope returns the environment scope of the logged key
+func (e ChangeSourceEvent) scope() string {
+	if strings.HasPrefix(strings.ToUpper(e.Key), `\REGISTRY\USER\`) {
+		return ScopeUser
+	}
+	return ScopeSystem
+}
+
+// enablePrivilege enables a privilege in the process token, SeSecurityPrivilege is needed to read or write a SACL
+func enablePrivilege(name string) error {
+	var token windows.Token
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, &token); err != nil {
+		return fmt.Errorf("failed to open process token: %w", err)
+	}
+	defer token.Close()
+
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	var luid windows.LUID
+	if err := windows.LookupPrivilegeValue(nil, namePtr, &luid); err != nil {
+		return fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	privileges := windows.Tokenprivileges{PrivilegeCount: 1}
+	privileges.Privileges[0] = windows.LUIDAndAttributes{Luid: luid, Attributes: windows.SE_PRIVILEGE_ENABLED}
+	if err := windows.AdjustTokenPrivileges(token, false, &privileges, uint32(unsafe.Sizeof(privileges)), nil, nil); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", name, err)
+	}
+	// AdjustTokenPrivileges succeeds without assigning privileges the token does not hold
+	if windows.GetLastError() == windows.ERROR_NOT_ALL_ASSIGNED {
+		return fmt.Errorf("%s is not held by this account", name)
+	}
+	return nil
+}
+
+// enableChangeAuditing turns on registry auditing and adds an audit entry to the SACL of both Environment keys
+// Existing SACL entries are kept; running it again is harmless
+func enableChangeAuditing() error {
+	if readOnlyMode {
+		return errReadOnly
+	}
+	if err := enablePrivilege("SeSecurityPrivilege"); err != nil {
+		return err
+	}
+
+	if output, err := runHidden("auditpol.exe", "/set", "/subcategory:"+registryAuditSubcategory, "/success:enable"); err != nil {
+		return fmt.Errorf("failed to enable registry auditing: %v: %s", err, output)
+	}
+
+	for _, key := range auditedEnvironmentKeys {
+		sd, err := windows.GetNamedSecurityInfo(key, windows.SE_REGISTRY_KEY, windows.SACL_SECURITY_INFORMATION)
+		if err != nil {
+			return fmt.Errorf("failed to read the audit settings of %s: %w", key, err)
+		}
+		sddl := sd.String()
+		if hasEnvironmentAuditACE(sddl) {
+			continue
+		}
+		if !strings.HasPrefix(sddl, "S:") {
+			sddl = "S:"
+		}
+		updated, err := windows.SecurityDescriptorFromString(sddl + environmentAuditACE)
+		if err != nil {
+			return fmt.Errorf("failed to build the audit settings of %s: %w", key, err)
+		}
+		sacl, _, err := updated.SACL()
+		if err != nil {
+			return fmt.Errorf("failed to build the audit settings of %s: %w", key, err)
+		}
+		if err := windows.SetNamedSecurityInfo(key, windows.SE_REGISTRY_KEY, windows.SACL_SECURITY_INFORMATION, nil, nil, nil, sacl); err != nil {
+			return fmt.Errorf("failed to write the audit settings of %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// hasEnvironmentAuditACE reports whether an SDDL string already contains the audit entry
+func hasEnvironmentAuditACE(sddl string) bool {
+	for _, form := range environmentAuditACEForms {
+		if strings.Contains(sddl, form) {
+			return true
+		}
+	}
+	return false
+}
+
+// changeAuditingEnabled reports whether both Environment keys carry the audit entry
+func changeAuditingEnabled() (bool, error) {
+	if err := enablePrivilege("SeSecurityPrivilege"); err != nil {
+		return false, err
+	}
+	for _, key := range auditedEnvironmentKeys {
+		sd, err := windows.GetNamedSecurityInfo(key, windows.SE_REGISTRY_KEY, windows.SACL_SECURITY_INFORMATION)
+		if err != nil {
+			return false, fmt.Errorf("failed to read the audit settings of %s: %w", key, err)
+		}
+		if !hasEnvironmentAuditACE(sd.String()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// eventLogRecord is the part of a Security log event rendered by wevtutil that is needed here
+type eventLogRecord struct {
+	System struct {
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+	} `xml:"System"`
+	Data []struct {
+		Name  string `xml:"Name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"EventData>Data"`
+}
+
+// registryOperations translates the message placeholders used for the OperationType field
+var registryOperations = map[string]string{
+	"%%1904": "created",
+	"%%1905": "modified",
+	"%%1906": "deleted",
+}
+
+// loadChangeSourceEvents reads recent Environment key writes from the Security event log, newest first
+func loadChangeSourceEvents() ([]ChangeSourceEvent, error) {
+	query := fmt.Sprintf("*[System[(EventID=%d)]]", registryValueModifiedEvent)
+	output, err := runHidden("wevtutil.exe", "qe", "Security", "/q:"+query, "/f:xml", "/rd:true", fmt.Sprintf("/c:%d", maxForensicEvents))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Security event log: %v: %s", err, output)
+	}
+	return parseChangeSourceEvents(output)
+}
+
+// parseChangeSourceEvents decodes wevtutil XML output and keeps the events of the Environment keys
+func parseChangeSourceEvents(output string) ([]ChangeSourceEvent, error) {
+	var document struct {
+		Events []eventLogRecord `xml:"Event"`
+	}
+	if err := xml.Unmarshal([]byte("<Events>"+output+"</Events>"), &document); err != nil {
+		return nil, fmt.Errorf("failed to parse Security event log output: %w", err)
+	}
+
+	var events []ChangeSourceEvent
+	for _, record := range document.Events {
+		fields := map[string]string{}
+		for _, data := range record.Data {
+			fields[data.Name] = strings.TrimSpace(data.Value)
+		}
+		if !strings.HasSuffix(strings.ToUpper(fields["ObjectName"]), `\ENVIRONMENT`) {
+			continue
+		}
+		event := ChangeSourceEvent{
+			User:      fields["SubjectDomainName"] + `\` + fields["SubjectUserName"],
+			Process:   fields["ProcessName"],
+			Key:       fields["ObjectName"],
+			Name:      fields["ObjectValueName"],
+			Operation: fields["OperationType"],
+			OldValue:  fields["OldValue"],
+			NewValue:  fields["NewValue"],
+		}
+		if word, ok := registryOperations[event.Operation]; ok {
+			event.Operation = word
+		}
+		event.Time, _ = time.Parse(time.RFC3339Nano, record.System.TimeCreated.SystemTime)
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.After(events[j].Time) })
+	return events, nil
+}
+
+// summary renders an event as a single line, masking sensitive values
+func (e ChangeSourceEvent) summary(patterns []string) string {
+	oldValue, newValue := e.OldValue, e.NewValue
+	if (Variable{Name: e.Name}).isSensitive(patterns) {
+		oldValue, newValue = maskedValue, maskedValue
+	}
+	return fmt.Sprintf("%s  %-6s %s %s by %s (%s)  -  %q -> %q",
+		e.Time.Local().Format("2006-01-02 15:04:05"), e.scope(), e.Name, e.Operation, e.User, e.Process, oldValue, newValue)
+}
+
+// showChangeSourcesWindow lists which process and user modified environment variables according to the Security log
+func showChangeSourcesWindow(settings *Settings, isAdmin bool, parent fyne.Window) {
+	if !isAdmin {
+		dialog.ShowInformation("Admin Required", "Registry auditing and the Security event log are only accessible when running as Administrator.", parent)
+		return
+	}
+
+	win := fyne.CurrentApp().NewWindow("Change Sources")
+	var events []ChangeSourceEvent
+	statusLabel := widget.NewLabel("Reading the Security event log...")
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("Filter by variable name")
+
+	var shown []ChangeSourceEvent
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(shown[id].summary(settings.SensitivePatterns))
+		},
+	)
+	applyFilter := func() {
+		filter := strings.ToUpper(strings.TrimSpace(filterEntry.Text))
+		shown = nil
+		for _, e := range events {
+			if filter == "" || strings.Contains(strings.ToUpper(e.Name), filter) {
+				shown = append(shown, e)
+			}
+		}
+		list.Refresh()
+	}
+	filterEntry.OnChanged = func(string) { applyFilter() }
+
+	reload := func() {
+		go func() {
+			enabled, err := changeAuditingEnabled()
+			if err != nil {
+				statusLabel.SetText(fmt.Sprintf("Could not check audit settings: %v", err))
+				return
+			}
+			loaded, err := loadChangeSourceEvents()
+			if err != nil {
+				statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			events = loaded
+			applyFilter()
+			status := fmt.Sprintf("%d change(s) of environment variables found in the Security event log.", len(events))
+			if !enabled {
+				status += " Auditing is off: click 'Enable Auditing' to record future changes."
+			}
+			statusLabel.SetText(status)
+		}()
+	}
+
+	enableButton := widget.NewButton("Enable Auditing", func() {
+		go func() {
+			if err := enableChangeAuditing(); err != nil {
+				dialog.ShowError(fmt.Errorf("error enabling auditing: %v", err), win)
+				return
+			}
+			dialog.ShowInformation("Auditing Enabled", "Writes to the user and system Environment keys are now recorded in the Security event log, including the process and account that made them.", win)
+			reload()
+		}()
+	})
+	if readOnlyMode {
+		enableButton.Disable()
+	}
+
+	win.SetContent(container.NewBorder(
+		container.NewVBox(statusLabel, filterEntry),
+		container.NewHBox(enableButton, widget.NewButton("Refresh", reload)),
+		nil, nil,
+		list,
+	))
+	win.Resize(fyne.NewSize(1000, 500))
+	win.Show()
+	reload()
+}
@@ -5,6 +5,7 @@ go 1.24.5
 require (
 	fyne.io/fyne/v2 v2.6.2
 	github.com/sqweek/dialog v0.0.0-20240226140203-065105509627
+	golang.org/x/crypto v0.33.0
 	golang.org/x/sys v0.34.0
 	gopkg.in/yaml.v2 v2.4.0
 )
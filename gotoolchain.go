@@ -0,0 +1,186 @@
+// gotoolchain.go
+// Go helper - checks GOROOT, GOPATH, GOBIN and GOFLAGS and wires the Go binary directory into the user PATH
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// goVariables are the Go variables the helper checks
+var goVariables = []string{"GOROOT", "GOPATH", "GOBIN", "GOFLAGS"}
+
+// goInstallDirs returns the directories containing go.exe, from the Path first, then the installer default
+func goInstallDirs(ctx toolchainContext) []string {
+	var dirs []string
+	add := func(dir string) {
+		if _, err := os.Stat(filepath.Join(dir, "go.exe")); err == nil && !containsPathEntry(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, scope := range []string{ScopeSystem, ScopeUser} {
+		if v, ok := ctx.lookup(scope, "Path"); ok {
+			for _, entry := range pathEntries(v.Value) {
+				add(expandedPathEntry(entry))
+			}
+		}
+	}
+	add(filepath.Join(os.Getenv("ProgramFiles"), "Go", "bin"))
+	return dirs
+}
+
+// goLookup returns the value of a Go variable, the user scope winning over the system scope like go env does
+func goLookup(ctx toolchainContext) map[string]ScopedVariable {
+	found := map[string]ScopedVariable{}
+	for _, name := range goVariables {
+		for _, scope := range []string{ScopeUser, ScopeSystem} {
+			if v, ok := ctx.lookup(scope, name); ok {
+				found[name] = v
+				break
+			}
+		}
+	}
+	return found
+}
+
+// goBinEntry returns the PATH entry go install writes to: %GOBIN%, else the bin directory of the first GOPATH entry
+// A single GOPATH is referenced as %GOPATH%\bin so the Path follows it, a list cannot be and is spelled out
+func goBinEntry(vars map[string]ScopedVariable) string {
+	if _, ok := vars["GOBIN"]; ok {
+		return "%GOBIN%"
+	}
+	gopath, ok := vars["GOPATH"]
+	if !ok {
+		return `%USERPROFILE%\go\bin`
+	}
+	if entries := pathEntries(gopath.Value); len(entries) > 1 {
+		return filepath.Join(entries[0], "bin")
+	}
+	return `%GOPATH%\bin`
+}
+
+// goWarnings lists what is wrong or unnecessary in the Go variables; removable names what the user may delete
+func goWarnings(ctx toolchainContext, vars map[string]ScopedVariable, installs []string) (warnings []string, removable []ScopedVariable) {
+	if v, ok := vars["GOROOT"]; ok {
+		root := expandedPathEntry(v.Value)
+		switch {
+		case !isDirectory(root) || !fileExists(filepath.Join(root, "bin", "go.exe")):
+			warnings = append(warnings, fmt.Sprintf("GOROOT (%s) is %s, which has no bin\\go.exe. The go command fails to find its standard library, remove GOROOT.", v.Scope, v.Value))
+			removable = append(removable, v)
+		case len(installs) > 0 && normalizePathEntry(installs[0]) != normalizePathEntry(filepath.Join(root, "bin")):
+			warnings = append(warnings, fmt.Sprintf("GOROOT (%s) is %s but the go.exe found first is in %s. A mismatched GOROOT breaks builds after an upgrade, remove GOROOT.", v.Scope, v.Value, installs[0]))
+			removable = append(removable, v)
+		default:
+			warnings = append(warnings, fmt.Sprintf("GOROOT (%s) is set but unnecessary, go finds its own installation. It goes stale on the next upgrade to a different directory.", v.Scope))
+			removable = append(removable, v)
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	if v, ok := vars["GOPATH"]; ok {
+		for _, entry := range pathEntries(v.Value) {
+			dir := expandedPathEntry(entry)
+			if !filepath.IsAbs(dir) {
+				warnings = append(warnings, fmt.Sprintf("GOPATH entry %q is not an absolute path, go refuses to run with it.", entry))
+			}
+			if root, ok := vars["GOROOT"]; ok && normalizePathEntry(dir) == normalizePathEntry(expandedPathEntry(root.Value)) {
+				warnings = append(warnings, "GOPATH is the same directory as GOROOT, go refuses to run with it.")
+			}
+		}
+		if normalizePathEntry(expandedPathEntry(v.Value)) == normalizePathEntry(filepath.Join(home, "go")) {
+			warnings = append(warnings, fmt.Sprintf("GOPATH (%s) is the default %%USERPROFILE%%\\go and can be removed.", v.Scope))
+			removable = append(removable, v)
+		}
+	}
+
+	if v, ok := vars["GOBIN"]; ok && !filepath.IsAbs(expandedPathEntry(v.Value)) {
+		warnings = append(warnings, fmt.Sprintf("GOBIN (%s) must be an absolute path, go install fails with %q.", v.Scope, v.Value))
+	}
+
+	// GOFLAGS is split on spaces and every field must be a flag, values need -flag=value
+	if v, ok := vars["GOFLAGS"]; ok {
+		for _, field := range strings.Fields(v.Value) {
+			if !strings.HasPrefix(field, "-") || strings.Trim(field, "-") == "" {
+				warnings = append(warnings, fmt.Sprintf("GOFLAGS field %q is not a flag. Every field must be -flag or -flag=value, a value separated by a space is not allowed.", field))
+			}
+		}
+	}
+
+	if len(installs) == 0 {
+		warnings = append(warnings, "No go.exe was found on the Path or in %ProgramFiles%\\Go\\bin.")
+	}
+	return warnings, removable
+}
+
+// newGoHelper builds the Go card
+func newGoHelper(ctx toolchainContext) fyne.CanvasObject {
+	vars := goLookup(ctx)
+	installs := goInstallDirs(ctx)
+	box := container.NewVBox()
+
+	lines := []string{}
+	for _, dir := range installs {
+		lines = append(lines, "go.exe: "+dir)
+	}
+	for _, name := range goVariables {
+		if v, ok := vars[name]; ok {
+			lines = append(lines, fmt.Sprintf("%s (%s): %s", name, v.Scope, v.Value))
+		} else {
+			lines = append(lines, name+": not set")
+		}
+	}
+	box.Add(widget.NewLabel(strings.Join(lines, "\n")))
+
+	warnings, removable := goWarnings(ctx, vars, installs)
+	for _, warning := range warnings {
+		label := widget.NewLabel("⚠️  " + warning)
+		label.Wrapping = fyne.TextWrapWord
+		box.Add(label)
+	}
+	for _, v := range removable {
+		v.Operation = "delete"
+		button := widget.NewButton(fmt.Sprintf("Remove %s (%s)", v.Name, v.Scope), func() {
+			ctx.apply("Go helper", []ScopedVariable{v})
+		})
+		markElevates(button, ctx.isAdmin, v.Scope == ScopeSystem)
+		hideInReadOnly(button)
+		box.Add(button)
+	}
+
+	// The bin directory counts as wired when either Path lists it, spelled out or through a reference
+	entry := goBinEntry(vars)
+	target := expandedPathEntry(entry)
+	if gobin, ok := vars["GOBIN"]; ok {
+		target = expandedPathEntry(gobin.Value)
+	} else if gopath, ok := vars["GOPATH"]; ok && entry == `%GOPATH%\bin` {
+		target = filepath.Join(expandedPathEntry(gopath.Value), "bin")
+	}
+	wired := false
+	for _, scope := range []string{ScopeUser, ScopeSystem} {
+		if v, ok := ctx.lookup(scope, "Path"); ok {
+			for _, e := range pathEntries(v.Value) {
+				wired = wired || normalizePathEntry(expandedPathEntry(e)) == normalizePathEntry(target) || normalizePathEntry(e) == normalizePathEntry(entry)
+			}
+		}
+	}
+	if wired {
+		box.Add(widget.NewLabel(fmt.Sprintf("✅ Programs installed with go install (%s) are on the Path.", target)))
+		return box
+	}
+	box.Add(widget.NewLabel(fmt.Sprintf("Programs installed with go install (%s) are not on the Path.", target)))
+	addButton := widget.NewButton(fmt.Sprintf("Add %s to User Path", entry), func() {
+		never := func(string) bool { return false }
+		if change, ok := ctx.pathChange(ScopeUser, never, entry); ok {
+			ctx.apply("Go helper", []ScopedVariable{change})
+		}
+	})
+	hideInReadOnly(addButton)
+	box.Add(addButton)
+	return box
+}
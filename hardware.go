@@ -0,0 +1,86 @@
+// hardware.go
+// Hardware facts - memory and graphics adapter information used by when: conditions and value scripts
+package main
+
+import (
+	"math"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// displayAdapterClassPath is the device class key holding one subkey per installed graphics adapter
+const displayAdapterClassPath = `SYSTEM\CurrentControlSet\Control\Class\{4d36e968-e325-11ce-bfc1-08002be10318}`
+
+var procGlobalMemoryStatusEx = kernel32.NewProc("GlobalMemoryStatusEx")
+
+// memoryStatusEx mirrors the MEMORYSTATUSEX structure
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// totalMemoryGB returns the installed physical memory in GiB, rounded to the nearest whole number
+func totalMemoryGB() float64 {
+	status := memoryStatusEx{}
+	status.Length = uint32(unsafe.Sizeof(status))
+	if r, _, _ := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status))); r == 0 {
+		return 0
+	}
+	return math.Round(float64(status.TotalPhys) / (1 << 30))
+}
+
+// graphicsAdapters returns the names of the installed graphics adapters
+func graphicsAdapters() []string {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, displayAdapterClassPath, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return nil
+	}
+	defer key.Close()
+
+	subkeys, err := key.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil
+	}
+	var adapters []string
+	for _, name := range subkeys {
+		adapter, err := registry.OpenKey(key, name, registry.QUERY_VALUE)
+		if err != nil {
+			continue // "Properties" and similar subkeys are not readable adapters
+		}
+		if desc, _, err := adapter.GetStringValue("DriverDesc"); err == nil && desc != "" {
+			adapters = append(adapters, desc)
+		}
+		adapter.Close()
+	}
+	return adapters
+}
+
+// addHardwareFacts adds memory and GPU facts to facts
+func addHardwareFacts(facts map[string]interface{}) {
+	facts["ram_gb"] = totalMemoryGB()
+
+	adapters := graphicsAdapters()
+	facts["gpu_names"] = strings.Join(adapters, ";")
+	hasVendor := func(names ...string) bool {
+		for _, adapter := range adapters {
+			for _, name := range names {
+				if strings.Contains(strings.ToLower(adapter), name) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	facts["has_nvidia_gpu"] = hasVendor("nvidia")
+	facts["has_amd_gpu"] = hasVendor("amd", "radeon")
+	facts["has_intel_gpu"] = hasVendor("intel")
+}
@@ -0,0 +1,244 @@
+// history.go
+// Audit log - records every apply operation together with the config metadata in an append-only JSON lines file
+// Also provides the History tab that lists the recorded entries
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	sqweekdialog "github.com/sqweek/dialog"
+)
+
+// historyFileName is the audit log file stored in the application data directory
+const historyFileName = "history.jsonl"
+
+// historyMu serializes writes to the audit log, an import rewrites the whole file
+var historyMu sync.Mutex
+
+// HistoryEntry represents a single audit log record
+type HistoryEntry struct {
+	Timestamp  time.Time       `json:"timestamp"`             // When the operation finished
+	Action     string          `json:"action"`                // Operation type, e.g. "apply"
+	ConfigPath string          `json:"config_path,omitempty"` // Config file the operation was based on
+	Metadata   *ConfigMetadata `json:"metadata,omitempty"`    // Metadata block of the config, if present
+	Host       string          `json:"host,omitempty"`        // Machine the operation ran on
+	User       string          `json:"user,omitempty"`        // Account that ran the operation
+	Success    bool            `json:"success"`               // Whether the operation completed without error
+	Message    string          `json:"message,omitempty"`     // Error or summary message
+	Timings    string          `json:"timings,omitempty"`     // How long each phase of the operation took
+}
+
+// appendHistory writes an entry to the audit log, filling in timestamp, host and user when missing
+func appendHistory(entry HistoryEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if entry.Host == "" {
+		entry.Host, _ = os.Hostname()
+	}
+	if entry.User == "" {
+		entry.User = os.Getenv("USERNAME")
+	}
+
+	path, err := appDataPath(historyFileName)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file %s: %w", path, err)
+	}
+	return nil
+}
+
+// recordApplyHistory logs the outcome of applying a config, reporting audit log failures to the console only
+// A non-nil timer adds the phase timings to the entry and the console log
+func recordApplyHistory(configPath string, config Config, applyErr error, timer *phaseTimer) {
+	entry := HistoryEntry{
+		Action:     "apply",
+		ConfigPath: configPath,
+		Metadata:   config.Metadata,
+		Success:    applyErr == nil,
+	}
+	if timer != nil {
+		entry.Timings = timer.log()
+	}
+	if applyErr != nil {
+		entry.Message = applyErr.Error()
+	}
+
+	if err := appendHistory(entry); err != nil {
+		fmt.Printf("Warning: Could not write audit log: %v\n", err)
+	}
+}
+
+// loadHistory reads all audit log entries, oldest first; a missing log yields no entries
+func loadHistory() ([]HistoryEntry, error) {
+	path, err := appDataPath(historyFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			// Skip damaged lines instead of hiding the rest of the history
+			fmt.Printf("Warning: Skipping unreadable history entry: %v\n", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read history file %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// summary renders a history entry as a single line for the History tab
+func (e HistoryEntry) summary() string {
+	result := "OK"
+	if !e.Success {
+		result = "FAILED"
+	}
+	source := e.ConfigPath
+	if e.Metadata != nil && e.Metadata.Name != "" {
+		source = e.Metadata.Name
+	}
+	line := fmt.Sprintf("%s  %-8s %-6s %s", e.Timestamp.Local().Format("2006-01-02 15:04:05"), e.Action, result, source)
+	// Entries imported from another installation name the machine they ran on
+	if hostname, _ := os.Hostname(); e.Host != "" && !strings.EqualFold(e.Host, hostname) {
+		line += "  @" + e.Host
+	}
+	if e.Message != "" {
+		line += "  -  " + e.Message
+	}
+	if e.Timings != "" {
+		line += "  [" + e.Timings + "]"
+	}
+	return line
+}
+
+// newHistoryTab builds the History tab listing audit log entries, newest first
+func newHistoryTab(parent fyne.Window, settings *Settings, isAdmin bool) fyne.CanvasObject {
+	var entries []HistoryEntry
+
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(entries[len(entries)-1-id].summary())
+		},
+	)
+
+	reload := func() {
+		loaded, err := loadHistory()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error loading history: %v", err), parent)
+		}
+		entries = loaded
+		list.Refresh()
+	}
+	reload()
+
+	refreshButton := widget.NewButton("Refresh", reload)
+
+	// The log can be handed to a ticketing system as CSV or JSON, and the log of an old machine merged in when migrating
+	exportButton := widget.NewButton("Export...", func() {
+		go func() {
+			savePath, err := sqweekdialog.File().Title("Export History").Filter("CSV", "csv").Filter("JSON", "json").Save()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error saving file: %v", err), parent)
+				}
+				return
+			}
+			lower := strings.ToLower(savePath)
+			if !strings.HasSuffix(lower, ".csv") && !strings.HasSuffix(lower, ".json") {
+				savePath += ".csv"
+			}
+			data, err := renderHistory(entries, savePath)
+			if err == nil {
+				err = ioutil.WriteFile(savePath, data, 0644)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error exporting history: %v", err), parent)
+				return
+			}
+			dialog.ShowInformation("History Exported", fmt.Sprintf("%d entries written to:\n%s", len(entries), savePath), parent)
+		}()
+	})
+	importButton := widget.NewButton("Import...", func() {
+		go func() {
+			path, err := sqweekdialog.File().Title("Import History").Filter("History export", "csv", "json", "jsonl").Load()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error choosing file: %v", err), parent)
+				}
+				return
+			}
+			added, skipped, err := importHistory(path)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error importing history: %v", err), parent)
+				return
+			}
+			reload()
+			dialog.ShowInformation("History Imported", fmt.Sprintf("%d entries imported, %d already present or without a timestamp.", added, skipped), parent)
+		}()
+	})
+	hideInReadOnly(importButton)
+
+	// Changes made outside this application are only attributable through the Security event log
+	// Reading it needs elevation
+	sourcesButton := widget.NewButton("Change Sources...", func() {
+		showChangeSourcesWindow(settings, isAdmin, parent)
+	})
+	requireAdmin(sourcesButton, isAdmin, true)
+	adminBar := newElevateBar(parent)
+	adminBar.update(isAdmin, true, "Change Sources reads the Security event log, which needs administrator privileges.")
+	return container.NewBorder(
+		widget.NewLabel("Audit log of applied configurations (newest first):"),
+		container.NewVBox(adminBar, container.NewHBox(refreshButton, exportButton, importButton, sourcesButton)),
+		nil, nil,
+		list,
+	)
+}
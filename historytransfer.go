@@ -0,0 +1,204 @@
+// historytransfer.go
+// History export and import - writes the audit log as CSV or JSON for ticketing systems and merges the log of another installation
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportHistoryFlag writes the audit log to a file and exits, e.g. --export-history=C:\tickets\history.csv
+const exportHistoryFlag = "--export-history="
+
+// historyColumns are the CSV columns of an exported audit log
+var historyColumns = []string{"timestamp", "action", "success", "config_path", "config_name", "host", "user", "message", "timings"}
+
+// renderHistory renders audit log entries as CSV when path ends in .csv and as a JSON array otherwise
+func renderHistory(entries []HistoryEntry, path string) ([]byte, error) {
+	if !strings.HasSuffix(strings.ToLower(path), ".csv") {
+		if entries == nil {
+			entries = []HistoryEntry{}
+		}
+		return marshalExportJSON(entries)
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true // Excel on Windows expects CRLF line endings
+	rows := [][]string{historyColumns}
+	for _, e := range entries {
+		name := ""
+		if e.Metadata != nil {
+			name = e.Metadata.Name
+		}
+		rows = append(rows, []string{e.Timestamp.Format(time.RFC3339Nano), e.Action, strconv.FormatBool(e.Success), e.ConfigPath, name, e.Host, e.User, e.Message, e.Timings})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to encode CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseHistory reads exported audit log entries: a JSON array, the JSON lines of a history file or a CSV export
+func parseHistory(data []byte, path string) ([]HistoryEntry, error) {
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF}) // Excel writes a UTF-8 BOM
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case strings.HasSuffix(strings.ToLower(path), ".csv"):
+		return parseHistoryCSV(data)
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var entries []HistoryEntry
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse history file %s: %w", path, err)
+		}
+		return entries, nil
+	}
+	var entries []HistoryEntry
+	for i, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of history file %s: %w", i+1, path, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseHistoryCSV reads a CSV export, the header row names the columns in any order
+func parseHistoryCSV(data []byte) ([]HistoryEntry, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["timestamp"]; !ok {
+		return nil, fmt.Errorf("CSV has no timestamp column, expected the columns %s", strings.Join(historyColumns, ","))
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var entries []HistoryEntry
+	for row, record := range records[1:] {
+		timestamp, err := time.Parse(time.RFC3339Nano, field(record, "timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid timestamp %q", row+2, field(record, "timestamp"))
+		}
+		entry := HistoryEntry{
+			Timestamp:  timestamp,
+			Action:     field(record, "action"),
+			ConfigPath: field(record, "config_path"),
+			Host:       field(record, "host"),
+			User:       field(record, "user"),
+			Message:    field(record, "message"),
+			Timings:    field(record, "timings"),
+		}
+		entry.Success, _ = strconv.ParseBool(field(record, "success"))
+		if name := field(record, "config_name"); name != "" {
+			entry.Metadata = &ConfigMetadata{Name: name}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// historyKey identifies an entry so importing the same file twice adds nothing
+func (e HistoryEntry) historyKey() string {
+	return strings.Join([]string{e.Timestamp.UTC().Format(time.RFC3339Nano), e.Action, strings.ToLower(e.Host), strings.ToLower(e.User), e.ConfigPath}, "|")
+}
+
+// importHistory merges the entries of an exported audit log into this one, keeping their original time, host and user
+// The merged log is sorted by time and replaces the history file in one rename
+func importHistory(path string) (added, skipped int, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read history file %s: %w", path, err)
+	}
+	imported, err := parseHistory(data, path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	entries, err := loadHistory()
+	if err != nil {
+		return 0, 0, err
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		seen[e.historyKey()] = true
+	}
+	for _, e := range imported {
+		if e.Timestamp.IsZero() || seen[e.historyKey()] {
+			skipped++
+			continue
+		}
+		seen[e.historyKey()] = true
+		entries = append(entries, e)
+		added++
+	}
+	if added == 0 {
+		return 0, skipped, nil
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to encode history entry: %w", err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+	historyPath, err := appDataPath(historyFileName)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Write to a temporary file first so a failed write never loses the existing log
+	tmpPath := historyPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return 0, 0, fmt.Errorf("failed to write history file %s: %w", historyPath, err)
+	}
+	if err := os.Rename(tmpPath, historyPath); err != nil {
+		return 0, 0, fmt.Errorf("failed to write history file %s: %w", historyPath, err)
+	}
+	return added, skipped, nil
+}
+
+// runExportHistoryCommand writes the audit log for --export-history and returns the process exit code
+func runExportHistoryCommand(path string) int {
+	entries, err := loadHistory()
+	if err != nil {
+		return writeCommandError("History export", err, exitFailed)
+	}
+	data, err := renderHistory(entries, path)
+	if err == nil {
+		err = ioutil.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		return writeCommandError("History export", err, exitFailed)
+	}
+	fmt.Printf("%d history entries written to %s\n", len(entries), path)
+	return exitOK
+}
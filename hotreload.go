@@ -0,0 +1,94 @@
+// hotreload.go
+// Hot reload - watches the selected config file so external edits refresh the preview and are never applied blind
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// configWatchInterval is how often the selected config file is checked for changes
+const configWatchInterval = 2 * time.Second
+
+// configWatcher polls the selected config file and reports changes made on disk
+type configWatcher struct {
+	mu          sync.Mutex
+	path        string
+	modTime     time.Time
+	size        int64
+	changedAt   time.Time // Last change detected since the config was last applied, zero if none
+	previewedAt time.Time // Last time the preview showed the file's content, zero if never previewed
+	onChange    func(path string)
+}
+
+// newConfigWatcher starts polling in the background, onChange is called from the polling goroutine
+func newConfigWatcher(onChange func(path string)) *configWatcher {
+	w := &configWatcher{onChange: onChange}
+	go func() {
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			w.poll()
+		}
+	}()
+	return w
+}
+
+// watch switches the watcher to path, remote configs and empty paths are not watched
+func (w *configWatcher) watch(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.path, w.changedAt, w.previewedAt = "", time.Time{}, time.Time{}
+	if path == "" || isRemoteConfig(path) {
+		return
+	}
+	w.path = path
+	if modTime, size, err := configStat(path); err == nil {
+		w.modTime, w.size = modTime, size
+	} else {
+		w.modTime, w.size = time.Time{}, 0
+	}
+}
+
+// poll checks the watched file once and reports a change
+func (w *configWatcher) poll() {
+	w.mu.Lock()
+	path := w.path
+	if path == "" {
+		w.mu.Unlock()
+		return
+	}
+	modTime, size, err := configStat(path)
+	if err != nil || (modTime.Equal(w.modTime) && size == w.size) {
+		w.mu.Unlock()
+		return
+	}
+	w.modTime, w.size = modTime, size
+	w.changedAt = time.Now()
+	w.mu.Unlock()
+
+	if w.onChange != nil {
+		w.onChange(path)
+	}
+}
+
+// markPreviewed records that the preview now shows the current file content
+func (w *configWatcher) markPreviewed() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.previewedAt = time.Now()
+}
+
+// markApplied records a successful apply, clearing the changed state
+func (w *configWatcher) markApplied() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.changedAt = time.Time{}
+}
+
+// changedSincePreview reports whether the file changed after it was last previewed
+func (w *configWatcher) changedSincePreview() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.previewedAt.IsZero() && w.changedAt.After(w.previewedAt)
+}
@@ -0,0 +1,88 @@
+// infrastructure.go
+// Infrastructure exports - Terraform .tfvars and Ansible vars files from a config
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// invalidIdentifierChars matches characters not allowed in Terraform and Ansible variable names
+var invalidIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// terraformExportFormat renders a terraform.tfvars file
+var terraformExportFormat = exportFormat{
+	Name:        "Terraform .tfvars",
+	Description: "name = \"value\" assignments for terraform.tfvars. Sensitive variables are marked with a comment so their variable blocks can set sensitive = true.",
+	Extension:   ".tfvars",
+	Render:      renderTerraformVars,
+}
+
+// ansibleExportFormat renders an Ansible vars file
+var ansibleExportFormat = exportFormat{
+	Name:        "Ansible vars.yml",
+	Description: "user_environment and system_environment dictionaries for vars.yml, e.g. for ansible.windows.win_environment with level user or machine. Sensitive values are marked for ansible-vault.",
+	Extension:   ".yml",
+	Render:      renderAnsibleVars,
+}
+
+// infrastructureIdentifier converts a variable name into a valid identifier
+func infrastructureIdentifier(name string) string {
+	id := invalidIdentifierChars.ReplaceAllString(name, "_")
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "_" + id
+	}
+	return id
+}
+
+// terraformQuote returns value as an HCL string literal, escaping template sequences
+func terraformQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "${", "$${", "%{", "%%{")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// renderTerraformVars renders config as a terraform.tfvars file
+// Terraform rejects repeated assignments, so both scopes are merged and every identifier is assigned once
+func renderTerraformVars(config Config) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString(ciExportHeader(config))
+	for _, v := range uniqueExportKeys(exportVariables(config), infrastructureIdentifier) {
+		if v.Sensitive {
+			sb.WriteString("# Sensitive: declare with sensitive = true and keep this file out of version control\n")
+		}
+		fmt.Fprintf(&sb, "%s = %s\n", infrastructureIdentifier(v.Name), terraformQuote(v.Value))
+	}
+	return []byte(sb.String()), nil
+}
+
+// renderAnsibleVars renders config as an Ansible vars file with one dictionary per scope
+func renderAnsibleVars(config Config) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString(ciExportHeader(config))
+	sb.WriteString("---\n")
+	for _, section := range []struct {
+		key       string
+		variables []Variable
+	}{
+		{"user_environment", config.UserVariables},
+		{"system_environment", config.SystemVariables},
+	} {
+		var lines []string
+		for _, v := range section.variables {
+			if v.Operation != "set" {
+				continue
+			}
+			if v.Sensitive {
+				lines = append(lines, "  # Sensitive: replace with the output of ansible-vault encrypt_string")
+			}
+			lines = append(lines, fmt.Sprintf("  %s: %s", yamlQuote(v.Name), yamlQuote(v.Value)))
+		}
+		if len(lines) == 0 {
+			fmt.Fprintf(&sb, "%s: {}\n", section.key)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:\n%s\n", section.key, strings.Join(lines, "\n"))
+	}
+	return []byte(sb.String()), nil
+}
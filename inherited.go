@@ -0,0 +1,259 @@
+// inherited.go
+// Inherited environment verification - checks that the shell picked up the broadcast and finds running shells that still hold old values
+// The environment of another process is read from its process parameters, so no code runs inside it
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Reading the environment of another process
+const (
+	processReadAccess     = windows.PROCESS_QUERY_INFORMATION | windows.PROCESS_VM_READ
+	shellParentAccess     = windows.PROCESS_CREATE_PROCESS | windows.PROCESS_DUP_HANDLE | processReadAccess
+	maxEnvironmentSize    = 1 << 20 // Environment blocks are far smaller, larger sizes mean the parameters were misread
+	inheritedCheckRetries = 4       // The shell rebuilds its environment asynchronously after a posted broadcast
+	inheritedCheckDelay   = 500 * time.Millisecond
+)
+
+// staleProcess is a running shell whose environment misses part of an apply
+type staleProcess struct {
+	Process    ConsoleProcess
+	Mismatches []verifyMismatch
+}
+
+// inheritedVerification is the outcome of checking the environment inherited from the shell
+type inheritedVerification struct {
+	Checked    int              // Variables checked in the child of the shell
+	Mismatches []verifyMismatch // Variables the child of the shell does not see as applied
+	Stale      []staleProcess   // Running shells that need a restart or a console refresh
+	Unreadable int              // Running shells whose environment could not be read, usually elevated ones
+}
+
+// readProcessMemory copies size bytes at address of a process
+func readProcessMemory(process windows.Handle, address uintptr, size uintptr) ([]byte, error) {
+	buf := make([]byte, size)
+	var read uintptr
+	if err := windows.ReadProcessMemory(process, address, &buf[0], size, &read); err != nil {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// readProcessPointer reads a pointer-sized value at address of a process
+func readProcessPointer(process windows.Handle, address uintptr) (uintptr, error) {
+	buf, err := readProcessMemory(process, address, unsafe.Sizeof(uintptr(0)))
+	if err != nil {
+		return 0, err
+	}
+	if len(buf) < int(unsafe.Sizeof(uintptr(0))) {
+		return 0, fmt.Errorf("short read at %#x", address)
+	}
+	return *(*uintptr)(unsafe.Pointer(&buf[0])), nil
+}
+
+// processParametersAddress returns where the RTL_USER_PROCESS_PARAMETERS of a process are
+// Remote addresses are read as plain integers by field offset, they never end up in Go pointers
+func processParametersAddress(process windows.Handle, pid uint32) (uintptr, error) {
+	var info windows.PROCESS_BASIC_INFORMATION
+	if err := windows.NtQueryInformationProcess(process, windows.ProcessBasicInformation, unsafe.Pointer(&info), uint32(unsafe.Sizeof(info)), nil); err != nil {
+		return 0, fmt.Errorf("cannot query process %d: %w", pid, err)
+	}
+	var peb windows.PEB
+	address, err := readProcessPointer(process, uintptr(unsafe.Pointer(info.PebBaseAddress))+unsafe.Offsetof(peb.ProcessParameters))
+	if err != nil {
+		return 0, fmt.Errorf("cannot read the parameters of process %d: %w", pid, err)
+	}
+	return address, nil
+}
+
+// readProcessUnicodeString reads a UNICODE_STRING of a process, address points at its Length field
+func readProcessUnicodeString(process windows.Handle, address uintptr) (string, error) {
+	var s windows.NTUnicodeString
+	header, err := readProcessMemory(process, address, unsafe.Sizeof(s))
+	if err != nil {
+		return "", err
+	}
+	if len(header) < int(unsafe.Sizeof(s)) {
+		return "", fmt.Errorf("short read at %#x", address)
+	}
+	length := uintptr(uint16(header[0]) | uint16(header[1])<<8)
+	buffer, err := readProcessPointer(process, address+unsafe.Offsetof(s.Buffer))
+	if err != nil || length == 0 {
+		return "", err
+	}
+	data, err := readProcessMemory(process, buffer, length)
+	if err != nil {
+		return "", err
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// processEnvironment reads the current environment block of a running process
+func processEnvironment(pid uint32) (map[string]string, error) {
+	process, err := windows.OpenProcess(processReadAccess, false, pid)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(process)
+
+	var params windows.RTL_USER_PROCESS_PARAMETERS
+	paramsAddress, err := processParametersAddress(process, pid)
+	if err != nil {
+		return nil, err
+	}
+	envAddress, err := readProcessPointer(process, paramsAddress+unsafe.Offsetof(params.Environment))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the environment of process %d: %w", pid, err)
+	}
+	envSize, err := readProcessPointer(process, paramsAddress+unsafe.Offsetof(params.EnvironmentSize))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the environment of process %d: %w", pid, err)
+	}
+	if envAddress == 0 || envSize < 4 || envSize > maxEnvironmentSize {
+		return nil, fmt.Errorf("process %d has no readable environment block", pid)
+	}
+	block, err := readProcessMemory(process, envAddress, envSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the environment of process %d: %w", pid, err)
+	}
+	return environmentMap(parseEnvironmentBlock(block)), nil
+}
+
+// parseEnvironmentBlock splits a UTF-16 environment block into "NAME=value" entries, it ends at the first empty entry
+func parseEnvironmentBlock(block []byte) []string {
+	units := make([]uint16, len(block)/2)
+	for i := range units {
+		units[i] = uint16(block[2*i]) | uint16(block[2*i+1])<<8
+	}
+	var entries []string
+	start := 0
+	for i, u := range units {
+		if u != 0 {
+			continue
+		}
+		if i == start {
+			break
+		}
+		entries = append(entries, string(utf16.Decode(units[start:i])))
+		start = i + 1
+	}
+	return entries
+}
+
+// shellProcessID returns the process of the desktop shell, the process programs started from the Start menu and taskbar inherit from
+func shellProcessID() (uint32, error) {
+	hwnd := windows.GetShellWindow()
+	if hwnd == 0 {
+		return 0, fmt.Errorf("no desktop shell is running")
+	}
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return 0, fmt.Errorf("cannot find the shell process: %w", err)
+	}
+	return pid, nil
+}
+
+// shellChildEnvironment starts "cmd.exe /d /c set" hidden as a child of the shell with the environment the shell passes on, and parses its output
+// An unelevated shell makes the child unelevated too, so it sees exactly what a program started by the user gets
+func shellChildEnvironment() (map[string]string, error) {
+	pid, err := shellProcessID()
+	if err != nil {
+		return nil, err
+	}
+	shellEnv, err := processEnvironment(pid)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the shell's environment: %w", err)
+	}
+	shell, err := windows.OpenProcess(shellParentAccess, false, pid)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the shell process: %w", err)
+	}
+	defer windows.CloseHandle(shell)
+
+	env := make([]string, 0, len(shellEnv))
+	for name, value := range shellEnv {
+		env = append(env, name+"="+value)
+	}
+	cmd := exec.Command("cmd.exe", "/d", "/c", "set")
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, ParentProcess: syscall.Handle(shell)}
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("verification process started from the shell failed: %w", err)
+	}
+	return environmentMap(strings.Split(strings.ReplaceAll(string(output), "\r\n", "\n"), "\n")), nil
+}
+
+// verifyInheritedEnvironment checks an applied config in a child of the shell, proving the broadcast reached it,
+// and lists the running shells whose environment still misses the apply
+func verifyInheritedEnvironment(config Config) (inheritedVerification, error) {
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		return inheritedVerification{}, err
+	}
+
+	var result inheritedVerification
+	for attempt := 1; ; attempt++ {
+		actual, err := shellChildEnvironment()
+		if err != nil {
+			return result, err
+		}
+		result.Checked, result.Mismatches = compareAppliedEnvironment(config, actual, current)
+		if len(result.Mismatches) == 0 || attempt >= inheritedCheckRetries {
+			break
+		}
+		time.Sleep(inheritedCheckDelay)
+	}
+
+	processes, err := listConsoleProcesses()
+	if err != nil {
+		return result, err
+	}
+	for _, p := range processes {
+		actual, err := processEnvironment(p.PID)
+		if err != nil {
+			result.Unreadable++
+			continue
+		}
+		if _, mismatches := compareAppliedEnvironment(config, actual, current); len(mismatches) > 0 {
+			result.Stale = append(result.Stale, staleProcess{Process: p, Mismatches: mismatches})
+		}
+	}
+	return result, nil
+}
+
+// describe renders the outcome for a dialog, masking sensitive values
+func (r inheritedVerification) describe(config Config, patterns []string) string {
+	var sections []string
+	if len(r.Mismatches) > 0 {
+		sections = append(sections, "The desktop shell has not picked up the change, programs started from the Start menu or the taskbar still get the old values. Restart Explorer or sign out and in again:\n"+describeVerifyMismatches(r.Mismatches, config, patterns))
+	}
+	if len(r.Stale) > 0 {
+		lines := make([]string, 0, len(r.Stale))
+		for _, s := range r.Stale {
+			names := make([]string, len(s.Mismatches))
+			for i, m := range s.Mismatches {
+				names[i] = m.Name
+			}
+			lines = append(lines, fmt.Sprintf("%s (PID %d): %s", s.Process.Name, s.Process.PID, strings.Join(names, ", ")))
+		}
+		sections = append(sections, "These running shells still have the old values. Restart them from the Restart Advisor, or use \"Refresh Running Consoles\":\n"+strings.Join(lines, "\n"))
+	}
+	if r.Unreadable > 0 {
+		sections = append(sections, fmt.Sprintf("%d running shell(s) could not be checked, usually because they run elevated.", r.Unreadable))
+	}
+	return strings.Join(sections, "\n\n")
+}
@@ -0,0 +1,77 @@
+// installer.go
+// Installer mode - applies a config from an installer custom action: never shows a dialog, logs to a file and gives up after a timeout
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// installerApplyFlag applies the config unattended for an installer and exits
+const installerApplyFlag = "--installer-apply="
+
+// logFlag names the file the installer mode appends its log to
+const logFlag = "--log="
+
+// timeoutFlag bounds the runtime of the installer mode in seconds
+const timeoutFlag = "--timeout="
+
+// Runtime bounds of the installer mode in seconds
+const (
+	defaultInstallerTimeout = 120
+	maxInstallerTimeout     = 3600
+)
+
+// runInstallerApply is the entry point of --installer-apply: it applies the config like the logon task and returns the exit code of the contract
+// System variables are only written when the installer runs elevated, no UAC prompt or other dialog is ever shown
+func runInstallerApply(path, logPath string, timeoutSeconds int) int {
+	if logPath != "" {
+		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not open log file %s: %v\n", logPath, err)
+		} else {
+			defer logFile.Close()
+			os.Stdout, os.Stderr = logFile, logFile // Everything the apply prints ends up in the installer's log
+			log.SetOutput(logFile)
+		}
+	}
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultInstallerTimeout
+	} else if timeoutSeconds > maxInstallerTimeout {
+		timeoutSeconds = maxInstallerTimeout
+	}
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	context := fmt.Sprintf("Installer apply of %s", path)
+	fmt.Printf("%s %s started (timeout %s)\n", time.Now().Format(time.RFC3339), context, timeout)
+
+	code := installerApply(context, path, timeout)
+	fmt.Printf("%s %s finished with exit code %d (%s)\n", time.Now().Format(time.RFC3339), context, code, exitOutcomes[code])
+	return code
+}
+
+// installerApply runs the apply and stops waiting for it once the timeout has passed
+func installerApply(context, path string, timeout time.Duration) int {
+	if path == "" || !isSupportedConfigFile(path) {
+		return reportCommandError(context, &ValidationError{Err: fmt.Errorf("%q is not a supported config file", path)})
+	}
+	settings, err := loadSettings()
+	if err != nil {
+		fmt.Printf("Warning: Could not load settings: %v\n", err)
+	}
+	if settings.ReadOnly || readOnlyMode {
+		return reportCommandError(context, errReadOnly)
+	}
+	isAdmin, _ := isRunningAsAdmin()
+
+	done := make(chan error, 1)
+	go func() { done <- applyConfigUnattended(path, isAdmin, settings) }()
+	select {
+	case err := <-done:
+		return reportCommandError(context, err)
+	case <-time.After(timeout):
+		// The process exits right after and an apply still running stops where it is, each registry write is atomic
+		return reportCommandError(context, fmt.Errorf("%w after %s", errTimedOut, timeout))
+	}
+}
@@ -0,0 +1,87 @@
+// jetbrains.go
+// JetBrains exports - run configuration <envs> blocks and .env files for the EnvFile plugin
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// jetBrainsEnvsExportFormat renders the <envs> element used by IntelliJ/GoLand run configurations
+var jetBrainsEnvsExportFormat = exportFormat{
+	Name:        "JetBrains run configuration <envs>",
+	Description: "<envs> element to paste into a run configuration in .idea/workspace.xml or .run/*.run.xml (IntelliJ IDEA, GoLand, PyCharm, ...). Deletions are left out.",
+	Extension:   ".xml",
+	Render:      renderJetBrainsEnvs,
+}
+
+// envFileExportFormat renders a dotenv file as read by the EnvFile plugin
+var envFileExportFormat = exportFormat{
+	Name:        "EnvFile / .env",
+	Description: "NAME=value lines for the JetBrains EnvFile plugin and other dotenv readers. %VAR% references become ${VAR}; enable \"Substitute Environment Variables\" in EnvFile to expand them.",
+	Extension:   ".env",
+	Render:      renderEnvFile,
+}
+
+// jetBrainsEnvs mirrors the <envs> element of a run configuration
+type jetBrainsEnvs struct {
+	XMLName xml.Name       `xml:"envs"`
+	Envs    []jetBrainsEnv `xml:"env"`
+}
+
+// jetBrainsEnv is a single <env name="..." value="..."/> entry
+type jetBrainsEnv struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// renderJetBrainsEnvs renders config as a run configuration <envs> element
+func renderJetBrainsEnvs(config Config) ([]byte, error) {
+	envs := jetBrainsEnvs{}
+	for _, v := range exportVariables(config) {
+		if v.Operation == "set" {
+			envs.Envs = append(envs.Envs, jetBrainsEnv{Name: v.Name, Value: v.Value})
+		}
+	}
+	sort.Slice(envs.Envs, func(i, j int) bool { return envs.Envs[i].Name < envs.Envs[j].Name })
+
+	data, err := xml.MarshalIndent(envs, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode XML: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// renderEnvFile renders config as a dotenv file
+func renderEnvFile(config Config) ([]byte, error) {
+	var sb strings.Builder
+	if config.Metadata != nil && config.Metadata.Name != "" {
+		fmt.Fprintf(&sb, "# %s\n", config.Metadata.Name)
+	}
+	for _, v := range exportVariables(config) {
+		if v.Operation != "set" {
+			continue
+		}
+		value := v.Value
+		if v.isExpandable() {
+			value = expansionReference.ReplaceAllString(value, "$${$1}")
+		}
+		fmt.Fprintf(&sb, "%s=%s\n", v.Name, dotenvQuote(value))
+	}
+	return []byte(sb.String()), nil
+}
+
+// dotenvQuote quotes values that would otherwise be misread by dotenv parsers
+// Single quotes are preferred since their content is taken literally, keeping Windows paths intact
+func dotenvQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"'#\n\r") {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
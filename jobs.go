@@ -0,0 +1,376 @@
+// jobs.go
+// Job queue - runs applies, exports and remote operations one at a time as tracked jobs with cancellation
+// Finished jobs are kept in a history file so the Jobs tab still lists them after a restart
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// jobsFileName is the job history stored in the application data directory
+const jobsFileName = "jobs.json"
+
+// maxJobHistory is how many finished jobs are kept in the history file
+const maxJobHistory = 200
+
+// Job kinds
+const (
+	jobKindApply  = "apply"
+	jobKindExport = "export"
+	jobKindRemote = "remote"
+)
+
+// Job status values
+const (
+	jobStatusQueued      = "queued"
+	jobStatusRunning     = "running"
+	jobStatusSucceeded   = "succeeded"
+	jobStatusFailed      = "failed"
+	jobStatusCancelled   = "cancelled"
+	jobStatusInterrupted = "interrupted" // The application exited while the job was queued or running
+)
+
+// errJobCancelled is returned by a job that stopped because it was cancelled, by the user in a dialog or from the Jobs tab
+var errJobCancelled = errors.New("cancelled")
+
+// JobRecord is the persisted state of a job
+type JobRecord struct {
+	ID       int       `json:"id"`
+	Kind     string    `json:"kind"`
+	Title    string    `json:"title"`
+	Status   string    `json:"status"`
+	Message  string    `json:"message,omitempty"` // Error of a failed job
+	Queued   time.Time `json:"queued"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// job is a queued or running job together with the function doing its work
+type job struct {
+	JobRecord
+	run    func(ctx context.Context) error
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// jobQueue runs submitted jobs in order on a single worker, so two applies never write the registry at once
+type jobQueue struct {
+	mu       sync.Mutex
+	jobs     []*job // Oldest first, finished jobs included
+	nextID   int
+	loaded   bool
+	wake     chan struct{}
+	onChange func() // Called after a job changed, used to refresh the Jobs tab
+}
+
+// backgroundJobs is the job queue of this application instance
+var backgroundJobs = &jobQueue{wake: make(chan struct{}, 1)}
+
+// jobCancelled reports whether the job owning ctx was cancelled, checked by jobs between their phases
+func jobCancelled(ctx context.Context) bool {
+	return ctx.Err() != nil
+}
+
+// fileDialogOutcome is the job result of a failed file dialog: closing the dialog cancels the job, anything else fails it
+func fileDialogOutcome(err error) error {
+	if err.Error() == "cancelled" {
+		return errJobCancelled
+	}
+	return err
+}
+
+// loadHistory reads the job history once, marking jobs that never finished as interrupted; the caller must hold q.mu
+func (q *jobQueue) loadHistory() {
+	if q.loaded {
+		return
+	}
+	q.loaded = true
+	path, err := appDataPath(jobsFileName)
+	if err != nil {
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Warning: Could not read job history: %v\n", err)
+		}
+		return
+	}
+	var records []JobRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		fmt.Printf("Warning: Could not parse job history %s: %v\n", path, err)
+		return
+	}
+	for _, r := range records {
+		if r.Status == jobStatusQueued || r.Status == jobStatusRunning {
+			r.Status = jobStatusInterrupted
+			r.Message = "the application exited before the job finished"
+		}
+		if r.ID > q.nextID {
+			q.nextID = r.ID
+		}
+		q.jobs = append(q.jobs, &job{JobRecord: r})
+	}
+}
+
+// save writes the job records, dropping the oldest finished jobs beyond maxJobHistory; the caller must hold q.mu
+func (q *jobQueue) save() {
+	finished := 0
+	for _, j := range q.jobs {
+		if j.isFinished() {
+			finished++
+		}
+	}
+	kept := q.jobs[:0]
+	for _, j := range q.jobs {
+		if j.isFinished() && finished > maxJobHistory {
+			finished--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	q.jobs = kept
+
+	records := make([]JobRecord, len(q.jobs))
+	for i, j := range q.jobs {
+		records[i] = j.JobRecord
+	}
+	path, err := appDataPath(jobsFileName)
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(records, "", "  "); err == nil {
+			err = ioutil.WriteFile(path, data, 0644)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Warning: Could not write job history: %v\n", err)
+	}
+}
+
+// changed persists the queue and notifies the Jobs tab; the caller must hold q.mu, onChange is called after unlocking
+func (q *jobQueue) changed() func() {
+	q.save()
+	if q.onChange == nil {
+		return func() {}
+	}
+	return q.onChange
+}
+
+// isFinished reports whether the job will not run anymore
+func (j *job) isFinished() bool {
+	return j.Status != jobStatusQueued && j.Status != jobStatusRunning
+}
+
+// submit queues a job and starts the worker when it is idle; it returns how many jobs are ahead of the new one
+func (q *jobQueue) submit(kind, title string, run func(ctx context.Context) error) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.mu.Lock()
+	q.loadHistory()
+	q.nextID++
+	ahead := 0
+	for _, j := range q.jobs {
+		if !j.isFinished() {
+			ahead++
+		}
+	}
+	q.jobs = append(q.jobs, &job{
+		JobRecord: JobRecord{ID: q.nextID, Kind: kind, Title: title, Status: jobStatusQueued, Queued: time.Now()},
+		run:       run,
+		ctx:       ctx,
+		cancel:    cancel,
+	})
+	notify := q.changed()
+	q.mu.Unlock()
+	notify()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return ahead
+}
+
+// startWorker runs queued jobs one after another for the lifetime of the application
+func (q *jobQueue) startWorker() {
+	go func() {
+		for range q.wake {
+			for {
+				j := q.next()
+				if j == nil {
+					break
+				}
+				err := j.run(j.ctx)
+				q.finish(j, err)
+			}
+		}
+	}()
+}
+
+// next marks the oldest queued job as running and returns it, nil when nothing is queued
+func (q *jobQueue) next() *job {
+	q.mu.Lock()
+	var next *job
+	for _, j := range q.jobs {
+		if j.Status == jobStatusQueued && j.run != nil {
+			next = j
+			break
+		}
+	}
+	if next == nil {
+		q.mu.Unlock()
+		return nil
+	}
+	next.Status, next.Started = jobStatusRunning, time.Now()
+	notify := q.changed()
+	q.mu.Unlock()
+	notify()
+	return next
+}
+
+// finish records the outcome of a job
+func (q *jobQueue) finish(j *job, err error) {
+	q.mu.Lock()
+	j.Finished = time.Now()
+	switch {
+	case errors.Is(err, errJobCancelled) || errors.Is(err, context.Canceled):
+		j.Status = jobStatusCancelled
+	case err != nil:
+		j.Status, j.Message = jobStatusFailed, err.Error()
+	default:
+		j.Status = jobStatusSucceeded
+	}
+	j.cancel()
+	notify := q.changed()
+	q.mu.Unlock()
+	notify()
+}
+
+// cancelJob cancels a queued job before it starts, or asks a running job to stop at its next checkpoint
+func (q *jobQueue) cancelJob(id int) {
+	q.mu.Lock()
+	for _, j := range q.jobs {
+		if j.ID != id || j.isFinished() {
+			continue
+		}
+		j.cancel()
+		if j.Status == jobStatusQueued {
+			j.Status, j.Finished = jobStatusCancelled, time.Now()
+		}
+	}
+	notify := q.changed()
+	q.mu.Unlock()
+	notify()
+}
+
+// clearFinished removes finished jobs from the history
+func (q *jobQueue) clearFinished() {
+	q.mu.Lock()
+	kept := q.jobs[:0]
+	for _, j := range q.jobs {
+		if !j.isFinished() {
+			kept = append(kept, j)
+		}
+	}
+	q.jobs = kept
+	notify := q.changed()
+	q.mu.Unlock()
+	notify()
+}
+
+// list returns the job records, newest first
+func (q *jobQueue) list() []JobRecord {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.loadHistory()
+	records := make([]JobRecord, len(q.jobs))
+	for i, j := range q.jobs {
+		records[len(records)-1-i] = j.JobRecord
+	}
+	return records
+}
+
+// summary renders a job as a single line for the Jobs tab
+func (r JobRecord) summary() string {
+	line := fmt.Sprintf("#%d  %s  %-11s %-7s %s", r.ID, r.Queued.Local().Format("2006-01-02 15:04:05"), r.Status, r.Kind, r.Title)
+	if !r.Started.IsZero() && !r.Finished.IsZero() {
+		line += fmt.Sprintf("  (%s)", r.Finished.Sub(r.Started).Round(time.Millisecond))
+	}
+	if r.Message != "" {
+		line += "  -  " + r.Message
+	}
+	return line
+}
+
+// queuedStatus is the status text shown when a job has to wait for others
+func queuedStatus(what string, ahead int) string {
+	if ahead == 0 {
+		return what
+	}
+	return fmt.Sprintf("%s Queued behind %d job(s), see the Jobs tab.", what, ahead)
+}
+
+// newJobsTab builds the Jobs tab listing queued, running and finished jobs
+func newJobsTab(parent fyne.Window) fyne.CanvasObject {
+	var records []JobRecord
+	selected := -1
+
+	list := widget.NewList(
+		func() int { return len(records) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(records[id].summary())
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	list.OnUnselected = func(widget.ListItemID) { selected = -1 }
+
+	reload := func() {
+		records = backgroundJobs.list()
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+	backgroundJobs.mu.Lock()
+	backgroundJobs.onChange = reload
+	backgroundJobs.mu.Unlock()
+	reload()
+
+	cancelButton := widget.NewButton("Cancel Job", func() {
+		if selected < 0 {
+			dialog.ShowInformation("Error", "Please select a job first.", parent)
+			return
+		}
+		r := records[selected]
+		if r.Status != jobStatusQueued && r.Status != jobStatusRunning {
+			dialog.ShowInformation("Job Finished", fmt.Sprintf("Job #%d has already %s.", r.ID, r.Status), parent)
+			return
+		}
+		backgroundJobs.cancelJob(r.ID)
+	})
+	clearButton := widget.NewButton("Clear Finished", backgroundJobs.clearFinished)
+
+	header := widget.NewLabel("Applies, exports and remote operations run one at a time in the order they were started (newest first). A running job stops at its next step when cancelled.")
+	header.Wrapping = fyne.TextWrapWord
+	return container.NewBorder(
+		header,
+		container.NewHBox(cancelButton, clearButton),
+		nil, nil,
+		list,
+	)
+}
@@ -0,0 +1,92 @@
+// kubernetes.go
+// Kubernetes exports - ConfigMap and Secret manifests from a config
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// invalidKubernetesNameChars matches characters not allowed in DNS-1123 resource names
+var invalidKubernetesNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// invalidKubernetesKeyChars matches characters not allowed in ConfigMap and Secret keys
+var invalidKubernetesKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// kubernetesExportFormat renders a ConfigMap for plain and a Secret for sensitive variables
+var kubernetesExportFormat = exportFormat{
+	Name:        "Kubernetes ConfigMap/Secret",
+	Description: "ConfigMap manifest for regular variables and Secret manifest (base64-encoded) for sensitive ones, ready for kubectl apply -f and envFrom. With redaction enabled the Secret holds placeholders instead of secret values.",
+	Extension:   ".yaml",
+	Render:      renderKubernetesManifests,
+}
+
+// kubernetesManifest is the subset of a ConfigMap or Secret needed for environment data
+type kubernetesManifest struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubernetesMetadata `yaml:"metadata"`
+	Type       string             `yaml:"type,omitempty"`
+	Data       map[string]string  `yaml:"data"`
+}
+
+// kubernetesMetadata holds the resource name and labels
+type kubernetesMetadata struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// kubernetesResourceName converts the config name into a valid resource name
+func kubernetesResourceName(name string) string {
+	name = strings.Trim(invalidKubernetesNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 63 {
+		name = strings.Trim(name[:63], "-")
+	}
+	if name == "" {
+		return "environment"
+	}
+	return name
+}
+
+// kubernetesKey converts a variable name into a valid ConfigMap or Secret key
+func kubernetesKey(name string) string {
+	return invalidKubernetesKeyChars.ReplaceAllString(name, "_")
+}
+
+// renderKubernetesManifests renders config as ConfigMap and Secret manifests in one multi-document file
+func renderKubernetesManifests(config Config) ([]byte, error) {
+	name := kubernetesResourceName(exportName(config, "environment"))
+	labels := map[string]string{"app.kubernetes.io/managed-by": "system-variable-manager"}
+
+	configMap := kubernetesManifest{APIVersion: "v1", Kind: "ConfigMap", Metadata: kubernetesMetadata{Name: name, Labels: labels}, Data: map[string]string{}}
+	secret := kubernetesManifest{APIVersion: "v1", Kind: "Secret", Metadata: kubernetesMetadata{Name: name + "-secrets", Labels: labels}, Type: "Opaque", Data: map[string]string{}}
+	// Names that map to the same key are reported instead of overwriting each other
+	for _, v := range uniqueExportKeys(exportVariables(config), kubernetesKey) {
+		key := kubernetesKey(v.Name)
+		if v.Sensitive {
+			secret.Data[key] = base64.StdEncoding.EncodeToString([]byte(v.Value))
+		} else {
+			configMap.Data[key] = v.Value
+		}
+	}
+
+	var documents []string
+	for _, manifest := range []kubernetesManifest{configMap, secret} {
+		if len(manifest.Data) == 0 {
+			continue
+		}
+		data, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s manifest: %w", manifest.Kind, err)
+		}
+		documents = append(documents, string(data))
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("no variables to export")
+	}
+	return []byte(strings.Join(documents, "---\n")), nil
+}
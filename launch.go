@@ -0,0 +1,74 @@
+// launch.go
+// Post-apply launcher - starts a program with the freshly applied environment so changes can be verified immediately
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// freshEnvironment builds the environment block a newly started process would receive from Explorer
+// The block is read from the registry instead of inheriting this process's (now stale) environment
+func freshEnvironment() ([]string, error) {
+	env, err := windows.GetCurrentProcessToken().Environ(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create environment block: %w", err)
+	}
+	return env, nil
+}
+
+// freshEnvironmentMap returns the fresh environment keyed by upper-cased variable name
+// Environment variable names are case-insensitive on Windows, so lookups must normalize case
+func freshEnvironmentMap() (map[string]string, error) {
+	env, err := freshEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	return environmentMap(env), nil
+}
+
+// environmentMap converts "NAME=value" entries into a map keyed by upper-cased name
+// Hidden per-drive entries such as "=C:=C:\" start with '=' and are skipped
+func environmentMap(env []string) map[string]string {
+	result := make(map[string]string, len(env))
+	for _, kv := range env {
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			continue
+		}
+		result[strings.ToUpper(kv[:i])] = kv[i+1:]
+	}
+	return result
+}
+
+// launchWithFreshEnvironment runs a command line in its own window using the current registry environment
+func launchWithFreshEnvironment(commandLine string) error {
+	commandLine = strings.TrimSpace(commandLine)
+	if commandLine == "" {
+		return fmt.Errorf("no command specified")
+	}
+
+	env, err := freshEnvironment()
+	if err != nil {
+		return err
+	}
+
+	// Use "start" so console programs get their own window even though this application has no console
+	cmd := exec.Command("cmd.exe")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine:    fmt.Sprintf(`/C start "" %s`, commandLine),
+		HideWindow: true,
+	}
+	cmd.Env = env
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to launch %q: %w", commandLine, err)
+	}
+	// Release the intermediate cmd.exe process, the launched program runs independently
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,282 @@
+// listeditor.go
+// List-value editor - edits PATH-style variables entry by entry with a per-variable separator and validation rule
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Validation rules for list entries
+const (
+	listValidateNone      = "none"      // Any text
+	listValidateDirectory = "directory" // An existing directory, after expanding %VAR% references
+	listValidateExtension = "extension" // A file extension such as .EXE
+	listValidateClasspath = "classpath" // An existing directory, .jar or .zip file, or dir\* wildcard
+)
+
+// listValidationRules are the rules accepted in the settings
+var listValidationRules = []string{listValidateNone, listValidateDirectory, listValidateExtension, listValidateClasspath}
+
+// extensionEntryPattern matches a PATHEXT entry
+var extensionEntryPattern = regexp.MustCompile(`^\.[A-Za-z0-9_]+$`)
+
+// ListVariableRule says how the value of a list variable is split and what its entries must be
+type ListVariableRule struct {
+	Name      string `yaml:"name"`      // Variable name or glob
+	Separator string `yaml:"separator"` // Text between entries, usually ";"
+	Validate  string `yaml:"validate"`  // One of the listValidate constants
+}
+
+// defaultListVariables are the list variables Windows and common toolchains use
+var defaultListVariables = []ListVariableRule{
+	{Name: "Path", Separator: ";", Validate: listValidateDirectory},
+	{Name: "PATHEXT", Separator: ";", Validate: listValidateExtension},
+	{Name: "PSModulePath", Separator: ";", Validate: listValidateDirectory},
+	{Name: "CLASSPATH", Separator: ";", Validate: listValidateClasspath},
+	{Name: "PYTHONPATH", Separator: ";", Validate: listValidateDirectory},
+}
+
+// String renders the rule as a line of the settings field, e.g. "PATHEXT ; extension"
+func (r ListVariableRule) String() string {
+	return fmt.Sprintf("%s %s %s", r.Name, r.Separator, r.Validate)
+}
+
+// listRuleFor returns the rule of the first list variable pattern matching name
+func (s Settings) listRuleFor(name string) (ListVariableRule, bool) {
+	for _, rule := range s.ListVariables {
+		if nameMatchesAny(name, []string{rule.Name}) {
+			return rule, true
+		}
+	}
+	return ListVariableRule{}, false
+}
+
+// parseListVariableRules parses the settings field, one "NAME SEPARATOR [RULE]" line per variable
+func parseListVariableRules(text string) ([]ListVariableRule, error) {
+	var rules []ListVariableRule
+	for i, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected NAME SEPARATOR [RULE], e.g. \"PATHEXT ; extension\"", i+1)
+		}
+		rule := ListVariableRule{Name: fields[0], Separator: fields[1], Validate: listValidateNone}
+		if len(fields) == 3 {
+			rule.Validate = strings.ToLower(fields[2])
+		}
+		valid := false
+		for _, known := range listValidationRules {
+			valid = valid || rule.Validate == known
+		}
+		if !valid {
+			return nil, fmt.Errorf("line %d: unknown rule %q, use one of %s", i+1, fields[2], strings.Join(listValidationRules, ", "))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// splitListValue splits a value into its entries; empty entries are kept so they can be reported and removed
+func (r ListVariableRule) splitListValue(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, r.Separator)
+}
+
+// checkEntry returns what is wrong with a single entry, or an empty string when it is fine
+func (r ListVariableRule) checkEntry(entry string) string {
+	trimmed := strings.TrimSpace(entry)
+	if trimmed == "" {
+		return "empty entry"
+	}
+	expanded := strings.Trim(trimmed, `"`)
+	if value, err := registry.ExpandString(expanded); err == nil {
+		expanded = value
+	}
+
+	switch r.Validate {
+	case listValidateDirectory:
+		if info, err := os.Stat(expanded); err != nil || !info.IsDir() {
+			return "directory does not exist"
+		}
+	case listValidateExtension:
+		if !extensionEntryPattern.MatchString(trimmed) {
+			return "not a file extension such as .EXE"
+		}
+	case listValidateClasspath:
+		if strings.HasSuffix(expanded, "*") {
+			expanded = strings.TrimRight(strings.TrimSuffix(expanded, "*"), `\/`)
+		}
+		info, err := os.Stat(expanded)
+		switch {
+		case err != nil:
+			return "file or directory does not exist"
+		case !info.IsDir() && !strings.EqualFold(filepath.Ext(expanded), ".jar") && !strings.EqualFold(filepath.Ext(expanded), ".zip"):
+			return "not a directory, .jar or .zip file"
+		}
+	}
+	return ""
+}
+
+// checkEntries returns the problem of every entry, duplicates included
+func (r ListVariableRule) checkEntries(entries []string) []string {
+	problems := make([]string, len(entries))
+	seen := map[string]bool{}
+	for i, entry := range entries {
+		key := strings.ToUpper(strings.TrimRight(strings.Trim(strings.TrimSpace(entry), `"`), `\/`))
+		if key != "" && seen[key] {
+			problems[i] = "duplicate entry"
+			continue
+		}
+		seen[key] = true
+		problems[i] = r.checkEntry(entry)
+	}
+	return problems
+}
+
+// showListEditorWindow edits the entries of a list variable and writes the joined value on Save
+func showListEditorWindow(settings *Settings, isAdmin bool, v ScopedVariable, rule ListVariableRule, onDone func()) {
+	window := fyne.CurrentApp().NewWindow(fmt.Sprintf("Edit %s (%s)", v.Name, v.Scope))
+	window.Resize(fyne.NewSize(820, 560))
+
+	entries := rule.splitListValue(v.Value)
+	problems := rule.checkEntries(entries)
+	selected := -1
+
+	summaryLabel := widget.NewLabel("")
+	entryField := widget.NewEntry()
+	entryField.SetPlaceHolder("Entry text")
+
+	list := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			text := fmt.Sprintf("%3d  %s", id+1, entries[id])
+			if problems[id] != "" {
+				text = fmt.Sprintf("%3d  ⚠️ %s  (%s)", id+1, entries[id], problems[id])
+			}
+			o.(*widget.Label).SetText(text)
+		},
+	)
+	refresh := func() {
+		problems = rule.checkEntries(entries)
+		invalid := 0
+		for _, p := range problems {
+			if p != "" {
+				invalid++
+			}
+		}
+		summaryLabel.SetText(fmt.Sprintf("%d entries separated by %q, %d with problems (%s rule).", len(entries), rule.Separator, invalid, rule.Validate))
+		list.Refresh()
+	}
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		entryField.SetText(entries[id])
+	}
+	list.OnUnselected = func(widget.ListItemID) { selected = -1 }
+	refresh()
+
+	// Typed entries are normalized like imported values when enabled
+	typedEntry := func() string {
+		if _, ok := pathValueRule(v.Name, *settings); ok && settings.NormalizePaths {
+			return normalizePath(entryField.Text)
+		}
+		return entryField.Text
+	}
+	move := func(delta int) {
+		target := selected + delta
+		if selected < 0 || target < 0 || target >= len(entries) {
+			return
+		}
+		entries[selected], entries[target] = entries[target], entries[selected]
+		refresh()
+		list.Select(target)
+	}
+	addButton := widget.NewButton("Add", func() {
+		if strings.TrimSpace(entryField.Text) == "" {
+			return
+		}
+		entries = append(entries, typedEntry())
+		refresh()
+		list.Select(len(entries) - 1)
+	})
+	replaceButton := widget.NewButton("Replace", func() {
+		if selected < 0 {
+			return
+		}
+		entries[selected] = typedEntry()
+		refresh()
+	})
+	removeButton := widget.NewButton("Remove", func() {
+		if selected < 0 {
+			return
+		}
+		entries = append(entries[:selected], entries[selected+1:]...)
+		list.UnselectAll()
+		refresh()
+	})
+	removeInvalidButton := widget.NewButton("Remove Problem Entries", func() {
+		var kept []string
+		for i, entry := range entries {
+			if problems[i] == "" {
+				kept = append(kept, entry)
+			}
+		}
+		entries = kept
+		list.UnselectAll()
+		refresh()
+	})
+
+	saveButton := widget.NewButton("Save", func() {
+		updated := v
+		updated.Value = strings.Join(entries, rule.Separator)
+		updated.Operation = "set"
+		if err := validateVariable(updated.Variable); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		go func() {
+			changes := []ScopedVariable{updated}
+			if !confirmDangerousChanges(changes, *settings, window) {
+				return
+			}
+			if err := applyDirectChanges("List editor", changes, isAdmin, *settings); err != nil {
+				dialog.ShowError(fmt.Errorf("error applying %s: %v", v.Name, err), window)
+				return
+			}
+			window.Close()
+			if onDone != nil {
+				onDone()
+			}
+		}()
+	})
+	markElevates(saveButton, isAdmin, v.Scope == ScopeSystem)
+	upButton := widget.NewButton("Move Up", func() { move(-1) })
+	downButton := widget.NewButton("Move Down", func() { move(1) })
+	hideInReadOnly(addButton, replaceButton, upButton, downButton, removeButton, removeInvalidButton, saveButton)
+
+	window.SetContent(container.NewBorder(
+		container.NewVBox(summaryLabel, container.NewBorder(nil, nil, nil, container.NewHBox(addButton, replaceButton), entryField)),
+		container.NewHBox(
+			upButton, downButton, removeButton, removeInvalidButton,
+			saveButton,
+			widget.NewButton("Close", window.Close),
+		),
+		nil, nil,
+		list,
+	))
+	window.Show()
+}
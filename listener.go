@@ -0,0 +1,234 @@
+// listener.go
+// Listener mode - an authenticated HTTP endpoint that applies profiles on request from orchestration tooling
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultListenerAddress only accepts connections from the local machine
+const defaultListenerAddress = "127.0.0.1:8765"
+
+// ListenerSettings controls the optional apply listener
+type ListenerSettings struct {
+	Enabled         bool     `yaml:"enabled"`          // Start the listener with the application
+	Address         string   `yaml:"address"`          // host:port to listen on
+	Token           string   `yaml:"token"`            // Bearer token every request must carry
+	AllowedProfiles []string `yaml:"allowed_profiles"` // Profiles that may be applied remotely, empty allows all
+	AllowedClients  []string `yaml:"allowed_clients"`  // Client IPs or CIDR ranges, empty allows all
+	RequireApproval bool     `yaml:"require_approval"` // Queue requests until the signed-in user approves them
+}
+
+// listenerApplyRequest is the JSON body of POST /apply
+type listenerApplyRequest struct {
+	Profile     string `json:"profile"`
+	CallbackURL string `json:"callback_url,omitempty"` // Where the outcome of a request waiting for approval is posted
+}
+
+// listenerResponse is the JSON body of every listener response
+type listenerResponse struct {
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+	Profiles []string `json:"profiles,omitempty"`
+	ID       string   `json:"id,omitempty"` // Approval request to poll with GET /requests?id=<id>
+}
+
+// generateListenerToken returns a random token for the listener settings
+func generateListenerToken() (string, error) {
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// applyListener serves apply requests, applying one profile at a time
+type applyListener struct {
+	settings *Settings
+	isAdmin  bool
+}
+
+// listenerApplies serializes listener and approved applies so concurrent requests do not interleave registry writes
+var listenerApplies sync.Mutex
+
+// startListener starts the apply listener in the background when it is enabled
+func startListener(settings *Settings, isAdmin bool) error {
+	options := settings.Listener
+	if !options.Enabled {
+		return nil
+	}
+	if strings.TrimSpace(options.Token) == "" {
+		return fmt.Errorf("the listener requires a token, set one in Settings")
+	}
+	address := options.Address
+	if address == "" {
+		address = defaultListenerAddress
+	}
+
+	l := &applyListener{settings: settings, isAdmin: isAdmin}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apply", l.handleApply)
+	mux.HandleFunc("/status", l.handleStatus)
+	mux.HandleFunc("/requests", l.handleRequest)
+	server := &http.Server{Addr: address, Handler: l.authorize(mux), ReadHeaderTimeout: 10 * time.Second}
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	fmt.Printf("Listening for apply requests on %s\n", address)
+	go func() {
+		if err := server.Serve(ln); err != nil {
+			fmt.Printf("Warning: Listener stopped: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// authorize rejects requests from clients that are not allowed or do not carry the token
+func (l *applyListener) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		options := l.settings.Listener
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil || !clientAllowed(net.ParseIP(host), options.AllowedClients) {
+			writeListenerResponse(w, http.StatusForbidden, listenerResponse{Status: "error", Error: "client not allowed"})
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(options.Token)) != 1 {
+			writeListenerResponse(w, http.StatusUnauthorized, listenerResponse{Status: "error", Error: "invalid token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientAllowed reports whether ip matches one of the allowed IPs or CIDR ranges
+func clientAllowed(ip net.IP, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// profileAllowed reports whether a profile may be applied through the listener
+func profileAllowed(name string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, entry := range allowed {
+		if strings.EqualFold(entry, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleStatus reports that the listener is running and which profiles it may apply
+func (l *applyListener) handleStatus(w http.ResponseWriter, r *http.Request) {
+	names, err := listProfiles()
+	if err != nil {
+		writeListenerResponse(w, http.StatusInternalServerError, listenerResponse{Status: "error", Error: err.Error()})
+		return
+	}
+	var profiles []string
+	for _, name := range names {
+		if profileAllowed(name, l.settings.Listener.AllowedProfiles) {
+			profiles = append(profiles, name)
+		}
+	}
+	writeListenerResponse(w, http.StatusOK, listenerResponse{Status: "ok", Profiles: profiles})
+}
+
+// handleApply applies the requested profile
+func (l *applyListener) handleApply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeListenerResponse(w, http.StatusMethodNotAllowed, listenerResponse{Status: "error", Error: "use POST"})
+		return
+	}
+	if readOnlyMode {
+		writeListenerResponse(w, http.StatusForbidden, listenerResponse{Status: "error", Error: errReadOnly.Error()})
+		return
+	}
+
+	var request listenerApplyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&request); err != nil || strings.TrimSpace(request.Profile) == "" {
+		writeListenerResponse(w, http.StatusBadRequest, listenerResponse{Status: "error", Error: `expected {"profile": "<name>"}`})
+		return
+	}
+	name := strings.TrimSpace(request.Profile)
+	if !profileAllowed(name, l.settings.Listener.AllowedProfiles) {
+		writeListenerResponse(w, http.StatusForbidden, listenerResponse{Status: "error", Error: fmt.Sprintf("profile %q is not allowed", name)})
+		return
+	}
+	path, err := profilePath(name)
+	if err == nil && !fileExists(path) {
+		err = fmt.Errorf("profile %q does not exist", name)
+	}
+	if err != nil {
+		writeListenerResponse(w, http.StatusNotFound, listenerResponse{Status: "error", Error: err.Error()})
+		return
+	}
+
+	// With approvals required the request waits for the signed-in user, the sender polls or gets a callback
+	if l.settings.Listener.RequireApproval {
+		if err := validateCallbackURL(request.CallbackURL); err != nil {
+			writeListenerResponse(w, http.StatusBadRequest, listenerResponse{Status: "error", Error: err.Error()})
+			return
+		}
+		id, err := approvalRequests.submit(ApprovalRequest{Profile: name, Origin: approvalFromListener, Requester: r.RemoteAddr, CallbackURL: request.CallbackURL})
+		if err != nil {
+			writeListenerResponse(w, http.StatusInternalServerError, listenerResponse{Status: "error", Error: err.Error()})
+			return
+		}
+		writeListenerResponse(w, http.StatusAccepted, listenerResponse{Status: approvalPending, ID: id})
+		return
+	}
+
+	listenerApplies.Lock()
+	defer listenerApplies.Unlock()
+	fmt.Printf("Listener: applying profile %s for %s\n", name, r.RemoteAddr)
+	if err := applyConfigUnattended(path, l.isAdmin, *l.settings); err != nil {
+		writeListenerResponse(w, http.StatusInternalServerError, listenerResponse{Status: "error", Error: err.Error()})
+		return
+	}
+	writeListenerResponse(w, http.StatusOK, listenerResponse{Status: "applied"})
+}
+
+// handleRequest reports the status of an approval request: pending, applied, rejected or failed with the reason
+func (l *applyListener) handleRequest(w http.ResponseWriter, r *http.Request) {
+	request, ok := approvalRequests.lookup(r.URL.Query().Get("id"))
+	if !ok {
+		writeListenerResponse(w, http.StatusNotFound, listenerResponse{Status: "error", Error: "unknown request id"})
+		return
+	}
+	writeListenerResponse(w, http.StatusOK, listenerResponse{Status: request.Status, Error: request.Error, ID: request.ID})
+}
+
+// writeListenerResponse writes a JSON response
+func writeListenerResponse(w http.ResponseWriter, status int, response listenerResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,118 @@
+// logontask.go
+// Logon-time reapply - a per-user logon task applies a chosen profile at sign-in, undoing resets made by other software
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// logonApplyFlag runs a headless unattended apply of the named profile and exits
+const logonApplyFlag = "--apply-profile="
+
+// runKeyPath is the per-user autostart key used when Task Scheduler refuses to create the logon task
+const runKeyPath = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+// logonTaskName returns the Task Scheduler name of the current user's reapply task
+func logonTaskName() string {
+	return fmt.Sprintf("SystemVariableManager Reapply (%s)", os.Getenv("USERNAME"))
+}
+
+// logonApplyCommandLine returns the command line the logon task runs for a profile
+func logonApplyCommandLine(profile string) (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("cannot find executable path: %w", err)
+	}
+	commandLine := quoteCommandArgument(exePath) + " " + quoteCommandArgument(logonApplyFlag+profile)
+	if portableMode {
+		commandLine += " " + portableFlag
+	}
+	return commandLine, nil
+}
+
+// runHidden runs a console tool without flashing a window and returns its combined output
+func runHidden(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// registerLogonTask makes the named profile reapply every time the current user signs in
+// A Task Scheduler logon task is preferred; where standard users may not create one, the per-user Run key is used instead
+// The returned string describes which mechanism was registered
+func registerLogonTask(profile string) (string, error) {
+	path, err := profilePath(profile)
+	if err != nil {
+		return "", err
+	}
+	if !fileExists(path) {
+		return "", fmt.Errorf("profile %q does not exist", profile)
+	}
+	commandLine, err := logonApplyCommandLine(profile)
+	if err != nil {
+		return "", err
+	}
+
+	// Only one mechanism may be active, or the profile would be applied twice per logon
+	unregisterLogonTask()
+
+	user := os.Getenv("USERNAME")
+	if domain := os.Getenv("USERDOMAIN"); domain != "" {
+		user = domain + `\` + user
+	}
+	output, taskErr := runHidden("schtasks.exe", "/Create", "/F", "/TN", logonTaskName(), "/SC", "ONLOGON", "/RU", user, "/IT", "/RL", "LIMITED", "/TR", commandLine)
+	if taskErr == nil {
+		return fmt.Sprintf("Task Scheduler task %q", logonTaskName()), nil
+	}
+	fmt.Printf("Could not create logon task (%v): %s\n", taskErr, output)
+
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, runKeyPath, registry.SET_VALUE)
+	if err != nil {
+		return "", fmt.Errorf("failed to create logon task (%s) and failed to open the Run key: %w", output, err)
+	}
+	defer key.Close()
+	if err := key.SetStringValue(logonTaskName(), commandLine); err != nil {
+		return "", fmt.Errorf("failed to create logon task (%s) and failed to write the Run key: %w", output, err)
+	}
+	return `the HKCU\` + runKeyPath + ` key`, nil
+}
+
+// unregisterLogonTask removes the reapply task and the Run key fallback, ignoring whichever does not exist
+func unregisterLogonTask() {
+	if output, err := runHidden("schtasks.exe", "/Delete", "/F", "/TN", logonTaskName()); err != nil {
+		fmt.Printf("No logon task removed: %s\n", output)
+	}
+	if key, err := registry.OpenKey(registry.CURRENT_USER, runKeyPath, registry.SET_VALUE); err == nil {
+		key.DeleteValue(logonTaskName())
+		key.Close()
+	}
+}
+
+// runLogonApply is the entry point of the logon task: it applies the profile unattended and returns the exit code
+func runLogonApply(profile string) int {
+	settings, err := loadSettings()
+	if err != nil {
+		fmt.Printf("Warning: Could not load settings: %v\n", err)
+	}
+	context := fmt.Sprintf("Logon reapply of profile %s", profile)
+	if settings.ReadOnly || readOnlyMode {
+		return reportCommandError(context, errReadOnly)
+	}
+	isAdmin, _ := isRunningAsAdmin()
+
+	path, err := profilePath(profile)
+	if err == nil && !fileExists(path) {
+		err = fmt.Errorf("profile %q does not exist", profile)
+	}
+	if err != nil {
+		return reportCommandError(context, &ValidationError{Err: err})
+	}
+	return reportCommandError(context, applyConfigUnattended(path, isAdmin, settings))
+}
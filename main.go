@@ -4,13 +4,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"fyne.io/fyne/v2"
@@ -21,33 +24,64 @@ import (
 	"fyne.io/fyne/v2/widget"
 	sqweekdialog "github.com/sqweek/dialog"
 	"golang.org/x/sys/windows/registry"
-	"gopkg.in/yaml.v2"
 )
 
-// Variable represents a single environment variable with its operation type
-type Variable struct {
-	Name      string `yaml:"name"`      // Environment variable name
-	Value     string `yaml:"value"`     // Environment variable value
-	Operation string `yaml:"operation"` // "set" to create/update, "delete" to remove
-}
-
-// Config represents the structure of a YAML configuration file
-type Config struct {
-	UserVariables   []Variable `yaml:"user_variables"`   // Variables for current user only
-	SystemVariables []Variable `yaml:"system_variables"` // System-wide variables (requires admin)
-}
-
 const (
 	HWND_BROADCAST   = 0xffff // Send message to all top-level windows
 	WM_SETTINGCHANGE = 0x001A // Windows message for environment variable changes
+	SMTO_ABORTIFHUNG = 0x0002 // Return without waiting if the receiving window appears hung
+)
+
+// Registry locations of the environment variables
+const (
+	userEnvironmentPath   = "Environment"                                                      // Below HKEY_CURRENT_USER
+	systemEnvironmentPath = "SYSTEM\\CurrentControlSet\\Control\\Session Manager\\Environment" // Below HKEY_LOCAL_MACHINE
 )
 
 func main() {
+	// The elevated helper, the logon task, quiet applies and installer applies run without showing a window and exit
+	options := parseCommandLine(os.Args[1:])
+	portableMode = detectPortableMode(options.Portable)
+	jsonErrors = options.JSONErrors
+	// The unattended modes below honour --read-only too, the setting is checked by each of them
+	readOnlyMode = options.ReadOnly
+	if options.ApplySystem != "" {
+		os.Exit(runElevatedApply(options.ApplySystem))
+	}
+	if options.ApplyProfile != "" {
+		os.Exit(runLogonApply(options.ApplyProfile))
+	}
+	if options.Doctor {
+		os.Exit(runDoctorCommand())
+	}
+	if options.Compliance != "" {
+		os.Exit(runComplianceCommand(options.Compliance, options.ReportPath))
+	}
+	if options.ExportHistory != "" {
+		os.Exit(runExportHistoryCommand(options.ExportHistory))
+	}
+	if options.QuietApply {
+		os.Exit(runQuietApply(options.ConfigPath))
+	}
+	if options.Installer != "" {
+		os.Exit(runInstallerApply(options.Installer, options.LogPath, options.Timeout))
+	}
+
 	// Initialize Fyne application with dark theme
 	myApp := app.New()
 	myApp.Settings().SetTheme(theme.DarkTheme())
-	myWindow := myApp.NewWindow("Environment Variable Manager")
-	myWindow.Resize(fyne.NewSize(600, 400))
+	title := "Environment Variable Manager"
+	if portableMode {
+		title += " (Portable)"
+	}
+	myWindow := myApp.NewWindow(title)
+	backgroundJobs.startWorker()
+
+	// Encrypted configs ask for their passphrase when they are loaded
+	passphrasePrompt = func(title string, confirm bool) (string, bool) {
+		return showPassphrasePrompt(title, confirm, myWindow)
+	}
+	myWindow.Resize(fyne.NewSize(800, 600))
 
 	// Check if running with administrator privileges
 	isAdmin, err := isRunningAsAdmin()
@@ -55,17 +89,46 @@ func main() {
 		log.Printf("Warning: Could not determine admin status: %v", err)
 	}
 
+	// Load persisted settings, falling back to defaults if the file is unreadable
+	settings, err := loadSettings()
+	if err != nil {
+		log.Printf("Warning: Could not load settings: %v", err)
+	}
+
+	// Read-only audit mode is enabled by the --read-only flag or the setting
+	readOnlyMode = options.ReadOnly || settings.ReadOnly
+
+	// Take automatic environment backups in the background according to the schedule
+	startBackupScheduler(&settings, isAdmin)
+
+	// Remove temporary variables once their expires time has passed
+	if !readOnlyMode {
+		startExpiryEnforcer(&settings, isAdmin)
+	}
+
+	// Alert when another program rewrites one of the watched variables
+	startVariableWatch(&settings, sendWatchNotification(myApp))
+
+	// Requests held for approval raise a desktop notification
+	approvalRequests.notify = sendWatchNotification(myApp)
+
+	// Accept apply requests from orchestration tooling when the listener is enabled
+	var listenerErr error
+	if !readOnlyMode {
+		listenerErr = startListener(&settings, isAdmin)
+	}
+
 	adminStatus := "Standard User"
 	if isAdmin {
 		adminStatus = "Administrator"
 	}
+	if readOnlyMode {
+		adminStatus += " (read-only audit mode, changes are disabled)"
+	}
 
 	// Initialize UI state variables
-	selectedFilePath := ""
-	// Check if a config file was passed as command line argument (used during UAC elevation)
-	if len(os.Args) > 1 {
-		selectedFilePath = os.Args[1]
-	}
+	// A config file may be passed as command line argument (used during UAC elevation)
+	selectedFilePath := options.ConfigPath
 
 	// Create UI labels for file path and status feedback
 	filePathLabel := widget.NewLabel("No file selected.")
@@ -78,115 +141,487 @@ func main() {
 		statusLabel.SetText("File pre-selected. Click 'Preview Changes' or 'Apply Variables' to proceed.")
 	}
 
-	// Handler function to preview changes without applying them
-	previewChanges := func() {
-		if selectedFilePath == "" {
-			dialog.ShowInformation("Error", "Please select a YAML configuration file first.", myWindow)
+	// Freshness of the selected config when it is loaded from a URL
+	remoteStatusLabel := widget.NewLabel("")
+	remoteStatusLabel.Wrapping = fyne.TextWrapWord
+	remoteStatusLabel.Hide()
+	refreshRemoteStatus := func() {
+		if !isRemoteConfig(selectedFilePath) {
+			remoteStatusLabel.Hide()
 			return
 		}
+		remoteStatusLabel.SetText(describeRemoteStatus(selectedFilePath))
+		remoteStatusLabel.Show()
+	}
 
-		// Validate file extension before processing
-		if !isValidYAMLFile(selectedFilePath) {
-			dialog.ShowError(fmt.Errorf("invalid file type: please select a valid YAML file (.yaml or .yml extension)"), myWindow)
+	// Shown when the selected config file was edited on disk and the edit has not been applied yet
+	changedLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	changedLabel.Hide()
+
+	// The open preview window, refreshed automatically when the selected config changes on disk
+	var preview *previewHandle
+	var previewMu sync.Mutex
+	var loadSelectedConfig func() (Config, error)
+	var watcher *configWatcher
+	watcher = newConfigWatcher(func(path string) {
+		changedLabel.SetText(fmt.Sprintf("⚠️  %s changed on disk at %s - changed since last apply", filepath.Base(path), time.Now().Format("15:04:05")))
+		changedLabel.Show()
+
+		previewMu.Lock()
+		open := preview
+		previewMu.Unlock()
+		if open == nil {
 			return
 		}
-
-		yamlFile, err := ioutil.ReadFile(selectedFilePath)
+		config, err := loadSelectedConfig()
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("error reading YAML file %s: %v", selectedFilePath, err), myWindow)
+			statusLabel.SetText(fmt.Sprintf("Error reloading config: %v", err))
 			return
 		}
+		open.update(config, fmt.Sprintf("🔄 Reloaded at %s after the file changed on disk", time.Now().Format("15:04:05")))
+		watcher.markPreviewed()
+	})
+	watcher.watch(selectedFilePath)
+
+	// selectConfig makes a file the selected config, used by the file chooser and the Profiles tab
+	selectConfig := func(filePath string) {
+		selectedFilePath = filePath
+		watcher.watch(filePath)
+		changedLabel.Hide()
+		filePathLabel.SetText(fmt.Sprintf("Selected: %s", selectedFilePath))
+		filePathLabel.Refresh()
+		statusLabel.SetText("File selected. Click 'Preview Changes' or 'Apply Variables' to proceed.")
+		statusLabel.Refresh()
+		refreshRemoteStatus()
+		if isRemoteConfig(filePath) {
+			// Download right away so the freshness is known before previewing
+			go func() {
+				if _, err := loadConfig(filePath); err != nil {
+					statusLabel.SetText(fmt.Sprintf("Error loading config: %v", err))
+				}
+				refreshRemoteStatus()
+			}()
+		}
+	}
+
+	// Optional program to launch after a successful apply so the new environment can be verified
+	runAfterApplyEntry := widget.NewEntry()
+	runAfterApplyEntry.SetPlaceHolder("Program to run after apply, e.g. cmd.exe, powershell.exe, wt.exe")
+	runAfterApplyCheck := widget.NewCheck("Run after apply", nil)
+
+	// Optional end-to-end check that a new process sees the applied values
+	verifyAfterApplyCheck := widget.NewCheck("Verify in a new process after apply", nil)
+
+	// Optional prefix applied to every variable name so one config can be materialized several times
+	namespaceEntry := widget.NewEntry()
+	namespaceEntry.SetPlaceHolder("Optional namespace prefix, e.g. STG_")
 
-		var config Config
-		err = yaml.Unmarshal(yamlFile, &config)
+	// Optional tags limiting preview and apply to the config entries carrying one of them
+	tagFilterEntry := widget.NewEntry()
+	tagFilterEntry.SetPlaceHolder("Optional tags, e.g. java, corp-proxy")
+
+	// loadConfigAt loads a config and its parents for this machine, with value scripts evaluated,
+	// path values normalized when enabled, limited to the tag filter and the namespace prefix applied; loadSelectedConfig loads the selected one
+	loadConfigAt := func(path string) (Config, error) {
+		config, err := loadConfigForMachine(path)
+		refreshRemoteStatus()
 		if err != nil {
-			dialog.ShowError(fmt.Errorf("error unmarshaling YAML: %v", err), myWindow)
+			return config, err
+		}
+		// Staged changes were filtered and prefixed when they were staged
+		if isStagedChangesFile(path) {
+			return config, nil
+		}
+		config = filterConfigByTags(config, parseTagList(tagFilterEntry.Text))
+		config, err = enforcePathOrder(normalizeConfigPaths(config, settings), settings)
+		if err != nil {
+			return config, err
+		}
+		return applyNamespace(config, strings.TrimSpace(namespaceEntry.Text))
+	}
+	loadSelectedConfig = func() (Config, error) { return loadConfigAt(selectedFilePath) }
+
+	// Handler function to preview changes without applying them
+	previewChanges := func() {
+		if selectedFilePath == "" {
+			dialog.ShowInformation("Error", "Please select a YAML configuration file first.", myWindow)
+			return
+		}
+
+		// Validate file extension before processing
+		if !isSupportedConfigFile(selectedFilePath) {
+			dialog.ShowError(fmt.Errorf("invalid file type: please select a valid YAML file (.yaml or .yml extension), encrypted config (.yaml.enc), backup archive (.evmbackup), registry export (.reg), CSV inventory (.csv) or a folder of configs"), myWindow)
 			return
 		}
 
-		showPreviewWindow(myApp, config, isAdmin)
+		// Loading may ask for a passphrase, which blocks until answered
+		go func() {
+			config, err := loadSelectedConfig()
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+
+			handle := showPreviewWindow(myApp, config, selectedFilePath, isAdmin, settings)
+			watcher.markPreviewed()
+			previewMu.Lock()
+			preview = handle
+			previewMu.Unlock()
+			handle.window.SetOnClosed(func() {
+				previewMu.Lock()
+				if preview == handle {
+					preview = nil
+				}
+				previewMu.Unlock()
+			})
+		}()
 	}
 
 	// Handler function to apply environment variables from selected YAML file
-	applyEnvVars := func() {
+	// With stage the resolved config is written to the staging area instead, to be committed after review
+	runApply := func(stage bool) {
 		if selectedFilePath == "" {
 			dialog.ShowInformation("Error", "Please select a YAML configuration file first.", myWindow)
 			return
 		}
 
 		// Validate file extension before processing
-		if !isValidYAMLFile(selectedFilePath) {
-			dialog.ShowError(fmt.Errorf("invalid file type: please select a valid YAML file (.yaml or .yml extension)"), myWindow)
+		if !isSupportedConfigFile(selectedFilePath) {
+			dialog.ShowError(fmt.Errorf("invalid file type: please select a valid YAML file (.yaml or .yml extension), encrypted config (.yaml.enc), backup archive (.evmbackup), registry export (.reg), CSV inventory (.csv) or a folder of configs"), myWindow)
 			return
 		}
 
-		statusLabel.SetText("Applying variables... Please wait.")
-		statusLabel.Refresh()
+		// Run as a job so applies never overlap and the Jobs tab can track and cancel them
+		// The job keeps the config chosen now even when another one is selected while it waits
+		source := selectedFilePath
+		title := "Apply "
+		if stage {
+			title = "Stage "
+		}
+		ahead := backgroundJobs.submit(jobKindApply, title+filepath.Base(source), func(ctx context.Context) error {
+			statusLabel.SetText("Applying variables... Please wait.")
+			statusLabel.Refresh()
 
-		// Run in goroutine to prevent UI blocking during registry operations
-		go func() {
-			yamlFile, err := ioutil.ReadFile(selectedFilePath)
+			// Time each phase for the console log, the result report and the audit log
+			timer := newPhaseTimer("Apply")
+			timer.begin("parse")
+			config, err := loadConfigAt(source)
 			if err != nil {
-				statusLabel.SetText(fmt.Sprintf("Error reading YAML file: %v", err))
-				dialog.ShowError(fmt.Errorf("error reading YAML file %s: %v", selectedFilePath, err), myWindow)
+				statusLabel.SetText(fmt.Sprintf("Error loading config: %v", err))
+				dialog.ShowError(err, myWindow)
 				statusLabel.Refresh()
-				return
+				recordApplyHistory(source, config, err, timer)
+				return err
+			}
+
+			// Never apply external edits blind: the previewed content is no longer what is on disk
+			timer.begin("confirm")
+			if source == selectedFilePath && watcher.changedSincePreview() {
+				answer := make(chan bool)
+				dialog.ShowConfirm("Config Changed", "The config file changed on disk after it was previewed.\n\nApply the new content without reviewing it?", func(ok bool) {
+					answer <- ok
+				}, myWindow)
+				if !<-answer {
+					statusLabel.SetText("Apply cancelled. Preview the changed config first.")
+					statusLabel.Refresh()
+					return errJobCancelled
+				}
 			}
 
-			var config Config
-			err = yaml.Unmarshal(yamlFile, &config)
+			// Declared parameters are asked for in one form before any other prompt
+			var paramValues map[string]string
+			if len(config.Params) > 0 {
+				var ok bool
+				if paramValues, ok = showParamsForm(config.Params, myWindow); !ok {
+					statusLabel.SetText("Apply cancelled.")
+					statusLabel.Refresh()
+					return errJobCancelled
+				}
+			}
+
+			// Substitute {{...}} placeholders, asking the user for prompted values
+			// Staging keeps the unresolved secrets so they are never written to disk
+			timer.begin("validate")
+			unresolved := config
+			config, err = resolveConfigPlaceholders(config, newPlaceholderResolver(func(label string, secret bool) (string, bool) {
+				return showValuePrompt(label, secret, myWindow)
+			}).withParams(config.Params, paramValues))
 			if err != nil {
-				statusLabel.SetText(fmt.Sprintf("Error unmarshaling YAML: %v", err))
-				dialog.ShowError(fmt.Errorf("error unmarshaling YAML: %v", err), myWindow)
+				statusLabel.SetText(fmt.Sprintf("Error resolving placeholders: %v", err))
+				dialog.ShowError(err, myWindow)
 				statusLabel.Refresh()
-				return
+				recordApplyHistory(source, config, err, timer)
+				return err
+			}
+
+			// Resolved placeholder values may be paths too, and may need reordering
+			config, err = enforcePathOrder(normalizeConfigPaths(config, settings), settings)
+			if err != nil {
+				statusLabel.SetText(fmt.Sprintf("Error ordering path entries: %v", err))
+				dialog.ShowError(err, myWindow)
+				statusLabel.Refresh()
+				recordApplyHistory(source, config, err, timer)
+				return err
+			}
+
+			// Turn delete_matching entries into deletions of the variables they match right now
+			config, err = expandConfigPatterns(config)
+			if err != nil {
+				statusLabel.SetText(fmt.Sprintf("Error expanding patterns: %v", err))
+				dialog.ShowError(err, myWindow)
+				statusLabel.Refresh()
+				recordApplyHistory(source, config, err, timer)
+				return err
+			}
+
+			// Sections with mode: sync also delete the managed variables they no longer list
+			config, err = expandSyncMode(config, source, settings.ProtectedVariables)
+			if err != nil {
+				statusLabel.SetText(fmt.Sprintf("Error resolving sync mode: %v", err))
+				dialog.ShowError(err, myWindow)
+				statusLabel.Refresh()
+				recordApplyHistory(source, config, err, timer)
+				return err
+			}
+
+			// The optional import wizard lets the user decide on every value the config would change
+			var wizardSummary string
+			if settings.ImportWizard {
+				timer.begin("confirm")
+				reviewed, result, ok := runImportWizard(config, settings, myWindow)
+				if !ok {
+					statusLabel.SetText("Apply cancelled.")
+					statusLabel.Refresh()
+					return errJobCancelled
+				}
+				config, wizardSummary = reviewed, result.summary()
+				fmt.Println(wizardSummary)
+				timer.begin("validate")
+			}
+
+			// Dropping the default PowerShell module paths breaks PowerShell, so it needs an explicit yes
+			if current, err := loadCurrentValueIndex(); err == nil {
+				if warnings := psModulePathWarnings(config.UserVariables, config.SystemVariables, current); len(warnings) > 0 {
+					timer.begin("confirm")
+					answer := make(chan bool)
+					dialog.ShowConfirm("PowerShell Module Paths", strings.Join(warnings, "\n\n")+"\n\nApply anyway?", func(ok bool) {
+						answer <- ok
+					}, myWindow)
+					if !<-answer {
+						statusLabel.SetText("Apply cancelled.")
+						statusLabel.Refresh()
+						return errJobCancelled
+					}
+					timer.begin("validate")
+				}
+			}
+
+			// Phase one of a two-phase apply ends here, writable scopes and dangerous changes are checked at the commit
+			if stage {
+				if err := stageChanges(config, unresolved, source); err != nil {
+					statusLabel.SetText(fmt.Sprintf("Error staging changes: %v", err))
+					dialog.ShowError(fmt.Errorf("error staging changes: %v", err), myWindow)
+					statusLabel.Refresh()
+					return err
+				}
+				if err := appendHistory(HistoryEntry{Action: "stage", ConfigPath: source, Metadata: config.Metadata, Success: true, Message: fmt.Sprintf("%d change(s) staged for review", len(configChanges(config)))}); err != nil {
+					log.Printf("Warning: Could not write audit log: %v", err)
+				}
+				statusLabel.SetText(fmt.Sprintf("%d change(s) staged. Review and commit them with 'Review Staged Changes'.", len(configChanges(config))))
+				statusLabel.Refresh()
+				return nil
+			}
+
+			// Report unwritable scopes up front instead of failing variable by variable mid-apply
+			// Without administrator privileges the system scope is written through the elevated helper
+			if err := preflightConfig(config).blocked(!isAdmin); err != nil {
+				statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+				dialog.ShowError(err, myWindow)
+				statusLabel.Refresh()
+				recordApplyHistory(source, config, err, timer)
+				return err
+			}
+
+			// Dangerous deletions and overwrites must be confirmed by typing the variable name
+			timer.begin("confirm")
+			if !confirmDangerousChanges(configChanges(config), settings, myWindow) {
+				statusLabel.SetText("Apply cancelled.")
+				statusLabel.Refresh()
+				return errJobCancelled
+			}
+
+			// Ask the user on the UI whether to continue when the error policy is "prompt"
+			promptOnError := func(failure VariableError) bool {
+				answer := make(chan bool)
+				dialog.ShowConfirm("Variable Failed", fmt.Sprintf("Failed to apply %s:\n%v\n\nContinue with the remaining variables?", failure.Name, failure.Err), func(ok bool) {
+					answer <- ok
+				}, myWindow)
+				return <-answer
+			}
+			var failures []VariableError
+			options := applyOptions{
+				ErrorPolicy:       settings.ErrorPolicy,
+				Prompt:            promptOnError,
+				SensitivePatterns: settings.SensitivePatterns,
+				Protected:         settings.ProtectedVariables,
+				Source:            source,
+				FromConfig:        !isQueuedChangesFile(source), // Queued changes are made in the UI and the file is cleared after applying
+			}
+
+			// Confirmations can take a while, the job may have been cancelled meanwhile
+			if jobCancelled(ctx) {
+				statusLabel.SetText("Apply cancelled.")
+				statusLabel.Refresh()
+				return errJobCancelled
 			}
 
 			// Apply user environment variables (always accessible)
+			timer.begin("registry writes")
 			fmt.Println("Applying user environment variables...")
-			if err := applyVariables(config.UserVariables, registry.CURRENT_USER, "Environment"); err != nil {
+			if err := applyVariables(config.UserVariables, registry.CURRENT_USER, userEnvironmentPath, options); err != nil && !collectApplyFailures(err, &failures) {
 				statusLabel.SetText(fmt.Sprintf("Error applying user variables: %v", err))
 				dialog.ShowError(fmt.Errorf("error applying user variables: %v", err), myWindow)
 				statusLabel.Refresh()
-				return
+				recordApplyHistory(source, config, err, timer)
+				return err
+			}
+
+			// Stopping between the scopes leaves the user variables applied, which the audit log records
+			if jobCancelled(ctx) && len(config.SystemVariables) > 0 {
+				statusLabel.SetText("Apply cancelled after the user variables were applied.")
+				statusLabel.Refresh()
+				recordApplyHistory(source, config, fmt.Errorf("cancelled after the user variables were applied"), timer)
+				return errJobCancelled
 			}
 
 			// Apply system environment variables (requires administrator privileges)
 			if isAdmin {
 				fmt.Println("Applying system environment variables...")
-				if err := applyVariables(config.SystemVariables, registry.LOCAL_MACHINE, "SYSTEM\\CurrentControlSet\\Control\\Session Manager\\Environment"); err != nil {
+				if err := applyVariables(config.SystemVariables, registry.LOCAL_MACHINE, systemEnvironmentPath, options); err != nil && !collectApplyFailures(err, &failures) {
 					statusLabel.SetText(fmt.Sprintf("Error applying system variables: %v", err))
 					dialog.ShowError(fmt.Errorf("error applying system variables: %v", err), myWindow)
 					statusLabel.Refresh()
-					return
+					recordApplyHistory(source, config, err, timer)
+					return err
 				}
 			} else if len(config.SystemVariables) > 0 {
-				// Inform user that system variables were skipped due to insufficient privileges
-				statusLabel.SetText("System variables were ignored. Relaunch as admin to apply them.")
-				dialog.ShowInformation("Admin Required", "To apply system environment variables, please relaunch the app as Administrator.", myWindow)
+				// Elevate just this write: a helper process asks for UAC approval once and exits afterwards
+				statusLabel.SetText("Waiting for administrator approval to apply system variables...")
 				statusLabel.Refresh()
-				return
+				fmt.Println("Applying system environment variables through an elevated helper...")
+				timer.begin("elevated writes")
+				if err := applySystemVariablesElevated(config.SystemVariables, options); err != nil && !collectApplyFailures(err, &failures) {
+					if errors.Is(err, errElevationCancelled) {
+						statusLabel.SetText("System variables were not applied: administrator approval was cancelled.")
+						dialog.ShowInformation("Admin Required", "System environment variables were not applied because the administrator prompt was cancelled.\n\nUser variables were applied.", myWindow)
+					} else {
+						statusLabel.SetText(fmt.Sprintf("Error applying system variables: %v", err))
+						dialog.ShowError(fmt.Errorf("error applying system variables: %v", err), myWindow)
+					}
+					statusLabel.Refresh()
+					recordApplyHistory(source, config, fmt.Errorf("system variables not applied: %w", err), timer)
+					return fmt.Errorf("system variables not applied: %w", err)
+				}
 			}
 
 			// Broadcast WM_SETTINGCHANGE to notify other applications of environment changes
+			timer.begin("broadcast")
 			fmt.Println("Broadcasting WM_SETTINGCHANGE message...")
-			if err := broadcastSettingChange(); err != nil {
+			var outcome error
+			if err := broadcastSettingChange(settings.Broadcast, settings.Retry); err != nil {
+				outcome = fmt.Errorf("error broadcasting WM_SETTINGCHANGE: %w", err)
 				statusLabel.SetText(fmt.Sprintf("Error broadcasting changes: %v", err))
 				dialog.ShowError(fmt.Errorf("error broadcasting WM_SETTINGCHANGE: %v", err), myWindow)
 				statusLabel.Refresh()
+				recordApplyHistory(source, config, fmt.Errorf("error broadcasting WM_SETTINGCHANGE: %w", err), timer)
+			} else if len(failures) > 0 {
+				// Some variables failed but the error policy allowed the apply to finish
+				applyErr := &ApplyErrors{Failures: failures}
+				outcome = applyErr
+				recordApplyHistory(source, config, applyErr, timer)
+				statusLabel.SetText(fmt.Sprintf("Environment variables applied with %d error(s). Timings: %s", len(failures), timer.summary()))
+				dialog.ShowError(fmt.Errorf("some variables could not be applied: %v", applyErr), myWindow)
+				statusLabel.Refresh()
 			} else {
-				statusLabel.SetText("Environment variables applied successfully. Some applications may need to be restarted.")
-				dialog.ShowInformation("Success", "Environment variables applied successfully.\n\nPlease note: Some applications (like Explorer, Command Prompt, PowerShell) may need to be restarted to reflect the changes.", myWindow)
+				recordApplyHistory(source, config, nil, timer)
+				recordAppliedChange(config)
+				if source == selectedFilePath {
+					watcher.markApplied()
+					changedLabel.Hide()
+				}
+
+				// Queued and staged changes are done once they have been applied
+				if isQueuedChangesFile(source) {
+					if err := clearQueuedChanges(); err != nil {
+						log.Printf("Warning: %v", err)
+					}
+				}
+				if isStagedChangesFile(source) {
+					if err := clearStagedChanges(); err != nil {
+						log.Printf("Warning: %v", err)
+					}
+				}
+				status := "Environment variables applied successfully. Some applications may need to be restarted, the Restart Advisor lists them."
+				if wizardSummary != "" {
+					status += " " + wizardSummary
+				}
+				statusLabel.SetText(status)
+				dialog.ShowInformation("Success", fmt.Sprintf("Environment variables applied successfully.\n\nPlease note: Some applications (like Explorer, Command Prompt, PowerShell) may need to be restarted to reflect the changes.\n\nTimings: %s", timer.summary()), myWindow)
 				statusLabel.Refresh()
+
+				// Check in a hidden process that the values reach newly started programs
+				if verifyAfterApplyCheck.Checked {
+					checked, mismatches, err := verifyAppliedConfig(config)
+					switch {
+					case err != nil:
+						statusLabel.SetText(fmt.Sprintf("Variables applied, but the verification failed: %v", err))
+						dialog.ShowError(fmt.Errorf("error verifying the apply: %v", err), myWindow)
+					case len(mismatches) > 0:
+						statusLabel.SetText(fmt.Sprintf("Variables applied, but %d of %d checked variable(s) do not match in a new process.", len(mismatches), checked))
+						dialog.ShowInformation("Verification Mismatches", describeVerifyMismatches(mismatches, config, settings.SensitivePatterns), myWindow)
+					default:
+						statusLabel.SetText(fmt.Sprintf("%s Verified %d variable(s) in a new process.", status, checked))
+					}
+					statusLabel.Refresh()
+
+					// The registry is right, now check the broadcast reached the shell and which running shells are stale
+					if err == nil && len(mismatches) == 0 {
+						inherited, err := verifyInheritedEnvironment(config)
+						switch {
+						case err != nil:
+							statusLabel.SetText(fmt.Sprintf("%s Verified %d variable(s) in a new process, but the shell's environment could not be checked: %v", status, checked, err))
+						case len(inherited.Mismatches) > 0 || len(inherited.Stale) > 0:
+							statusLabel.SetText(fmt.Sprintf("%s Verified %d variable(s) in a new process, %d stale variable(s) in the shell, %d running shell(s) need a restart.", status, checked, len(inherited.Mismatches), len(inherited.Stale)))
+							dialog.ShowInformation("Inherited Environment", inherited.describe(config, settings.SensitivePatterns), myWindow)
+						default:
+							statusLabel.SetText(fmt.Sprintf("%s Verified %d variable(s) in a new process and in a program started from the shell.", status, checked))
+						}
+						statusLabel.Refresh()
+					}
+				}
+
+				// Launch the verification program with the fresh environment if requested
+				if runAfterApplyCheck.Checked && strings.TrimSpace(runAfterApplyEntry.Text) != "" {
+					if err := launchWithFreshEnvironment(runAfterApplyEntry.Text); err != nil {
+						statusLabel.SetText(fmt.Sprintf("Variables applied, but the post-apply command failed: %v", err))
+						dialog.ShowError(fmt.Errorf("error running post-apply command: %v", err), myWindow)
+						statusLabel.Refresh()
+					}
+				}
 			}
-		}()
+			return outcome
+		})
+		if ahead > 0 {
+			statusLabel.SetText(queuedStatus("Apply queued.", ahead))
+			statusLabel.Refresh()
+		}
 	}
 
 	// Create UI buttons with their respective handlers
 	chooseFileButton := widget.NewButton("Choose YAML Config File", func() {
 		// Run file dialog in goroutine to prevent UI blocking
 		go func() {
-			filePath, err := sqweekdialog.File().Filter("YAML Config", "yaml", "yml").Load()
+			filePath, err := sqweekdialog.File().Filter("YAML Config", "yaml", "yml").Filter("Encrypted Config", "enc").Filter("Environment Backup", "evmbackup").Filter("Registry Export", "reg").Filter("CSV Inventory", "csv").Load()
 			if err != nil {
 				if err.Error() == "cancelled" {
 					statusLabel.SetText("File selection cancelled.")
@@ -197,47 +632,134 @@ func main() {
 				statusLabel.Refresh()
 				return
 			}
-			selectedFilePath = filePath
-			filePathLabel.SetText(fmt.Sprintf("Selected: %s", selectedFilePath))
-			filePathLabel.Refresh()
-			statusLabel.SetText("File selected. Click 'Preview Changes' or 'Apply Variables' to proceed.")
+			selectConfig(filePath)
+		}()
+	})
+
+	// Button to select a folder whose configs are previewed and applied together, ordered by their file names
+	chooseFolderButton := widget.NewButton("Choose Config Folder", func() {
+		go func() {
+			dir, err := sqweekdialog.Directory().Title("Choose a folder of configs").Browse()
+			if err != nil {
+				if err.Error() == "cancelled" {
+					statusLabel.SetText("Folder selection cancelled.")
+				} else {
+					statusLabel.SetText(fmt.Sprintf("Error choosing folder: %v", err))
+					dialog.ShowError(fmt.Errorf("error choosing folder: %v", err), myWindow)
+				}
+				statusLabel.Refresh()
+				return
+			}
+			selectConfig(dir)
+			statusLabel.SetText(fmt.Sprintf("Folder selected, %s.", configFolderSummary(dir)))
 			statusLabel.Refresh()
 		}()
 	})
 
+	// Button to select a config served over http(s), cached locally for offline use
+	openURLButton := widget.NewButton("Open Config URL...", func() {
+		urlEntry := widget.NewEntry()
+		urlEntry.SetPlaceHolder("https://example.com/configs/dev.yaml")
+		if isRemoteConfig(selectedFilePath) {
+			urlEntry.SetText(selectedFilePath)
+		}
+		urlEntry.Validator = func(text string) error {
+			if !isRemoteConfig(strings.TrimSpace(text)) {
+				return fmt.Errorf("enter an http:// or https:// URL")
+			}
+			return nil
+		}
+		form := dialog.NewForm("Open Config URL", "Open", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("URL", urlEntry),
+		}, func(confirmed bool) {
+			if confirmed {
+				selectConfig(strings.TrimSpace(urlEntry.Text))
+			}
+		}, myWindow)
+		form.Resize(fyne.NewSize(560, 160))
+		form.Show()
+	})
+
+	// Button to select the changes queued from the New Variable dialog as the config
+	queuedButton := widget.NewButton("Use Queued Changes", func() {
+		queued, err := loadQueuedChanges()
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		if len(queued.UserVariables) == 0 && len(queued.SystemVariables) == 0 {
+			dialog.ShowInformation("No Queued Changes", "There are no queued changes. Use 'New Variable' on the Variables tab to queue one.", myWindow)
+			return
+		}
+		path, err := queuedChangesPath()
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		selectConfig(path)
+	})
+
+	// Machines that require a review stage every apply, the staged config itself is committed
+	applyEnvVars := func() { runApply(settings.RequireStaging && !isStagedChangesFile(selectedFilePath)) }
+
 	previewButton := widget.NewButton("Preview Changes", previewChanges)
 	applyButton := widget.NewButton("Apply Variables", applyEnvVars)
+	stageButton := widget.NewButton("Stage for Review", func() { runApply(true) })
+	reviewStagedButton := widget.NewButton("Review Staged Changes", func() {
+		showStagedChangesWindow(myApp, myWindow, &settings, func(path string) {
+			selectConfig(path)
+			runApply(false)
+		})
+	})
+	markElevates(applyButton, isAdmin, true) // System variables of the config are written after administrator approval
 
 	// Button to relaunch application with administrator privileges
-	runAsAdminButton := widget.NewButton("Relaunch as Admin", func() {
-		go func() {
-			// Preserve command line arguments when elevating
-			args := os.Args[1:]
-			if selectedFilePath != "" && !contains(args, selectedFilePath) {
-				args = append(args, selectedFilePath)
-			}
+	// The selected config is passed on so it stays selected after elevation
+	runAsAdminButton := widget.NewButtonWithIcon("Relaunch as Admin", shieldIcon, func() {
+		go relaunchElevated(myWindow, selectedFilePath)
+	})
+	if isAdmin {
+		runAsAdminButton.Disable()
+	}
 
-			err := elevateAsAdmin(args...)
+	// Button to push the selected config's variables into already running consoles
+	refreshConsolesButton := widget.NewButton("Refresh Running Consoles", func() {
+		if selectedFilePath == "" {
+			dialog.ShowInformation("Error", "Please select a YAML configuration file first.", myWindow)
+			return
+		}
+
+		go func() {
+			config, err := loadSelectedConfig()
 			if err != nil {
-				dialog.ShowError(fmt.Errorf("failed to relaunch as admin: %v", err), myWindow)
-			} else {
-				myApp.Quit()
+				dialog.ShowError(err, myWindow)
+				return
 			}
+
+			showConsoleRefreshWindow(myApp, config)
 		}()
 	})
 
+	// Button to list the programs that started before the last apply and restart them
+	restartAdvisorButton := widget.NewButton("Restart Advisor", func() {
+		showRestartAdvisor(myApp)
+	})
+
 	// Button to export current environment variables to YAML file
 	exportButton := widget.NewButton("Export Variables to YAML", func() {
-		go func() {
+		ahead := backgroundJobs.submit(jobKindExport, "Export variables to YAML", func(ctx context.Context) error {
 			statusLabel.SetText("Exporting variables... Please wait.")
 			statusLabel.Refresh()
 
+			timer := newPhaseTimer("Export")
+			timer.begin("registry reads")
 			configToExport, exportErr := exportEnvironmentVariables(isAdmin)
+			timer.end()
 			if exportErr != nil {
 				statusLabel.SetText(fmt.Sprintf("Error exporting variables: %v", exportErr))
 				dialog.ShowError(fmt.Errorf("error exporting variables: %v", exportErr), myWindow)
 				statusLabel.Refresh()
-				return
+				return exportErr
 			}
 
 			savePath, err := sqweekdialog.File().Filter("YAML Config", "yaml", "yml").Save()
@@ -249,13 +771,23 @@ func main() {
 					dialog.ShowError(fmt.Errorf("error saving file: %v", err), myWindow)
 				}
 				statusLabel.Refresh()
-				return
+				return fileDialogOutcome(err)
 			}
 
 			if savePath == "" {
 				statusLabel.SetText("Export cancelled.")
 				statusLabel.Refresh()
-				return
+				return errJobCancelled
+			}
+
+			// Replace secret values with prompts when redaction is enabled
+			if settings.RedactOnExport {
+				configToExport.UserVariables = redactSensitiveVariables(configToExport.UserVariables, settings.SensitivePatterns)
+				configToExport.SystemVariables = redactSensitiveVariables(configToExport.SystemVariables, settings.SensitivePatterns)
+			}
+			// Rewrite paths inside user profiles so the export works for other users
+			if settings.PortableExport {
+				configToExport.UserVariables = portableUserPaths(configToExport.UserVariables)
 			}
 
 			// Ensure exported file has proper YAML extension
@@ -263,185 +795,426 @@ func main() {
 				savePath += ".yaml"
 			}
 
-			if saveErr := saveConfigToFile(configToExport, savePath); saveErr != nil {
+			// When exporting over an existing config, show what changed since it was written
+			if previous, err := loadConfig(savePath); err == nil {
+				changes := diffConfigs(previous, configToExport)
+				switch showExportDiffDialog(changes, settings.SensitivePatterns, myWindow) {
+				case exportChoiceCancel:
+					statusLabel.SetText("Export cancelled.")
+					statusLabel.Refresh()
+					return errJobCancelled
+				case exportChoiceDelta:
+					deltaPath := changesFilePath(savePath)
+					saveErr := saveConfigToFile(changesToConfig(changes), deltaPath)
+					if saveErr != nil {
+						statusLabel.SetText(fmt.Sprintf("Error writing changes file: %v", saveErr))
+						dialog.ShowError(fmt.Errorf("error writing changes file: %v", saveErr), myWindow)
+					} else {
+						statusLabel.SetText(fmt.Sprintf("%d change(s) written to: %s", len(changes), deltaPath))
+						dialog.ShowInformation("Export Success", fmt.Sprintf("Changes since the last export written to:\n%s", deltaPath), myWindow)
+					}
+					statusLabel.Refresh()
+					return saveErr
+				}
+			}
+
+			// The time spent in the save dialog is left out of the timings
+			timer.begin("write")
+			saveErr := saveConfigToFile(configToExport, savePath)
+			if saveErr != nil {
 				statusLabel.SetText(fmt.Sprintf("Error writing config to file: %v", saveErr))
 				dialog.ShowError(fmt.Errorf("error writing config to file: %v", saveErr), myWindow)
 				statusLabel.Refresh()
 			} else {
-				statusLabel.SetText(fmt.Sprintf("Variables exported successfully to: %s", savePath))
+				timer.end()
+				statusLabel.SetText(fmt.Sprintf("Variables exported successfully to: %s (%s)", savePath, timer.log()))
 				dialog.ShowInformation("Export Success", fmt.Sprintf("All current environment variables exported to:\n%s", savePath), myWindow)
 				statusLabel.Refresh()
 			}
-		}()
+			return saveErr
+		})
+		if ahead > 0 {
+			statusLabel.SetText(queuedStatus("Export queued.", ahead))
+			statusLabel.Refresh()
+		}
 	})
 
-	// Layout all UI components vertically
-	content := container.NewVBox(
+	// Button to export the current environment as a compressed backup archive
+	archiveButton := widget.NewButton("Export Backup Archive", func() {
+		ahead := backgroundJobs.submit(jobKindExport, "Export backup archive", func(ctx context.Context) error {
+			statusLabel.SetText("Creating backup archive... Please wait.")
+			statusLabel.Refresh()
+
+			configToArchive, exportErr := exportEnvironmentVariables(isAdmin)
+			if exportErr != nil {
+				statusLabel.SetText(fmt.Sprintf("Error exporting variables: %v", exportErr))
+				dialog.ShowError(fmt.Errorf("error exporting variables: %v", exportErr), myWindow)
+				statusLabel.Refresh()
+				return exportErr
+			}
+
+			savePath, err := sqweekdialog.File().Filter("Environment Backup", "evmbackup").Save()
+			if err != nil || savePath == "" {
+				if err != nil && err.Error() != "cancelled" {
+					statusLabel.SetText(fmt.Sprintf("Error saving file: %v", err))
+					dialog.ShowError(fmt.Errorf("error saving file: %v", err), myWindow)
+				} else {
+					statusLabel.SetText("Backup cancelled.")
+				}
+				statusLabel.Refresh()
+				if err != nil {
+					return fileDialogOutcome(err)
+				}
+				return errJobCancelled
+			}
+
+			// Ensure the archive has the backup extension so it can be restored later
+			if !isBackupArchive(savePath) {
+				savePath += backupArchiveExtension
+			}
+
+			saveErr := saveBackupArchive(configToArchive, savePath)
+			if saveErr != nil {
+				statusLabel.SetText(fmt.Sprintf("Error writing backup archive: %v", saveErr))
+				dialog.ShowError(fmt.Errorf("error writing backup archive: %v", saveErr), myWindow)
+			} else {
+				statusLabel.SetText(fmt.Sprintf("Backup archive written to: %s", savePath))
+				dialog.ShowInformation("Backup Success", fmt.Sprintf("Environment backup archive written to:\n%s\n\nChoose it as the config file to restore it.", savePath), myWindow)
+			}
+			statusLabel.Refresh()
+			return saveErr
+		})
+		if ahead > 0 {
+			statusLabel.SetText(queuedStatus("Backup queued.", ahead))
+			statusLabel.Refresh()
+		}
+	})
+
+	// Button to export the current environment or the selected config in another format, including formats added by plugins
+	exportAsButton := widget.NewButton("Export As...", func() {
+		showExportFormatDialog(myWindow, &settings, isAdmin, selectedFilePath, loadSelectedConfig)
+	})
+
+	// Button to export or compare another machine's environment over the remote registry
+	remoteExportButton := widget.NewButton("Export Remote Machine...", func() {
+		showRemoteExportWindow(&settings, isAdmin)
+	})
+
+	// Button to compare two exports, or an export with this machine
+	compareButton := widget.NewButton("Compare Environments...", func() {
+		showComparisonWindow(&settings, isAdmin)
+	})
+
+	// Button to apply the selected config to other machines over the remote registry
+	fleetButton := widget.NewButton("Apply to Machines...", func() {
+		if selectedFilePath == "" {
+			dialog.ShowInformation("Error", "Please select a YAML configuration file first.", myWindow)
+			return
+		}
+		path := selectedFilePath
+		showFleetApplyWindow(&settings, path, func() (Config, error) { return loadFleetConfig(path) })
+	})
+
+	// Button to check this machine against a baseline config, the selected config by default
+	complianceButton := widget.NewButton("Compliance Report...", func() {
+		showComplianceWindow(&settings, selectedFilePath)
+	})
+
+	// Button to review the profiles waiting for approval
+	approvalsButton := widget.NewButton("Pending Approvals", func() {
+		showApprovalsWindow(myApp, &settings, isAdmin)
+	})
+
+	// Hide everything that writes to the environment in read-only mode
+	hideInReadOnly(queuedButton, applyButton, stageButton, runAfterApplyCheck, runAfterApplyEntry, verifyAfterApplyCheck, refreshConsolesButton, fleetButton)
+
+	// Layout the config workflow vertically on its own tab
+	configTab := container.NewVScroll(container.NewVBox(
 		widget.NewLabel("This application manages Windows user and system environment variables."),
 		widget.NewLabel("Click 'Choose YAML Config File' to select your configuration."),
 		chooseFileButton,
+		chooseFolderButton,
+		openURLButton,
+		queuedButton,
 		filePathLabel,
+		remoteStatusLabel,
+		changedLabel,
+		container.NewBorder(nil, nil, widget.NewLabel("Namespace:"), nil, namespaceEntry),
+		container.NewBorder(nil, nil, widget.NewLabel("Only tags:"), nil, tagFilterEntry),
 		previewButton,
 		applyButton,
+		container.NewHBox(stageButton, reviewStagedButton),
+		container.NewBorder(nil, nil, runAfterApplyCheck, nil, runAfterApplyEntry),
+		verifyAfterApplyCheck,
+		refreshConsolesButton,
+		restartAdvisorButton,
+		approvalsButton,
+		fleetButton,
 		exportButton,
+		exportAsButton,
+		archiveButton,
+		remoteExportButton,
+		compareButton,
+		complianceButton,
 		runAsAdminButton,
-		widget.NewLabel(fmt.Sprintf("Privilege Level: %s", adminStatus)),
+		newCapabilityPanel(adminStatus, isAdmin),
 		widget.NewSeparator(),
 		statusLabel,
+	))
+
+	// Organize the feature set into tabs
+	var content *container.AppTabs
+	configTabItem := container.NewTabItem("Config / Apply", configTab)
+	useProfile := func(path string) {
+		selectConfig(path)
+		content.Select(configTabItem)
+	}
+	// The sandbox compares with the selected config, when there is one
+	openSandbox := func() {
+		var proposed func() (Config, error)
+		if selectedFilePath != "" {
+			proposed = loadSelectedConfig
+		}
+		showExpansionSandbox(&settings, proposed)
+	}
+	variablesTab, searchVariables := newVariablesTab(myWindow, &settings, isAdmin)
+	variablesTabItem := container.NewTabItem("Variables", variablesTab)
+	content = container.NewAppTabs(
+		variablesTabItem,
+		container.NewTabItem("Effective", newEffectiveTab(myWindow, &settings, isAdmin, openSandbox)),
+		container.NewTabItem("Dashboard", newDashboardTab(&settings, isAdmin)),
+		container.NewTabItem("Toolchains", newToolchainsTab(myWindow, &settings, isAdmin)),
+		configTabItem,
+		container.NewTabItem("History", newHistoryTab(myWindow, &settings, isAdmin)),
+		container.NewTabItem("Jobs", newJobsTab(myWindow)),
+		container.NewTabItem("Profiles", newProfilesTab(myWindow, &settings, func() string { return selectedFilePath }, useProfile)),
+		container.NewTabItem("Trash", newTrashTab(myWindow, &settings, isAdmin)),
+		container.NewTabItem("Settings", newSettingsTab(myWindow, &settings, isAdmin)),
 	)
+	// Start on the config workflow, which is what the application is usually opened for
+	content.Select(configTabItem)
+
+	// Ctrl+Shift+P opens the command palette with every action of the application
+	paletteCommands := func() []paletteCommand {
+		commands := []paletteCommand{
+			{Title: "Open Config File...", Run: func(string) { chooseFileButton.OnTapped() }},
+			{Title: "Open Config Folder...", Run: func(string) { chooseFolderButton.OnTapped() }},
+			{Title: "Open Config URL...", Run: func(string) { openURLButton.OnTapped() }},
+			{Title: "Preview Changes", Run: func(string) { previewChanges() }},
+			{Title: "Export Variables to YAML...", Run: func(string) { exportButton.OnTapped() }},
+			{Title: "Export Backup Archive...", Run: func(string) { archiveButton.OnTapped() }},
+			{Title: "Export As...", Run: func(string) { exportAsButton.OnTapped() }},
+			{Title: "Export Remote Machine...", Run: func(string) { remoteExportButton.OnTapped() }},
+			{Title: "Compare Environments...", Run: func(string) { compareButton.OnTapped() }},
+			{Title: "Compliance Report...", Run: func(string) { complianceButton.OnTapped() }},
+			{Title: "Take Snapshot Now", Run: func(string) {
+				go func() {
+					path, err := createBackup(settings.Backup, isAdmin)
+					if err != nil {
+						dialog.ShowError(fmt.Errorf("error creating backup: %v", err), myWindow)
+						return
+					}
+					statusLabel.SetText(fmt.Sprintf("Snapshot written to: %s", path))
+					statusLabel.Refresh()
+				}()
+			}},
+			{Title: "Search Variables", Run: func(query string) {
+				content.Select(variablesTabItem)
+				searchVariables(query)
+			}},
+			{Title: "Find & Replace in Values", Run: func(string) {
+				showFindReplaceWindow(&settings, isAdmin, nil)
+			}},
+			{Title: "Expansion Sandbox...", Run: func(string) { openSandbox() }},
+			{Title: "Project Environments...", Run: func(string) { showProjectEnvironmentsWindow(&settings) }},
+			{Title: "Run Doctor...", Run: func(string) { showDoctorWindow(&settings, isAdmin, nil) }},
+			{Title: "Relaunch as Admin", Run: func(string) { runAsAdminButton.OnTapped() }},
+			{Title: "Review Staged Changes...", Run: func(string) { reviewStagedButton.OnTapped() }},
+			{Title: "Pending Approvals...", Run: func(string) { approvalsButton.OnTapped() }},
+		}
+		if !readOnlyMode {
+			commands = append(commands,
+				paletteCommand{Title: "Apply Variables", Run: func(string) { applyEnvVars() }},
+				paletteCommand{Title: "Stage for Review", Run: func(string) { stageButton.OnTapped() }},
+				paletteCommand{Title: "New Variable...", Run: func(string) {
+					showNewVariableDialog(myWindow, &settings, isAdmin, nil)
+				}},
+				paletteCommand{Title: "Refresh Running Consoles", Run: func(string) { refreshConsolesButton.OnTapped() }},
+				paletteCommand{Title: "Restart Advisor", Run: func(string) { restartAdvisorButton.OnTapped() }},
+				paletteCommand{Title: "Apply to Machines...", Run: func(string) { fleetButton.OnTapped() }},
+				paletteCommand{Title: "Use Queued Changes", Run: func(string) { queuedButton.OnTapped() }},
+			)
+		}
+		for _, item := range content.Items {
+			item := item
+			commands = append(commands, paletteCommand{Title: "Go to " + item.Text, Run: func(string) { content.Select(item) }})
+		}
+		if names, err := listProfiles(); err == nil {
+			for _, name := range names {
+				name := name
+				commands = append(commands, paletteCommand{Title: "Switch to Profile: " + name, Run: func(string) {
+					if path, err := profilePath(name); err == nil {
+						useProfile(path)
+					}
+				}})
+			}
+		}
+		return commands
+	}
+	myWindow.Canvas().AddShortcut(commandPaletteShortcut, func(fyne.Shortcut) {
+		showCommandPalette(myWindow, paletteCommands)
+	})
+
+	// Correct drift from the enforced profile within the maintenance window
+	if !readOnlyMode {
+		startEnforcer(&settings, isAdmin, myWindow, sendWatchNotification(myApp))
+	}
 
 	myWindow.SetContent(content)
+	if listenerErr != nil {
+		statusLabel.SetText(fmt.Sprintf("Listener not started: %v", listenerErr))
+	}
 	myWindow.ShowAndRun()
 }
 
-// isValidYAMLFile checks if the provided file path has a valid YAML extension
-func isValidYAMLFile(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	return ext == ".yaml" || ext == ".yml"
+// hideInReadOnly hides UI elements that modify the environment when read-only mode is active
+func hideInReadOnly(objects ...fyne.CanvasObject) {
+	if !readOnlyMode {
+		return
+	}
+	for _, o := range objects {
+		o.Hide()
+	}
 }
 
-// showPreviewWindow creates and displays a window showing all pending environment variable changes
-func showPreviewWindow(app fyne.App, config Config, isAdmin bool) {
-	previewWindow := app.NewWindow("Preview Changes")
-	previewWindow.Resize(fyne.NewSize(700, 500))
+// showValuePrompt asks the user for a placeholder value and blocks until the dialog is closed
+// Secret prompts use a masked entry; ok is false when the user cancelled
+func showValuePrompt(label string, secret bool, parent fyne.Window) (value string, ok bool) {
+	entry := widget.NewEntry()
+	if secret {
+		entry = widget.NewPasswordEntry()
+	}
 
-	var content []string
+	answer := make(chan bool)
+	dialog.ShowForm("Value Required", "OK", "Cancel", []*widget.FormItem{
+		widget.NewFormItem(label, entry),
+	}, func(confirmed bool) {
+		answer <- confirmed
+	}, parent)
 
-	// Display user environment variables section
-	if len(config.UserVariables) > 0 {
-		content = append(content, "USER ENVIRONMENT VARIABLES:")
-		content = append(content, "")
-		for _, v := range config.UserVariables {
-			switch v.Operation {
-			case "set":
-				content = append(content, fmt.Sprintf("  SET: %s = %s", v.Name, v.Value))
-			case "delete":
-				content = append(content, fmt.Sprintf("  DELETE: %s", v.Name))
-			default:
-				content = append(content, fmt.Sprintf("  UNKNOWN OPERATION (%s): %s = %s", v.Operation, v.Name, v.Value))
-			}
-		}
-		content = append(content, "")
+	if !<-answer {
+		return "", false
 	}
+	return entry.Text, true
+}
 
-	// Display system environment variables section with admin warning
-	if len(config.SystemVariables) > 0 {
-		content = append(content, "SYSTEM ENVIRONMENT VARIABLES:")
-		if !isAdmin {
-			content = append(content, "  ⚠️  WARNING: Running as standard user - system variables will be IGNORED")
-		}
-		content = append(content, "")
-		for _, v := range config.SystemVariables {
-			prefix := "  "
-			if !isAdmin {
-				prefix = "  [IGNORED] "
-			}
-			switch v.Operation {
-			case "set":
-				content = append(content, fmt.Sprintf("%sSET: %s = %s", prefix, v.Name, v.Value))
-			case "delete":
-				content = append(content, fmt.Sprintf("%sDELETE: %s", prefix, v.Name))
-			default:
-				content = append(content, fmt.Sprintf("%sUNKNOWN OPERATION (%s): %s = %s", prefix, v.Operation, v.Name, v.Value))
-			}
+// Choices offered when exporting over an existing config file
+const (
+	exportChoiceCancel    = iota // Keep the existing file untouched
+	exportChoiceOverwrite        // Replace the existing file with the full export
+	exportChoiceDelta            // Write only the differences to a separate changes file
+)
+
+// showExportDiffDialog shows the differences between an existing export and the new one and returns the user's choice
+func showExportDiffDialog(changes []VariableChange, sensitivePatterns []string, parent fyne.Window) int {
+	text := "No variables changed since this file was written."
+	if len(changes) > 0 {
+		text = fmt.Sprintf("%d change(s) since this file was written:\n\n%s", len(changes), strings.Join(describeChanges(changes, sensitivePatterns), "\n"))
+	}
+	diffLabel := widget.NewLabel(text)
+	diffLabel.Wrapping = fyne.TextWrapWord
+	scroll := container.NewScroll(diffLabel)
+	scroll.SetMinSize(fyne.NewSize(520, 300))
+
+	choice := make(chan int, 1)
+	d := dialog.NewCustomWithoutButtons("File Already Exists", scroll, parent)
+	choose := func(c int) func() {
+		return func() {
+			choice <- c
+			d.Hide()
 		}
-		content = append(content, "")
 	}
 
-	if len(config.UserVariables) == 0 && len(config.SystemVariables) == 0 {
-		content = append(content, "No environment variables found in the configuration file.")
+	buttons := []fyne.CanvasObject{
+		widget.NewButton("Cancel", choose(exportChoiceCancel)),
+		widget.NewButton("Overwrite", choose(exportChoiceOverwrite)),
 	}
+	if len(changes) > 0 {
+		buttons = append(buttons, widget.NewButton("Write Changes File", choose(exportChoiceDelta)))
+	}
+	d.SetButtons(buttons)
+	d.Show()
+	return <-choice
+}
 
-	content = append(content, "")
-	content = append(content, "Note: After applying changes, a WM_SETTINGCHANGE message will be")
-	content = append(content, "broadcast to notify other applications of the environment changes.")
-
-	// Use a Label for better theme compatibility and automatic text color handling
-	previewLabel := widget.NewLabel(strings.Join(content, "\n"))
-	previewLabel.Wrapping = fyne.TextWrapWord
-	previewLabel.Alignment = fyne.TextAlignLeading
-
-	scrollContainer := container.NewScroll(previewLabel)
-	scrollContainer.SetMinSize(fyne.NewSize(680, 400))
-
-	closeButton := widget.NewButton("Close", func() {
-		previewWindow.Close()
-	})
-
-	windowContent := container.NewVBox(
-		widget.NewLabel("The following changes will be made to your environment variables:"),
-		widget.NewSeparator(),
-		scrollContainer,
-		widget.NewSeparator(),
-		container.NewHBox(closeButton),
-	)
-
-	previewWindow.SetContent(windowContent)
-	previewWindow.Show()
+// changesFilePath derives the path of the delta file written next to an export, e.g. env.yaml -> env.changes.yaml
+func changesFilePath(exportPath string) string {
+	ext := filepath.Ext(exportPath)
+	return strings.TrimSuffix(exportPath, ext) + ".changes" + ext
 }
 
-// applyVariables processes a list of environment variables and applies them to the Windows registry
-func applyVariables(variables []Variable, hive registry.Key, subkeyPath string) error {
-	// Get human-readable hive name for error messages
-	var hiveName string
-	switch hive {
-	case registry.CURRENT_USER:
-		hiveName = "HKEY_CURRENT_USER"
-	case registry.LOCAL_MACHINE:
-		hiveName = "HKEY_LOCAL_MACHINE"
-	default:
-		hiveName = fmt.Sprintf("UnknownHive(%d)", hive)
-	}
-
-	// Open registry key with write permissions
-	key, err := registry.OpenKey(hive, subkeyPath, registry.SET_VALUE)
-	if err != nil {
-		return fmt.Errorf("failed to open registry key %s\\%s: %w", hiveName, subkeyPath, err)
+// isSupportedConfigFile checks if the provided file can be loaded as a config
+func isSupportedConfigFile(filePath string) bool {
+	if isRemoteConfig(filePath) {
+		return true // The URL's format is detected when it is downloaded
 	}
-	defer key.Close()
+	return isValidYAMLFile(filePath) || isEncryptedConfig(filePath) || isBackupArchive(filePath) || isRegFile(filePath) || isCSVFile(filePath) || isConfigFolder(filePath)
+}
 
-	// Process each variable according to its operation type
-	for _, v := range variables {
-		switch v.Operation {
-		case "set":
-			if err := key.SetStringValue(v.Name, v.Value); err != nil {
-				fmt.Printf("  Failed to set %s=%s: %v\n", v.Name, v.Value, err)
-			} else {
-				fmt.Printf("  Successfully set %s=%s\n", v.Name, v.Value)
-			}
-		case "delete":
-			if err := key.DeleteValue(v.Name); err != nil {
-				if os.IsNotExist(err) {
-					fmt.Printf("  Variable %s already deleted or did not exist.\n", v.Name)
-				} else {
-					fmt.Printf("  Failed to delete %s: %v\n", v.Name, err)
-				}
-			} else {
-				fmt.Printf("  Successfully deleted %s\n", v.Name)
-			}
-		default:
-			fmt.Printf("  Unknown operation '%s' for variable %s. Skipping.\n", v.Operation, v.Name)
-		}
-	}
-	return nil
+// isValidYAMLFile checks if the provided file path has a valid YAML extension
+func isValidYAMLFile(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".yaml" || ext == ".yml"
 }
 
 // broadcastSettingChange notifies all Windows applications that environment variables have changed
 // This allows applications like Explorer and Command Prompt to pick up the new values
-func broadcastSettingChange() error {
-	user32 := syscall.NewLazyDLL("user32.dll")
-	sendMessageTimeout := user32.NewProc("SendMessageTimeoutW")
+// A broadcast that times out or fails transiently is repeated according to the retry policy
+func broadcastSettingChange(options BroadcastSettings, retry RetrySettings) (err error) {
+	// The outcome is shown in the capability report
+	defer func() { recordBroadcastResult(options.Mode, err) }()
+
+	if options.Mode == BroadcastModeSkip {
+		fmt.Println("Skipping WM_SETTINGCHANGE broadcast (disabled in settings).")
+		return nil
+	}
+	return withRetry(retry, "Broadcasting the environment change", func() error { return sendSettingChange(options) })
+}
 
+// sendSettingChange sends WM_SETTINGCHANGE once in the configured broadcast mode
+func sendSettingChange(options BroadcastSettings) error {
+	user32 := syscall.NewLazyDLL("user32.dll")
 	environmentStrPtr := uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr("Environment")))
 
+	// SendNotifyMessageW posts the message without waiting, so a hung top-level window cannot stall the apply
+	if options.Mode == BroadcastModeNotify {
+		sendNotifyMessage := user32.NewProc("SendNotifyMessageW")
+		ret, _, err := sendNotifyMessage.Call(
+			uintptr(HWND_BROADCAST),   // Send to all top-level windows
+			uintptr(WM_SETTINGCHANGE), // Environment setting changed message
+			0,                         // wParam (unused)
+			environmentStrPtr,         // lParam (pointer to "Environment" string)
+		)
+		if ret == 0 {
+			return fmt.Errorf("SendNotifyMessageW failed: %w", err)
+		}
+		return nil
+	}
+
+	timeout := options.TimeoutMs
+	if timeout <= 0 {
+		timeout = defaultSettings().Broadcast.TimeoutMs
+	}
+
 	// Call Windows API to broadcast the environment change message
+	sendMessageTimeout := user32.NewProc("SendMessageTimeoutW")
 	ret, _, err := sendMessageTimeout.Call(
 		uintptr(HWND_BROADCAST),   // Send to all top-level windows
 		uintptr(WM_SETTINGCHANGE), // Environment setting changed message
 		0,                         // wParam (unused)
 		environmentStrPtr,         // lParam (pointer to "Environment" string)
-		0,                         // Normal message sending
-		5000,                      // 5 second timeout
+		SMTO_ABORTIFHUNG,          // Don't wait for windows that are not responding
+		uintptr(timeout),          // Per-window timeout in milliseconds
 		0,                         // Return value (unused)
 	)
 
@@ -453,18 +1226,26 @@ func broadcastSettingChange() error {
 
 // exportEnvironmentVariables reads all current environment variables from the Windows registry
 func exportEnvironmentVariables(isAdmin bool) (Config, error) {
-	var config Config
+	config := Config{Version: CurrentConfigVersion}
 	var err error
 
+	// Describe where and when the export was taken so the file is self-describing
+	hostname, _ := os.Hostname()
+	config.Metadata = &ConfigMetadata{
+		Name:    fmt.Sprintf("Environment export from %s", hostname),
+		Author:  os.Getenv("USERNAME"),
+		Created: time.Now().Format(time.RFC3339),
+	}
+
 	// Always export user variables (accessible to all users)
-	config.UserVariables, err = readVariablesFromRegistry(registry.CURRENT_USER, "Environment")
+	config.UserVariables, err = readVariablesFromRegistry(registry.CURRENT_USER, userEnvironmentPath)
 	if err != nil {
 		return Config{}, fmt.Errorf("failed to read user environment variables: %w", err)
 	}
 
 	// Only export system variables if running as administrator
 	if isAdmin {
-		config.SystemVariables, err = readVariablesFromRegistry(registry.LOCAL_MACHINE, "SYSTEM\\CurrentControlSet\\Control\\Session Manager\\Environment")
+		config.SystemVariables, err = readVariablesFromRegistry(registry.LOCAL_MACHINE, systemEnvironmentPath)
 		if err != nil {
 			return Config{}, fmt.Errorf("failed to read system environment variables: %w", err)
 		}
@@ -472,6 +1253,20 @@ func exportEnvironmentVariables(isAdmin bool) (Config, error) {
 		fmt.Println("Skipping system environment variable export: Application not running as Administrator.")
 	}
 
+	// Stamp managed variables with their last modification so stale ones can be spotted in the file
+	managed, err := loadManagedIndex()
+	if err != nil {
+		fmt.Printf("Warning: Could not load managed variables: %v\n", err)
+	}
+	annotateModified(config.UserVariables, ScopeUser, managed)
+	annotateModified(config.SystemVariables, ScopeSystem, managed)
+	tags, err := loadTagIndex()
+	if err != nil {
+		fmt.Printf("Warning: Could not load tags: %v\n", err)
+	}
+	annotateTags(config.UserVariables, ScopeUser, tags)
+	annotateTags(config.SystemVariables, ScopeSystem, tags)
+
 	return config, nil
 }
 
@@ -479,15 +1274,7 @@ func exportEnvironmentVariables(isAdmin bool) (Config, error) {
 func readVariablesFromRegistry(hive registry.Key, subkeyPath string) ([]Variable, error) {
 	var variables []Variable
 	// Get human-readable hive name for error messages
-	var hiveName string
-	switch hive {
-	case registry.CURRENT_USER:
-		hiveName = "HKEY_CURRENT_USER"
-	case registry.LOCAL_MACHINE:
-		hiveName = "HKEY_LOCAL_MACHINE"
-	default:
-		hiveName = fmt.Sprintf("UnknownHive(%d)", hive)
-	}
+	hiveName := registryHiveName(hive)
 
 	// Open registry key with read permissions
 	key, err := registry.OpenKey(hive, subkeyPath, registry.READ)
@@ -504,31 +1291,20 @@ func readVariablesFromRegistry(hive registry.Key, subkeyPath string) ([]Variable
 
 	// Read each environment variable value
 	for _, name := range names {
-		value, _, err := key.GetStringValue(name)
+		value, valType, err := key.GetStringValue(name)
 		if err != nil {
 			fmt.Printf("  Warning: Could not read value for %s: %v\n", name, err)
 			continue
 		}
-		variables = append(variables, Variable{Name: name, Value: value, Operation: "set"})
+		varType := TypeString
+		if valType == registry.EXPAND_SZ {
+			varType = TypeExpand
+		}
+		variables = append(variables, Variable{Name: name, Value: value, Operation: "set", Type: varType})
 	}
 	return variables, nil
 }
 
-// saveConfigToFile marshals a Config struct to YAML format and saves it to disk
-func saveConfigToFile(config Config, filePath string) error {
-	yamlData, err := yaml.Marshal(&config)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config to YAML: %w", err)
-	}
-
-	// Write YAML data to file with standard permissions
-	err = ioutil.WriteFile(filePath, yamlData, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write YAML to file %s: %w", filePath, err)
-	}
-	return nil
-}
-
 // isRunningAsAdmin checks if the current process has administrator privileges using Windows API
 func isRunningAsAdmin() (bool, error) {
 	shell32 := syscall.NewLazyDLL("shell32.dll")
@@ -0,0 +1,375 @@
+// managed.go
+// Managed variables - remembers which variables this tool created and reports managed variables no config sets any more
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// managedFileName stores the variables created by this tool
+const managedFileName = "managed.json"
+
+// managedTimeLayout formats timestamps of managed variables; it sorts chronologically as text
+const managedTimeLayout = "2006-01-02 15:04"
+
+// ManagedVariable records a variable this tool created
+type ManagedVariable struct {
+	Scope      string    `json:"scope"`                 // ScopeUser or ScopeSystem
+	Name       string    `json:"name"`                  // Variable name
+	Source     string    `json:"source,omitempty"`      // Config path or UI action that created it
+	FromConfig bool      `json:"from_config,omitempty"` // Source is a config file rather than a UI action
+	Created    time.Time `json:"created"`               // When the variable was created
+	Modified   time.Time `json:"modified,omitempty"`    // When this tool last created or changed the value
+	ModifiedBy string    `json:"modified_by,omitempty"` // Config path or UI action of that change
+}
+
+// key identifies the variable independent of name casing
+func (m ManagedVariable) key() string {
+	return managedKey(m.Scope, m.Name)
+}
+
+// managedKey builds the lookup key of a variable in a scope
+func managedKey(scope, name string) string {
+	return scope + "/" + strings.ToUpper(name)
+}
+
+// managedMu serializes access to the managed variables file
+var managedMu sync.Mutex
+
+// readManagedFile reads the managed variables, the caller must hold managedMu
+func readManagedFile() ([]ManagedVariable, error) {
+	path, err := appDataPath(managedFileName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read managed variables file %s: %w", path, err)
+	}
+	var entries []ManagedVariable
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse managed variables file %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// writeManagedFile writes the managed variables, the caller must hold managedMu
+func writeManagedFile(entries []ManagedVariable) error {
+	path, err := appDataPath(managedFileName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode managed variables: %w", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write managed variables file %s: %w", path, err)
+	}
+	return nil
+}
+
+// recordManagedChanges marks the created variables of a scope as managed, stamps the modified ones and forgets the deleted ones
+// Modified variables only update the records of variables that are already managed
+func recordManagedChanges(scope, source string, fromConfig bool, created, modified, deleted []string) error {
+	if len(created) == 0 && len(modified) == 0 && len(deleted) == 0 {
+		return nil
+	}
+	managedMu.Lock()
+	defer managedMu.Unlock()
+
+	entries, err := readManagedFile()
+	if err != nil {
+		return err
+	}
+	drop := map[string]bool{}
+	for _, name := range append(created, deleted...) {
+		drop[managedKey(scope, name)] = true
+	}
+	stamp := map[string]bool{}
+	for _, name := range modified {
+		stamp[managedKey(scope, name)] = true
+	}
+	now := time.Now()
+	kept := entries[:0]
+	for _, e := range entries {
+		if drop[e.key()] {
+			continue
+		}
+		if stamp[e.key()] {
+			e.Modified, e.ModifiedBy = now, source
+		}
+		kept = append(kept, e)
+	}
+	for _, name := range created {
+		kept = append(kept, ManagedVariable{Scope: scope, Name: name, Source: source, FromConfig: fromConfig, Created: now, Modified: now, ModifiedBy: source})
+	}
+	return writeManagedFile(kept)
+}
+
+// forgetManagedVariables stops tracking the given variables without touching the environment
+func forgetManagedVariables(variables []ManagedVariable) error {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+
+	entries, err := readManagedFile()
+	if err != nil {
+		return err
+	}
+	drop := map[string]bool{}
+	for _, v := range variables {
+		drop[v.key()] = true
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if !drop[e.key()] {
+			kept = append(kept, e)
+		}
+	}
+	return writeManagedFile(kept)
+}
+
+// managedIndex looks up managed variables by scope and name
+type managedIndex map[string]ManagedVariable
+
+// loadManagedIndex reads the managed variables into an index
+func loadManagedIndex() (managedIndex, error) {
+	managedMu.Lock()
+	entries, err := readManagedFile()
+	managedMu.Unlock()
+
+	index := managedIndex{}
+	for _, e := range entries {
+		index[e.key()] = e
+	}
+	return index, err
+}
+
+// lookup returns the managed record of a variable, if there is one
+func (index managedIndex) lookup(scope, name string) (ManagedVariable, bool) {
+	m, ok := index[managedKey(scope, name)]
+	return m, ok
+}
+
+// describe renders the ownership line shown in the variable details
+func (m ManagedVariable) describe() string {
+	line := fmt.Sprintf("Managed: created by this tool on %s from %s", m.Created.Local().Format(managedTimeLayout), m.Source)
+	if modified, by := m.lastModified(); !modified.Equal(m.Created) {
+		line += fmt.Sprintf(", last modified on %s by %s", modified.Local().Format(managedTimeLayout), by)
+	}
+	return line
+}
+
+// lastModified returns when and by what the variable was last written, records from before modification tracking use the creation
+func (m ManagedVariable) lastModified() (time.Time, string) {
+	if m.Modified.IsZero() {
+		return m.Created, m.Source
+	}
+	return m.Modified, m.ModifiedBy
+}
+
+// annotateModified fills the informational modified fields of exported variables from the managed records
+func annotateModified(variables []Variable, scope string, managed managedIndex) {
+	for i := range variables {
+		if m, ok := managed.lookup(scope, variables[i].Name); ok {
+			modified, by := m.lastModified()
+			variables[i].Modified, variables[i].ModifiedBy = modified.Format(time.RFC3339), by
+		}
+	}
+}
+
+// configSetsVariable reports whether a config sets the named variable in a scope
+func configSetsVariable(config Config, scope, name string) bool {
+	variables := config.UserVariables
+	if scope == ScopeSystem {
+		variables = config.SystemVariables
+	}
+	for _, v := range variables {
+		if v.Operation == "set" && strings.EqualFold(v.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// managedReport lists managed variables that no config sets any more and records of variables removed outside the tool
+type managedReport struct {
+	Orphaned []ManagedVariable // Still present, but neither their source config nor any profile sets them
+	Missing  []ManagedVariable // No longer present in the registry
+}
+
+// buildManagedReport compares the managed records with the registry, the source configs and the saved profiles
+// Variables created from the UI are never orphaned, only those a config created
+func buildManagedReport() (managedReport, error) {
+	var report managedReport
+	index, err := loadManagedIndex()
+	if err != nil {
+		return report, err
+	}
+	current, err := readAllVariables()
+	if err != nil {
+		return report, err
+	}
+	present := map[string]bool{}
+	for _, v := range current {
+		present[managedKey(v.Scope, v.Name)] = true
+	}
+
+	// Every saved profile counts as an owner, an unreadable one is skipped
+	var owners []Config
+	if profiles, err := listProfiles(); err == nil {
+		for _, name := range profiles {
+			if path, err := profilePath(name); err == nil {
+				if config, err := loadConfig(path); err == nil {
+					owners = append(owners, config)
+				}
+			}
+		}
+	}
+	// A source config that was deleted owns nothing; one that exists but cannot be read (e.g. encrypted or offline) gets the benefit of the doubt
+	type sourceConfig struct {
+		config   Config
+		exists   bool
+		readable bool
+	}
+	sources := map[string]sourceConfig{}
+	ownedBySource := func(m ManagedVariable) bool {
+		source, loaded := sources[m.Source]
+		if !loaded {
+			source.exists = isRemoteConfig(m.Source) || fileExists(m.Source)
+			if source.exists {
+				config, err := loadConfig(m.Source)
+				if err != nil {
+					fmt.Printf("Warning: Could not check %s for managed variables: %v\n", m.Source, err)
+				}
+				source.config, source.readable = config, err == nil
+			}
+			sources[m.Source] = source
+		}
+		if !source.exists {
+			return false
+		}
+		return !source.readable || configSetsVariable(source.config, m.Scope, m.Name)
+	}
+
+	for _, m := range index {
+		if !present[m.key()] {
+			report.Missing = append(report.Missing, m)
+			continue
+		}
+		if !m.FromConfig || ownedBySource(m) {
+			continue
+		}
+		owned := false
+		for _, config := range owners {
+			if configSetsVariable(config, m.Scope, m.Name) {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			report.Orphaned = append(report.Orphaned, m)
+		}
+	}
+
+	sortManaged := func(list []ManagedVariable) {
+		sort.Slice(list, func(i, j int) bool { return list[i].key() < list[j].key() })
+	}
+	sortManaged(report.Orphaned)
+	sortManaged(report.Missing)
+	return report, nil
+}
+
+// showManagedReportWindow lists orphaned managed variables with options to delete them or stop managing them
+// onDone is called after variables were deleted
+func showManagedReportWindow(settings *Settings, isAdmin bool, onDone func()) {
+	window := fyne.CurrentApp().NewWindow("Orphaned Managed Variables")
+	window.Resize(fyne.NewSize(900, 600))
+
+	rows := container.NewVBox()
+	var render func()
+	render = func() {
+		rows.RemoveAll()
+		report, err := buildManagedReport()
+		if err != nil {
+			rows.Add(widget.NewLabel(fmt.Sprintf("⚠️  Could not build the report: %v", err)))
+		}
+		if len(report.Orphaned) == 0 {
+			rows.Add(widget.NewLabel("No orphaned variables: every variable this tool created from a config is still set by that config or a profile."))
+		} else {
+			rows.Add(widget.NewLabel(fmt.Sprintf("%d variable(s) were created from a config that no longer sets them, and no profile does either.", len(report.Orphaned))))
+		}
+
+		for _, m := range report.Orphaned {
+			m := m
+			deleteButton := widget.NewButton("Delete", func() {
+				changes := []ScopedVariable{{Scope: m.Scope, Variable: Variable{Name: m.Name, Operation: "delete"}}}
+				dialog.ShowConfirm("Delete Variable", fmt.Sprintf("Delete %s from the %s environment? It can be restored from the Trash tab.", m.Name, m.Scope), func(ok bool) {
+					if !ok {
+						return
+					}
+					go func() {
+						if !confirmDangerousChanges(changes, *settings, window) {
+							return
+						}
+						if err := applyDirectChanges("Orphaned managed variables", changes, isAdmin, *settings); err != nil {
+							dialog.ShowError(fmt.Errorf("error deleting %s: %v", m.Name, err), window)
+						}
+						render()
+						if onDone != nil {
+							onDone()
+						}
+					}()
+				}, window)
+			})
+			markElevates(deleteButton, isAdmin, m.Scope == ScopeSystem)
+			hideInReadOnly(deleteButton)
+			forgetButton := widget.NewButton("Stop Managing", func() {
+				if err := forgetManagedVariables([]ManagedVariable{m}); err != nil {
+					dialog.ShowError(fmt.Errorf("error updating managed variables: %v", err), window)
+				}
+				render()
+			})
+
+			rows.Add(widget.NewSeparator())
+			rows.Add(widget.NewLabelWithStyle(fmt.Sprintf("%s  (%s)", m.Name, m.Scope), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			rows.Add(widget.NewLabel(m.describe()))
+			rows.Add(container.NewHBox(deleteButton, forgetButton))
+		}
+
+		// Records of variables removed by other means are dropped so they do not linger
+		if len(report.Missing) > 0 {
+			if err := forgetManagedVariables(report.Missing); err != nil {
+				fmt.Printf("Warning: Could not prune managed variables: %v\n", err)
+			} else {
+				rows.Add(widget.NewSeparator())
+				rows.Add(widget.NewLabel(fmt.Sprintf("%d managed variable(s) were deleted outside this tool and are no longer tracked.", len(report.Missing))))
+			}
+		}
+		rows.Refresh()
+	}
+	go render()
+
+	window.SetContent(container.NewBorder(
+		nil,
+		container.NewHBox(widget.NewButton("Refresh", func() { go render() }), widget.NewButton("Close", window.Close)),
+		nil, nil,
+		container.NewScroll(rows),
+	))
+	window.Show()
+}
@@ -0,0 +1,91 @@
+// metrics.go
+// Performance metrics - times the phases of apply and export runs for the console log, the result report and the audit log
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// phaseTiming is how long one named phase took
+type phaseTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// phaseTimer measures consecutive phases of a run; starting a phase ends the previous one
+type phaseTimer struct {
+	label   string        // Run being measured, e.g. "Apply"
+	current string        // Phase in progress, empty when none
+	since   time.Time     // When the current phase started
+	phases  []phaseTiming // Finished phases in order
+}
+
+// newPhaseTimer prepares timing a run, the first begin starts the clock
+func newPhaseTimer(label string) *phaseTimer {
+	return &phaseTimer{label: label}
+}
+
+// begin ends the running phase and starts the named one
+// Phases that run more than once, such as registry writes for both scopes, are added up
+func (t *phaseTimer) begin(name string) {
+	t.end()
+	t.current = name
+	t.since = time.Now()
+}
+
+// end finishes the running phase, if any
+func (t *phaseTimer) end() {
+	if t.current == "" {
+		return
+	}
+	name, elapsed := t.current, time.Since(t.since)
+	t.current = ""
+	for i := range t.phases {
+		if t.phases[i].Name == name {
+			t.phases[i].Duration += elapsed
+			return
+		}
+	}
+	t.phases = append(t.phases, phaseTiming{Name: name, Duration: elapsed})
+}
+
+// summary renders the finished phases and their total, e.g. "parse 12ms, registry writes 40ms (total 52ms)"
+// Time between phases, such as a save dialog, is not counted
+func (t *phaseTimer) summary() string {
+	if t == nil {
+		return ""
+	}
+	t.end()
+	var parts []string
+	var sum time.Duration
+	for _, p := range t.phases {
+		parts = append(parts, fmt.Sprintf("%s %s", p.Name, formatPhaseDuration(p.Duration)))
+		sum += p.Duration
+	}
+	total := fmt.Sprintf("total %s", formatPhaseDuration(sum))
+	if len(parts) == 0 {
+		return total
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, ", "), total)
+}
+
+// log prints the timings to the console log and returns the summary
+func (t *phaseTimer) log() string {
+	summary := t.summary()
+	fmt.Printf("%s timings: %s\n", t.label, summary)
+	return summary
+}
+
+// formatPhaseDuration rounds a duration to a readable precision
+func formatPhaseDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(10 * time.Millisecond).String()
+	}
+}
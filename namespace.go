@@ -0,0 +1,75 @@
+// namespace.go
+// Namespace prefixes - materializes a config under prefixed variable names so it can be applied several times side by side
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// validateNamespacePrefix checks that a prefix can be prepended to variable names
+func validateNamespacePrefix(prefix string) error {
+	if strings.TrimSpace(prefix) != prefix || strings.ContainsAny(prefix, "=% \t\x00") {
+		return fmt.Errorf("namespace prefix %q must not contain whitespace, '=' or '%%'", prefix)
+	}
+	return nil
+}
+
+// applyNamespace returns a copy of config with prefix prepended to every variable name and name pattern
+// %VAR% references to variables defined in the config itself are rewritten to the prefixed names,
+// so e.g. PATH entries like %APP_HOME%\bin point at the namespaced APP_HOME
+func applyNamespace(config Config, prefix string) (Config, error) {
+	if prefix == "" {
+		return config, nil
+	}
+	if err := validateNamespacePrefix(prefix); err != nil {
+		return config, err
+	}
+
+	defined := map[string]bool{}
+	for _, v := range append(append([]Variable{}, config.UserVariables...), config.SystemVariables...) {
+		if v.Name != "" {
+			defined[strings.ToUpper(v.Name)] = true
+		}
+	}
+
+	config.UserVariables = namespaceVariables(config.UserVariables, prefix, defined)
+	config.SystemVariables = namespaceVariables(config.SystemVariables, prefix, defined)
+	return config, nil
+}
+
+// referencePattern finds %NAME% references inside values
+var referencePattern = regexp.MustCompile(`%([^%=\s]+)%`)
+
+// namespaceVariables prefixes the names, patterns and internal references of a variable list
+func namespaceVariables(variables []Variable, prefix string, defined map[string]bool) []Variable {
+	result := make([]Variable, len(variables))
+	for i, v := range variables {
+		if v.Name != "" {
+			v.Name = prefix + v.Name
+		}
+		if v.Pattern != "" {
+			v.Pattern = namespacePattern(v.Pattern, prefix)
+		}
+		v.Value = referencePattern.ReplaceAllStringFunc(v.Value, func(ref string) string {
+			name := ref[1 : len(ref)-1]
+			if defined[strings.ToUpper(name)] {
+				return "%" + prefix + name + "%"
+			}
+			return ref
+		})
+		result[i] = v
+	}
+	return result
+}
+
+// namespacePattern restricts a glob or regex name pattern to names starting with prefix
+func namespacePattern(pattern, prefix string) string {
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		return regexPatternPrefix + regexp.QuoteMeta(prefix) + "(?:" + strings.TrimPrefix(pattern, regexPatternPrefix) + ")"
+	}
+	// Escape glob metacharacters so the prefix is matched literally
+	escaped := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(prefix)
+	return escaped + pattern
+}
@@ -0,0 +1,140 @@
+// newvariable.go
+// New Variable dialog - creates a single variable from the UI and applies it immediately or queues it for later
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showNewVariableDialog asks for the name, value, scope and type of a new variable
+// onDone is called after the variable was applied or queued so callers can refresh their views
+func showNewVariableDialog(parent fyne.Window, settings *Settings, isAdmin bool, onDone func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. JAVA_HOME")
+	valueEntry := widget.NewMultiLineEntry()
+	valueEntry.SetPlaceHolder("Value; use %OTHER_VAR% references with the expand type")
+	valueEntry.Wrapping = fyne.TextWrapBreak
+	scopeSelect := widget.NewSelect([]string{ScopeUser, ScopeSystem}, nil)
+	scopeSelect.SetSelected(ScopeUser)
+	typeSelect := widget.NewSelect([]string{TypeString, TypeExpand}, nil)
+	typeSelect.SetSelected(TypeString)
+
+	expiresEntry := widget.NewEntry()
+	expiresEntry.SetPlaceHolder("Optional, e.g. 8h, 7d or 2026-12-31")
+
+	errorLabel := widget.NewLabel("")
+	errorLabel.Wrapping = fyne.TextWrapWord
+
+	// Path values show what normalization will change and their trailing separators while typing
+	pathHintLabel := widget.NewLabel("")
+	pathHintLabel.Wrapping = fyne.TextWrapBreak
+	updatePathHint := func(string) { pathHintLabel.SetText(editorPathHint(nameEntry.Text, valueEntry.Text, *settings)) }
+	nameEntry.OnChanged = updatePathHint
+	valueEntry.OnChanged = updatePathHint
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Value", valueEntry),
+		widget.NewFormItem("Scope", scopeSelect),
+		widget.NewFormItem("Type", typeSelect),
+		widget.NewFormItem("Expires", expiresEntry),
+	)
+
+	var d *dialog.CustomDialog
+
+	// collect validates the fields and returns the variable to write
+	collect := func() (ScopedVariable, bool) {
+		v := ScopedVariable{
+			Scope:    scopeSelect.Selected,
+			Variable: Variable{Name: nameEntry.Text, Value: valueEntry.Text, Operation: "set", Type: typeSelect.Selected, Expires: strings.TrimSpace(expiresEntry.Text)},
+		}
+		if settings.NormalizePaths {
+			v.Variable, _ = normalizeVariable(v.Variable, *settings)
+		}
+		if err := validateVariable(v.Variable); err != nil {
+			errorLabel.SetText(fmt.Sprintf("⚠️  %v", err))
+			return v, false
+		}
+		if v.Expires != "" {
+			if _, err := parseExpiry(v.Expires, time.Now()); err != nil {
+				errorLabel.SetText(fmt.Sprintf("⚠️  %v", err))
+				return v, false
+			}
+		}
+		errorLabel.SetText("")
+		return v, true
+	}
+
+	applyNow := func() {
+		v, ok := collect()
+		if !ok {
+			return
+		}
+		write := func() {
+			d.Hide()
+			go func() {
+				changes := []ScopedVariable{v}
+				if !confirmDangerousChanges(changes, *settings, parent) {
+					return
+				}
+				if err := applyDirectChanges("New Variable dialog", changes, isAdmin, *settings); err != nil {
+					dialog.ShowError(fmt.Errorf("error applying %s: %v", v.Name, err), parent)
+				} else {
+					dialog.ShowInformation("Variable Applied", fmt.Sprintf("%s was set in the %s environment.", v.Name, v.Scope), parent)
+				}
+				if onDone != nil {
+					onDone()
+				}
+			}()
+		}
+
+		// Ask before overwriting a variable that already exists
+		current, err := loadCurrentValueIndex()
+		if existing, exists := current.lookup(v.Scope, v.Name); err == nil && exists {
+			dialog.ShowConfirm("Variable Exists", fmt.Sprintf("%s already exists in the %s environment with the value:\n%s\n\nOverwrite it?", existing.Name, v.Scope, existing.displayValue(settings.SensitivePatterns)), func(ok bool) {
+				if ok {
+					write()
+				}
+			}, parent)
+			return
+		}
+		write()
+	}
+
+	queueForLater := func() {
+		v, ok := collect()
+		if !ok {
+			return
+		}
+		if err := queueVariable(v.Scope, v.Variable); err != nil {
+			errorLabel.SetText(fmt.Sprintf("⚠️  %v", err))
+			return
+		}
+		d.Hide()
+		dialog.ShowInformation("Variable Queued", fmt.Sprintf("%s was added to the queued changes.\n\nUse 'Use Queued Changes' on the Config / Apply tab to preview and apply them.", v.Name), parent)
+		if onDone != nil {
+			onDone()
+		}
+	}
+
+	// Applying a system variable now asks for administrator approval
+	applyNowButton := widget.NewButton("Apply Now", applyNow)
+	scopeSelect.OnChanged = func(scope string) { markElevates(applyNowButton, isAdmin, scope == ScopeSystem) }
+
+	content := container.NewVBox(form, pathHintLabel, errorLabel)
+	d = dialog.NewCustomWithoutButtons("New Variable", content, parent)
+	d.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("Cancel", func() { d.Hide() }),
+		widget.NewButton("Queue for Later", queueForLater),
+		applyNowButton,
+	})
+	d.Resize(fyne.NewSize(520, 420))
+	d.Show()
+}
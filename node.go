@@ -0,0 +1,174 @@
+// node.go
+// Node.js helper - lists side-by-side Node.js versions and switches the user PATH entry that selects one
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// nodeVersion is a directory containing node.exe
+type nodeVersion struct {
+	Version string // e.g. "v20.11.1", or the directory name when it is not a version
+	Dir     string
+	Source  string // Where it was found, e.g. "nvm"
+}
+
+// label returns the text shown in the version dropdown
+func (n nodeVersion) label() string {
+	return fmt.Sprintf("%s (%s)", n.Version, n.Source)
+}
+
+// versionNumbers parses "v20.11.1" into its numbers for sorting, non-numeric parts count as zero
+func versionNumbers(version string) []int {
+	parts := strings.Split(strings.TrimPrefix(strings.ToLower(version), "v"), ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		numbers[i], _ = strconv.Atoi(part)
+	}
+	return numbers
+}
+
+// newerVersion reports whether version a sorts before b, newest first
+func newerVersion(a, b string) bool {
+	x, y := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] > y[i]
+		}
+	}
+	return len(x) > len(y)
+}
+
+// nodeVersionRoots returns the directories holding one subdirectory per Node.js version, with their source name
+// nvm-windows uses NVM_HOME (default %APPDATA%\nvm) with v<version> directories, fnm node-versions\<version>\installation
+func nodeVersionRoots(ctx toolchainContext) map[string]string {
+	roots := map[string]string{}
+	for _, scope := range []string{ScopeUser, ScopeSystem} {
+		if v, ok := ctx.lookup(scope, "NVM_HOME"); ok {
+			roots[expandedPathEntry(v.Value)] = "nvm"
+		}
+	}
+	appData := os.Getenv("APPDATA")
+	roots[filepath.Join(appData, "nvm")] = "nvm"
+	roots[filepath.Join(appData, "fnm", "node-versions")] = "fnm"
+	return roots
+}
+
+// detectNodeVersions finds the installed Node.js versions, newest first
+func detectNodeVersions(ctx toolchainContext) []nodeVersion {
+	var versions []nodeVersion
+	seen := map[string]bool{}
+	add := func(n nodeVersion) {
+		key := normalizePathEntry(n.Dir)
+		if seen[key] {
+			return
+		}
+		if _, err := os.Stat(filepath.Join(n.Dir, "node.exe")); err != nil {
+			return
+		}
+		seen[key] = true
+		versions = append(versions, n)
+	}
+
+	for root, source := range nodeVersionRoots(ctx) {
+		dirs, _ := filepath.Glob(filepath.Join(root, "*"))
+		for _, dir := range dirs {
+			if source == "fnm" {
+				add(nodeVersion{Version: filepath.Base(dir), Dir: filepath.Join(dir, "installation"), Source: source})
+				continue
+			}
+			add(nodeVersion{Version: filepath.Base(dir), Dir: dir, Source: source})
+		}
+	}
+
+	// A regular installer puts a single version here; under nvm-windows it is the NVM_SYMLINK link instead
+	add(nodeVersion{Version: "installed", Dir: filepath.Join(os.Getenv("ProgramFiles"), "nodejs"), Source: "Program Files"})
+
+	sort.SliceStable(versions, func(i, j int) bool { return newerVersion(versions[i].Version, versions[j].Version) })
+	return versions
+}
+
+// newNodeHelper builds the Node.js card
+func newNodeHelper(ctx toolchainContext) fyne.CanvasObject {
+	versions := detectNodeVersions(ctx)
+	box := container.NewVBox()
+	if len(versions) == 0 {
+		box.Add(widget.NewLabel("No Node.js versions found (NVM_HOME or %APPDATA%\\nvm, fnm in %APPDATA%\\fnm, %ProgramFiles%\\nodejs)."))
+		return box
+	}
+
+	// The managed entry is the first user Path entry pointing at one of the versions
+	isVersionEntry := func(entry string) bool {
+		for _, n := range versions {
+			if isBelowDirectory(entry, n.Dir) {
+				return true
+			}
+		}
+		return false
+	}
+	user, _ := ctx.lookup(ScopeUser, "Path")
+	active := -1
+	for _, entry := range pathEntries(user.Value) {
+		for i, n := range versions {
+			if active < 0 && isBelowDirectory(entry, n.Dir) {
+				active = i
+			}
+		}
+	}
+
+	// nvm-windows switches versions through a symlink that sits in the Path as well
+	for _, scope := range []string{ScopeUser, ScopeSystem} {
+		if symlink, ok := ctx.lookup(scope, "NVM_SYMLINK"); ok {
+			label := widget.NewLabel(fmt.Sprintf("ℹ️  nvm-windows is installed (NVM_SYMLINK %s). The version selected here comes first on the user Path and wins over \"nvm use\" until you remove it.", symlink.Value))
+			label.Wrapping = fyne.TextWrapWord
+			box.Add(label)
+			break
+		}
+	}
+
+	labels := make([]string, len(versions))
+	for i, n := range versions {
+		labels[i] = n.label()
+	}
+	activeLabel := widget.NewLabel("No version is selected on the user Path.")
+	selector := widget.NewSelect(labels, nil)
+	if active >= 0 {
+		activeLabel.SetText(fmt.Sprintf("Active on the user Path: %s (%s)", versions[active].label(), versions[active].Dir))
+		selector.SetSelected(labels[active])
+	}
+	// Selecting a version rewrites the managed entry at once
+	selector.OnChanged = func(string) {
+		i := selector.SelectedIndex()
+		if i < 0 || i == active {
+			return
+		}
+		if change, ok := ctx.pathChange(ScopeUser, isVersionEntry, versions[i].Dir); ok {
+			ctx.apply("Node.js helper", []ScopedVariable{change})
+		}
+	}
+	removeButton := widget.NewButton("Remove from User Path", func() {
+		if change, ok := ctx.pathChange(ScopeUser, isVersionEntry); ok {
+			ctx.apply("Node.js helper", []ScopedVariable{change})
+		}
+	})
+	if active < 0 {
+		removeButton.Disable()
+	}
+	if readOnlyMode {
+		selector.Disable()
+	}
+	hideInReadOnly(removeButton)
+
+	box.Add(activeLabel)
+	box.Add(container.NewBorder(nil, nil, widget.NewLabel("Active version:"), removeButton, selector))
+	return box
+}
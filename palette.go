@@ -0,0 +1,142 @@
+// palette.go
+// Command palette - a Ctrl+Shift+P searchable list of every action for keyboard-centric use
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// paletteCommand is one action offered in the command palette
+type paletteCommand struct {
+	Title string             // Text shown and searched in the palette
+	Run   func(query string) // Called with the text typed into the palette
+}
+
+// paletteEntry is the palette's search field, handling Escape and the arrow keys
+type paletteEntry struct {
+	widget.Entry
+	onEscape func()
+	onMove   func(delta int)
+}
+
+// newPaletteEntry creates the search field
+func newPaletteEntry() *paletteEntry {
+	e := &paletteEntry{}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+// TypedKey closes the palette on Escape and moves the selection with Up and Down
+func (e *paletteEntry) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyEscape:
+		e.onEscape()
+	case fyne.KeyUp:
+		e.onMove(-1)
+	case fyne.KeyDown:
+		e.onMove(1)
+	default:
+		e.Entry.TypedKey(key)
+	}
+}
+
+// matchCommands returns the commands whose title contains every word of query
+func matchCommands(commands []paletteCommand, query string) []paletteCommand {
+	words := strings.Fields(strings.ToLower(query))
+	var matches []paletteCommand
+	for _, c := range commands {
+		title := strings.ToLower(c.Title)
+		ok := true
+		for _, w := range words {
+			ok = ok && strings.Contains(title, w)
+		}
+		if ok {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// showCommandPalette opens the palette over parent; commands is called each time so the list reflects the current state
+// Commands whose title starts with "Search" always match, so e.g. "Search Variables" can use the typed text as query
+func showCommandPalette(parent fyne.Window, commands func() []paletteCommand) {
+	all := commands()
+	shown := all
+	selected := 0
+
+	entry := newPaletteEntry()
+	entry.SetPlaceHolder("Type a command...")
+
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			label := item.(*widget.Label)
+			// The keyboard selection is shown in bold, Enter runs it
+			label.TextStyle = fyne.TextStyle{Bold: id == selected}
+			label.SetText(shown[id].Title)
+		},
+	)
+
+	var popUp *widget.PopUp
+	run := func(c paletteCommand) {
+		query := entry.Text
+		// Text that merely located the command is not an argument
+		if len(matchCommands([]paletteCommand{c}, query)) > 0 {
+			query = ""
+		}
+		popUp.Hide()
+		c.Run(query)
+	}
+
+	// Clicking a command runs it
+	list.OnSelected = func(id widget.ListItemID) {
+		list.Unselect(id)
+		run(shown[id])
+	}
+
+	filter := func(query string) {
+		shown = matchCommands(all, query)
+		// Search commands take the typed text as argument, keep them available for any input
+		for _, c := range all {
+			if strings.HasPrefix(c.Title, "Search") && len(matchCommands([]paletteCommand{c}, query)) == 0 {
+				shown = append(shown, c)
+			}
+		}
+		selected = 0
+		list.Refresh()
+	}
+	entry.OnChanged = filter
+	entry.OnSubmitted = func(string) {
+		if selected >= 0 && selected < len(shown) {
+			run(shown[selected])
+		}
+	}
+	entry.onEscape = func() { popUp.Hide() }
+	entry.onMove = func(delta int) {
+		if len(shown) == 0 {
+			return
+		}
+		selected = (selected + delta + len(shown)) % len(shown)
+		list.ScrollTo(selected)
+		list.Refresh()
+	}
+
+	content := container.NewBorder(entry, nil, nil, nil, list)
+	popUp = widget.NewPopUp(content, parent.Canvas())
+	size := parent.Canvas().Size()
+	paletteSize := fyne.NewSize(fyne.Min(560, size.Width-40), fyne.Min(360, size.Height-80))
+	popUp.Resize(paletteSize)
+	popUp.Move(fyne.NewPos((size.Width-paletteSize.Width)/2, 40))
+	filter("")
+	popUp.Show()
+	parent.Canvas().Focus(entry)
+}
+
+// commandPaletteShortcut is Ctrl+Shift+P
+var commandPaletteShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyP, Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift}
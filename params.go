@@ -0,0 +1,151 @@
+// params.go
+// Config parameters - a params section declares {{param.name}} placeholders filled in through a generated form at apply time
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// paramPlaceholderPrefix starts a placeholder naming a declared parameter, e.g. {{param.install_dir}}
+const paramPlaceholderPrefix = "param."
+
+// validParamName restricts parameter names to identifiers so placeholders stay unambiguous
+var validParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ConfigParam declares a value the user supplies when the config is applied
+type ConfigParam struct {
+	Name        string  `yaml:"name"`                  // Referenced as {{param.name}}
+	Label       string  `yaml:"label,omitempty"`       // Form label, the name when empty
+	Description string  `yaml:"description,omitempty"` // Hint shown below the input
+	Default     *string `yaml:"default,omitempty"`     // Prefilled value, parameters without one must be filled in
+	Secret      bool    `yaml:"secret,omitempty"`      // Masked input, and variables using it are sensitive
+}
+
+// label returns the text shown in the parameters form
+func (p ConfigParam) label() string {
+	if strings.TrimSpace(p.Label) != "" {
+		return p.Label
+	}
+	return p.Name
+}
+
+// validateParams rejects unnamed, misnamed and duplicate parameters
+func validateParams(params []ConfigParam) error {
+	seen := map[string]bool{}
+	for _, p := range params {
+		if !validParamName.MatchString(p.Name) {
+			return fmt.Errorf("invalid parameter name %q: use letters, digits and underscores", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("parameter %q is declared more than once", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// overrideParams replaces inherited parameters with child parameters of the same name and appends the rest
+func overrideParams(base, child []ConfigParam) []ConfigParam {
+	merged := append([]ConfigParam(nil), base...)
+	for _, p := range child {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == p.Name {
+				merged[i], replaced = p, true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// paramDefaults returns the default of every parameter, for applies without a form
+func paramDefaults(params []ConfigParam) (map[string]string, error) {
+	values := make(map[string]string, len(params))
+	for _, p := range params {
+		if p.Default == nil {
+			return nil, fmt.Errorf("parameter %q has no default and requires interactive input", p.Name)
+		}
+		values[p.Name] = *p.Default
+	}
+	return values, nil
+}
+
+// resolveWithParamDefaults resolves the placeholders of config with the parameter defaults, for uses without a form
+func resolveWithParamDefaults(config Config) (Config, error) {
+	values, err := paramDefaults(config.Params)
+	if err != nil {
+		return Config{}, err
+	}
+	return resolveConfigPlaceholders(config, newPlaceholderResolver(nil).withParams(config.Params, values))
+}
+
+// describeParams lists the parameters for the preview
+func describeParams(params []ConfigParam) []string {
+	if len(params) == 0 {
+		return nil
+	}
+	lines := []string{"Parameters (asked for when applying):"}
+	for _, p := range params {
+		line := fmt.Sprintf("  %s", p.label())
+		switch {
+		case p.Default == nil:
+			line += " - required"
+		case p.Secret:
+			line += " - default " + maskedValue
+		default:
+			line += fmt.Sprintf(" - default %q", *p.Default)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// showParamsForm asks for all parameters in one generated form and blocks until it is closed
+// ok is false when the user cancelled; it must not run on the UI thread
+func showParamsForm(params []ConfigParam, parent fyne.Window) (values map[string]string, ok bool) {
+	entries := make([]*widget.Entry, len(params))
+	items := make([]*widget.FormItem, len(params))
+	for i, p := range params {
+		entry := widget.NewEntry()
+		if p.Secret {
+			entry = widget.NewPasswordEntry()
+		}
+		if p.Default != nil {
+			entry.SetText(*p.Default)
+		} else {
+			entry.Validator = func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("a value is required")
+				}
+				return nil
+			}
+		}
+		entries[i] = entry
+		items[i] = widget.NewFormItem(p.label(), entry)
+		items[i].HintText = p.Description
+	}
+
+	answer := make(chan bool)
+	dialog.ShowForm("Config Parameters", "Apply", "Cancel", items, func(confirmed bool) {
+		answer <- confirmed
+	}, parent)
+
+	if !<-answer {
+		return nil, false
+	}
+	values = make(map[string]string, len(params))
+	for i, p := range params {
+		values[p.Name] = entries[i].Text
+	}
+	return values, true
+}
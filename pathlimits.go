@@ -0,0 +1,102 @@
+// pathlimits.go
+// Path limits - flags path values beyond MAX_PATH with \\?\ or short-path alternatives, and UNC entries in PATH
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Path length limits of programs that are not long path aware
+const (
+	maxPathLength      = 260 // MAX_PATH, including the terminating NUL
+	maxDirectoryLength = 248 // Longest directory CreateDirectory accepts, leaving room for an 8.3 file name
+)
+
+// longPathsEnabled reports whether the LongPathsEnabled policy lifts MAX_PATH for long path aware programs
+func longPathsEnabled() bool {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\FileSystem`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer key.Close()
+	value, _, err := key.GetIntegerValue("LongPathsEnabled")
+	return err == nil && value == 1
+}
+
+// isUNCPath reports whether a path points to a network share, \\server\share or \\?\UNC\server\share
+// Local \\?\C:\ and device \\.\ paths are not UNC paths
+func isUNCPath(path string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(path))
+	switch {
+	case strings.HasPrefix(upper, `\\?\UNC\`):
+		return true
+	case strings.HasPrefix(upper, `\\?\`), strings.HasPrefix(upper, `\\.\`):
+		return false
+	}
+	return strings.HasPrefix(upper, `\\`)
+}
+
+// shortPathName returns the 8.3 short form of an existing path, ok is false when the volume has no short names
+func shortPathName(path string) (string, bool) {
+	long, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", false
+	}
+	buffer := make([]uint16, maxPathLength)
+	n, err := windows.GetShortPathName(long, &buffer[0], uint32(len(buffer)))
+	if err != nil || n == 0 || int(n) > len(buffer) {
+		return "", false
+	}
+	short := windows.UTF16ToString(buffer[:n])
+	return short, len(short) < len(path)
+}
+
+// pathLimitWarnings describes the paths of a value that break programs limited to MAX_PATH,
+// and UNC entries of PATH-like variables, which every program start may wait on while the server is unreachable
+func pathLimitWarnings(name, value string, rule ListVariableRule) []string {
+	var warnings []string
+	entries := []string{value}
+	if rule.Separator != "" {
+		entries = rule.splitListValue(value)
+	}
+	searched := concatenatedVariables[strings.ToUpper(name)]
+	for _, entry := range entries {
+		expanded := expandedPathEntry(entry)
+		if expanded == "" {
+			continue
+		}
+
+		if searched && isUNCPath(expanded) {
+			warnings = append(warnings, fmt.Sprintf("%s: %q is a network share. Commands searched through %s wait on it when the server is slow or offline, which also slows down logons; copy the tools locally or map them when needed", name, entry, name))
+		}
+
+		if len(expanded) < maxDirectoryLength || strings.HasPrefix(expanded, `\\?\`) {
+			continue
+		}
+		warning := fmt.Sprintf("%s: %q is %d characters long, programs without long path support fail above %d", name, entry, len(expanded), maxDirectoryLength)
+		var suggestions []string
+		if short, ok := shortPathName(expanded); ok {
+			suggestions = append(suggestions, "use the short path "+short)
+		}
+		// Program searches through PATH do not accept \\?\ entries, single paths usually do
+		if !searched {
+			prefixed := `\\?\` + expanded
+			if isUNCPath(expanded) {
+				prefixed = `\\?\UNC\` + strings.TrimPrefix(expanded, `\\`)
+			}
+			suggestions = append(suggestions, "prefix it as "+prefixed+" for programs that accept it")
+		}
+		if !longPathsEnabled() {
+			suggestions = append(suggestions, "enable LongPathsEnabled for long path aware programs")
+		}
+		if len(suggestions) > 0 {
+			warning += "; " + strings.Join(suggestions, ", or ")
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
@@ -0,0 +1,138 @@
+// pathnormalize.go
+// Path value normalization - cleans slashes, quotes and doubled backslashes in path-type values and warns about trailing separators
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pathVariablePatterns are name globs of variables holding a single path, list variables come from the list rules
+var pathVariablePatterns = []string{"*_HOME", "*_ROOT", "*_DIR", "TEMP", "TMP", "GOPATH", "GOBIN", "GOROOT"}
+
+// doubledBackslashes matches runs of backslashes inside a path
+var doubledBackslashes = regexp.MustCompile(`\\{2,}`)
+
+// pathValueRule returns how a variable's value is split into paths; ok is false when it is not a path-type value
+func pathValueRule(name string, settings Settings) (ListVariableRule, bool) {
+	if rule, ok := settings.listRuleFor(name); ok {
+		return rule, rule.Validate == listValidateDirectory || rule.Validate == listValidateClasspath
+	}
+	if nameMatchesAny(name, pathVariablePatterns) {
+		return ListVariableRule{Name: name, Validate: listValidateDirectory}, true
+	}
+	return ListVariableRule{}, false
+}
+
+// normalizePath cleans a single path: forward slashes become backslashes, stray quotes are removed
+// and doubled backslashes collapse, keeping the \\ of UNC and \\?\ prefixes; URLs are left alone
+func normalizePath(path string) string {
+	trimmed := strings.TrimSpace(path)
+	if strings.Contains(trimmed, "://") {
+		return path
+	}
+	trimmed = strings.ReplaceAll(trimmed, `"`, "")
+	trimmed = strings.ReplaceAll(trimmed, "/", `\`)
+	prefix := ""
+	if strings.HasPrefix(trimmed, `\\`) {
+		prefix, trimmed = `\\`, strings.TrimLeft(trimmed, `\`)
+	}
+	return prefix + doubledBackslashes.ReplaceAllString(trimmed, `\`)
+}
+
+// normalizePathValue normalizes every path of a value split by the rule's separator
+func normalizePathValue(value string, rule ListVariableRule) string {
+	if rule.Separator == "" {
+		return normalizePath(value)
+	}
+	entries := rule.splitListValue(value)
+	for i, entry := range entries {
+		if strings.TrimSpace(entry) != "" {
+			entries[i] = normalizePath(entry)
+		}
+	}
+	return strings.Join(entries, rule.Separator)
+}
+
+// pathValueWarnings describes trailing separators of a path-type value, which normalization reports but keeps
+func pathValueWarnings(name, value string, rule ListVariableRule) []string {
+	var warnings []string
+	entries := []string{value}
+	if rule.Separator != "" {
+		entries = rule.splitListValue(value)
+		if strings.HasSuffix(value, rule.Separator) {
+			warnings = append(warnings, fmt.Sprintf("%s ends with %q, which adds an empty entry", name, rule.Separator))
+		}
+	}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		// A drive root such as C:\ needs its backslash
+		if len(entry) > 1 && strings.HasSuffix(entry, `\`) && !strings.HasSuffix(entry, `:\`) {
+			warnings = append(warnings, fmt.Sprintf("%s: %q ends with a backslash, tools joining a file name to it may produce a doubled backslash", name, entry))
+		}
+	}
+	return warnings
+}
+
+// normalizeVariable returns the variable with its path-type value normalized and whether that changed it
+func normalizeVariable(v Variable, settings Settings) (Variable, bool) {
+	rule, ok := pathValueRule(v.Name, settings)
+	// Placeholders are normalized once they are resolved, their prompt texts may contain slashes
+	if !ok || v.Operation != "set" || v.ValueScript != "" || hasPlaceholders(v.Value) {
+		return v, false
+	}
+	normalized := normalizePathValue(v.Value, rule)
+	changed := normalized != v.Value
+	v.Value = normalized
+	return v, changed
+}
+
+// normalizeConfigPaths normalizes the path-type values of both sections when normalization is enabled
+func normalizeConfigPaths(config Config, settings Settings) Config {
+	if !settings.NormalizePaths {
+		return config
+	}
+	normalize := func(variables []Variable) []Variable {
+		result := make([]Variable, len(variables))
+		for i, v := range variables {
+			var changed bool
+			result[i], changed = normalizeVariable(v, settings)
+			if changed {
+				fmt.Printf("Normalized path value of %s\n", v.Name)
+			}
+		}
+		return result
+	}
+	config.UserVariables = normalize(config.UserVariables)
+	config.SystemVariables = normalize(config.SystemVariables)
+	return config
+}
+
+// configPathWarnings lists the trailing separator and path limit warnings of a config's path-type values
+func configPathWarnings(config Config, settings Settings) []string {
+	var warnings []string
+	for _, v := range append(append([]Variable{}, config.UserVariables...), config.SystemVariables...) {
+		if rule, ok := pathValueRule(v.Name, settings); ok && v.Operation == "set" {
+			warnings = append(warnings, pathValueWarnings(v.Name, v.Value, rule)...)
+			warnings = append(warnings, pathLimitWarnings(v.Name, v.Value, rule)...)
+		}
+	}
+	return append(warnings, pathOrderWarnings(config)...)
+}
+
+// editorPathHint describes what saving a value typed in an editor would normalize or warn about, empty when nothing
+func editorPathHint(name, value string, settings Settings) string {
+	rule, ok := pathValueRule(name, settings)
+	if !ok || strings.TrimSpace(value) == "" {
+		return ""
+	}
+	var lines []string
+	if normalized := normalizePathValue(value, rule); settings.NormalizePaths && normalized != value {
+		lines = append(lines, "Saved normalized as: "+normalized)
+	}
+	for _, warning := range append(pathValueWarnings(name, value, rule), pathLimitWarnings(name, value, rule)...) {
+		lines = append(lines, "⚠️  "+warning)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,284 @@
+// pathorder.go
+// PATH ordering policy - path_order rules require some entries of a list variable to come before or after others, applies reorder to satisfy them
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PathOrderRule requires the entries matching Entry to come before every entry matching Before, or after every entry matching After
+type PathOrderRule struct {
+	Entry    string `yaml:"entry"`              // Glob (* and ?) or regex: expression matching the entries to place
+	Before   string `yaml:"before,omitempty"`   // The placed entries must precede the entries matching this
+	After    string `yaml:"after,omitempty"`    // The placed entries must follow the entries matching this
+	Variable string `yaml:"variable,omitempty"` // List variable the rule orders, Path when empty
+	Scope    string `yaml:"scope,omitempty"`    // ScopeUser or ScopeSystem, both when empty
+}
+
+// variable returns the name of the list variable the rule orders
+func (r PathOrderRule) variable() string {
+	if r.Variable == "" {
+		return "Path"
+	}
+	return r.Variable
+}
+
+// appliesTo reports whether the rule orders the variable in the scope
+func (r PathOrderRule) appliesTo(scope, name string) bool {
+	return strings.EqualFold(r.variable(), name) && (r.Scope == "" || strings.EqualFold(r.Scope, scope))
+}
+
+// describe renders the rule for messages
+func (r PathOrderRule) describe() string {
+	if r.Before != "" {
+		return fmt.Sprintf("%q before %q in %s", r.Entry, r.Before, r.variable())
+	}
+	return fmt.Sprintf("%q after %q in %s", r.Entry, r.After, r.variable())
+}
+
+// compileEntryPattern builds a case-insensitive matcher for list entries
+// A glob must match the whole entry, * also matches backslashes; regex: expressions must match the whole entry too
+func compileEntryPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("pattern must not be empty")
+	}
+	expr := strings.TrimPrefix(pattern, regexPatternPrefix)
+	if !strings.HasPrefix(pattern, regexPatternPrefix) {
+		expr = regexp.QuoteMeta(strings.TrimRight(pattern, `\`))
+		expr = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(expr)
+	}
+	re, err := regexp.Compile(`(?i)^(?:` + expr + `)\\?$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entry pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// validatePathOrder rejects rules without an entry, with both or neither of before and after, or with an unknown scope
+func validatePathOrder(rules []PathOrderRule) error {
+	for i, r := range rules {
+		switch {
+		case (r.Before == "") == (r.After == ""):
+			return fmt.Errorf("path_order rule %d: set exactly one of before and after", i+1)
+		case r.Scope != "" && !strings.EqualFold(r.Scope, ScopeUser) && !strings.EqualFold(r.Scope, ScopeSystem):
+			return fmt.Errorf("path_order rule %d: invalid scope %q, use %q or %q", i+1, r.Scope, ScopeUser, ScopeSystem)
+		}
+		for _, pattern := range []string{r.Entry, r.Before + r.After} {
+			if _, err := compileEntryPattern(pattern); err != nil {
+				return fmt.Errorf("path_order rule %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pathOrderMatcher matches entries of one rule, against the entry as written and with %VAR% references expanded
+type pathOrderMatcher struct {
+	rule          PathOrderRule
+	entry, anchor *regexp.Regexp
+	env           map[string]string
+}
+
+func (m pathOrderMatcher) matches(re *regexp.Regexp, entry string) bool {
+	entry = strings.TrimSpace(entry)
+	return re.MatchString(entry) || re.MatchString(expandReferences(entry, m.env))
+}
+
+// place moves the entries the rule places next to its anchor, it returns the entries and whether anything moved
+// Entries matching both patterns are never used as the anchor, so "C:\Tools\*" before "C:\*" works
+func (m pathOrderMatcher) place(entries []string) ([]string, bool) {
+	isEntry := func(i int) bool { return m.matches(m.entry, entries[i]) }
+	isAnchor := func(i int) bool { return !isEntry(i) && m.matches(m.anchor, entries[i]) }
+
+	anchor := -1
+	for i := range entries {
+		if isAnchor(i) && (anchor < 0 || m.rule.After != "") {
+			anchor = i
+		}
+		if anchor >= 0 && m.rule.Before != "" {
+			break
+		}
+	}
+	if anchor < 0 {
+		return entries, false
+	}
+
+	var moving, rest []string
+	insertAt := -1
+	for i, entry := range entries {
+		misplaced := isEntry(i) && ((m.rule.Before != "" && i > anchor) || (m.rule.After != "" && i < anchor))
+		if misplaced {
+			moving = append(moving, entry)
+			continue
+		}
+		if i == anchor {
+			insertAt = len(rest)
+			if m.rule.After != "" {
+				insertAt++
+			}
+		}
+		rest = append(rest, entry)
+	}
+	if len(moving) == 0 {
+		return entries, false
+	}
+	result := append(append(append([]string{}, rest[:insertAt]...), moving...), rest[insertAt:]...)
+	return result, true
+}
+
+// orderListValue reorders the entries of a list value until every rule holds
+// Rules are applied in turn; when they still move entries after as many passes as there are entries times rules, they contradict each other
+func orderListValue(name, value, separator string, matchers []pathOrderMatcher) (string, error) {
+	entries := strings.Split(value, separator)
+	for pass := 0; pass <= len(entries)*len(matchers); pass++ {
+		moved := false
+		for _, m := range matchers {
+			var changed bool
+			if entries, changed = m.place(entries); changed {
+				moved = true
+			}
+		}
+		if !moved {
+			return strings.Join(entries, separator), nil
+		}
+	}
+	return "", fmt.Errorf("the path_order rules for %s contradict each other", name)
+}
+
+// enforcePathOrder reorders the list values of a config to satisfy its path_order rules
+// A list variable the config does not set is reordered from its current value and added as a set entry when it violates a rule
+func enforcePathOrder(config Config, settings Settings) (Config, error) {
+	if len(config.PathOrder) == 0 {
+		return config, nil
+	}
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		return config, err
+	}
+	env, err := freshEnvironmentMap()
+	if err != nil {
+		return config, err
+	}
+
+	enforce := func(scope string, variables []Variable) ([]Variable, error) {
+		seen := map[string]bool{}
+		for _, r := range config.PathOrder {
+			name := r.variable()
+			if seen[strings.ToUpper(name)] {
+				continue
+			}
+			seen[strings.ToUpper(name)] = true
+
+			var matchers []pathOrderMatcher
+			for _, rule := range config.PathOrder {
+				if rule.appliesTo(scope, name) {
+					entry, _ := compileEntryPattern(rule.Entry)
+					anchor, _ := compileEntryPattern(rule.Before + rule.After)
+					matchers = append(matchers, pathOrderMatcher{rule: rule, entry: entry, anchor: anchor, env: env})
+				}
+			}
+			if len(matchers) == 0 {
+				continue
+			}
+			separator := ";"
+			if rule, ok := settings.listRuleFor(name); ok && rule.Separator != "" {
+				separator = rule.Separator
+			}
+
+			target := -1
+			for i, v := range variables {
+				if strings.EqualFold(v.Name, name) {
+					target = i
+				}
+			}
+			switch {
+			case target >= 0 && variables[target].Operation != "set":
+				continue // The config deletes it
+			case target >= 0 && (variables[target].ValueScript != "" || hasPlaceholders(variables[target].Value)):
+				fmt.Printf("Warning: %s (%s) is ordered after its placeholders are resolved\n", name, scope)
+				continue
+			case target >= 0:
+				ordered, err := orderListValue(name, variables[target].Value, separator, matchers)
+				if err != nil {
+					return nil, err
+				}
+				if ordered != variables[target].Value {
+					fmt.Printf("Reordered %s (%s) to satisfy path_order\n", name, scope)
+					variables[target].Value = ordered
+				}
+			default:
+				existing, ok := current.lookup(scope, name)
+				if !ok {
+					continue
+				}
+				ordered, err := orderListValue(name, existing.Value, separator, matchers)
+				if err != nil {
+					return nil, err
+				}
+				if ordered != existing.Value {
+					fmt.Printf("Reordering the current %s (%s) to satisfy path_order\n", name, scope)
+					variables = append(variables, Variable{Name: existing.Name, Value: ordered, Operation: "set", Type: existing.Type, Origin: "reordered by path_order"})
+				}
+			}
+		}
+		return variables, nil
+	}
+
+	users, err := enforce(ScopeUser, append([]Variable{}, config.UserVariables...))
+	if err != nil {
+		return config, err
+	}
+	systems, err := enforce(ScopeSystem, append([]Variable{}, config.SystemVariables...))
+	if err != nil {
+		return config, err
+	}
+	config.UserVariables, config.SystemVariables = users, systems
+	return config, nil
+}
+
+// pathOrderWarnings names rules that reordering one scope cannot satisfy
+// Windows puts the user Path after the system Path, so a user entry can never precede a system entry
+func pathOrderWarnings(config Config) []string {
+	if len(config.PathOrder) == 0 {
+		return nil
+	}
+	current, _ := loadCurrentValueIndex()
+	env, _ := freshEnvironmentMap()
+	value := func(scope string, variables []Variable, name string) string {
+		for i := len(variables) - 1; i >= 0; i-- {
+			if strings.EqualFold(variables[i].Name, name) {
+				return variables[i].Value
+			}
+		}
+		existing, _ := current.lookup(scope, name)
+		return existing.Value
+	}
+	anyMatches := func(m pathOrderMatcher, re *regexp.Regexp, value string) bool {
+		for _, entry := range strings.Split(value, ";") {
+			if entry != "" && m.matches(re, entry) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var warnings []string
+	for _, r := range config.PathOrder {
+		if r.Scope != "" || !concatenatedVariables[strings.ToUpper(r.variable())] {
+			continue
+		}
+		entry, _ := compileEntryPattern(r.Entry)
+		anchor, _ := compileEntryPattern(r.Before + r.After)
+		m := pathOrderMatcher{rule: r, entry: entry, anchor: anchor, env: env}
+		user, system := value(ScopeUser, config.UserVariables, r.variable()), value(ScopeSystem, config.SystemVariables, r.variable())
+		if r.Before != "" && anyMatches(m, entry, user) && !anyMatches(m, entry, system) && anyMatches(m, anchor, system) {
+			warnings = append(warnings, fmt.Sprintf("path_order %s: the entry is in the user %s and the anchor in the system %s, which comes first; move the entry to the system %s", r.describe(), r.variable(), r.variable(), r.variable()))
+		}
+		if r.After != "" && anyMatches(m, entry, system) && anyMatches(m, anchor, user) && !anyMatches(m, anchor, system) {
+			warnings = append(warnings, fmt.Sprintf("path_order %s: the entry is in the system %s, which comes before the user %s holding the anchor; move the entry to the user %s", r.describe(), r.variable(), r.variable(), r.variable()))
+		}
+	}
+	return warnings
+}
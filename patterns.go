@@ -0,0 +1,122 @@
+// patterns.go
+// Pattern operations - expands delete_matching entries into deletions of the concrete variables whose names match
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// OperationDeleteMatching removes every variable of the scope whose name matches the entry's pattern
+const OperationDeleteMatching = "delete_matching"
+
+// regexPatternPrefix marks a pattern as a regular expression instead of a glob
+const regexPatternPrefix = "regex:"
+
+// isPatternOperation reports whether the variable is an operation on a name pattern rather than a single name
+func (v Variable) isPatternOperation() bool {
+	return v.Operation == OperationDeleteMatching
+}
+
+// compileNamePattern builds a case-insensitive matcher for variable names
+// Patterns are globs such as MYAPP_*, or regular expressions with the regex: prefix that must match the whole name
+func compileNamePattern(pattern string) (func(name string) bool, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("pattern must not be empty")
+	}
+
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		expr := strings.TrimPrefix(pattern, regexPatternPrefix)
+		re, err := regexp.Compile("(?i)^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	glob := strings.ToUpper(pattern)
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid name pattern %q: %w", pattern, err)
+	}
+	return func(name string) bool {
+		matched, _ := path.Match(glob, strings.ToUpper(name))
+		return matched
+	}, nil
+}
+
+// expandPatternVariables replaces pattern operations with delete operations for each matching variable of current
+// Other entries are passed through unchanged; current holds the variables presently stored in the scope
+func expandPatternVariables(variables []Variable, current []Variable) ([]Variable, error) {
+	var expanded []Variable
+	for _, v := range variables {
+		if !v.isPatternOperation() {
+			expanded = append(expanded, v)
+			continue
+		}
+
+		matches, err := compileNamePattern(v.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range current {
+			if matches(c.Name) {
+				expanded = append(expanded, Variable{Name: c.Name, Operation: "delete", Sensitive: v.Sensitive || c.Sensitive, Force: v.Force, MatchedBy: v.Pattern, Origin: v.Origin})
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// expandConfigPatterns reads the current environment and expands all pattern operations in config
+func expandConfigPatterns(config Config) (Config, error) {
+	hasPatterns := false
+	for _, v := range append(append([]Variable{}, config.UserVariables...), config.SystemVariables...) {
+		hasPatterns = hasPatterns || v.isPatternOperation()
+	}
+	if !hasPatterns {
+		return config, nil
+	}
+
+	all, err := readAllVariables()
+	if err != nil {
+		return config, err
+	}
+	userVars, systemVars := splitScopes(all)
+
+	if config.UserVariables, err = expandPatternVariables(config.UserVariables, userVars); err != nil {
+		return config, fmt.Errorf("user_variables: %w", err)
+	}
+	if config.SystemVariables, err = expandPatternVariables(config.SystemVariables, systemVars); err != nil {
+		return config, fmt.Errorf("system_variables: %w", err)
+	}
+	return config, nil
+}
+
+// splitScopes separates scoped variables into user and system variables
+func splitScopes(variables []ScopedVariable) (userVars, systemVars []Variable) {
+	for _, v := range variables {
+		if v.Scope == ScopeSystem {
+			systemVars = append(systemVars, v.Variable)
+		} else {
+			userVars = append(userVars, v.Variable)
+		}
+	}
+	return userVars, systemVars
+}
+
+// scopeVariables returns the current variables of one scope sorted by name
+func (idx currentValueIndex) scopeVariables(scope string) []Variable {
+	var variables []Variable
+	for _, v := range idx {
+		if v.Scope == scope {
+			variables = append(variables, v.Variable)
+		}
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return strings.ToUpper(variables[i].Name) < strings.ToUpper(variables[j].Name)
+	})
+	return variables
+}
@@ -0,0 +1,60 @@
+// pending.go
+// Pending-change awareness - detects variables whose registry value differs from what running processes inherited
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// restartRequiredLabel marks variables whose change has not reached processes started before it
+const restartRequiredLabel = "⟳ restart required"
+
+// pendingIndex compares the environment a new process would get with the one this process inherited at startup
+// Applications started at the same time as this one (e.g. from the same Explorer session) see the inherited values
+type pendingIndex struct {
+	fresh     map[string]string // Environment built from the registry now
+	inherited map[string]string // Environment this process was started with
+}
+
+// loadPendingIndex captures the fresh and inherited environments
+func loadPendingIndex() (pendingIndex, error) {
+	fresh, err := freshEnvironmentMap()
+	if err != nil {
+		return pendingIndex{}, err
+	}
+	return pendingIndex{fresh: fresh, inherited: environmentMap(os.Environ())}, nil
+}
+
+// restartRequired reports whether the variable has a different value, or only exists, in one of the environments
+func (p pendingIndex) restartRequired(name string) bool {
+	if p.fresh == nil {
+		return false
+	}
+	key := strings.ToUpper(name)
+	freshValue, inFresh := p.fresh[key]
+	inheritedValue, inInherited := p.inherited[key]
+	return inFresh != inInherited || freshValue != inheritedValue
+}
+
+// status returns the indicator shown for a variable
+func (p pendingIndex) status(name string) string {
+	if p.restartRequired(name) {
+		return restartRequiredLabel
+	}
+	return ""
+}
+
+// count returns how many of the names need a restart to take effect
+func (p pendingIndex) count(names []string) int {
+	n := 0
+	seen := map[string]bool{}
+	for _, name := range names {
+		key := strings.ToUpper(name)
+		if !seen[key] && p.restartRequired(name) {
+			n++
+		}
+		seen[key] = true
+	}
+	return n
+}
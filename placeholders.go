@@ -0,0 +1,233 @@
+// placeholders.go
+// Value placeholders - {{...}} expressions in config values that are resolved at apply time (prompts and generators)
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// placeholderPattern matches {{expression}} with optional surrounding whitespace inside the braces
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// Placeholder prefixes for interactive input
+const (
+	promptPlaceholderPrefix       = "prompt:"        // {{prompt:Label}} asks for a value in a regular input box
+	secretPromptPlaceholderPrefix = "prompt_secret:" // {{prompt_secret:Label}} asks for a value with masked input
+)
+
+// Generator placeholders evaluated at apply time
+const (
+	uuidGenerator      = "uuid"   // {{uuid}} produces a random version 4 UUID
+	randomGenerator    = "random" // {{random:N}} produces N random alphanumeric characters
+	nowGenerator       = "now"    // {{now:layout}} formats the current time with a Go time layout
+	defaultRandomChars = 16       // Length used by {{random}} without an explicit count
+	maxRandomChars     = 4096     // Upper bound to keep generated values within registry limits
+)
+
+// randomAlphabet is the character set used by the random generator
+const randomAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// promptInputFunc asks the user for a value; ok is false when the user cancelled the dialog
+type promptInputFunc func(label string, secret bool) (value string, ok bool)
+
+// placeholderResolver substitutes placeholders in config values
+type placeholderResolver struct {
+	prompt  promptInputFunc   // UI callback for prompt placeholders
+	answers map[string]string // Prompt answers by label, so a label used several times is asked once
+	plugins []PluginManifest  // Resolver plugins, loaded on first use
+	loaded  bool              // Whether plugins has been loaded
+	params  map[string]string // Values of the config's declared parameters
+	secrets map[string]bool   // Parameters declared secret
+}
+
+// newPlaceholderResolver creates a resolver that uses prompt for interactive placeholders
+func newPlaceholderResolver(prompt promptInputFunc) *placeholderResolver {
+	return &placeholderResolver{prompt: prompt, answers: make(map[string]string)}
+}
+
+// withParams sets the parameter values for {{param.name}} placeholders
+func (r *placeholderResolver) withParams(params []ConfigParam, values map[string]string) *placeholderResolver {
+	r.params, r.secrets = values, make(map[string]bool)
+	for _, p := range params {
+		if p.Secret {
+			r.secrets[p.Name] = true
+		}
+	}
+	return r
+}
+
+// hasPlaceholders reports whether a value contains any {{...}} expression
+func hasPlaceholders(value string) bool {
+	return placeholderPattern.MatchString(value)
+}
+
+// resolveValue replaces every placeholder in value, stopping at the first one that cannot be resolved
+func (r *placeholderResolver) resolveValue(value string) (string, error) {
+	var resolveErr error
+	resolved := placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if resolveErr != nil {
+			return match
+		}
+		expression := placeholderPattern.FindStringSubmatch(match)[1]
+		result, err := r.resolveExpression(expression)
+		if err != nil {
+			resolveErr = err
+			return match
+		}
+		return result
+	})
+	return resolved, resolveErr
+}
+
+// resolveExpression evaluates the contents of a single {{...}} placeholder
+func (r *placeholderResolver) resolveExpression(expression string) (string, error) {
+	switch {
+	case strings.HasPrefix(expression, secretPromptPlaceholderPrefix):
+		return r.ask(strings.TrimSpace(strings.TrimPrefix(expression, secretPromptPlaceholderPrefix)), true)
+	case strings.HasPrefix(expression, promptPlaceholderPrefix):
+		return r.ask(strings.TrimSpace(strings.TrimPrefix(expression, promptPlaceholderPrefix)), false)
+	case strings.HasPrefix(expression, pluginPlaceholderPrefix):
+		return r.resolvePlugin(strings.TrimPrefix(expression, pluginPlaceholderPrefix))
+	case strings.HasPrefix(expression, paramPlaceholderPrefix):
+		name := strings.TrimSpace(strings.TrimPrefix(expression, paramPlaceholderPrefix))
+		value, ok := r.params[name]
+		if !ok {
+			return "", fmt.Errorf("placeholder {{%s}} names a parameter the params section does not declare", expression)
+		}
+		return value, nil
+	}
+
+	// Generators take an optional argument after the first colon
+	name, arg, hasArg := strings.Cut(expression, ":")
+	switch strings.TrimSpace(name) {
+	case uuidGenerator:
+		return generateUUID()
+	case randomGenerator:
+		length := defaultRandomChars
+		if hasArg {
+			n, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil || n <= 0 || n > maxRandomChars {
+				return "", fmt.Errorf("invalid length in {{%s}}: must be between 1 and %d", expression, maxRandomChars)
+			}
+			length = n
+		}
+		return generateRandomString(length)
+	case nowGenerator:
+		layout := time.RFC3339
+		if hasArg && arg != "" {
+			layout = arg
+		}
+		return time.Now().Format(layout), nil
+	default:
+		return "", fmt.Errorf("unknown placeholder {{%s}}", expression)
+	}
+}
+
+// resolvePlugin evaluates {{plugin:name:argument}} with the resolver plugin of that name
+func (r *placeholderResolver) resolvePlugin(expression string) (string, error) {
+	if !r.loaded {
+		plugins, err := loadPlugins()
+		if err != nil {
+			return "", err
+		}
+		r.plugins, r.loaded = plugins, true
+	}
+
+	name, argument, _ := strings.Cut(expression, ":")
+	plugin, ok := findPlugin(r.plugins, PluginKindResolver, strings.TrimSpace(name))
+	if !ok {
+		return "", fmt.Errorf("no resolver plugin named %q in the plugins directory", name)
+	}
+	return plugin.resolve(argument)
+}
+
+// generateUUID returns a random RFC 4122 version 4 UUID
+func generateUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate UUID: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// generateRandomString returns length cryptographically random alphanumeric characters
+func generateRandomString(length int) (string, error) {
+	limit := big.NewInt(int64(len(randomAlphabet)))
+	var sb strings.Builder
+	sb.Grow(length)
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random value: %w", err)
+		}
+		sb.WriteByte(randomAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// ask returns the cached answer for label or prompts the user for it
+func (r *placeholderResolver) ask(label string, secret bool) (string, error) {
+	if answer, ok := r.answers[label]; ok {
+		return answer, nil
+	}
+	if r.prompt == nil {
+		return "", fmt.Errorf("placeholder {{prompt:%s}} requires interactive input", label)
+	}
+
+	answer, ok := r.prompt(label, secret)
+	if !ok {
+		return "", fmt.Errorf("input cancelled for %q", label)
+	}
+	r.answers[label] = answer
+	return answer, nil
+}
+
+// resolveVariables returns a copy of variables with placeholders in "set" values substituted
+func (r *placeholderResolver) resolveVariables(variables []Variable) ([]Variable, error) {
+	resolved := make([]Variable, len(variables))
+	for i, v := range variables {
+		if v.Operation == "set" && hasPlaceholders(v.Value) {
+			// Values entered through masked prompts or fetched by plugins (typically secrets backends) stay masked
+			if strings.Contains(v.Value, secretPromptPlaceholderPrefix) || strings.Contains(v.Value, pluginPlaceholderPrefix) || r.usesSecretParam(v.Value) {
+				v.Sensitive = true
+			}
+			value, err := r.resolveValue(v.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve value of %s: %w", v.Name, err)
+			}
+			v.Value = value
+		}
+		resolved[i] = v
+	}
+	return resolved, nil
+}
+
+// usesSecretParam reports whether value references a parameter declared secret
+func (r *placeholderResolver) usesSecretParam(value string) bool {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(value, -1) {
+		if name, ok := strings.CutPrefix(match[1], paramPlaceholderPrefix); ok && r.secrets[strings.TrimSpace(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveConfigPlaceholders substitutes placeholders in all user and system variables of config
+func resolveConfigPlaceholders(config Config, r *placeholderResolver) (Config, error) {
+	var err error
+	if config.UserVariables, err = r.resolveVariables(config.UserVariables); err != nil {
+		return Config{}, err
+	}
+	if config.SystemVariables, err = r.resolveVariables(config.SystemVariables); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
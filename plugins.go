@@ -0,0 +1,190 @@
+// plugins.go
+// Plugins - external programs discovered from the plugins directory that resolve values or add export formats
+// Plugins are described by YAML manifests so integrations can be added without rebuilding the application
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// pluginsDirName is the directory below the application data directory holding plugin manifests
+const pluginsDirName = "plugins"
+
+// Plugin kinds
+const (
+	PluginKindResolver = "resolver" // Resolves {{plugin:name:argument}} placeholders, e.g. from a secrets backend
+	PluginKindExporter = "exporter" // Converts the environment into another file format
+)
+
+// defaultPluginTimeout bounds how long a plugin may run when its manifest sets no timeout
+const defaultPluginTimeout = 30 * time.Second
+
+// pluginPlaceholderPrefix introduces a placeholder resolved by a resolver plugin
+const pluginPlaceholderPrefix = "plugin:"
+
+// PluginManifest describes one plugin, loaded from <name>.yaml in the plugins directory
+type PluginManifest struct {
+	Name           string   `yaml:"name"`            // Name used in placeholders and menus, defaults to the manifest file name
+	Kind           string   `yaml:"kind"`            // PluginKindResolver or PluginKindExporter
+	Description    string   `yaml:"description"`     // Shown in the export dialog
+	Command        string   `yaml:"command"`         // Executable, relative paths are resolved against the plugins directory
+	Args           []string `yaml:"args"`            // Arguments passed before the request-specific argument
+	Extension      string   `yaml:"extension"`       // File extension of exporter output, e.g. .env
+	TimeoutSeconds int      `yaml:"timeout_seconds"` // Maximum run time, defaults to 30 seconds
+}
+
+// pluginExportDocument is the JSON document exporter plugins receive on stdin
+type pluginExportDocument struct {
+	Metadata        *ConfigMetadata  `json:"metadata,omitempty"`
+	UserVariables   []pluginVariable `json:"user_variables"`
+	SystemVariables []pluginVariable `json:"system_variables"`
+}
+
+// pluginVariable is a variable as passed to plugins
+type pluginVariable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+// pluginsDir returns the plugins directory, creating it so users know where to put manifests
+func pluginsDir() (string, error) {
+	base, err := appDataDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, pluginsDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plugins directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// loadPlugins reads every manifest in the plugins directory, skipping invalid ones with a warning
+func loadPlugins() ([]PluginManifest, error) {
+	dir, err := pluginsDir()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins in %s: %w", dir, err)
+	}
+
+	var plugins []PluginManifest
+	for _, path := range matches {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Warning: Skipping plugin %s: %v\n", path, err)
+			continue
+		}
+		var manifest PluginManifest
+		if err := yaml.Unmarshal(data, &manifest); err != nil {
+			fmt.Printf("Warning: Skipping plugin %s: %v\n", path, err)
+			continue
+		}
+		if manifest.Name == "" {
+			manifest.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if manifest.Command == "" || (manifest.Kind != PluginKindResolver && manifest.Kind != PluginKindExporter) {
+			fmt.Printf("Warning: Skipping plugin %s: a command and kind (resolver or exporter) are required\n", path)
+			continue
+		}
+		if !filepath.IsAbs(manifest.Command) && strings.ContainsAny(manifest.Command, `\/`) {
+			manifest.Command = filepath.Join(dir, manifest.Command)
+		} else if !filepath.IsAbs(manifest.Command) {
+			// Prefer an executable placed next to the manifest over one found on PATH
+			if local := filepath.Join(dir, manifest.Command); fileExists(local) {
+				manifest.Command = local
+			}
+		}
+		plugins = append(plugins, manifest)
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, nil
+}
+
+// fileExists reports whether path names an existing file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// findPlugin returns the plugin of the given kind and name
+func findPlugin(plugins []PluginManifest, kind, name string) (PluginManifest, bool) {
+	for _, p := range plugins {
+		if p.Kind == kind && strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return PluginManifest{}, false
+}
+
+// run executes the plugin with the extra arguments and stdin, returning its standard output
+func (p PluginManifest) run(stdin []byte, extraArgs ...string) ([]byte, error) {
+	timeout := defaultPluginTimeout
+	if p.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Command, append(append([]string{}, p.Args...), extraArgs...)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin %s timed out after %s", p.Name, timeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("plugin %s failed: %v: %s", p.Name, err, message)
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w", p.Name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// resolve asks a resolver plugin for the value of argument, which is passed as the last command line argument
+func (p PluginManifest) resolve(argument string) (string, error) {
+	output, err := p.run(nil, argument)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
+
+// export passes config to an exporter plugin as JSON and returns the rendered file
+func (p PluginManifest) export(config Config) ([]byte, error) {
+	doc := pluginExportDocument{Metadata: config.Metadata}
+	convert := func(variables []Variable) []pluginVariable {
+		converted := make([]pluginVariable, 0, len(variables))
+		for _, v := range variables {
+			converted = append(converted, pluginVariable{Name: v.Name, Value: v.Value, Type: v.typeLabel()})
+		}
+		return converted
+	}
+	doc.UserVariables = convert(config.UserVariables)
+	doc.SystemVariables = convert(config.SystemVariables)
+
+	input, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export document: %w", err)
+	}
+	return p.run(input)
+}
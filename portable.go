@@ -0,0 +1,74 @@
+// portable.go
+// Portable exports - rewrites paths inside a user profile (C:\Users\alice\...) as %USERPROFILE% references
+// so an exported config works for other users and machines
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// userProfileReference replaces the profile folder in portable values
+const userProfileReference = "%USERPROFILE%"
+
+// portableSkipVariables describe the profile itself and are exported as they are
+var portableSkipVariables = []string{"USERPROFILE", "HOMEDRIVE", "HOMEPATH", "HOMESHARE"}
+
+// sharedProfileFolders are folders of the profiles directory that belong to no user
+var sharedProfileFolders = []string{"Public", "Default", "Default User", "All Users"}
+
+// userProfilePathPattern matches a profile folder at the start of a path: this user's profile, which may be relocated,
+// or any folder of a Users directory, so exports of other users and machines are rewritten too
+// The folder must end at a backslash, a separator or the end of the value, so C:\Users\bob never matches C:\Users\bobby
+func userProfilePathPattern() *regexp.Regexp {
+	alternatives := []string{`[a-z]:\\Users\\[^\\/;",]+`}
+	if profile := strings.TrimRight(os.Getenv("USERPROFILE"), `\`); profile != "" {
+		alternatives = append([]string{regexp.QuoteMeta(profile)}, alternatives...)
+	}
+	return regexp.MustCompile(`(?i)(^|[;"=,\s])(` + strings.Join(alternatives, "|") + `)([\\/;",]|$)`)
+}
+
+// portableValue rewrites the profile folders in value as %USERPROFILE%, ok is false when there were none
+func portableValue(value string, pattern *regexp.Regexp) (string, bool) {
+	var result strings.Builder
+	last, changed := 0, false
+	// Searching resumes after the folder, the separator following it can start the next path
+	for pos := 0; pos < len(value); {
+		m := pattern.FindStringSubmatchIndex(value[pos:])
+		if m == nil {
+			break
+		}
+		start, end := pos+m[4], pos+m[5]
+		pos = end
+		if nameMatchesAny(filepath.Base(value[start:end]), sharedProfileFolders) {
+			continue
+		}
+		result.WriteString(value[last:start])
+		result.WriteString(userProfileReference)
+		last, changed = end, true
+	}
+	if !changed {
+		return value, false
+	}
+	result.WriteString(value[last:])
+	return result.String(), true
+}
+
+// portableUserPaths returns a copy of variables with profile paths rewritten as %USERPROFILE% references
+// Rewritten variables become expandable so Windows resolves the reference for whoever applies the config
+// Only pass user variables: system values are expanded before USERPROFILE is defined and are shared by services and every user
+func portableUserPaths(variables []Variable) []Variable {
+	pattern := userProfilePathPattern()
+	portable := make([]Variable, len(variables))
+	for i, v := range variables {
+		if v.Operation == "set" && v.ValueScript == "" && !nameMatchesAny(v.Name, portableSkipVariables) {
+			if value, ok := portableValue(v.Value, pattern); ok {
+				v.Value, v.Type = value, TypeExpand
+			}
+		}
+		portable[i] = v
+	}
+	return portable
+}
@@ -0,0 +1,86 @@
+// preflight.go
+// Permission preflight - checks which environment scopes this process can write before any variable is applied
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// scopeAccess is the preflight result for one environment scope
+type scopeAccess struct {
+	Scope    string // ScopeUser or ScopeSystem
+	Needed   bool   // The config changes variables in this scope
+	Writable bool   // The Environment key could be opened with the access an apply needs
+	Err      error  // Why the key could not be opened
+}
+
+// preflightReport holds the access check of both scopes
+type preflightReport struct {
+	User   scopeAccess
+	System scopeAccess
+}
+
+// checkScopeAccess opens an Environment key with the same access applyVariables requests
+func checkScopeAccess(hive registry.Key, subkeyPath string) error {
+	key, err := registry.OpenKey(hive, subkeyPath, registry.SET_VALUE|registry.QUERY_VALUE)
+	if err != nil {
+		return fmt.Errorf("cannot open %s\\%s for writing: %w", registryHiveName(hive), subkeyPath, err)
+	}
+	key.Close()
+	return nil
+}
+
+// preflightConfig checks write access to every scope the config changes
+func preflightConfig(config Config) preflightReport {
+	check := func(scope string, hive registry.Key, subkeyPath string, variables []Variable) scopeAccess {
+		access := scopeAccess{Scope: scope, Needed: len(variables) > 0}
+		if readOnlyMode {
+			access.Err = errReadOnly
+		} else {
+			access.Err = checkScopeAccess(hive, subkeyPath)
+		}
+		access.Writable = access.Err == nil
+		return access
+	}
+	return preflightReport{
+		User:   check(ScopeUser, registry.CURRENT_USER, userEnvironmentPath, config.UserVariables),
+		System: check(ScopeSystem, registry.LOCAL_MACHINE, systemEnvironmentPath, config.SystemVariables),
+	}
+}
+
+// blocked returns an error naming the needed scopes that are not writable, skipping the system scope when it can be elevated
+func (r preflightReport) blocked(canElevate bool) error {
+	var problems []string
+	if r.User.Needed && !r.User.Writable {
+		problems = append(problems, fmt.Sprintf("user scope not writable: %v", r.User.Err))
+	}
+	if r.System.Needed && !r.System.Writable && !canElevate {
+		problems = append(problems, fmt.Sprintf("system scope not writable: %v", r.System.Err))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w, nothing was applied: %s", errPermissionDenied, strings.Join(problems, "; "))
+}
+
+// describe renders the report as preview lines, one per scope the config changes
+// canElevate tells whether an unwritable system scope will be written through the elevated helper
+func (r preflightReport) describe(canElevate bool) []string {
+	var lines []string
+	for _, access := range []scopeAccess{r.User, r.System} {
+		switch {
+		case !access.Needed:
+			continue
+		case access.Writable:
+			lines = append(lines, fmt.Sprintf("✅  %s scope writable", access.Scope))
+		case access.Scope == ScopeSystem && canElevate:
+			lines = append(lines, "⚠️  system scope not writable by this process - applying asks for administrator approval (UAC)")
+		default:
+			lines = append(lines, fmt.Sprintf("⚠️  %s scope not writable - %v", access.Scope, access.Err))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,416 @@
+// preview.go
+// Preview window - shows the pending changes of a config side by side with the current registry values
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Placeholder texts shown in the side-by-side columns
+const (
+	previewNotSet  = "(not set)"
+	previewDeleted = "(deleted)"
+)
+
+// currentValueIndex maps "scope/UPPERCASE_NAME" to the variable currently stored in the registry
+type currentValueIndex map[string]ScopedVariable
+
+// loadCurrentValueIndex reads the registry so proposed values can be compared with current ones
+func loadCurrentValueIndex() (currentValueIndex, error) {
+	all, err := readAllVariables()
+	index := make(currentValueIndex, len(all))
+	for _, v := range all {
+		index[v.Scope+"/"+strings.ToUpper(v.Name)] = v
+	}
+	return index, err
+}
+
+// lookup returns the current variable with the given scope and name
+func (idx currentValueIndex) lookup(scope, name string) (ScopedVariable, bool) {
+	v, ok := idx[scope+"/"+strings.ToUpper(name)]
+	return v, ok
+}
+
+// Orderings offered by the preview window's "Sort by" selector
+const (
+	previewSortScope  = "Scope"
+	previewSortName   = "Name"
+	previewSortStatus = "Status"
+)
+
+// previewItem is one pending variable change together with its comparison status
+type previewItem struct {
+	Scope    string
+	Variable Variable
+	Status   string // One of the previewStatus* values
+}
+
+// Comparison status of a pending change, in the order used when sorting by status
+const (
+	previewStatusNew       = "new"
+	previewStatusChanged   = "changed"
+	previewStatusDelete    = "delete"
+	previewStatusUnchanged = "unchanged"
+	previewStatusUnknown   = "unknown"
+)
+
+// previewStatusOrder ranks statuses so the most significant changes are listed first
+var previewStatusOrder = map[string]int{
+	previewStatusNew:       0,
+	previewStatusChanged:   1,
+	previewStatusDelete:    2,
+	previewStatusUnchanged: 3,
+	previewStatusUnknown:   4,
+}
+
+// previewStatus compares a pending variable with the current registry value
+func previewStatus(scope string, v Variable, current currentValueIndex) string {
+	existing, exists := current.lookup(scope, v.Name)
+	switch v.Operation {
+	case "set":
+		switch {
+		case !exists:
+			return previewStatusNew
+		case existing.Value == v.Value:
+			return previewStatusUnchanged
+		default:
+			return previewStatusChanged
+		}
+	case "delete":
+		if !exists {
+			return previewStatusUnchanged
+		}
+		return previewStatusDelete
+	default:
+		return previewStatusUnknown
+	}
+}
+
+// sortPreviewItems orders items by the selected key, falling back to scope and name
+func sortPreviewItems(items []previewItem, sortBy string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if sortBy == previewSortStatus && a.Status != b.Status {
+			return previewStatusOrder[a.Status] < previewStatusOrder[b.Status]
+		}
+		if sortBy != previewSortName && a.Scope != b.Scope {
+			return a.Scope == ScopeUser
+		}
+		if sortBy == previewSortScope {
+			return false // Keep config file order within each scope
+		}
+		return strings.ToUpper(a.Variable.Name) < strings.ToUpper(b.Variable.Name)
+	})
+}
+
+// previewHandle gives access to an open preview window so it can be refreshed when the config changes
+type previewHandle struct {
+	window fyne.Window
+	update func(config Config, notice string) // Replaces the previewed config, showing notice above the changes
+}
+
+// showPreviewWindow creates and displays a window showing all pending environment variable changes
+// Each variable is shown with its current registry value on the left and the proposed value on the right
+// Values of sensitive variables are masked, source is the config path sync mode prunes the variables of
+func showPreviewWindow(app fyne.App, config Config, source string, isAdmin bool, settings Settings) *previewHandle {
+	previewWindow := app.NewWindow("Preview Changes")
+	previewWindow.Resize(fyne.NewSize(900, 600))
+
+	var current currentValueIndex
+	var err error
+	var items []previewItem
+	var patternErrors []string
+	var notice string
+	var access preflightReport
+	build := func() {
+		current, err = loadCurrentValueIndex()
+		access = preflightConfig(config)
+		items, patternErrors = nil, nil
+		addItems := func(scope string, variables []Variable) {
+			for _, v := range variables {
+				if !v.isPatternOperation() {
+					items = append(items, previewItem{Scope: scope, Variable: v, Status: previewStatus(scope, v, current)})
+					continue
+				}
+
+				// Show the concrete variables a pattern deletion would remove
+				matches, expandErr := expandPatternVariables([]Variable{v}, current.scopeVariables(scope))
+				if expandErr != nil {
+					patternErrors = append(patternErrors, expandErr.Error())
+				}
+				if len(matches) == 0 {
+					items = append(items, previewItem{Scope: scope, Variable: v, Status: previewStatusUnchanged})
+				}
+				for _, m := range matches {
+					items = append(items, previewItem{Scope: scope, Variable: m, Status: previewStatus(scope, m, current)})
+				}
+			}
+		}
+		addItems(ScopeUser, config.UserVariables)
+		addItems(ScopeSystem, config.SystemVariables)
+
+		// Warn when the resulting PSModulePath would break PowerShell
+		var userItems, systemItems []Variable
+		for _, item := range items {
+			if item.Scope == ScopeSystem {
+				systemItems = append(systemItems, item.Variable)
+			} else {
+				userItems = append(userItems, item.Variable)
+			}
+		}
+		patternErrors = append(patternErrors, psModulePathWarnings(userItems, systemItems, current)...)
+		patternErrors = append(patternErrors, configPathWarnings(config, settings)...)
+		patternErrors = append(patternErrors, configSecretWarnings(config)...)
+
+		// Show the managed variables a sync mode section would delete
+		var managed managedIndex
+		for _, scope := range []string{ScopeUser, ScopeSystem} {
+			if config.sectionMode(scope) != ConfigModeSync {
+				continue
+			}
+			if managed == nil {
+				var managedErr error
+				if managed, managedErr = loadManagedIndex(); managedErr != nil {
+					patternErrors = append(patternErrors, fmt.Sprintf("could not load managed variables: %v", managedErr))
+				}
+			}
+			var listed []Variable
+			for _, item := range items {
+				if item.Scope == scope {
+					listed = append(listed, item.Variable)
+				}
+			}
+			exists := func(name string) bool { _, ok := current.lookup(scope, name); return ok }
+			for _, v := range syncPruneVariables(listed, scope, source, managed, exists, settings.ProtectedVariables) {
+				items = append(items, previewItem{Scope: scope, Variable: v, Status: previewStatus(scope, v, current)})
+			}
+		}
+
+		// Warn when the changes push the environment block towards its size limit
+		changes := make([]ScopedVariable, len(items))
+		for i, item := range items {
+			changes[i] = ScopedVariable{Scope: item.Scope, Variable: item.Variable}
+		}
+		patternErrors = append(patternErrors, environmentSizeWarnings(changes, current)...)
+	}
+	build()
+
+	rows := container.NewVBox()
+	render := func(sortBy string) {
+		rows.RemoveAll()
+		if notice != "" {
+			rows.Add(widget.NewLabelWithStyle(notice, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		}
+		if err != nil {
+			rows.Add(widget.NewLabel(fmt.Sprintf("⚠️  Could not read current values: %v", err)))
+		}
+
+		// Display the config metadata block as a header when present
+		if header := config.Metadata.describe(); len(header) > 0 {
+			rows.Add(widget.NewLabel(strings.Join(header, "\n")))
+			rows.Add(widget.NewSeparator())
+		}
+		if lines := describeParams(config.Params); len(lines) > 0 {
+			rows.Add(widget.NewLabel(strings.Join(lines, "\n")))
+			rows.Add(widget.NewSeparator())
+		}
+
+		for _, patternErr := range patternErrors {
+			rows.Add(widget.NewLabel(fmt.Sprintf("⚠️  %s", patternErr)))
+		}
+
+		if lines := access.describe(!isAdmin); len(lines) > 0 {
+			rows.Add(widget.NewLabel(strings.Join(lines, "\n")))
+		}
+
+		sorted := append([]previewItem(nil), items...)
+		sortPreviewItems(sorted, sortBy)
+
+		// Sorting by scope keeps the user and system sections, other orderings show a single mixed list
+		section := ""
+		for _, item := range sorted {
+			if sortBy == previewSortScope && item.Scope != section {
+				section = item.Scope
+				title := "USER ENVIRONMENT VARIABLES:"
+				if section == ScopeSystem {
+					title = "SYSTEM ENVIRONMENT VARIABLES:"
+				}
+				rows.Add(widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+				rows.Add(previewColumnHeaders())
+			} else if section == "" {
+				section = "all"
+				rows.Add(previewColumnHeaders())
+			}
+
+			prefix := ""
+			if sortBy != previewSortScope {
+				prefix = "[" + strings.ToUpper(item.Scope) + "] "
+			}
+			if item.Scope == ScopeSystem && !access.System.Writable && !isAdmin {
+				prefix = "[UAC] " + prefix
+			}
+			rows.Add(previewRow(item.Scope, item.Variable, current, prefix, settings))
+		}
+
+		if len(items) == 0 {
+			rows.Add(widget.NewLabel("No environment variables found in the configuration file."))
+		}
+
+		rows.Add(widget.NewLabel("Note: After applying changes, a WM_SETTINGCHANGE message will be\nbroadcast to notify other applications of the environment changes."))
+		rows.Refresh()
+	}
+
+	sortSelect := widget.NewSelect([]string{previewSortScope, previewSortName, previewSortStatus}, render)
+	sortSelect.SetSelected(previewSortScope)
+
+	scrollContainer := container.NewScroll(rows)
+	scrollContainer.SetMinSize(fyne.NewSize(880, 440))
+
+	closeButton := widget.NewButton("Close", func() {
+		previewWindow.Close()
+	})
+
+	windowContent := container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewHBox(widget.NewLabel("Sort by:"), sortSelect),
+			widget.NewLabel("The following changes will be made to your environment variables:")),
+		widget.NewSeparator(),
+		scrollContainer,
+		widget.NewSeparator(),
+		container.NewHBox(closeButton),
+	)
+
+	previewWindow.SetContent(windowContent)
+	previewWindow.Show()
+
+	return &previewHandle{
+		window: previewWindow,
+		update: func(updated Config, updateNotice string) {
+			config, notice = updated, updateNotice
+			build()
+			render(sortSelect.Selected)
+		},
+	}
+}
+
+// previewColumnHeaders returns the "Current | Proposed" header row
+func previewColumnHeaders() fyne.CanvasObject {
+	return container.NewGridWithColumns(2,
+		widget.NewLabelWithStyle("Current", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+		widget.NewLabelWithStyle("Proposed", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+	)
+}
+
+// previewRow renders one variable as an operation title followed by the current and proposed values
+func previewRow(scope string, v Variable, current currentValueIndex, prefix string, settings Settings) fyne.CanvasObject {
+	sensitivePatterns := settings.SensitivePatterns
+	existing, exists := current.lookup(scope, v.Name)
+	sensitive := v.isSensitive(sensitivePatterns) || (exists && existing.isSensitive(sensitivePatterns))
+
+	var title string
+	var left, right *widget.RichText
+	switch v.Operation {
+	case "set":
+		title = fmt.Sprintf("%sSET: %s", prefix, v.Name)
+		if v.Expires != "" {
+			if at, err := parseExpiry(v.Expires, time.Now()); err != nil {
+				title += fmt.Sprintf("  ⚠️  %v", err)
+			} else {
+				title += fmt.Sprintf("  (expires %s)", at.Format("2006-01-02 15:04"))
+			}
+		}
+		switch {
+		case !exists:
+			left = plainValueText(previewNotSet)
+			right = highlightedValueText([]diffToken{{Text: v.Value, Changed: true}}, theme.ColorNameSuccess, sensitive)
+		case existing.Value == v.Value:
+			title += "  (unchanged)"
+			left = highlightedValueText([]diffToken{{Text: existing.Value}}, "", sensitive)
+			right = highlightedValueText([]diffToken{{Text: v.Value}}, "", sensitive)
+		default:
+			oldTokens, newTokens := wordDiff(existing.Value, v.Value)
+			left = highlightedValueText(oldTokens, theme.ColorNameError, sensitive)
+			right = highlightedValueText(newTokens, theme.ColorNameSuccess, sensitive)
+		}
+	case "delete":
+		title = fmt.Sprintf("%sDELETE: %s", prefix, v.Name)
+		if v.MatchedBy != "" {
+			title += fmt.Sprintf("  (matches %s)", v.MatchedBy)
+		}
+		if exists {
+			left = highlightedValueText([]diffToken{{Text: existing.Value, Changed: true}}, theme.ColorNameError, sensitive)
+		} else {
+			title += "  (does not exist)"
+			left = plainValueText(previewNotSet)
+		}
+		right = plainValueText(previewDeleted)
+	case OperationDeleteMatching:
+		title = fmt.Sprintf("%sDELETE MATCHING: %s  (no matching variables)", prefix, v.Pattern)
+		left = plainValueText(previewNotSet)
+		right = plainValueText(previewNotSet)
+	default:
+		title = fmt.Sprintf("%sUNKNOWN OPERATION (%s): %s", prefix, v.Operation, v.Name)
+		left = plainValueText("")
+		right = plainValueText(v.displayValue(sensitivePatterns))
+	}
+
+	// Entries of configs using extends show whether they come from or replace a parent
+	if v.Origin != "" {
+		title += fmt.Sprintf("  [%s]", v.Origin)
+	}
+
+	// Protected variables are refused by the apply engine unless the entry sets force: true
+	if err := protectionError(v, existing.Value, exists, settings.ProtectedVariables); err != nil {
+		title += "  🔒 PROTECTED - will be refused (set force: true to override)"
+	} else if v.Force && isConfirmName(v.Name, settings.ProtectedVariables) {
+		title += "  🔓 force: true"
+	}
+
+	return container.NewVBox(
+		widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewGridWithColumns(2, left, right),
+	)
+}
+
+// plainValueText renders a value without any highlighting
+func plainValueText(text string) *widget.RichText {
+	rt := widget.NewRichText(&widget.TextSegment{Text: text, Style: widget.RichTextStyleInline})
+	rt.Wrapping = fyne.TextWrapBreak
+	return rt
+}
+
+// highlightedValueText renders value tokens, coloring changed tokens with color
+// Sensitive values are masked entirely, only indicating whether they changed
+func highlightedValueText(tokens []diffToken, color fyne.ThemeColorName, sensitive bool) *widget.RichText {
+	if sensitive {
+		changed := false
+		for _, t := range tokens {
+			changed = changed || t.Changed
+		}
+		tokens = []diffToken{{Text: maskedValue, Changed: changed}}
+	}
+
+	segments := make([]widget.RichTextSegment, 0, len(tokens))
+	for _, t := range tokens {
+		style := widget.RichTextStyleInline
+		if t.Changed && color != "" {
+			style.ColorName = color
+			style.TextStyle = fyne.TextStyle{Bold: true}
+		}
+		segments = append(segments, &widget.TextSegment{Text: t.Text, Style: style})
+	}
+	if len(segments) == 0 {
+		segments = append(segments, &widget.TextSegment{Text: "", Style: widget.RichTextStyleInline})
+	}
+
+	rt := widget.NewRichText(segments...)
+	rt.Wrapping = fyne.TextWrapBreak
+	return rt
+}
@@ -0,0 +1,321 @@
+// profiles.go
+// Profiles - named configs kept in the application data directory for quick reuse from the Profiles tab
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	sqweekdialog "github.com/sqweek/dialog"
+)
+
+// profilesFolderName is the directory inside the application data directory that holds profile configs
+const profilesFolderName = "profiles"
+
+// invalidProfileNameChars matches characters that are not allowed in profile file names
+var invalidProfileNameChars = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
+
+// profilesDir returns the profile directory, creating it if necessary
+func profilesDir() (string, error) {
+	base, err := appDataDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, profilesFolderName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create profiles directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// listProfiles returns the names of all saved profiles in alphabetical order
+func listProfiles() ([]string, error) {
+	dir, err := profilesDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isValidYAMLFile(entry.Name()) {
+			names = append(names, strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// profilePath returns the config file path of the named profile
+func profilePath(name string) (string, error) {
+	if strings.TrimSpace(name) == "" || invalidProfileNameChars.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	dir, err := profilesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".yaml"), nil
+}
+
+// saveProfile stores a config as the named profile, replacing an existing profile of the same name
+func saveProfile(name string, config Config) error {
+	path, err := profilePath(name)
+	if err != nil {
+		return err
+	}
+	return saveConfigToFile(config, path)
+}
+
+// deleteProfile removes the named profile
+func deleteProfile(name string) error {
+	path, err := profilePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete profile %s: %w", name, err)
+	}
+	return nil
+}
+
+// newProfilesTab builds the Profiles tab
+// selectedConfig returns the currently selected config file and selectConfig makes a file the selected config
+func newProfilesTab(parent fyne.Window, settings *Settings, selectedConfig func() string, selectConfig func(path string)) fyne.CanvasObject {
+	var names []string
+	selected := -1
+
+	list := widget.NewList(
+		func() int { return len(names) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(names[id])
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	list.OnUnselected = func(widget.ListItemID) { selected = -1 }
+
+	reload := func() {
+		loaded, err := listProfiles()
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error listing profiles: %v", err), parent)
+		}
+		names = loaded
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+	reload()
+
+	// Save the currently selected config file under a profile name
+	saveButton := widget.NewButton("Save Selected Config as Profile", func() {
+		source := selectedConfig()
+		if source == "" {
+			dialog.ShowInformation("Error", "Please select a configuration file first.", parent)
+			return
+		}
+		// Loading may ask for a passphrase, which blocks until answered
+		go func() {
+			config, err := loadConfig(source)
+			if err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+
+			nameEntry := widget.NewEntry()
+			if config.Metadata != nil {
+				nameEntry.SetText(invalidProfileNameChars.ReplaceAllString(config.Metadata.Name, "_"))
+			}
+			dialog.ShowForm("Save Profile", "Save", "Cancel", []*widget.FormItem{
+				widget.NewFormItem("Profile Name", nameEntry),
+			}, func(confirmed bool) {
+				if !confirmed {
+					return
+				}
+				if err := saveProfile(strings.TrimSpace(nameEntry.Text), config); err != nil {
+					dialog.ShowError(fmt.Errorf("error saving profile: %v", err), parent)
+					return
+				}
+				reload()
+			}, parent)
+		}()
+	})
+
+	useButton := widget.NewButton("Use Profile", func() {
+		if selected < 0 {
+			dialog.ShowInformation("Error", "Please select a profile first.", parent)
+			return
+		}
+		path, err := profilePath(names[selected])
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+		selectConfig(path)
+	})
+
+	// Reapply a profile at every sign-in for variables that other software resets at logon
+	logonLabel := widget.NewLabel("")
+	refreshLogonLabel := func() {
+		if settings.LogonProfile == "" {
+			logonLabel.SetText("Reapply at logon: off")
+		} else {
+			logonLabel.SetText(fmt.Sprintf("Reapply at logon: %s", settings.LogonProfile))
+		}
+	}
+	refreshLogonLabel()
+
+	deleteButton := widget.NewButton("Delete Profile", func() {
+		if selected < 0 {
+			dialog.ShowInformation("Error", "Please select a profile first.", parent)
+			return
+		}
+		name := names[selected]
+		dialog.ShowConfirm("Delete Profile", fmt.Sprintf("Delete profile %q?", name), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := deleteProfile(name); err != nil {
+				dialog.ShowError(err, parent)
+			}
+			// A deleted profile can no longer be reapplied at logon
+			if strings.EqualFold(name, settings.LogonProfile) {
+				go func() {
+					unregisterLogonTask()
+					settings.LogonProfile = ""
+					if err := saveSettings(*settings); err != nil {
+						dialog.ShowError(fmt.Errorf("error saving settings: %v", err), parent)
+					}
+					refreshLogonLabel()
+				}()
+			}
+			reload()
+		}, parent)
+	})
+
+	logonButton := widget.NewButton("Reapply at Logon", func() {
+		if selected < 0 {
+			dialog.ShowInformation("Error", "Please select a profile first.", parent)
+			return
+		}
+		name := names[selected]
+		go func() {
+			registered, err := registerLogonTask(name)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error registering logon task: %v", err), parent)
+				return
+			}
+			settings.LogonProfile = name
+			if err := saveSettings(*settings); err != nil {
+				dialog.ShowError(fmt.Errorf("error saving settings: %v", err), parent)
+			}
+			refreshLogonLabel()
+			dialog.ShowInformation("Reapply at Logon", fmt.Sprintf("Profile %q will be applied every time you sign in.\n\nRegistered with %s.", name, registered), parent)
+		}()
+	})
+
+	removeLogonButton := widget.NewButton("Remove Logon Task", func() {
+		go func() {
+			unregisterLogonTask()
+			settings.LogonProfile = ""
+			if err := saveSettings(*settings); err != nil {
+				dialog.ShowError(fmt.Errorf("error saving settings: %v", err), parent)
+			}
+			refreshLogonLabel()
+		}()
+	})
+	if readOnlyMode {
+		logonButton.Disable()
+	}
+
+	templatesButton := widget.NewButton("Browse Templates...", func() {
+		showTemplateBrowser(settings, reload)
+	})
+
+	// Move the whole profile set, optionally with the environment snapshots, to another machine
+	exportBundleButton := widget.NewButton("Export Bundle...", func() {
+		includeSnapshots := widget.NewCheck("Include environment snapshots from the backup directory", nil)
+		includeSnapshots.SetChecked(true)
+		dialog.ShowCustomConfirm("Export Bundle", "Export", "Cancel", includeSnapshots, func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				savePath, err := sqweekdialog.File().Title("Export Profile Bundle").Filter("Profile Bundle", strings.TrimPrefix(bundleExtension, ".")).Save()
+				if err != nil {
+					if err.Error() != "cancelled" {
+						dialog.ShowError(fmt.Errorf("error choosing file: %v", err), parent)
+					}
+					return
+				}
+				if !strings.EqualFold(filepath.Ext(savePath), bundleExtension) {
+					savePath += bundleExtension
+				}
+				manifest, err := exportProfileBundle(savePath, settings.Backup, includeSnapshots.Checked)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("error exporting bundle: %v", err), parent)
+					return
+				}
+				dialog.ShowInformation("Bundle Exported", fmt.Sprintf("%d profile(s) and %d snapshot(s) written to:\n%s", len(manifest.Profiles), len(manifest.Snapshots), savePath), parent)
+			}()
+		}, parent)
+	})
+
+	importBundleButton := widget.NewButton("Import Bundle...", func() {
+		go func() {
+			filePath, err := sqweekdialog.File().Title("Import Profile Bundle").Filter("Profile Bundle", strings.TrimPrefix(bundleExtension, ".")).Load()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error choosing file: %v", err), parent)
+				}
+				return
+			}
+			overwrite := widget.NewCheck("Replace profiles that already exist", nil)
+			dialog.ShowCustomConfirm("Import Bundle", "Import", "Cancel", overwrite, func(ok bool) {
+				if !ok {
+					return
+				}
+				result, err := importProfileBundle(filePath, settings.Backup, overwrite.Checked)
+				reload()
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("error importing bundle: %v", err), parent)
+					return
+				}
+				message := fmt.Sprintf("Imported %d profile(s) and %d snapshot(s).", result.Profiles, result.Snapshots)
+				if len(result.Skipped) > 0 {
+					message += fmt.Sprintf("\n\nSkipped because they already exist:\n%s", strings.Join(result.Skipped, "\n"))
+				}
+				dialog.ShowInformation("Bundle Imported", message, parent)
+			}, parent)
+		}()
+	})
+
+	projectsButton := widget.NewButton("Project Environments...", func() {
+		showProjectEnvironmentsWindow(settings)
+	})
+
+	return container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Saved profiles. 'Use Profile' makes a profile the selected config on the Config / Apply tab."),
+			logonLabel,
+		),
+		container.NewVBox(
+			container.NewHBox(saveButton, useButton, deleteButton, templatesButton, widget.NewButton("Refresh", reload)),
+			container.NewHBox(logonButton, removeLogonButton, exportBundleButton, importBundleButton, projectsButton),
+		),
+		nil, nil,
+		list,
+	)
+}
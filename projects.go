@@ -0,0 +1,288 @@
+// projects.go
+// Project environments - configs tied to project directories, loaded by a PowerShell prompt hook instead of the registry
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	sqweekdialog "github.com/sqweek/dialog"
+	"golang.org/x/sys/windows/registry"
+)
+
+// projectHookFileName is the generated script the PowerShell profiles dot-source
+const projectHookFileName = "project-environments.ps1"
+
+// projectHookMarker identifies the line this application adds to a PowerShell profile
+const projectHookMarker = "# SystemVariableManager project environments"
+
+// ProjectConfig ties a config file to a project directory and its subdirectories
+type ProjectConfig struct {
+	Directory string `yaml:"directory"` // Project root
+	Config    string `yaml:"config"`    // Config file whose variables are loaded in terminals opened below Directory
+}
+
+// powerShellProfilePaths returns the CurrentUserAllHosts profiles of Windows PowerShell and PowerShell 7
+// The Documents folder is read from the shell folders because it is often redirected, e.g. to OneDrive
+func powerShellProfilePaths() []string {
+	home, _ := os.UserHomeDir()
+	documents := filepath.Join(home, "Documents")
+	if key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Explorer\User Shell Folders`, registry.QUERY_VALUE); err == nil {
+		if value, _, err := key.GetStringValue("Personal"); err == nil {
+			if expanded, err := registry.ExpandString(value); err == nil && expanded != "" {
+				documents = expanded
+			}
+		}
+		key.Close()
+	}
+	return []string{
+		filepath.Join(documents, "WindowsPowerShell", "profile.ps1"),
+		filepath.Join(documents, "PowerShell", "profile.ps1"),
+	}
+}
+
+// projectHookLine returns the profile line loading the generated script, skipped while the script does not exist
+func projectHookLine(script string) string {
+	return fmt.Sprintf("if (Test-Path %s) { . %s } %s", powerShellQuote(script), powerShellQuote(script), projectHookMarker)
+}
+
+// projectHookInstalled reports whether any PowerShell profile loads the generated script
+func projectHookInstalled() bool {
+	for _, profile := range powerShellProfilePaths() {
+		if data, err := os.ReadFile(profile); err == nil && strings.Contains(string(data), projectHookMarker) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectVariables loads the variables of a project config the way an unattended apply would
+// The scopes are merged as in a new process, PATH-like variables are joined and user variables win otherwise
+func projectVariables(path string) ([]Variable, error) {
+	config, err := loadConfigForMachine(path)
+	if err != nil {
+		return nil, err
+	}
+	if config, err = resolveWithParamDefaults(config); err != nil {
+		return nil, err
+	}
+	// delete_matching and sync mode describe registry state, they have no meaning for a single session
+	return exportVariables(config), nil
+}
+
+// renderProjectHook returns the PowerShell script switching variables whenever the prompt enters another project
+// Values a project overrides are saved on entry and restored when the prompt leaves the project
+func renderProjectHook(projects []ProjectConfig) (string, []error) {
+	var errs []error
+	var b strings.Builder
+	b.WriteString("# Generated by SystemVariableManager, regenerate it from the Project Environments window instead of editing it\n")
+	b.WriteString("$global:SvmProjects = @(\n")
+
+	// The longest matching directory wins, so nested projects override their parents
+	sorted := append([]ProjectConfig{}, projects...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].Directory) > len(sorted[j].Directory) })
+	for _, p := range sorted {
+		variables, err := projectVariables(p.Config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Directory, err))
+			continue
+		}
+		names := make([]string, len(variables))
+		for i, v := range variables {
+			names[i] = powerShellQuote(v.Name)
+		}
+		fmt.Fprintf(&b, "    @{ Dir = %s; Names = @(%s); Apply = { %s } }\n",
+			powerShellQuote(strings.TrimRight(filepath.Clean(p.Directory), `\`)), strings.Join(names, ", "), powerShellEnvironmentScript(variables))
+	}
+	b.WriteString(`)
+
+function global:Update-SvmProjectEnvironment {
+    $here = (Get-Location).ProviderPath
+    $match = $null
+    foreach ($p in $global:SvmProjects) {
+        if ($here -eq $p.Dir -or $here.StartsWith($p.Dir + '\', [StringComparison]::OrdinalIgnoreCase)) { $match = $p; break }
+    }
+    if ($match -eq $global:SvmActiveProject) { return }
+    if ($global:SvmActiveProject) {
+        foreach ($name in $global:SvmSavedValues.Keys) { [Environment]::SetEnvironmentVariable($name, $global:SvmSavedValues[$name]) }
+    }
+    $global:SvmActiveProject = $match
+    $global:SvmSavedValues = @{}
+    if ($match) {
+        foreach ($name in $match.Names) { $global:SvmSavedValues[$name] = [Environment]::GetEnvironmentVariable($name) }
+        & $match.Apply
+        Write-Host "Loaded the environment of $($match.Dir)" -ForegroundColor DarkGray
+    }
+}
+
+if (-not $global:SvmOriginalPrompt) {
+    $global:SvmOriginalPrompt = $function:prompt
+    function global:prompt { Update-SvmProjectEnvironment; & $global:SvmOriginalPrompt }
+}
+`)
+	return b.String(), errs
+}
+
+// writeProjectHook regenerates the hook script from the project list
+func writeProjectHook(projects []ProjectConfig) (string, []error) {
+	path, err := appDataPath(projectHookFileName)
+	if err != nil {
+		return "", []error{err}
+	}
+	script, errs := renderProjectHook(projects)
+	if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+		return "", append(errs, fmt.Errorf("failed to write %s: %w", path, err))
+	}
+	return path, errs
+}
+
+// installProjectHook adds the line loading the hook script to both PowerShell profiles, once
+func installProjectHook(script string) error {
+	for _, profile := range powerShellProfilePaths() {
+		data, err := os.ReadFile(profile)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", profile, err)
+		}
+		if strings.Contains(string(data), projectHookMarker) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(profile), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(profile), err)
+		}
+		text := string(data)
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\r\n"
+		}
+		if err := os.WriteFile(profile, []byte(text+projectHookLine(script)+"\r\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", profile, err)
+		}
+	}
+	return nil
+}
+
+// uninstallProjectHook removes the hook line from both PowerShell profiles
+func uninstallProjectHook() error {
+	for _, profile := range powerShellProfilePaths() {
+		data, err := os.ReadFile(profile)
+		if err != nil {
+			continue
+		}
+		var kept []string
+		for _, line := range strings.SplitAfter(string(data), "\n") {
+			if !strings.Contains(line, projectHookMarker) {
+				kept = append(kept, line)
+			}
+		}
+		if err := os.WriteFile(profile, []byte(strings.Join(kept, "")), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", profile, err)
+		}
+	}
+	return nil
+}
+
+// showProjectEnvironmentsWindow manages the project directories and the PowerShell hook loading their configs
+func showProjectEnvironmentsWindow(settings *Settings) {
+	window := fyne.CurrentApp().NewWindow("Project Environments")
+	window.Resize(fyne.NewSize(820, 480))
+
+	selected := -1
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	list := widget.NewList(
+		func() int { return len(settings.ProjectConfigs) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			p := settings.ProjectConfigs[id]
+			o.(*widget.Label).SetText(fmt.Sprintf("%s  →  %s", p.Directory, p.Config))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) { selected = id }
+	list.OnUnselected = func(widget.ListItemID) { selected = -1 }
+
+	// Every change to the list regenerates the script, so an installed hook picks it up in the next terminal
+	update := func() {
+		if err := saveSettings(*settings); err != nil {
+			dialog.ShowError(fmt.Errorf("error saving settings: %v", err), window)
+		}
+		path, errs := writeProjectHook(settings.ProjectConfigs)
+		status := fmt.Sprintf("%d project(s). Hook script: %s", len(settings.ProjectConfigs), path)
+		if projectHookInstalled() {
+			status += "\nThe PowerShell profiles load it, new terminals opened in a project get its variables."
+		} else {
+			status += "\nThe hook is not installed in the PowerShell profiles yet."
+		}
+		for _, err := range errs {
+			status += fmt.Sprintf("\n⚠️  %v", err)
+		}
+		statusLabel.SetText(status)
+		list.Refresh()
+	}
+	update()
+
+	addButton := widget.NewButton("Add Project...", func() {
+		go func() {
+			dir, err := sqweekdialog.Directory().Title("Project Directory").Browse()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error choosing directory: %v", err), window)
+				}
+				return
+			}
+			config, err := sqweekdialog.File().Title("Config for "+filepath.Base(dir)).Filter("YAML Config", "yaml", "yml").Load()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error choosing file: %v", err), window)
+				}
+				return
+			}
+			if _, err := projectVariables(config); err != nil {
+				dialog.ShowError(fmt.Errorf("error loading %s: %v", config, err), window)
+				return
+			}
+			settings.ProjectConfigs = append(settings.ProjectConfigs, ProjectConfig{Directory: dir, Config: config})
+			update()
+		}()
+	})
+	removeButton := widget.NewButton("Remove Project", func() {
+		if selected < 0 {
+			return
+		}
+		settings.ProjectConfigs = append(settings.ProjectConfigs[:selected], settings.ProjectConfigs[selected+1:]...)
+		list.UnselectAll()
+		update()
+	})
+	installButton := widget.NewButton("Install Hook", func() {
+		path, _ := writeProjectHook(settings.ProjectConfigs)
+		if err := installProjectHook(path); err != nil {
+			dialog.ShowError(fmt.Errorf("error installing hook: %v", err), window)
+			return
+		}
+		update()
+		dialog.ShowInformation("Hook Installed", fmt.Sprintf("Added to:\n%s\n\nOpen a new PowerShell window to use it.", strings.Join(powerShellProfilePaths(), "\n")), window)
+	})
+	uninstallButton := widget.NewButton("Remove Hook", func() {
+		if err := uninstallProjectHook(); err != nil {
+			dialog.ShowError(fmt.Errorf("error removing hook: %v", err), window)
+			return
+		}
+		update()
+	})
+
+	window.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("Configs loaded into PowerShell sessions whose current directory is inside a project. Nothing is written to the registry."),
+			statusLabel,
+		),
+		container.NewHBox(addButton, removeButton, widget.NewButton("Regenerate", update), installButton, uninstallButton, widget.NewButton("Close", window.Close)),
+		nil, nil,
+		list,
+	))
+	window.Show()
+}
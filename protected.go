@@ -0,0 +1,25 @@
+// protected.go
+// Protected variables - variables a config may only delete or overwrite when the entry sets force: true
+package main
+
+import "fmt"
+
+// defaultProtectedVariables are variables whose loss or corruption can make a machine unusable
+var defaultProtectedVariables = []string{"Path", "PATHEXT", "ComSpec", "windir", "SystemRoot", "TEMP", "TMP", "PSModulePath", "OS"}
+
+// protectionError returns an error when applying v would delete or overwrite a protected variable without force
+// exists and currentValue describe the value presently stored in the registry
+func protectionError(v Variable, currentValue string, exists bool, protected []string) error {
+	if v.Force || !exists || !isConfirmName(v.Name, protected) {
+		return nil
+	}
+	switch v.Operation {
+	case "delete":
+		return fmt.Errorf("%s is protected and cannot be deleted without force: true", v.Name)
+	case "set":
+		if v.Value != currentValue {
+			return fmt.Errorf("%s is protected and cannot be overwritten without force: true", v.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// prune.go
+// Sync mode - sections with mode: sync delete every managed variable they do not list, enforcing the config as the exact desired state
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// syncMatchedBy is shown in the preview for deletions produced by sync mode
+const syncMatchedBy = "mode: sync"
+
+// syncPruneVariables returns deletions for the managed variables of a scope that the section does not name
+// Only variables the config at source created are pruned, those of other configs and UI actions are kept;
+// exists filters out records of variables that are already gone and protected variables are left alone
+// because a generated deletion cannot carry force: true
+func syncPruneVariables(variables []Variable, scope, source string, managed managedIndex, exists func(name string) bool, protected []string) []Variable {
+	listed := map[string]bool{}
+	for _, v := range variables {
+		listed[strings.ToUpper(v.Name)] = true
+	}
+
+	var pruned []Variable
+	for _, m := range managed {
+		if m.Scope != scope || !m.FromConfig || !strings.EqualFold(m.Source, source) || listed[strings.ToUpper(m.Name)] || !exists(m.Name) {
+			continue
+		}
+		if isConfirmName(m.Name, protected) {
+			fmt.Printf("Sync mode keeps protected variable %s (%s)\n", m.Name, scope)
+			continue
+		}
+		pruned = append(pruned, Variable{Name: m.Name, Operation: "delete", MatchedBy: syncMatchedBy})
+	}
+	sort.Slice(pruned, func(i, j int) bool { return strings.ToUpper(pruned[i].Name) < strings.ToUpper(pruned[j].Name) })
+	return pruned
+}
+
+// expandSyncMode appends the deletions of sync mode sections to config
+// Call it after placeholders and patterns are resolved so every listed name is final
+func expandSyncMode(config Config, source string, protected []string) (Config, error) {
+	if config.sectionMode(ScopeUser) != ConfigModeSync && config.sectionMode(ScopeSystem) != ConfigModeSync {
+		return config, nil
+	}
+	managed, err := loadManagedIndex()
+	if err != nil {
+		return config, err
+	}
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		return config, err
+	}
+
+	if config.sectionMode(ScopeUser) == ConfigModeSync {
+		exists := func(name string) bool { _, ok := current.lookup(ScopeUser, name); return ok }
+		config.UserVariables = append(config.UserVariables, syncPruneVariables(config.UserVariables, ScopeUser, source, managed, exists, protected)...)
+	}
+	if config.sectionMode(ScopeSystem) == ConfigModeSync {
+		exists := func(name string) bool { _, ok := current.lookup(ScopeSystem, name); return ok }
+		config.SystemVariables = append(config.SystemVariables, syncPruneVariables(config.SystemVariables, ScopeSystem, source, managed, exists, protected)...)
+	}
+	return config, nil
+}
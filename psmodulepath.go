@@ -0,0 +1,131 @@
+// psmodulepath.go
+// PSModulePath safeguards - warns before a config drops the default module paths PowerShell needs or fights a policy
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// psModulePathName is the variable PowerShell searches for modules
+const psModulePathName = "PSModulePath"
+
+// defaultPSModulePaths are the system entries Windows ships; without them built-in modules such as
+// Microsoft.PowerShell.Management do not auto-load and Windows PowerShell and many scripts break
+var defaultPSModulePaths = []string{
+	`%ProgramFiles%\WindowsPowerShell\Modules`,
+	`%SystemRoot%\system32\WindowsPowerShell\v1.0\Modules`,
+}
+
+// psModulePathPolicyFiles are the Group Policy Preferences caches that list enforced environment variables
+var psModulePathPolicyFiles = []string{
+	`%ProgramData%\Microsoft\Group Policy\History\*\Machine\Preferences\EnvironmentVariables\EnvironmentVariables.xml`,
+	`%LOCALAPPDATA%\Microsoft\Group Policy\History\*\User\Preferences\EnvironmentVariables\EnvironmentVariables.xml`,
+}
+
+// finalPSModulePath returns the PSModulePath a scope has after applying variables, and whether variables touch it
+func finalPSModulePath(scope string, variables []Variable, current currentValueIndex) (value string, touched bool) {
+	if existing, ok := current.lookup(scope, psModulePathName); ok {
+		value = existing.Value
+	}
+	for _, v := range variables {
+		if !strings.EqualFold(v.Name, psModulePathName) {
+			continue
+		}
+		switch v.Operation {
+		case "set":
+			value, touched = v.Value, true
+		case "delete":
+			value, touched = "", true
+		}
+	}
+	return value, touched
+}
+
+// missingDefaultModulePaths returns the default module paths the combined system and user value lacks
+// PowerShell combines the machine and user values, so a default is only missing when neither lists it
+func missingDefaultModulePaths(system, user string) []string {
+	present := map[string]bool{}
+	for _, entry := range append(pathEntries(system), pathEntries(user)...) {
+		present[normalizePathEntry(entry)] = true
+	}
+	var missing []string
+	for _, entry := range defaultPSModulePaths {
+		if !present[normalizePathEntry(entry)] {
+			missing = append(missing, entry)
+		}
+	}
+	return missing
+}
+
+// psModulePathPolicyScopes returns the scopes whose PSModulePath is enforced by Group Policy Preferences
+func psModulePathPolicyScopes() []string {
+	var scopes []string
+	for i, pattern := range psModulePathPolicyFiles {
+		expanded, err := registry.ExpandString(pattern)
+		if err != nil {
+			continue
+		}
+		files, _ := filepath.Glob(expanded)
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err == nil && strings.Contains(strings.ToLower(string(data)), `name="psmodulepath"`) {
+				scopes = append(scopes, []string{ScopeSystem, ScopeUser}[i])
+				break
+			}
+		}
+	}
+	return scopes
+}
+
+// psModulePathWarnings describes what applying the user and system variables would do wrong to PSModulePath
+// It is silent when the variables do not touch PSModulePath, existing problems are the doctor's business
+func psModulePathWarnings(user, system []Variable, current currentValueIndex) []string {
+	userValue, userTouched := finalPSModulePath(ScopeUser, user, current)
+	systemValue, systemTouched := finalPSModulePath(ScopeSystem, system, current)
+	if !userTouched && !systemTouched {
+		return nil
+	}
+
+	var warnings []string
+	if systemTouched && strings.TrimSpace(systemValue) == "" {
+		warnings = append(warnings, "PSModulePath: the system value would be removed, PowerShell loses every module location Windows provides")
+	}
+	if missing := missingDefaultModulePaths(systemValue, userValue); len(missing) > 0 {
+		warnings = append(warnings, fmt.Sprintf("PSModulePath: the default module path(s) %s would be dropped, built-in PowerShell modules stop loading", strings.Join(missing, "; ")))
+	}
+	for _, scope := range psModulePathPolicyScopes() {
+		if (scope == ScopeSystem && systemTouched) || (scope == ScopeUser && userTouched) {
+			warnings = append(warnings, fmt.Sprintf("PSModulePath: the %s value is enforced by Group Policy Preferences and is overwritten at the next policy refresh", scope))
+		}
+	}
+	return warnings
+}
+
+// psModulePathForced reports whether every PSModulePath entry of the variables sets force: true
+func psModulePathForced(variables []Variable) bool {
+	for _, v := range variables {
+		if strings.EqualFold(v.Name, psModulePathName) && !v.Force {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPSModulePathUnattended refuses PSModulePath changes that would break PowerShell when nobody can confirm them
+// Entries setting force: true are applied anyway
+func checkPSModulePathUnattended(config Config) error {
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		return err
+	}
+	warnings := psModulePathWarnings(config.UserVariables, config.SystemVariables, current)
+	if len(warnings) == 0 || psModulePathForced(append(append([]Variable{}, config.UserVariables...), config.SystemVariables...)) {
+		return nil
+	}
+	return fmt.Errorf("refusing to apply without force: true on the PSModulePath entries: %s", strings.Join(warnings, "; "))
+}
@@ -0,0 +1,259 @@
+// python.go
+// Python helper - detects Python, conda and virtualenv installs and switches the user PATH between them
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows/registry"
+)
+
+// pythonInstall is a directory containing python.exe
+type pythonInstall struct {
+	Label string // Shown in the selector, e.g. "Python 3.12 (PythonCore)"
+	Dir   string // Directory of python.exe
+	Kind  string // "python", "conda" or "virtualenv"
+}
+
+// scriptsDir returns the directory pip installs console scripts to
+func (p pythonInstall) scriptsDir() string {
+	return filepath.Join(p.Dir, "Scripts")
+}
+
+// pathDirs returns the PATH entries that activate the install; conda also needs Library\bin for its DLLs
+// Virtualenvs keep python.exe in Scripts already, so they need only that directory
+func (p pythonInstall) pathDirs() []string {
+	if p.Kind == "virtualenv" {
+		return []string{p.Dir}
+	}
+	dirs := []string{p.Dir, p.scriptsDir()}
+	if p.Kind == "conda" {
+		dirs = append(dirs, filepath.Join(p.Dir, "Library", "bin"))
+	}
+	return dirs
+}
+
+// pythonRegistryRoots are where PEP 514 registers installed Pythons
+var pythonRegistryRoots = []struct {
+	hive registry.Key
+	path string
+}{
+	{registry.CURRENT_USER, `SOFTWARE\Python`},
+	{registry.LOCAL_MACHINE, `SOFTWARE\Python`},
+	{registry.LOCAL_MACHINE, `SOFTWARE\WOW6432Node\Python`},
+}
+
+// detectPythonInstalls finds registered Pythons, conda installations with their environments and virtualenvs
+func detectPythonInstalls() []pythonInstall {
+	var installs []pythonInstall
+	seen := map[string]bool{}
+	add := func(p pythonInstall) {
+		key := normalizePathEntry(p.Dir)
+		if seen[key] {
+			return
+		}
+		if _, err := os.Stat(filepath.Join(p.Dir, "python.exe")); err != nil {
+			return
+		}
+		seen[key] = true
+		installs = append(installs, p)
+	}
+
+	// PEP 514: SOFTWARE\Python\<Company>\<Tag>\InstallPath
+	for _, root := range pythonRegistryRoots {
+		companies, err := registry.OpenKey(root.hive, root.path, registry.READ)
+		if err != nil {
+			continue
+		}
+		companyNames, _ := companies.ReadSubKeyNames(-1)
+		companies.Close()
+		for _, company := range companyNames {
+			if strings.EqualFold(company, "PyLauncher") {
+				continue
+			}
+			tags, err := registry.OpenKey(root.hive, root.path+`\`+company, registry.READ)
+			if err != nil {
+				continue
+			}
+			tagNames, _ := tags.ReadSubKeyNames(-1)
+			tags.Close()
+			for _, tag := range tagNames {
+				key, err := registry.OpenKey(root.hive, root.path+`\`+company+`\`+tag+`\InstallPath`, registry.QUERY_VALUE)
+				if err != nil {
+					continue
+				}
+				dir, _, err := key.GetStringValue("")
+				key.Close()
+				if err == nil && dir != "" {
+					kind := "python"
+					if strings.Contains(strings.ToLower(company), "continuum") || strings.Contains(strings.ToLower(company), "anaconda") {
+						kind = "conda"
+					}
+					add(pythonInstall{Label: fmt.Sprintf("Python %s (%s)", tag, company), Dir: filepath.Clean(dir), Kind: kind})
+				}
+			}
+		}
+	}
+
+	// Conda installations in their default locations, and their environments
+	home, _ := os.UserHomeDir()
+	programData := os.Getenv("ProgramData")
+	for _, root := range []string{
+		filepath.Join(home, "anaconda3"), filepath.Join(home, "miniconda3"), filepath.Join(home, "miniforge3"),
+		filepath.Join(programData, "Anaconda3"), filepath.Join(programData, "Miniconda3"), filepath.Join(programData, "miniforge3"),
+	} {
+		add(pythonInstall{Label: fmt.Sprintf("conda base (%s)", filepath.Base(root)), Dir: root, Kind: "conda"})
+		envs, _ := filepath.Glob(filepath.Join(root, "envs", "*"))
+		for _, env := range envs {
+			add(pythonInstall{Label: fmt.Sprintf("conda env %s (%s)", filepath.Base(env), filepath.Base(root)), Dir: env, Kind: "conda"})
+		}
+	}
+
+	// Virtualenvs keep python.exe in Scripts, so they are listed by that directory
+	workOn := os.Getenv("WORKON_HOME")
+	for _, root := range []string{workOn, filepath.Join(home, ".virtualenvs"), filepath.Join(home, "Envs")} {
+		if root == "" {
+			continue
+		}
+		envs, _ := filepath.Glob(filepath.Join(root, "*", "Scripts", "python.exe"))
+		for _, exe := range envs {
+			dir := filepath.Dir(exe)
+			add(pythonInstall{Label: fmt.Sprintf("virtualenv %s", filepath.Base(filepath.Dir(dir))), Dir: dir, Kind: "virtualenv"})
+		}
+	}
+
+	sort.SliceStable(installs, func(i, j int) bool { return installs[i].Label < installs[j].Label })
+	return installs
+}
+
+// pythonPathWarnings lists the known pitfalls of the current Python variables
+func pythonPathWarnings(ctx toolchainContext) []string {
+	var warnings []string
+	for _, scope := range []string{ScopeUser, ScopeSystem} {
+		if v, ok := ctx.lookup(scope, "PYTHONHOME"); ok {
+			warnings = append(warnings, fmt.Sprintf("PYTHONHOME is set in the %s environment (%s). Every Python, conda and virtualenv then loads its standard library from there, so all but one of them fail with \"No module named 'encodings'\". Set it per process, never globally.", scope, v.Value))
+		}
+		if v, ok := ctx.lookup(scope, "PYTHONPATH"); ok {
+			warnings = append(warnings, fmt.Sprintf("PYTHONPATH is set in the %s environment (%s). It is shared by every Python version and bypasses virtualenv isolation, prefer installing packages or a .pth file.", scope, v.Value))
+		}
+	}
+
+	// The Microsoft Store alias in WindowsApps opens the Store instead of running Python when it comes first
+	alias := filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "WindowsApps", "python.exe")
+	if _, err := os.Stat(alias); err == nil {
+		if user, ok := ctx.lookup(ScopeUser, "Path"); ok {
+			for _, entry := range pathEntries(user.Value) {
+				if isBelowDirectory(entry, filepath.Dir(alias)) {
+					warnings = append(warnings, "The user Path lists WindowsApps, whose python.exe alias opens the Microsoft Store. Select an install below to put it first, or turn the alias off under Settings > Apps > App execution aliases.")
+					break
+				}
+				if _, err := os.Stat(filepath.Join(expandedPathEntry(entry), "python.exe")); err == nil {
+					break
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// expandedPathEntry returns a PATH entry with %VAR% references expanded and quotes removed
+func expandedPathEntry(entry string) string {
+	entry = strings.Trim(strings.TrimSpace(entry), `"`)
+	if expanded, err := registry.ExpandString(entry); err == nil {
+		return expanded
+	}
+	return entry
+}
+
+// newPythonHelper builds the Python card
+func newPythonHelper(ctx toolchainContext) fyne.CanvasObject {
+	installs := detectPythonInstalls()
+	box := container.NewVBox()
+
+	for _, warning := range pythonPathWarnings(ctx) {
+		label := widget.NewLabel("⚠️  " + warning)
+		label.Wrapping = fyne.TextWrapWord
+		box.Add(label)
+	}
+
+	// Offer to remove the global PYTHONHOME and PYTHONPATH where they are set
+	for _, name := range []string{"PYTHONHOME", "PYTHONPATH"} {
+		for _, scope := range []string{ScopeUser, ScopeSystem} {
+			v, ok := ctx.lookup(scope, name)
+			if !ok {
+				continue
+			}
+			v.Operation = "delete"
+			button := widget.NewButton(fmt.Sprintf("Remove %s (%s)", name, scope), func() {
+				ctx.apply("Python helper", []ScopedVariable{v})
+			})
+			markElevates(button, ctx.isAdmin, scope == ScopeSystem)
+			hideInReadOnly(button)
+			box.Add(button)
+		}
+	}
+
+	if len(installs) == 0 {
+		box.Add(widget.NewLabel("No Python installs found (registered installs, conda in its default locations, virtualenvs in WORKON_HOME, ~/.virtualenvs and ~/Envs)."))
+		return box
+	}
+
+	// The active install is the first one the user Path points to
+	labels := make([]string, len(installs))
+	active := ""
+	user, _ := ctx.lookup(ScopeUser, "Path")
+	for i, p := range installs {
+		labels[i] = p.Label
+		for _, entry := range pathEntries(user.Value) {
+			if active == "" && normalizePathEntry(entry) == normalizePathEntry(p.pathDirs()[0]) {
+				active = p.Label
+			}
+		}
+	}
+	activeText := "None of the detected installs is on the user Path."
+	if active != "" {
+		activeText = "Active on the user Path: " + active
+	}
+
+	selector := widget.NewSelect(labels, nil)
+	if active != "" {
+		selector.SetSelected(active)
+	}
+	useButton := widget.NewButton("Use on User Path", func() {
+		i := selector.SelectedIndex()
+		if i < 0 {
+			return
+		}
+		// Only entries belonging to detected installs are replaced, other PATH entries are left alone
+		remove := func(entry string) bool {
+			for _, p := range installs {
+				for _, dir := range p.pathDirs() {
+					if normalizePathEntry(entry) == normalizePathEntry(dir) {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		if change, ok := ctx.pathChange(ScopeUser, remove, installs[i].pathDirs()...); ok {
+			ctx.apply("Python helper", []ScopedVariable{change})
+		}
+	})
+	hideInReadOnly(useButton)
+
+	lines := make([]string, len(installs))
+	for i, p := range installs {
+		lines[i] = fmt.Sprintf("%s: %s", p.Label, p.Dir)
+	}
+	box.Add(widget.NewLabel(strings.Join(lines, "\n")))
+	box.Add(widget.NewLabel(activeText))
+	box.Add(container.NewBorder(nil, nil, nil, useButton, selector))
+	return box
+}
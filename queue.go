@@ -0,0 +1,81 @@
+// queue.go
+// Queued changes - variables created in the UI that are kept in a config file until the user applies them
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// queuedChangesFileName is the config file in the application data directory that holds queued changes
+const queuedChangesFileName = "queued.yaml"
+
+// queuedChangesPath returns the location of the queued changes config
+func queuedChangesPath() (string, error) {
+	return appDataPath(queuedChangesFileName)
+}
+
+// isQueuedChangesFile reports whether filePath is the queued changes config
+func isQueuedChangesFile(filePath string) bool {
+	path, err := queuedChangesPath()
+	return err == nil && strings.EqualFold(path, filePath)
+}
+
+// loadQueuedChanges reads the queued changes, returning an empty config when nothing is queued
+func loadQueuedChanges() (Config, error) {
+	path, err := queuedChangesPath()
+	if err != nil {
+		return Config{}, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return Config{Version: CurrentConfigVersion}, nil
+	}
+	return loadConfig(path)
+}
+
+// queueVariable adds a change to the queue, replacing an earlier queued change of the same variable
+func queueVariable(scope string, v Variable) error {
+	config, err := loadQueuedChanges()
+	if err != nil {
+		return err
+	}
+
+	section := &config.UserVariables
+	if scope == ScopeSystem {
+		section = &config.SystemVariables
+	}
+	replaced := false
+	for i, queued := range *section {
+		if strings.EqualFold(queued.Name, v.Name) {
+			(*section)[i] = v
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		*section = append(*section, v)
+	}
+
+	if config.Metadata == nil {
+		config.Metadata = &ConfigMetadata{Name: "Queued changes", Description: "Variables queued in the application for a later apply"}
+	}
+
+	path, err := queuedChangesPath()
+	if err != nil {
+		return err
+	}
+	return saveConfigToFile(config, path)
+}
+
+// clearQueuedChanges removes all queued changes, typically after they were applied
+func clearQueuedChanges() error {
+	path, err := queuedChangesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear queued changes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// quietapply.go
+// Quiet apply - applies a config without opening the main window and reports the outcome in a toast, for file associations
+package main
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+// quietApplyFlag applies the config given after it, or as --quiet-apply=<file>, without a window
+const quietApplyFlag = "--quiet-apply"
+
+// quietToastAppID is the AppUserModelID toasts are shown under; ours is not registered, PowerShell's always is
+const quietToastAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// MessageBox styles of the summary shown when a toast cannot be shown or the apply failed
+const (
+	mbIconError       = 0x00000010
+	mbIconInformation = 0x00000040
+	mbSetForeground   = 0x00010000
+)
+
+// quietToastScript shows a toast with a title and a text line, both already XML-escaped and quoted for PowerShell
+const quietToastScript = `$ErrorActionPreference = 'Stop'
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml('<toast><visual><binding template="ToastGeneric"><text>%s</text><text>%s</text></binding></visual></toast>')
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('%s').Show([Windows.UI.Notifications.ToastNotification]::new($xml))
+`
+
+// runQuietApply is the entry point of --quiet-apply: it applies the config like an unattended trigger and returns the exit code of the contract
+// Unlike the listener and the logon task a user is at the desktop, so system variables ask for UAC approval when not elevated
+func runQuietApply(path string) int {
+	title := "Environment applied"
+	summary, err := quietApply(path)
+	if err != nil {
+		title = "Environment not applied"
+		reportCommandError("Quiet apply", err)
+	} else {
+		fmt.Println(summary)
+	}
+	showQuietSummary(title, summary, err != nil)
+	return exitCodeFor(err)
+}
+
+// quietApply applies the config and describes the outcome, the error decides the exit code
+func quietApply(path string) (string, error) {
+	name := filepath.Base(path)
+	if path == "" {
+		return fmt.Sprintf("No config given, use %s <file>.", quietApplyFlag), &ValidationError{Err: errors.New("no config given")}
+	}
+	if !isSupportedConfigFile(path) {
+		return fmt.Sprintf("%s is not a supported config file.", name), &ValidationError{Err: fmt.Errorf("%s is not a supported config file", path)}
+	}
+	settings, err := loadSettings()
+	if err != nil {
+		fmt.Printf("Warning: Could not load settings: %v\n", err)
+	}
+	if settings.ReadOnly || readOnlyMode {
+		return fmt.Sprintf("%s was not applied: %v.", name, errReadOnly), errReadOnly
+	}
+	isAdmin, _ := isRunningAsAdmin()
+
+	config, err := applyConfigUnattendedWith(path, isAdmin, true, settings)
+	var applyErrs *ApplyErrors
+	switch {
+	case errors.As(err, &applyErrs):
+		return fmt.Sprintf("%s was applied with %d failed variable(s): %v", name, len(applyErrs.Failures), err), err
+	case err != nil:
+		return fmt.Sprintf("%s was not applied: %v", name, err), err
+	}
+	return fmt.Sprintf("%s: %d user and %d system variable(s) applied. Programs started from now on see the new values.", name, len(config.UserVariables), len(config.SystemVariables)), nil
+}
+
+// showQuietSummary shows the outcome as a toast, failures and toasts that cannot be shown fall back to a message box
+// Failures use a message box right away so they are not missed while the toast fades
+func showQuietSummary(title, summary string, failed bool) {
+	if !failed {
+		if err := showToast(title, summary); err == nil {
+			return
+		}
+	}
+	style := uint32(mbIconInformation | mbSetForeground)
+	if failed {
+		style = mbIconError | mbSetForeground
+	}
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	summaryPtr, _ := syscall.UTF16PtrFromString(summary)
+	windows.MessageBox(0, summaryPtr, titlePtr, style)
+}
+
+// showToast shows a Windows toast through PowerShell and waits until it was handed to the notification center
+func showToast(title, text string) error {
+	quote := func(s string) string {
+		var escaped strings.Builder
+		if err := xml.EscapeText(&escaped, []byte(s)); err != nil {
+			return ""
+		}
+		return strings.ReplaceAll(escaped.String(), "'", "''")
+	}
+	script := fmt.Sprintf(quietToastScript, quote(title), quote(text), quietToastAppID)
+
+	// -EncodedCommand takes the script as base64 of its UTF-16LE bytes, so nothing in it needs command line quoting
+	units := utf16.Encode([]rune(script))
+	encoded := make([]byte, len(units)*2)
+	for i, u := range units {
+		encoded[2*i], encoded[2*i+1] = byte(u), byte(u>>8)
+	}
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-EncodedCommand", base64.StdEncoding.EncodeToString(encoded))
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("toast failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+// regfile.go
+// .reg import - converts regedit exports of the Environment keys into a Config
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// regFileExtension is the file extension of regedit exports
+const regFileExtension = ".reg"
+
+// Registry key paths (upper-cased) that hold environment variables
+const (
+	regUserEnvironmentKey   = `HKEY_CURRENT_USER\ENVIRONMENT`
+	regSystemEnvironmentKey = `\CONTROL\SESSION MANAGER\ENVIRONMENT`
+	regMachineSystemPrefix  = `HKEY_LOCAL_MACHINE\SYSTEM\`
+	regUsersPrefix          = `HKEY_USERS\`
+	regUsersEnvironmentPart = `\ENVIRONMENT`
+)
+
+// isRegFile checks if the provided file path has the .reg extension
+func isRegFile(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), regFileExtension)
+}
+
+// loadRegFile reads a .reg export from disk and converts its environment values into a Config
+func loadRegFile(filePath string) (Config, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading .reg file %s: %w", filePath, err)
+	}
+	return parseRegFile(data)
+}
+
+// parseRegFile converts the contents of a .reg export into a Config
+// Only values below the user and system Environment keys are imported, other keys are ignored
+func parseRegFile(data []byte) (Config, error) {
+	text := decodeRegFileText(data)
+	lines := joinRegContinuationLines(strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n"))
+	if len(lines) == 0 {
+		return Config{}, fmt.Errorf("empty .reg file")
+	}
+
+	// The header decides how hex(2) payloads are encoded
+	header := strings.TrimSpace(lines[0])
+	var unicodeHex bool
+	switch header {
+	case "Windows Registry Editor Version 5.00":
+		unicodeHex = true
+	case "REGEDIT4":
+		unicodeHex = false
+	default:
+		return Config{}, fmt.Errorf("not a registry export: unexpected header %q", header)
+	}
+
+	config := Config{Version: CurrentConfigVersion}
+	var section *[]Variable
+	for lineNo, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		// Key headers select which scope the following values belong to
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = nil
+			key := strings.ToUpper(strings.Trim(line, "[]"))
+			if strings.HasPrefix(key, "-") {
+				continue // Key deletions are not environment operations
+			}
+			switch regKeyScope(key) {
+			case ScopeUser:
+				section = &config.UserVariables
+			case ScopeSystem:
+				section = &config.SystemVariables
+			}
+			continue
+		}
+		if section == nil {
+			continue
+		}
+
+		v, ok, err := parseRegValueLine(line, unicodeHex)
+		if err != nil {
+			return Config{}, fmt.Errorf("line %d: %w", lineNo+2, err)
+		}
+		if ok {
+			*section = append(*section, v)
+		}
+	}
+	return config, nil
+}
+
+// regKeyScope maps an upper-cased registry key path to the environment scope it represents
+func regKeyScope(key string) string {
+	switch {
+	case key == regUserEnvironmentKey:
+		return ScopeUser
+	case strings.HasPrefix(key, regUsersPrefix) && strings.HasSuffix(key, regUsersEnvironmentPart) && strings.Count(key, `\`) == 2:
+		return ScopeUser
+	case strings.HasPrefix(key, regMachineSystemPrefix) && strings.HasSuffix(key, regSystemEnvironmentKey):
+		// CurrentControlSet, ControlSet001, ... all map to the system environment
+		return ScopeSystem
+	default:
+		return ""
+	}
+}
+
+// decodeRegFileText converts file bytes to a string, handling the UTF-16LE encoding regedit uses for version 5 exports
+func decodeRegFileText(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+		return decodeUTF16LE(data[2:])
+	}
+	// Strip a UTF-8 BOM if present
+	return string(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF}))
+}
+
+// decodeUTF16LE converts little-endian UTF-16 bytes to a string, stopping at the first NUL character
+func decodeUTF16LE(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		unit := uint16(data[i]) | uint16(data[i+1])<<8
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return string(utf16.Decode(units))
+}
+
+// joinRegContinuationLines merges values that regedit wraps over several lines with a trailing backslash
+func joinRegContinuationLines(lines []string) []string {
+	var joined []string
+	var current strings.Builder
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		// Only hex payloads are wrapped; quoted strings ending in a backslash are complete values
+		if strings.HasSuffix(trimmed, `\`) && (current.Len() > 0 || isRegHexLine(trimmed)) {
+			current.WriteString(strings.TrimSpace(strings.TrimSuffix(trimmed, `\`)))
+			continue
+		}
+		if current.Len() > 0 {
+			current.WriteString(strings.TrimSpace(trimmed))
+			joined = append(joined, current.String())
+			current.Reset()
+			continue
+		}
+		joined = append(joined, line)
+	}
+	if current.Len() > 0 {
+		joined = append(joined, current.String())
+	}
+	return joined
+}
+
+// isRegHexLine reports whether a value line carries a hex payload
+func isRegHexLine(line string) bool {
+	_, data, ok := splitRegValueLine(line)
+	return ok && strings.HasPrefix(strings.ToLower(data), "hex")
+}
+
+// splitRegValueLine splits `"name"=data` into the unescaped name and the raw data part
+func splitRegValueLine(line string) (name string, data string, ok bool) {
+	if !strings.HasPrefix(line, `"`) {
+		return "", "", false
+	}
+	var sb strings.Builder
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				sb.WriteByte(line[i])
+			}
+		case '"':
+			rest := line[i+1:]
+			if !strings.HasPrefix(rest, "=") {
+				return "", "", false
+			}
+			return sb.String(), rest[1:], true
+		default:
+			sb.WriteByte(line[i])
+		}
+	}
+	return "", "", false
+}
+
+// parseRegValueLine converts a single value line into a Variable
+// ok is false for values that are not environment strings (default values, DWORDs, binary data)
+func parseRegValueLine(line string, unicodeHex bool) (Variable, bool, error) {
+	if strings.HasPrefix(line, "@=") {
+		return Variable{}, false, nil
+	}
+
+	name, data, ok := splitRegValueLine(line)
+	if !ok {
+		return Variable{}, false, fmt.Errorf("malformed value line %q", line)
+	}
+
+	lower := strings.ToLower(data)
+	switch {
+	case data == "-":
+		return Variable{Name: name, Operation: "delete"}, true, nil
+	case strings.HasPrefix(data, `"`):
+		value, err := unquoteRegString(data)
+		if err != nil {
+			return Variable{}, false, fmt.Errorf("value %s: %w", name, err)
+		}
+		return Variable{Name: name, Value: value, Operation: "set", Type: TypeString}, true, nil
+	case strings.HasPrefix(lower, "hex(2):"):
+		value, err := decodeRegHexString(data[len("hex(2):"):], unicodeHex)
+		if err != nil {
+			return Variable{}, false, fmt.Errorf("value %s: %w", name, err)
+		}
+		return Variable{Name: name, Value: value, Operation: "set", Type: TypeExpand}, true, nil
+	case strings.HasPrefix(lower, "hex(1):"):
+		value, err := decodeRegHexString(data[len("hex(1):"):], unicodeHex)
+		if err != nil {
+			return Variable{}, false, fmt.Errorf("value %s: %w", name, err)
+		}
+		return Variable{Name: name, Value: value, Operation: "set", Type: TypeString}, true, nil
+	default:
+		fmt.Printf("  Skipping %s: unsupported registry value type for an environment variable\n", name)
+		return Variable{}, false, nil
+	}
+}
+
+// unquoteRegString decodes a quoted .reg string, where only \\ and \" are escaped
+func unquoteRegString(data string) (string, error) {
+	if len(data) < 2 || !strings.HasSuffix(data, `"`) {
+		return "", fmt.Errorf("unterminated string %s", data)
+	}
+	inner := data[1 : len(data)-1]
+	var sb strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) {
+			i++
+		}
+		sb.WriteByte(inner[i])
+	}
+	return sb.String(), nil
+}
+
+// decodeRegHexString decodes a comma-separated hex payload into a string
+// Version 5 exports store UTF-16LE code units, REGEDIT4 exports store single-byte characters
+func decodeRegHexString(payload string, unicodeHex bool) (string, error) {
+	payload = strings.NewReplacer(",", "", " ", "", "\t", "").Replace(payload)
+	raw, err := hex.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex data: %w", err)
+	}
+	if unicodeHex {
+		return decodeUTF16LE(raw), nil
+	}
+	if i := bytes.IndexByte(raw, 0); i >= 0 {
+		raw = raw[:i]
+	}
+	// Map each byte to the code point of the same value (Latin-1) instead of interpreting it as UTF-8
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes), nil
+}
@@ -0,0 +1,233 @@
+// remote.go
+// Remote configs - configs loaded from http(s) URLs, cached locally with an offline fallback to the last good copy
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// remoteCacheDirName is the directory below the application data directory holding downloaded configs
+const remoteCacheDirName = "remote-cache"
+
+// remoteFetchTimeout bounds how long a download may take before the cached copy is used
+const remoteFetchTimeout = 15 * time.Second
+
+// remoteStaleAfter is the age after which a cached copy that could not be refreshed is reported as stale
+const remoteStaleAfter = 24 * time.Hour
+
+// remoteCacheMeta is stored next to each cached config to make conditional requests
+type remoteCacheMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"` // Last time the server confirmed the cached copy is current
+}
+
+// remoteFetchStatus describes the outcome of the last load of a remote config
+type remoteFetchStatus struct {
+	CheckedAt time.Time // Last time the server confirmed the copy in use
+	Offline   bool      // The server could not be reached and the cached copy was used
+	Err       error     // Why the server could not be reached
+}
+
+var (
+	remoteStatusMu sync.Mutex
+	remoteStatuses = map[string]remoteFetchStatus{}
+)
+
+// isRemoteConfig reports whether a config path is an http(s) URL
+func isRemoteConfig(filePath string) bool {
+	lower := strings.ToLower(filePath)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// remoteCachePaths returns the cached config and metadata paths for a URL
+// The cached file keeps the URL's extension so archives and .reg exports are loaded like local files
+func remoteCachePaths(rawURL string) (string, string, error) {
+	base, err := appDataDir()
+	if err != nil {
+		return "", "", err
+	}
+	dir := filepath.Join(base, remoteCacheDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create remote config cache %s: %w", dir, err)
+	}
+
+	ext := ".yaml"
+	if u, err := url.Parse(rawURL); err == nil && isSupportedConfigFile(u.Path) {
+		ext = strings.ToLower(path.Ext(u.Path))
+		if isEncryptedConfig(u.Path) {
+			ext = encryptedConfigExtension
+		}
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	name := hex.EncodeToString(sum[:8])
+	return filepath.Join(dir, name+ext), filepath.Join(dir, name+".meta.json"), nil
+}
+
+// fetchRemoteConfig downloads a remote config into the cache and returns the path of the cached copy
+// When the server is unreachable or fails, the last good copy is used and the status records it as offline
+func fetchRemoteConfig(rawURL string) (string, error) {
+	cachePath, metaPath, err := remoteCachePaths(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	var meta remoteCacheMeta
+	if data, err := ioutil.ReadFile(metaPath); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	_, cacheErr := os.Stat(cachePath)
+	hasCache := cacheErr == nil
+
+	fetchErr := downloadRemoteConfig(rawURL, cachePath, hasCache, &meta)
+	if fetchErr != nil {
+		if !hasCache {
+			return "", fmt.Errorf("error downloading %s: %w", rawURL, fetchErr)
+		}
+		fmt.Printf("Warning: Could not refresh %s, using cached copy: %v\n", rawURL, fetchErr)
+		setRemoteStatus(rawURL, remoteFetchStatus{CheckedAt: meta.CheckedAt, Offline: true, Err: fetchErr})
+		return cachePath, nil
+	}
+
+	meta.URL = rawURL
+	meta.CheckedAt = time.Now()
+	if data, err := json.MarshalIndent(meta, "", "  "); err == nil {
+		ioutil.WriteFile(metaPath, data, 0644)
+	}
+	setRemoteStatus(rawURL, remoteFetchStatus{CheckedAt: meta.CheckedAt})
+	return cachePath, nil
+}
+
+// downloadRemoteConfig performs a conditional GET, writing the body to cachePath unless the server reports it unchanged
+func downloadRemoteConfig(rawURL, cachePath string, hasCache bool, meta *remoteCacheMeta) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if hasCache {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	client := &http.Client{Timeout: remoteFetchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && hasCache:
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	// Write to a temporary file first so a failed write never replaces the last good copy
+	tmpPath := cachePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, body, 0644); err != nil {
+		return fmt.Errorf("error caching config: %w", err)
+	}
+	// A captive portal page or a truncated response must not replace the last good copy either
+	if err := validateRemoteConfig(body, tmpPath, cachePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("server returned an invalid config: %w", err)
+	}
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		return fmt.Errorf("error caching config: %w", err)
+	}
+	meta.ETag = resp.Header.Get("ETag")
+	meta.LastModified = resp.Header.Get("Last-Modified")
+	return nil
+}
+
+// validateRemoteConfig parses a downloaded config, written to tmpPath, in the format of its cache file
+// Encrypted configs can only be checked for their header, the passphrase is asked for when they are loaded
+func validateRemoteConfig(body []byte, tmpPath, cachePath string) error {
+	var err error
+	switch {
+	case isEncryptedConfig(cachePath):
+		if !bytes.HasPrefix(body, encryptedConfigMagic) && !bytes.HasPrefix(body, legacyEncryptedConfigMagic) {
+			err = fmt.Errorf("not an encrypted config")
+		}
+	case isBackupArchive(cachePath):
+		_, err = loadBackupArchive(tmpPath)
+	case isRegFile(cachePath):
+		_, err = parseRegFile(body)
+	case isCSVFile(cachePath):
+		_, err = loadCSVFile(tmpPath)
+	default:
+		_, err = parseConfig(body)
+	}
+	return err
+}
+
+// setRemoteStatus records the outcome of loading a remote config
+func setRemoteStatus(rawURL string, status remoteFetchStatus) {
+	remoteStatusMu.Lock()
+	defer remoteStatusMu.Unlock()
+	remoteStatuses[rawURL] = status
+}
+
+// describeRemoteStatus returns a one-line freshness description of a remote config for the UI
+func describeRemoteStatus(rawURL string) string {
+	remoteStatusMu.Lock()
+	status, ok := remoteStatuses[rawURL]
+	remoteStatusMu.Unlock()
+	if !ok {
+		return "Remote config: not loaded yet"
+	}
+
+	checked := "never"
+	if !status.CheckedAt.IsZero() {
+		checked = status.CheckedAt.Format("2006-01-02 15:04")
+	}
+	if !status.Offline {
+		return fmt.Sprintf("Remote config: up to date (checked %s)", checked)
+	}
+	if status.CheckedAt.IsZero() || time.Since(status.CheckedAt) > remoteStaleAfter {
+		return fmt.Sprintf("⚠️  STALE remote config: server unreachable (%v), using cached copy last confirmed %s", status.Err, checked)
+	}
+	return fmt.Sprintf("⚠️  Offline: server unreachable (%v), using cached copy last confirmed %s", status.Err, checked)
+}
+
+// resolveConfigReference resolves a path named inside a config relative to the config's own location
+func resolveConfigReference(base, ref string) string {
+	if isRemoteConfig(ref) || (!isRemoteConfig(base) && filepath.IsAbs(ref)) {
+		return ref
+	}
+	if isRemoteConfig(base) {
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return ref
+		}
+		refURL, err := url.Parse(filepath.ToSlash(ref))
+		if err != nil {
+			return ref
+		}
+		return baseURL.ResolveReference(refURL).String()
+	}
+	return filepath.Clean(filepath.Join(filepath.Dir(base), ref))
+}
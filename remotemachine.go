@@ -0,0 +1,225 @@
+// remotemachine.go
+// Remote machine export - reads another machine's environment over the remote registry and saves or compares it
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	sqweekdialog "github.com/sqweek/dialog"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// remoteProfile is a user whose profile is loaded on the remote machine, i.e. who is signed in
+type remoteProfile struct {
+	SID  string
+	Name string // DOMAIN\user, or the SID when it cannot be resolved
+}
+
+// remoteHostName strips the backslashes and spaces users type around a host name
+func remoteHostName(host string) string {
+	return strings.TrimLeft(strings.TrimSpace(host), `\`)
+}
+
+// listRemoteProfiles returns the users with a loaded profile on host; their environment lives in HKEY_USERS\<SID>
+func listRemoteProfiles(host string, retry RetrySettings) ([]remoteProfile, error) {
+	users, err := openRemoteKey(host, registry.USERS, retry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the remote registry of %s: %w", host, err)
+	}
+	defer users.Close()
+	sids, err := users.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the users of %s: %w", host, err)
+	}
+
+	var profiles []remoteProfile
+	for _, sid := range sids {
+		// Local and domain accounts start with S-1-5-21, the _Classes keys belong to the same users
+		if !strings.HasPrefix(sid, "S-1-5-21-") || strings.HasSuffix(sid, "_Classes") {
+			continue
+		}
+		profile := remoteProfile{SID: sid, Name: sid}
+		if parsed, err := windows.StringToSid(sid); err == nil {
+			if account, domain, _, err := parsed.LookupAccount(host); err == nil {
+				profile.Name = domain + `\` + account
+			}
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// exportRemoteEnvironment reads the system environment of host and, when sid is set, the environment of that user
+func exportRemoteEnvironment(host, sid string, retry RetrySettings) (Config, error) {
+	config := Config{Version: CurrentConfigVersion}
+	config.Metadata = &ConfigMetadata{
+		Name:        fmt.Sprintf("Environment export from %s", host),
+		Author:      os.Getenv("USERNAME"),
+		Created:     time.Now().Format(time.RFC3339),
+		TargetHosts: []string{host},
+	}
+
+	machine, err := openRemoteKey(host, registry.LOCAL_MACHINE, retry)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to connect to the remote registry of %s: %w", host, err)
+	}
+	defer machine.Close()
+	if config.SystemVariables, err = readVariablesFromRegistry(machine, systemEnvironmentPath); err != nil {
+		return Config{}, fmt.Errorf("failed to read the system environment of %s: %w", host, err)
+	}
+
+	if sid != "" {
+		users, err := openRemoteKey(host, registry.USERS, retry)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to connect to the remote registry of %s: %w", host, err)
+		}
+		defer users.Close()
+		if config.UserVariables, err = readVariablesFromRegistry(users, sid+`\`+userEnvironmentPath); err != nil {
+			return Config{}, fmt.Errorf("failed to read the user environment of %s on %s: %w", sid, host, err)
+		}
+		config.Metadata.Description = fmt.Sprintf("User variables of %s", sid)
+	}
+	return config, nil
+}
+
+// showRemoteExportWindow connects to another machine, then exports its environment to YAML or compares it with this machine
+func showRemoteExportWindow(settings *Settings, isAdmin bool) {
+	window := fyne.CurrentApp().NewWindow("Export Remote Machine")
+	window.Resize(fyne.NewSize(760, 520))
+
+	hostEntry := widget.NewEntry()
+	hostEntry.SetPlaceHolder("Host name or IP address, e.g. LAB-PC-07")
+	var profiles []remoteProfile
+	userSelect := widget.NewSelect(nil, nil)
+	userSelect.PlaceHolder = "Connect to list the signed-in users"
+	statusLabel := widget.NewLabel("Needs the Remote Registry service on the remote machine and an account that is administrator there.")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	resultLabel := widget.NewLabel("")
+	resultLabel.Wrapping = fyne.TextWrapBreak
+
+	connectButton := widget.NewButton("Connect", func() {
+		host := remoteHostName(hostEntry.Text)
+		if host == "" {
+			return
+		}
+		statusLabel.SetText(fmt.Sprintf("Connecting to %s...", host))
+		go func() {
+			found, err := listRemoteProfiles(host, settings.Retry)
+			if err != nil {
+				statusLabel.SetText(fmt.Sprintf("⚠️  %v", err))
+				return
+			}
+			profiles = found
+			options := []string{"System variables only"}
+			for _, p := range profiles {
+				options = append(options, p.Name)
+			}
+			userSelect.Options = options
+			userSelect.SetSelectedIndex(0)
+			statusLabel.SetText(fmt.Sprintf("Connected to %s, %d signed-in user(s). Only users with a loaded profile can be read.", host, len(profiles)))
+		}()
+	})
+
+	// load reads the remote environment for the chosen user
+	load := func() (string, Config, error) {
+		host := remoteHostName(hostEntry.Text)
+		if host == "" || userSelect.SelectedIndex() < 0 {
+			return host, Config{}, fmt.Errorf("connect to a machine first")
+		}
+		sid := ""
+		if i := userSelect.SelectedIndex(); i > 0 {
+			sid = profiles[i-1].SID
+		}
+		config, err := exportRemoteEnvironment(host, sid, settings.Retry)
+		return host, config, err
+	}
+
+	exportButton := widget.NewButton("Export to YAML...", func() {
+		backgroundJobs.submit(jobKindRemote, "Export remote machine "+remoteHostName(hostEntry.Text), func(ctx context.Context) error {
+			host, config, err := load()
+			if err != nil {
+				dialog.ShowError(err, window)
+				return err
+			}
+			savePath, err := sqweekdialog.File().Title("Export "+host).Filter("YAML Config", "yaml", "yml").Save()
+			if err != nil {
+				if err.Error() != "cancelled" {
+					dialog.ShowError(fmt.Errorf("error saving file: %v", err), window)
+				}
+				return fileDialogOutcome(err)
+			}
+			if settings.RedactOnExport {
+				config.UserVariables = redactSensitiveVariables(config.UserVariables, settings.SensitivePatterns)
+				config.SystemVariables = redactSensitiveVariables(config.SystemVariables, settings.SensitivePatterns)
+			}
+			if settings.PortableExport {
+				config.UserVariables = portableUserPaths(config.UserVariables)
+			}
+			if !strings.HasSuffix(strings.ToLower(savePath), ".yaml") && !strings.HasSuffix(strings.ToLower(savePath), ".yml") {
+				savePath += ".yaml"
+			}
+			if err := saveConfigToFile(config, savePath); err != nil {
+				dialog.ShowError(fmt.Errorf("error writing config to file: %v", err), window)
+				return err
+			}
+			statusLabel.SetText(fmt.Sprintf("Environment of %s exported to: %s", host, savePath))
+			return nil
+		})
+	})
+
+	// Compare with this machine's export, the remote machine is the new side
+	compareButton := widget.NewButton("Compare with This Machine", func() {
+		backgroundJobs.submit(jobKindRemote, "Compare with remote machine "+remoteHostName(hostEntry.Text), func(ctx context.Context) error {
+			host, remote, err := load()
+			if err != nil {
+				dialog.ShowError(err, window)
+				return err
+			}
+			local, err := exportEnvironmentVariables(isAdmin)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error exporting variables: %v", err), window)
+				return err
+			}
+			if userSelect.SelectedIndex() == 0 {
+				local.UserVariables = nil
+			}
+			if !isAdmin {
+				remote.SystemVariables = nil
+			}
+			lines := describeChanges(diffConfigs(local, remote), settings.SensitivePatterns)
+			if len(lines) == 0 {
+				resultLabel.SetText(fmt.Sprintf("%s has the same variables as this machine.", host))
+				return nil
+			}
+			header := fmt.Sprintf("%d difference(s), \"added\" means only %s has the variable:", len(lines), host)
+			if !isAdmin {
+				header += "\nSystem variables are not compared because this machine's system environment is only exported as administrator."
+			}
+			resultLabel.SetText(header + "\n\n" + strings.Join(lines, "\n"))
+			return nil
+		})
+	})
+
+	window.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewForm(
+				widget.NewFormItem("Machine", container.NewBorder(nil, nil, nil, connectButton, hostEntry)),
+				widget.NewFormItem("User", userSelect),
+			),
+			statusLabel,
+		),
+		container.NewHBox(exportButton, compareButton, widget.NewButton("Close", window.Close)),
+		nil, nil,
+		container.NewVScroll(resultLabel),
+	))
+	window.Show()
+}
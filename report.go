@@ -0,0 +1,214 @@
+// report.go
+// Environment reports - renders an environment or a config, with what applying it would change here, as HTML or Markdown
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"time"
+)
+
+// htmlReportExportFormat renders a standalone HTML report
+var htmlReportExportFormat = exportFormat{
+	Name:        "HTML report",
+	Description: "Formatted document for change tickets and handover docs: all variables plus the differences from this machine's environment. Sensitive values are masked.",
+	Extension:   ".html",
+	Render:      renderHTMLReport,
+	KeepSecrets: true, // Masked by the report itself, redacted placeholders would show up as changes
+}
+
+// markdownReportExportFormat renders a Markdown report
+var markdownReportExportFormat = exportFormat{
+	Name:        "Markdown report",
+	Description: "Markdown version of the HTML report for wikis, pull requests and tickets. Sensitive values are masked.",
+	Extension:   ".md",
+	Render:      renderMarkdownReport,
+	KeepSecrets: true,
+}
+
+// Change column values of the report
+const (
+	reportUnchanged  = "unchanged"
+	reportNew        = "new"
+	reportChanged    = "changed"
+	reportDeleted    = "deleted"
+	reportNotPresent = "already absent"
+)
+
+// reportTable is a titled table of the report
+type reportTable struct {
+	Title   string
+	Headers []string
+	Rows    [][]string
+	Empty   string // Shown instead of an empty table
+	Changes bool   // Rows are highlighted by their Change column, the third one
+}
+
+// environmentReport is the content shared by the HTML and Markdown renderings
+type environmentReport struct {
+	Title  string
+	Facts  []string // Generation time, machine and config metadata
+	Tables []reportTable
+}
+
+// reportValue returns the value shown in the report, masked for sensitive variables
+func reportValue(v Variable) string {
+	if v.Sensitive && v.Value != "" {
+		return maskedValue
+	}
+	return v.Value
+}
+
+// buildEnvironmentReport collects the variables of config and how they compare with the registry
+func buildEnvironmentReport(config Config) environmentReport {
+	report := environmentReport{Title: exportName(config, "Environment report")}
+	host, _ := os.Hostname()
+	report.Facts = append(report.Facts,
+		fmt.Sprintf("Generated: %s", time.Now().Format("2006-01-02 15:04:05")),
+		fmt.Sprintf("Machine: %s, user %s", host, os.Getenv("USERNAME")),
+	)
+	report.Facts = append(report.Facts, config.Metadata.describe()...)
+
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		report.Facts = append(report.Facts, fmt.Sprintf("⚠️  Could not read current values: %v", err))
+	}
+
+	changes := reportTable{
+		Title:   "Differences from this machine",
+		Headers: []string{"Scope", "Name", "Change", "Current", "Config"},
+		Empty:   "None, applying this on the machine above changes nothing.",
+		Changes: true,
+	}
+	counts := map[string]int{}
+	section := func(scope string, variables []Variable) reportTable {
+		table := reportTable{Title: strings.ToUpper(scope[:1]) + scope[1:] + " variables", Headers: []string{"Name", "Type", "Value"}, Empty: "None."}
+		for _, v := range variables {
+			if v.Operation != "set" && v.Operation != "delete" {
+				continue
+			}
+			existing, exists := current.lookup(scope, v.Name)
+			existing.Sensitive = v.Sensitive
+			kind := reportUnchanged
+			switch {
+			case v.Operation == "delete" && exists:
+				kind = reportDeleted
+			case v.Operation == "delete":
+				kind = reportNotPresent
+			case !exists:
+				kind = reportNew
+			case existing.Value != v.Value || existing.typeLabel() != v.typeLabel():
+				kind = reportChanged
+			}
+			counts[kind]++
+
+			value := reportValue(v)
+			if v.Operation == "delete" {
+				value = previewDeleted
+			}
+			table.Rows = append(table.Rows, []string{v.Name, v.typeLabel(), value})
+			if kind == reportNew || kind == reportChanged || kind == reportDeleted {
+				currentValue := ""
+				if exists {
+					currentValue = reportValue(existing.Variable)
+				}
+				changes.Rows = append(changes.Rows, []string{scope, v.Name, kind, currentValue, value})
+			}
+		}
+		return table
+	}
+	user := section(ScopeUser, config.UserVariables)
+	system := section(ScopeSystem, config.SystemVariables)
+
+	report.Facts = append(report.Facts, fmt.Sprintf("Variables: %d user, %d system; %d new, %d changed, %d deleted, %d unchanged",
+		len(user.Rows), len(system.Rows), counts[reportNew], counts[reportChanged], counts[reportDeleted], counts[reportUnchanged]))
+	report.Tables = []reportTable{changes, user, system}
+	return report
+}
+
+// renderMarkdownReport renders config as a Markdown report
+func renderMarkdownReport(config Config) ([]byte, error) {
+	report := buildEnvironmentReport(config)
+	cell := func(s string) string {
+		s = strings.ReplaceAll(s, "|", `\|`)
+		return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "<br>"), "\n", "<br>")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", report.Title)
+	for _, fact := range report.Facts {
+		fmt.Fprintf(&sb, "- %s\n", fact)
+	}
+	for _, table := range report.Tables {
+		fmt.Fprintf(&sb, "\n## %s\n\n", table.Title)
+		if len(table.Rows) == 0 {
+			fmt.Fprintf(&sb, "%s\n", table.Empty)
+			continue
+		}
+		fmt.Fprintf(&sb, "| %s |\n|%s\n", strings.Join(table.Headers, " | "), strings.Repeat(" --- |", len(table.Headers)))
+		for _, row := range table.Rows {
+			cells := make([]string, len(row))
+			for i, value := range row {
+				cells[i] = cell(value)
+			}
+			fmt.Fprintf(&sb, "| %s |\n", strings.Join(cells, " | "))
+		}
+	}
+	sb.WriteString("\n_Generated by System Variable Manager_\n")
+	return []byte(sb.String()), nil
+}
+
+// reportStyle keeps the HTML report readable when printed or attached without external files
+const reportStyle = `body { font-family: "Segoe UI", sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+td { font-family: Consolas, monospace; word-break: break-all; }
+tr.new td { background: #e8f6e8; }
+tr.changed td { background: #fff6dc; }
+tr.deleted td { background: #fbe4e4; }`
+
+// renderHTMLReport renders config as a standalone HTML report
+func renderHTMLReport(config Config) ([]byte, error) {
+	report := buildEnvironmentReport(config)
+	// PATH-like values may break after each separator
+	cell := func(s string) string {
+		return strings.ReplaceAll(strings.ReplaceAll(html.EscapeString(s), ";", ";<wbr>"), "\n", "<br>")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", html.EscapeString(report.Title), reportStyle)
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n<ul>\n", html.EscapeString(report.Title))
+	for _, fact := range report.Facts {
+		fmt.Fprintf(&sb, "<li>%s</li>\n", html.EscapeString(fact))
+	}
+	sb.WriteString("</ul>\n")
+	for _, table := range report.Tables {
+		fmt.Fprintf(&sb, "<h2>%s</h2>\n", html.EscapeString(table.Title))
+		if len(table.Rows) == 0 {
+			fmt.Fprintf(&sb, "<p>%s</p>\n", html.EscapeString(table.Empty))
+			continue
+		}
+		sb.WriteString("<table>\n<tr>")
+		for _, header := range table.Headers {
+			fmt.Fprintf(&sb, "<th>%s</th>", html.EscapeString(header))
+		}
+		sb.WriteString("</tr>\n")
+		for _, row := range table.Rows {
+			class := ""
+			if table.Changes {
+				class = fmt.Sprintf(" class=\"%s\"", row[2])
+			}
+			fmt.Fprintf(&sb, "<tr%s>", class)
+			for _, value := range row {
+				fmt.Fprintf(&sb, "<td>%s</td>", cell(value))
+			}
+			sb.WriteString("</tr>\n")
+		}
+		sb.WriteString("</table>\n")
+	}
+	sb.WriteString("<p><em>Generated by System Variable Manager</em></p>\n</body>\n</html>\n")
+	return []byte(sb.String()), nil
+}
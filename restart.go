@@ -0,0 +1,291 @@
+// restart.go
+// Restart advisor - lists running shells, terminals and IDEs that started before an apply and restarts the selected ones
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows"
+)
+
+// restartHints names the programs the advisor looks for and what restarting them involves
+var restartHints = map[string]string{
+	"cmd.exe":             "Command Prompt, restart it or use \"Refresh Running Consoles\"",
+	"powershell.exe":      "Windows PowerShell, restart it or use \"Refresh Running Consoles\"",
+	"pwsh.exe":            "PowerShell, restart it or use \"Refresh Running Consoles\"",
+	"windowsterminal.exe": "Windows Terminal, new tabs inherit from it, so every tab has to be closed",
+	"code.exe":            "VS Code, its integrated terminals and extensions inherit from it, so the whole window has to be restarted",
+	"explorer.exe":        "Explorer, programs started from the Start menu and the taskbar inherit from it",
+}
+
+// shellRestartDelay is how long a terminated shell is given to be restarted by Windows before it is started again
+const shellRestartDelay = 2 * time.Second
+
+// lastAppliedChange remembers the last config applied in this session and when, for the restart advisor
+var lastAppliedChange struct {
+	mu     sync.Mutex
+	config Config
+	at     time.Time
+}
+
+// recordAppliedChange remembers a successful apply for the restart advisor
+func recordAppliedChange(config Config) {
+	lastAppliedChange.mu.Lock()
+	defer lastAppliedChange.mu.Unlock()
+	lastAppliedChange.config, lastAppliedChange.at = config, time.Now()
+}
+
+// restartCandidate is a running program that started before the change
+type restartCandidate struct {
+	PID         uint32
+	Name        string
+	Path        string
+	CommandLine string
+	Directory   string
+	Started     time.Time
+	Hint        string
+	Stale       []string // Variables whose old value the process still holds, empty when its environment could not be read
+}
+
+// listRestartCandidates finds the programs of this session that started before since and still hold an old value of the config
+// Child processes of the same program, such as the helper processes of VS Code, are left out; restarting the main one restarts them
+func listRestartCandidates(config Config, since time.Time) ([]restartCandidate, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot processes: %w", err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	names := map[uint32]string{}
+	parents := map[uint32]uint32{}
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		names[entry.ProcessID] = windows.UTF16ToString(entry.ExeFile[:])
+		parents[entry.ProcessID] = entry.ParentProcessID
+	}
+
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		return nil, err
+	}
+	var session uint32
+	windows.ProcessIdToSessionId(windows.GetCurrentProcessId(), &session)
+
+	var candidates []restartCandidate
+	for pid, name := range names {
+		hint, watched := restartHints[strings.ToLower(name)]
+		if !watched || strings.EqualFold(names[parents[pid]], name) {
+			continue
+		}
+		var processSession uint32
+		if windows.ProcessIdToSessionId(pid, &processSession) != nil || processSession != session {
+			continue
+		}
+		candidate, ok := inspectRestartCandidate(pid, since)
+		if !ok {
+			continue
+		}
+		candidate.Name, candidate.Hint = name, hint
+		if env, err := processEnvironment(pid); err == nil {
+			_, mismatches := compareAppliedEnvironment(config, env, current)
+			if len(mismatches) == 0 {
+				continue // Already has the new values, for example Explorer after the broadcast
+			}
+			for _, m := range mismatches {
+				candidate.Stale = append(candidate.Stale, m.Name)
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !strings.EqualFold(candidates[i].Name, candidates[j].Name) {
+			return strings.ToLower(candidates[i].Name) < strings.ToLower(candidates[j].Name)
+		}
+		return candidates[i].Started.Before(candidates[j].Started)
+	})
+	return candidates, nil
+}
+
+// inspectRestartCandidate reads the start time, command line and working directory of a process
+// ok is false when the process cannot be opened or started after since
+func inspectRestartCandidate(pid uint32, since time.Time) (restartCandidate, bool) {
+	process, err := windows.OpenProcess(processQueryLimitInfo, false, pid)
+	if err != nil {
+		return restartCandidate{}, false
+	}
+	var creation, exit, kernel, user windows.Filetime
+	err = windows.GetProcessTimes(process, &creation, &exit, &kernel, &user)
+	windows.CloseHandle(process)
+	if err != nil {
+		return restartCandidate{}, false
+	}
+	started := time.Unix(0, creation.Nanoseconds())
+	if !started.Before(since) {
+		return restartCandidate{}, false
+	}
+
+	candidate := restartCandidate{PID: pid, Path: processImagePath(pid), Started: started}
+	if process, err := windows.OpenProcess(processReadAccess, false, pid); err == nil {
+		var params windows.RTL_USER_PROCESS_PARAMETERS
+		if address, err := processParametersAddress(process, pid); err == nil {
+			candidate.CommandLine, _ = readProcessUnicodeString(process, address+unsafe.Offsetof(params.CommandLine))
+			candidate.Directory, _ = readProcessUnicodeString(process, address+unsafe.Offsetof(params.CurrentDirectory))
+		}
+		windows.CloseHandle(process)
+	}
+	return candidate, true
+}
+
+// label describes the candidate for the advisor list
+func (c restartCandidate) label() string {
+	label := fmt.Sprintf("%s (PID %d, started %s) - needs restart: %s", c.Name, c.PID, c.Started.Format("15:04:05"), c.Hint)
+	if len(c.Stale) > 0 {
+		label += fmt.Sprintf("\n    old values: %s", strings.Join(c.Stale, ", "))
+	} else {
+		label += "\n    started before the change, its environment could not be read"
+	}
+	return label
+}
+
+// restartProcess terminates a program and starts it again with the fresh environment, its command line and working directory
+// The shell is usually restarted by Windows itself, it is only started again when no shell appeared after a short wait
+func restartProcess(c restartCandidate) error {
+	if c.Path == "" {
+		return fmt.Errorf("the executable of %s (PID %d) is not accessible", c.Name, c.PID)
+	}
+	env, err := freshEnvironment()
+	if err != nil {
+		return err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_TERMINATE, false, c.PID)
+	if err != nil {
+		return fmt.Errorf("cannot open %s (PID %d): %w", c.Name, c.PID, err)
+	}
+	err = windows.TerminateProcess(process, 0)
+	windows.CloseHandle(process)
+	if err != nil {
+		return fmt.Errorf("cannot end %s (PID %d): %w", c.Name, c.PID, err)
+	}
+
+	if strings.EqualFold(c.Name, "explorer.exe") {
+		time.Sleep(shellRestartDelay)
+		if windows.GetShellWindow() != 0 {
+			return nil
+		}
+	}
+	commandLine := c.CommandLine
+	if commandLine == "" {
+		commandLine = quoteCommandArgument(c.Path)
+	}
+	cmd := exec.Command(c.Path)
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: commandLine, CreationFlags: windows.CREATE_NEW_CONSOLE}
+	cmd.Env = env
+	cmd.Dir = c.Directory
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ended %s (PID %d) but could not start it again: %w", c.Name, c.PID, err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// showRestartAdvisor lists the programs that started before the last apply of this session and restarts the selected ones
+func showRestartAdvisor(app fyne.App) {
+	advisorWindow := app.NewWindow("Restart Advisor")
+	advisorWindow.Resize(fyne.NewSize(750, 450))
+
+	lastAppliedChange.mu.Lock()
+	config, at := lastAppliedChange.config, lastAppliedChange.at
+	lastAppliedChange.mu.Unlock()
+
+	headerLabel := widget.NewLabel("")
+	headerLabel.Wrapping = fyne.TextWrapWord
+	resultLabel := widget.NewLabel("")
+	resultLabel.Wrapping = fyne.TextWrapWord
+	checks := container.NewVBox()
+	selected := map[uint32]bool{}
+	var candidates []restartCandidate
+
+	reload := func() {
+		checks.Objects = nil
+		selected = map[uint32]bool{}
+		if at.IsZero() {
+			headerLabel.SetText("No config was applied in this session yet. Apply one to see which running programs still need the new values.")
+			checks.Refresh()
+			return
+		}
+		var err error
+		candidates, err = listRestartCandidates(config, at)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("error listing running programs: %v", err), advisorWindow)
+		}
+		headerLabel.SetText(fmt.Sprintf("Programs started before the apply at %s that still have the old values. Ending a program loses its unsaved work.", at.Format("15:04:05")))
+		for _, c := range candidates {
+			c := c
+			checks.Add(widget.NewCheck(c.label(), func(checked bool) { selected[c.PID] = checked }))
+		}
+		if len(candidates) == 0 {
+			checks.Add(widget.NewLabel("Every running shell, terminal and IDE already has the new values or was started after the apply."))
+		}
+		checks.Refresh()
+	}
+
+	restartButton := widget.NewButton("Restart Selected", func() {
+		var chosen []restartCandidate
+		for _, c := range candidates {
+			if selected[c.PID] {
+				chosen = append(chosen, c)
+			}
+		}
+		if len(chosen) == 0 {
+			resultLabel.SetText("No programs selected.")
+			return
+		}
+		dialog.ShowConfirm("Restart Programs", fmt.Sprintf("End and restart %d program(s)? Unsaved work in them is lost.", len(chosen)), func(ok bool) {
+			if !ok {
+				return
+			}
+			go func() {
+				var results []string
+				for _, c := range chosen {
+					if err := restartProcess(c); err != nil {
+						results = append(results, err.Error())
+					} else {
+						results = append(results, fmt.Sprintf("%s (PID %d): restarted", c.Name, c.PID))
+					}
+				}
+				resultLabel.SetText(strings.Join(results, "\n"))
+				reload()
+			}()
+		}, advisorWindow)
+	})
+	hideInReadOnly(restartButton)
+	refreshButton := widget.NewButton("Refresh", reload)
+	closeButton := widget.NewButton("Close", func() {
+		advisorWindow.Close()
+	})
+
+	reload()
+	advisorWindow.SetContent(container.NewBorder(
+		container.NewVBox(headerLabel, widget.NewSeparator()),
+		container.NewVBox(
+			widget.NewSeparator(),
+			resultLabel,
+			container.NewHBox(restartButton, refreshButton, closeButton),
+		),
+		nil, nil,
+		container.NewScroll(checks),
+	))
+	advisorWindow.Show()
+}
@@ -0,0 +1,120 @@
+// retry.go
+// Retry policy - repeats remote registry connections and broadcasts that fail for transient reasons, with backoff
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Defaults of the retry policy
+const (
+	defaultRetryAttempts = 3
+	defaultRetryDelayMs  = 500
+	maxRetryAttempts     = 10
+)
+
+// maxRetryDelay caps the doubled delay between two attempts
+const maxRetryDelay = 30 * time.Second
+
+// transientErrors are the Windows errors worth another attempt: network hiccups, a busy or starting RPC server and timeouts
+var transientErrors = []syscall.Errno{
+	windows.ERROR_BAD_NETPATH, // Also returned while the Remote Registry service is still starting
+	windows.ERROR_NETWORK_BUSY,
+	windows.ERROR_UNEXP_NET_ERR,
+	windows.ERROR_BAD_NET_RESP,
+	windows.ERROR_NETNAME_DELETED,
+	windows.ERROR_SEM_TIMEOUT,
+	windows.ERROR_TIMEOUT,
+	windows.ERROR_BUSY,
+	windows.RPC_S_SERVER_UNAVAILABLE,
+	windows.RPC_S_SERVER_TOO_BUSY,
+	windows.RPC_S_CALL_FAILED,
+	windows.RPC_S_CALL_FAILED_DNE,
+}
+
+// RetryError is returned when every attempt of an operation failed, it lists the error of each attempt
+type RetryError struct {
+	Operation string
+	Attempts  []error
+}
+
+func (e *RetryError) Error() string {
+	if len(e.Attempts) == 1 {
+		return fmt.Sprintf("%s: %v", e.Operation, e.Attempts[0])
+	}
+	parts := make([]string, len(e.Attempts))
+	for i, err := range e.Attempts {
+		parts[i] = fmt.Sprintf("attempt %d: %v", i+1, err)
+	}
+	return fmt.Sprintf("%s failed after %d attempts (%s)", e.Operation, len(e.Attempts), strings.Join(parts, "; "))
+}
+
+// Unwrap returns the error of the last attempt so errors.Is sees the final cause
+func (e *RetryError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1]
+}
+
+// isTransientError reports whether err may go away when the operation is repeated
+func isTransientError(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	for _, transient := range transientErrors {
+		if errno == transient {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry runs op until it succeeds, fails with an error that is not transient, or the policy's attempts are used up
+// Every failed attempt is logged, the delay doubles after each one
+func withRetry(policy RetrySettings, operation string, op func() error) error {
+	attempts := policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := time.Duration(policy.DelayMs) * time.Millisecond
+
+	var failures []error
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			if len(failures) > 0 {
+				fmt.Printf("%s succeeded on attempt %d of %d.\n", operation, attempt, attempts)
+			}
+			return nil
+		}
+		failures = append(failures, err)
+		if attempt >= attempts || !isTransientError(err) {
+			if len(failures) == 1 {
+				return err // Nothing was retried, keep the error as it was
+			}
+			return &RetryError{Operation: operation, Attempts: failures}
+		}
+		fmt.Printf("Warning: %s failed on attempt %d of %d, retrying in %s: %v\n", operation, attempt, attempts, delay, err)
+		time.Sleep(delay)
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// openRemoteKey connects to a predefined key of host's registry, retrying transient connection failures
+func openRemoteKey(host string, root registry.Key, policy RetrySettings) (registry.Key, error) {
+	var key registry.Key
+	err := withRetry(policy, fmt.Sprintf("Connecting to the remote registry of %s", host), func() error {
+		var err error
+		key, err = registry.OpenRemoteKey(host, root)
+		return err
+	})
+	return key, err
+}
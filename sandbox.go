@@ -0,0 +1,207 @@
+// sandbox.go
+// Expansion sandbox - expands a typed value with %VAR% references against the current, system and proposed environments
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// sandboxEnvironment is one environment the sandbox expands values against, keyed by upper-cased name
+type sandboxEnvironment struct {
+	Name      string
+	Values    map[string]string
+	Sensitive map[string]bool // Names whose values are masked in the result
+	Err       error           // Why the environment is not available
+}
+
+// rawEffectiveValue returns the merged value of a variable before its references are expanded
+func rawEffectiveValue(e EffectiveVariable) string {
+	switch {
+	case e.User != nil && e.System != nil && concatenatedVariables[strings.ToUpper(e.Name)]:
+		return joinPathValue(e.System.Value, e.User.Value)
+	case e.User != nil:
+		return e.User.Value
+	default:
+		return e.System.Value
+	}
+}
+
+// overlayConfig returns the registry variables as they would be after applying the set and delete entries of config
+func overlayConfig(all []ScopedVariable, config Config) ([]ScopedVariable, map[string]bool) {
+	touched := map[string]bool{}
+	result := append([]ScopedVariable{}, all...)
+	overlay := func(scope string, variables []Variable) {
+		for _, v := range variables {
+			if v.Operation != "set" && v.Operation != "delete" {
+				continue
+			}
+			touched[strings.ToUpper(v.Name)] = true
+			kept := result[:0]
+			for _, sv := range result {
+				if sv.Scope != scope || !strings.EqualFold(sv.Name, v.Name) {
+					kept = append(kept, sv)
+				}
+			}
+			result = kept
+			if v.Operation == "set" {
+				result = append(result, ScopedVariable{Scope: scope, Variable: v})
+			}
+		}
+	}
+	overlay(ScopeUser, config.UserVariables)
+	overlay(ScopeSystem, config.SystemVariables)
+	return result, touched
+}
+
+// loadSandboxEnvironments reads the environments the sandbox offers; proposed is nil when no config is selected
+// The current environment is the block a new process gets, which also holds built-ins such as USERPROFILE
+func loadSandboxEnvironments(settings Settings, proposed func() (Config, error)) []sandboxEnvironment {
+	all, err := readAllVariables()
+	if err != nil {
+		return []sandboxEnvironment{{Name: "Current", Err: err}}
+	}
+	sensitive := map[string]bool{}
+	for _, sv := range all {
+		if sv.isSensitive(settings.SensitivePatterns) {
+			sensitive[strings.ToUpper(sv.Name)] = true
+		}
+	}
+
+	current := map[string]string{}
+	for _, e := range mergeEnvironment(all) {
+		current[strings.ToUpper(e.Name)] = e.Value
+	}
+	if fresh, err := freshEnvironmentMap(); err == nil {
+		current = fresh
+	}
+
+	var system []ScopedVariable
+	for _, sv := range all {
+		if sv.Scope == ScopeSystem {
+			system = append(system, sv)
+		}
+	}
+	systemValues := map[string]string{}
+	for _, e := range mergeEnvironment(system) {
+		systemValues[strings.ToUpper(e.Name)] = e.Value
+	}
+
+	environments := []sandboxEnvironment{
+		{Name: "Current", Values: current, Sensitive: sensitive},
+		{Name: "System only", Values: systemValues, Sensitive: sensitive},
+	}
+	if proposed == nil {
+		return environments
+	}
+
+	config, err := proposed()
+	if err != nil {
+		return append(environments, sandboxEnvironment{Name: "Proposed", Err: err})
+	}
+	overlaid, touched := overlayConfig(all, config)
+	values := make(map[string]string, len(current))
+	for name, value := range current {
+		if !touched[name] {
+			values[name] = value
+		}
+	}
+	merged := mergeEnvironment(overlaid)
+	for _, e := range merged {
+		if key := strings.ToUpper(e.Name); touched[key] {
+			values[key] = rawEffectiveValue(e)
+		}
+	}
+	// Touched values are expanded against the proposed environment, including built-ins the registry lacks
+	for _, e := range merged {
+		key := strings.ToUpper(e.Name)
+		if touched[key] && ((e.User != nil && e.User.isExpandable()) || (e.System != nil && e.System.isExpandable())) {
+			values[key] = expandReferences(values[key], values)
+		}
+	}
+	proposedSensitive := map[string]bool{}
+	for _, sv := range overlaid {
+		if sv.isSensitive(settings.SensitivePatterns) {
+			proposedSensitive[strings.ToUpper(sv.Name)] = true
+		}
+	}
+	return append(environments, sandboxEnvironment{Name: "Proposed", Values: values, Sensitive: proposedSensitive})
+}
+
+// expand expands text against the environment like Windows expands a REG_EXPAND_SZ value
+// It returns the result with sensitive values masked and the references left unresolved
+func (env sandboxEnvironment) expand(text string) (string, []string) {
+	var unresolved []string
+	result := expansionReference.ReplaceAllStringFunc(text, func(ref string) string {
+		key := strings.ToUpper(ref[1 : len(ref)-1])
+		value, ok := env.Values[key]
+		if !ok {
+			unresolved = append(unresolved, ref)
+			return ref
+		}
+		if env.Sensitive[key] && value != "" {
+			return maskedValue
+		}
+		return value
+	})
+	return result, unresolved
+}
+
+// showExpansionSandbox opens the sandbox; proposed loads the selected config, nil leaves the proposed environment out
+func showExpansionSandbox(settings *Settings, proposed func() (Config, error)) {
+	window := fyne.CurrentApp().NewWindow("Expansion Sandbox")
+	window.Resize(fyne.NewSize(760, 420))
+
+	input := widget.NewEntry()
+	input.SetPlaceHolder(`Value with references, e.g. %JAVA_HOME%\bin;%USERPROFILE%\tools`)
+	results := container.NewVBox()
+	var environments []sandboxEnvironment
+
+	render := func() {
+		results.RemoveAll()
+		for _, env := range environments {
+			var text string
+			switch {
+			case env.Err != nil:
+				text = fmt.Sprintf("⚠️  Not available: %v", env.Err)
+			case input.Text == "":
+				text = "Type a value above."
+			default:
+				result, unresolved := env.expand(input.Text)
+				text = result
+				if len(unresolved) > 0 {
+					text += fmt.Sprintf("\n\n⚠️  Not defined, left as typed: %s", strings.Join(unresolved, ", "))
+				}
+			}
+			label := widget.NewLabel(text)
+			label.Wrapping = fyne.TextWrapBreak
+			results.Add(widget.NewCard(env.Name, "", label))
+		}
+		results.Refresh()
+	}
+	// Loading the proposed config may ask for a passphrase, which blocks until answered
+	reload := func() {
+		go func() {
+			environments = loadSandboxEnvironments(*settings, proposed)
+			render()
+		}()
+	}
+	input.OnChanged = func(string) { render() }
+	reload()
+
+	window.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel("References expand in one pass like REG_EXPAND_SZ values. Proposed is the environment after applying the selected config."),
+			input,
+		),
+		container.NewHBox(widget.NewButton("Reload", reload), widget.NewButton("Close", window.Close)),
+		nil, nil,
+		container.NewVScroll(results),
+	))
+	window.Show()
+	window.Canvas().Focus(input)
+}
@@ -0,0 +1,687 @@
+// script.go
+// Value scripts - a small sandboxed expression language for computing variable values from host facts
+// Scripts can only read the facts and functions defined here, they cannot run programs, change files, the registry or the network
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// scriptTimeout bounds how long a single value script may run
+const scriptTimeout = 2 * time.Second
+
+// scriptMaxSteps bounds the number of evaluation steps of a single script, so runaway scripts stop even without the timer
+const scriptMaxSteps = 100000
+
+// scriptMaxLength bounds the length of a script's source and of any string it produces
+const scriptMaxLength = maxVariableValueLength
+
+// scriptFunction is a built-in function callable from value scripts
+type scriptFunction func(args []interface{}) (interface{}, error)
+
+// scriptFunctions are the functions available to value scripts
+var scriptFunctions = map[string]scriptFunction{
+	"env": func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount("env", args, 1, 2); err != nil {
+			return nil, err
+		}
+		if value, ok := os.LookupEnv(scriptString(args[0])); ok {
+			return value, nil
+		}
+		if len(args) == 2 {
+			return args[1], nil
+		}
+		return "", nil
+	},
+	"upper": scriptStringFunction("upper", strings.ToUpper),
+	"lower": scriptStringFunction("lower", strings.ToLower),
+	"trim":  scriptStringFunction("trim", strings.TrimSpace),
+	"replace": func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount("replace", args, 3, 3); err != nil {
+			return nil, err
+		}
+		return strings.ReplaceAll(scriptString(args[0]), scriptString(args[1]), scriptString(args[2])), nil
+	},
+	"contains": func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount("contains", args, 2, 2); err != nil {
+			return nil, err
+		}
+		return strings.Contains(strings.ToLower(scriptString(args[0])), strings.ToLower(scriptString(args[1]))), nil
+	},
+	"join": func(args []interface{}) (interface{}, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("join expects a separator and values")
+		}
+		parts := make([]string, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			if s := scriptString(arg); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, scriptString(args[0])), nil
+	},
+	"if": func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount("if", args, 3, 3); err != nil {
+			return nil, err
+		}
+		if scriptTruthy(args[0]) {
+			return args[1], nil
+		}
+		return args[2], nil
+	},
+	"default": func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount("default", args, 2, 2); err != nil {
+			return nil, err
+		}
+		if scriptTruthy(args[0]) {
+			return args[0], nil
+		}
+		return args[1], nil
+	},
+	"str": func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount("str", args, 1, 1); err != nil {
+			return nil, err
+		}
+		return scriptString(args[0]), nil
+	},
+	"int": func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount("int", args, 1, 1); err != nil {
+			return nil, err
+		}
+		n, err := scriptNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return math.Trunc(n), nil
+	},
+	"has_command": func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount("has_command", args, 1, 1); err != nil {
+			return nil, err
+		}
+		// Only searches PATH for the executable, nothing is run
+		_, err := exec.LookPath(scriptString(args[0]))
+		return err == nil, nil
+	},
+	"min": scriptNumberFunction("min", math.Min),
+	"max": scriptNumberFunction("max", math.Max),
+}
+
+// hostFacts returns the facts value scripts can refer to by name
+func hostFacts() map[string]interface{} {
+	facts := map[string]interface{}{
+		"os":          runtime.GOOS,
+		"arch":        runtime.GOARCH,
+		"cpu_count":   float64(runtime.NumCPU()),
+		"hostname":    "",
+		"username":    "",
+		"user_domain": os.Getenv("USERDOMAIN"),
+	}
+	facts["os_version"], facts["os_name"] = windowsVersion()
+	addHardwareFacts(facts)
+	if hostname, err := os.Hostname(); err == nil {
+		facts["hostname"] = hostname
+	}
+	if u, err := user.Current(); err == nil {
+		// On Windows the user name is DOMAIN\name
+		name := u.Username
+		if i := strings.LastIndex(name, `\`); i >= 0 {
+			name = name[i+1:]
+		}
+		facts["username"] = name
+	}
+	return facts
+}
+
+// evaluateScript runs source with the given facts and returns its result as a string
+func evaluateScript(source string, facts map[string]interface{}) (string, error) {
+	value, err := evaluateScriptValue(source, facts)
+	if err != nil {
+		return "", err
+	}
+	return scriptString(value), nil
+}
+
+// evaluateCondition runs source with the given facts and reports whether its result is true
+func evaluateCondition(source string, facts map[string]interface{}) (bool, error) {
+	value, err := evaluateScriptValue(source, facts)
+	if err != nil {
+		return false, err
+	}
+	return scriptTruthy(value), nil
+}
+
+// evaluateScriptValue runs source with the given facts under the step budget and timeout
+func evaluateScriptValue(source string, facts map[string]interface{}) (interface{}, error) {
+	if len(source) > scriptMaxLength {
+		return nil, fmt.Errorf("script is longer than %d characters", scriptMaxLength)
+	}
+
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		p := &scriptParser{facts: facts}
+		if err := p.tokenize(source); err != nil {
+			done <- result{err: err}
+			return
+		}
+		value, err := p.parseExpression()
+		if err == nil && p.pos < len(p.tokens) {
+			err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+		}
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-time.After(scriptTimeout):
+		return nil, fmt.Errorf("script did not finish within %s", scriptTimeout)
+	}
+}
+
+// evaluateValueScripts returns a copy of variables with the value of every value_script entry computed
+func evaluateValueScripts(variables []Variable, facts map[string]interface{}) ([]Variable, error) {
+	evaluated := make([]Variable, len(variables))
+	for i, v := range variables {
+		if v.Operation == "set" && strings.TrimSpace(v.ValueScript) != "" {
+			value, err := evaluateScript(v.ValueScript, facts)
+			if err != nil {
+				return nil, fmt.Errorf("value_script of %s: %w", v.Name, err)
+			}
+			v.Value = value
+		}
+		evaluated[i] = v
+	}
+	return evaluated, nil
+}
+
+// evaluateConfigScripts computes the values of all value_script entries in config
+func evaluateConfigScripts(config Config) (Config, error) {
+	return evaluateConfigScriptsWith(config, hostFacts())
+}
+
+// evaluateConfigScriptsWith computes the values of all value_script entries in config from the given facts
+func evaluateConfigScriptsWith(config Config, facts map[string]interface{}) (Config, error) {
+	var err error
+	if config.UserVariables, err = evaluateValueScripts(config.UserVariables, facts); err != nil {
+		return Config{}, err
+	}
+	if config.SystemVariables, err = evaluateValueScripts(config.SystemVariables, facts); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Script token kinds
+const (
+	scriptTokenNumber = iota
+	scriptTokenString
+	scriptTokenIdent
+	scriptTokenOperator
+)
+
+// scriptToken is a lexical element of a script
+type scriptToken struct {
+	kind int
+	text string
+}
+
+// scriptParser evaluates a script while parsing it with recursive descent
+type scriptParser struct {
+	tokens []scriptToken
+	pos    int
+	steps  int
+	facts  map[string]interface{}
+}
+
+// scriptOperators lists the operators, two-character ones first so they win over their prefixes
+var scriptOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "(", ")", ","}
+
+// tokenize splits source into tokens
+func (p *scriptParser) tokenize(source string) error {
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			p.tokens = append(p.tokens, scriptToken{scriptTokenNumber, string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			p.tokens = append(p.tokens, scriptToken{scriptTokenIdent, string(runes[start:i])})
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				// Only quotes and backslashes are escaped, so Windows paths can be written as is
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == r || runes[i+1] == '\\') {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return fmt.Errorf("unterminated string")
+			}
+			i++
+			p.tokens = append(p.tokens, scriptToken{scriptTokenString, sb.String()})
+		default:
+			matched := false
+			for _, op := range scriptOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					p.tokens = append(p.tokens, scriptToken{scriptTokenOperator, op})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return fmt.Errorf("unexpected character %q", r)
+			}
+		}
+	}
+	return nil
+}
+
+// step counts an evaluation step and fails once the budget is used up
+func (p *scriptParser) step() error {
+	p.steps++
+	if p.steps > scriptMaxSteps {
+		return fmt.Errorf("script exceeded %d evaluation steps", scriptMaxSteps)
+	}
+	return nil
+}
+
+// accept consumes the next token when it is the given operator
+func (p *scriptParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == scriptTokenOperator && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseExpression parses `a || b`, the lowest precedence level
+func (p *scriptParser) parseExpression() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = scriptTruthy(left) || scriptTruthy(right)
+	}
+	return left, nil
+}
+
+// parseAnd parses `a && b`
+func (p *scriptParser) parseAnd() (interface{}, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = scriptTruthy(left) && scriptTruthy(right)
+	}
+	return left, nil
+}
+
+// parseComparison parses a single optional comparison, strings compare case-insensitively
+func (p *scriptParser) parseComparison() (interface{}, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.accept(op) {
+			continue
+		}
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		return scriptCompare(op, left, right)
+	}
+	return left, nil
+}
+
+// parseSum parses `a + b` and `a - b`, + concatenates when either side is a string
+func (p *scriptParser) parseSum() (interface{}, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.accept("+"):
+			op = "+"
+		case p.accept("-"):
+			op = "-"
+		default:
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		_, leftIsString := left.(string)
+		_, rightIsString := right.(string)
+		if op == "+" && (leftIsString || rightIsString) {
+			joined := scriptString(left) + scriptString(right)
+			if len(joined) > scriptMaxLength {
+				return nil, fmt.Errorf("string result is longer than %d characters", scriptMaxLength)
+			}
+			left = joined
+			continue
+		}
+		if left, err = scriptArithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseProduct parses `a * b`, `a / b` and `a % b`
+func (p *scriptParser) parseProduct() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.accept("*"):
+			op = "*"
+		case p.accept("/"):
+			op = "/"
+		case p.accept("%"):
+			op = "%"
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = scriptArithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseUnary parses `!a` and `-a`
+func (p *scriptParser) parseUnary() (interface{}, error) {
+	if err := p.step(); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.accept("!"):
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return !scriptTruthy(value), nil
+	case p.accept("-"):
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		n, err := scriptNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		return -n, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses literals, facts, function calls and parenthesized expressions
+func (p *scriptParser) parsePrimary() (interface{}, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of script")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case scriptTokenNumber:
+		n, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token.text)
+		}
+		return n, nil
+	case scriptTokenString:
+		return token.text, nil
+	case scriptTokenIdent:
+		if p.accept("(") {
+			return p.parseCall(token.text)
+		}
+		switch token.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		if value, ok := p.facts[token.text]; ok {
+			return value, nil
+		}
+		return nil, fmt.Errorf("unknown name %q", token.text)
+	}
+
+	if token.text == "(" {
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token.text)
+}
+
+// parseCall parses the arguments of a function call and invokes the function
+func (p *scriptParser) parseCall(name string) (interface{}, error) {
+	fn, ok := scriptFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	var args []interface{}
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("expected ',' or ')' in call to %s", name)
+			}
+		}
+	}
+	value, err := fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return value, nil
+}
+
+// scriptString converts a script value to its string form
+func scriptString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// scriptNumber converts a script value to a number
+func scriptNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("value is not a number")
+	}
+}
+
+// scriptTruthy reports whether a script value counts as true: non-empty strings, non-zero numbers and true
+func scriptTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	default:
+		return false
+	}
+}
+
+// scriptArithmetic applies a numeric operator
+func scriptArithmetic(op string, left, right interface{}) (interface{}, error) {
+	a, err := scriptNumber(left)
+	if err != nil {
+		return nil, err
+	}
+	b, err := scriptNumber(right)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	default:
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(a, b), nil
+	}
+}
+
+// scriptCompare applies a comparison operator, numerically when both sides are numbers
+func scriptCompare(op string, left, right interface{}) (interface{}, error) {
+	var cmp int
+	a, aErr := scriptNumber(left)
+	b, bErr := scriptNumber(right)
+	if aErr == nil && bErr == nil {
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(strings.ToLower(scriptString(left)), strings.ToLower(scriptString(right)))
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// scriptArgCount checks the number of arguments passed to a function
+func scriptArgCount(name string, args []interface{}, minArgs, maxArgs int) error {
+	if len(args) < minArgs || len(args) > maxArgs {
+		if minArgs == maxArgs {
+			return fmt.Errorf("%s expects %d argument(s), got %d", name, minArgs, len(args))
+		}
+		return fmt.Errorf("%s expects %d to %d arguments, got %d", name, minArgs, maxArgs, len(args))
+	}
+	return nil
+}
+
+// scriptStringFunction wraps a string transformation as a one-argument script function
+func scriptStringFunction(name string, fn func(string) string) scriptFunction {
+	return func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount(name, args, 1, 1); err != nil {
+			return nil, err
+		}
+		return fn(scriptString(args[0])), nil
+	}
+}
+
+// scriptNumberFunction wraps a two-argument numeric function as a script function
+func scriptNumberFunction(name string, fn func(a, b float64) float64) scriptFunction {
+	return func(args []interface{}) (interface{}, error) {
+		if err := scriptArgCount(name, args, 2, 2); err != nil {
+			return nil, err
+		}
+		a, err := scriptNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := scriptNumber(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return fn(a, b), nil
+	}
+}
@@ -0,0 +1,152 @@
+// secrets.go
+// Plaintext secret scanning - flags values that look like credentials in the live environment and in configs, also when base64 or hex hides them
+// Environment variables are stored unencrypted in the registry and inherited by every process, so credentials belong in a secrets backend
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// secretRule recognizes one kind of credential
+type secretRule struct {
+	Kind    string
+	Pattern *regexp.Regexp
+}
+
+// secretRules match values that look like credentials regardless of the variable name
+var secretRules = []secretRule{
+	{"AWS access key ID", regexp.MustCompile(`^(AKIA|ASIA)[0-9A-Z]{16}$`)},
+	{"AWS secret access key", regexp.MustCompile(`(?i)aws_?secret_?access_?key\s*[=:]\s*[A-Za-z0-9/+]{40}`)},
+	{"GitHub token", regexp.MustCompile(`^gh[pousr]_[A-Za-z0-9]{36,}$`)},
+	{"GitHub token", regexp.MustCompile(`^github_pat_[A-Za-z0-9_]{40,}$`)},
+	{"Slack token", regexp.MustCompile(`^xox[abprs]-[A-Za-z0-9-]{10,}$`)},
+	{"API secret key", regexp.MustCompile(`^sk-[A-Za-z0-9_-]{20,}$`)},
+	{"Google API key", regexp.MustCompile(`^AIza[0-9A-Za-z_-]{35}$`)},
+	{"JSON Web Token", regexp.MustCompile(`^eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+$`)},
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"Azure storage key", regexp.MustCompile(`(?i)AccountKey=[A-Za-z0-9/+]{40,}={0,2}`)},
+	{"shared access signature", regexp.MustCompile(`(?i)(SharedAccessKey=[^;]+|[?&]sig=[A-Za-z0-9%/+]{20,})`)},
+	{"connection string with a password", regexp.MustCompile(`(?i)(^|;)\s*(password|pwd)\s*=\s*[^;{\s][^;]*`)},
+	{"password in a URL", regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+// Minimum lengths of values decoded in search of hidden credentials
+const (
+	minObfuscatedBase64 = 16
+	minObfuscatedHex    = 32
+)
+
+// secretFinding is a value that looks like a credential stored in plain text
+type secretFinding struct {
+	Scope      string
+	Name       string
+	Kind       string
+	Obfuscated string // "base64" or "hex" when the credential was found in the decoded value
+}
+
+// describe renders the finding for warnings and reports
+func (f secretFinding) describe() string {
+	kind := f.Kind
+	if f.Obfuscated != "" {
+		kind = fmt.Sprintf("%s hidden in %s", f.Kind, f.Obfuscated)
+	}
+	return fmt.Sprintf("%s (%s) holds a credential in plain text (%s)", f.Name, f.Scope, kind)
+}
+
+// secretSuggestion is the advice shown with plaintext secret findings
+const secretSuggestion = "Move it to a secrets backend and let configs fetch it at apply time with a {{plugin:...}} resolver or ask for it with {{prompt_secret:...}}, or keep it out of the environment altogether"
+
+// matchSecret returns the kind of credential a value looks like
+func matchSecret(value string) (string, bool) {
+	for _, rule := range secretRules {
+		if rule.Pattern.MatchString(value) {
+			return rule.Kind, true
+		}
+	}
+	return "", false
+}
+
+// scanSecretValue returns the kind of credential a value looks like and how it is obfuscated, if at all
+// Encoding a credential as base64 or hex does not protect it, so decoded values are checked too
+func scanSecretValue(value string) (kind, obfuscated string, ok bool) {
+	value = strings.TrimSpace(value)
+	if kind, ok := matchSecret(value); ok {
+		return kind, "", true
+	}
+	if len(value) >= minObfuscatedHex && len(value)%2 == 0 {
+		if decoded, err := hex.DecodeString(value); err == nil && isPrintableText(decoded) {
+			if kind, ok := matchSecret(strings.TrimSpace(string(decoded))); ok {
+				return kind, "hex", true
+			}
+		}
+	}
+	if len(value) >= minObfuscatedBase64 {
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if decoded, err := encoding.DecodeString(value); err == nil && isPrintableText(decoded) {
+				if kind, ok := matchSecret(strings.TrimSpace(string(decoded))); ok {
+					return kind, "base64", true
+				}
+				break
+			}
+		}
+	}
+	return "", "", false
+}
+
+// isPrintableText reports whether decoded bytes are text rather than binary data
+func isPrintableText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, r := range string(data) {
+		if r == unicode.ReplacementChar || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanVariablesForSecrets lists the variables whose values look like credentials
+// Values with placeholders are skipped: their secrets are filled in at apply time and never stored in the config
+func scanVariablesForSecrets(variables []ScopedVariable) []secretFinding {
+	var findings []secretFinding
+	for _, v := range variables {
+		if (v.Operation != "" && v.Operation != "set") || hasPlaceholders(v.Value) {
+			continue
+		}
+		if kind, obfuscated, ok := scanSecretValue(v.Value); ok {
+			findings = append(findings, secretFinding{Scope: v.Scope, Name: v.Name, Kind: kind, Obfuscated: obfuscated})
+		}
+	}
+	return findings
+}
+
+// configSecretWarnings warns about credentials written into a config in plain text, shown in the preview
+func configSecretWarnings(config Config) []string {
+	var variables []ScopedVariable
+	for _, v := range config.UserVariables {
+		variables = append(variables, ScopedVariable{Scope: ScopeUser, Variable: v})
+	}
+	for _, v := range config.SystemVariables {
+		variables = append(variables, ScopedVariable{Scope: ScopeSystem, Variable: v})
+	}
+	var warnings []string
+	for _, f := range scanVariablesForSecrets(variables) {
+		warnings = append(warnings, fmt.Sprintf("%s, in the config and after the apply in the registry. %s", f.describe(), secretSuggestion))
+	}
+	return warnings
+}
+
+// doctorSecretChecks flags credentials stored in the live environment
+func doctorSecretChecks(all []ScopedVariable) []doctorFinding {
+	var findings []doctorFinding
+	for _, f := range scanVariablesForSecrets(all) {
+		findings = append(findings, doctorFinding{Severity: doctorWarning, Check: "Secrets", Problem: fmt.Sprintf("%s. %s", f.describe(), secretSuggestion)})
+	}
+	return findings
+}
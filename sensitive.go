@@ -0,0 +1,57 @@
+// sensitive.go
+// Sensitive value handling - detects secret-looking variables and masks or redacts their values
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// maskedValue replaces sensitive values wherever they would be displayed or logged
+const maskedValue = "********"
+
+// defaultSensitivePatterns are name globs treated as sensitive when a variable is not explicitly marked
+var defaultSensitivePatterns = []string{"*TOKEN*", "*KEY*", "*PASSWORD*", "*PASSWD*", "*SECRET*", "*CREDENTIAL*"}
+
+// isSensitive reports whether a variable's value should be hidden
+// Variables are sensitive when marked explicitly or when their name matches one of the glob patterns
+func (v Variable) isSensitive(patterns []string) bool {
+	if v.Sensitive {
+		return true
+	}
+	return nameMatchesAny(v.Name, patterns)
+}
+
+// nameMatchesAny checks a variable name against case-insensitive glob patterns
+func nameMatchesAny(name string, patterns []string) bool {
+	upper := strings.ToUpper(name)
+	for _, pattern := range patterns {
+		if matched, err := path.Match(strings.ToUpper(pattern), upper); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// displayValue returns the value to show for a variable, masked if it is sensitive
+func (v Variable) displayValue(patterns []string) string {
+	if v.isSensitive(patterns) && v.Value != "" {
+		return maskedValue
+	}
+	return v.Value
+}
+
+// redactSensitiveVariables replaces sensitive values with secret prompts for export
+// Re-applying a redacted config asks for each removed value instead of writing the placeholder text
+func redactSensitiveVariables(variables []Variable, patterns []string) []Variable {
+	redacted := make([]Variable, len(variables))
+	for i, v := range variables {
+		if v.isSensitive(patterns) && v.Value != "" {
+			v.Value = fmt.Sprintf("{{%sEnter value for %s}}", secretPromptPlaceholderPrefix, v.Name)
+			v.Sensitive = true
+		}
+		redacted[i] = v
+	}
+	return redacted
+}
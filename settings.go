@@ -0,0 +1,523 @@
+// settings.go
+// Application settings - user preferences persisted as YAML in the application data directory
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"gopkg.in/yaml.v2"
+)
+
+// settingsFileName is the settings file stored in the application data directory
+const settingsFileName = "settings.yaml"
+
+// Broadcast modes controlling how WM_SETTINGCHANGE is delivered after an apply
+const (
+	BroadcastModeTimeout = "timeout" // SendMessageTimeoutW, waits for windows up to the configured timeout
+	BroadcastModeNotify  = "notify"  // SendNotifyMessageW, returns immediately without waiting for any window
+	BroadcastModeSkip    = "skip"    // Do not broadcast at all
+)
+
+// BroadcastSettings controls the WM_SETTINGCHANGE notification sent after applying variables
+type BroadcastSettings struct {
+	Mode      string `yaml:"mode"`       // One of the BroadcastMode constants
+	TimeoutMs int    `yaml:"timeout_ms"` // Per-window timeout used by the "timeout" mode
+}
+
+// RetrySettings controls how often remote registry connections and broadcasts are repeated after a transient failure
+type RetrySettings struct {
+	Attempts int `yaml:"attempts"` // Tries including the first one, 1 disables retries
+	DelayMs  int `yaml:"delay_ms"` // Wait before the first retry, doubled for every further one
+}
+
+// Settings holds all persisted user preferences
+type Settings struct {
+	Broadcast   BroadcastSettings `yaml:"broadcast"`    // Change notification behavior
+	ErrorPolicy string            `yaml:"error_policy"` // One of the ErrorPolicy constants, applied per variable
+
+	Retry RetrySettings `yaml:"retry"` // Retries of operations that can fail transiently
+
+	SensitivePatterns []string `yaml:"sensitive_patterns"` // Name globs whose values are masked
+	RedactOnExport    bool     `yaml:"redact_on_export"`   // Replace sensitive values with prompts when exporting
+	PortableExport    bool     `yaml:"portable_export"`    // Rewrite paths inside user profiles as %USERPROFILE% references when exporting
+
+	Backup BackupSettings `yaml:"backup"` // Automatic environment snapshots
+
+	ReadOnly bool `yaml:"read_only"` // Start in read-only audit mode with all write paths disabled
+
+	TypedConfirmation bool     `yaml:"typed_confirmation"` // Require typing the name before dangerous changes
+	ConfirmNames      []string `yaml:"confirm_names"`      // Variables whose deletion or overwrite needs a typed confirmation
+
+	ImportWizard bool `yaml:"import_wizard"` // Review entries that change existing values one by one before applying a config
+
+	RequireStaging bool `yaml:"require_staging"` // Apply Variables only stages a config, the staged changes are committed after review
+
+	ProtectedVariables []string `yaml:"protected_variables"` // Variables configs may only delete or overwrite with force: true
+
+	Listener ListenerSettings `yaml:"listener"` // HTTP endpoint applying profiles on request
+
+	Enforcement EnforcementSettings `yaml:"enforcement"` // Reapplying a profile when the environment drifts from it
+
+	WatchedVariables []string `yaml:"watched_variables"` // Name globs whose registry changes raise a notification and an audit log entry
+
+	TemplateIndexURL string `yaml:"template_index_url"` // Index of community templates shown by "Browse Templates"
+
+	LogonProfile string `yaml:"logon_profile,omitempty"` // Profile reapplied at sign-in by the logon task, empty when none is registered
+
+	FavoriteVariables []string `yaml:"favorite_variables"` // Variables pinned to the favorites strip of the Variables tab
+
+	ListVariables []ListVariableRule `yaml:"list_variables"` // Variables edited entry by entry in the list editor
+
+	NormalizePaths bool `yaml:"normalize_paths"` // Clean slashes, quotes and doubled backslashes of path values at import and in the editors
+
+	ProjectConfigs []ProjectConfig `yaml:"project_configs,omitempty"` // Configs loaded by the PowerShell hook in project directories
+
+	FleetParallelism int `yaml:"fleet_parallelism"` // Targets a fleet apply writes at the same time
+}
+
+// defaultSettings returns the settings used when no settings file exists yet
+func defaultSettings() Settings {
+	return Settings{
+		Broadcast: BroadcastSettings{
+			Mode:      BroadcastModeTimeout,
+			TimeoutMs: 5000,
+		},
+		ErrorPolicy: ErrorPolicyContinue,
+		Retry: RetrySettings{
+			Attempts: defaultRetryAttempts,
+			DelayMs:  defaultRetryDelayMs,
+		},
+		SensitivePatterns: defaultSensitivePatterns,
+		Backup: BackupSettings{
+			Schedule: BackupScheduleOff,
+			Keep:     defaultBackupKeep,
+		},
+		TypedConfirmation: true,
+		ConfirmNames:      defaultConfirmNames,
+
+		ProtectedVariables: defaultProtectedVariables,
+
+		Listener: ListenerSettings{
+			Address: defaultListenerAddress,
+		},
+		Enforcement: EnforcementSettings{
+			IntervalMinutes: defaultEnforcementInterval,
+			OutsideWindow:   EnforcementDefer,
+		},
+
+		TemplateIndexURL: defaultTemplateIndexURL,
+
+		FavoriteVariables: defaultFavoriteVariables,
+
+		ListVariables: defaultListVariables,
+
+		FleetParallelism: defaultFleetParallelism,
+	}
+}
+
+// loadSettings reads the settings file, falling back to defaults for missing files or keys
+func loadSettings() (Settings, error) {
+	settings := defaultSettings()
+
+	path, err := appDataPath(settingsFileName)
+	if err != nil {
+		return settings, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return settings, fmt.Errorf("failed to read settings file %s: %w", path, err)
+	}
+
+	// Unmarshal over the defaults so keys missing from older settings files keep their default values
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return defaultSettings(), fmt.Errorf("failed to parse settings file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// saveSettings writes the settings file to the application data directory
+func saveSettings(settings Settings) error {
+	path, err := appDataPath(settingsFileName)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write settings file %s: %w", path, err)
+	}
+	return nil
+}
+
+// newSettingsTab builds the Settings tab, an editor for the application settings that saves them on request
+func newSettingsTab(parent fyne.Window, settings *Settings, isAdmin bool) fyne.CanvasObject {
+	// Broadcast options, labels and modes are kept in the same order
+	broadcastLabels := []string{"Wait for windows (SendMessageTimeoutW)", "Don't wait (SendNotifyMessageW)", "Skip broadcast"}
+	broadcastModes := []string{BroadcastModeTimeout, BroadcastModeNotify, BroadcastModeSkip}
+	broadcastSelect := widget.NewSelect(broadcastLabels, nil)
+	for i, mode := range broadcastModes {
+		if mode == settings.Broadcast.Mode {
+			broadcastSelect.SetSelected(broadcastLabels[i])
+		}
+	}
+
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetText(strconv.Itoa(settings.Broadcast.TimeoutMs))
+
+	// Retry options
+	retryAttemptsEntry := widget.NewEntry()
+	retryAttemptsEntry.SetText(strconv.Itoa(settings.Retry.Attempts))
+	retryDelayEntry := widget.NewEntry()
+	retryDelayEntry.SetText(strconv.Itoa(settings.Retry.DelayMs))
+
+	// Per-variable error policy options
+	policyLabels := []string{"Stop on first error", "Continue and report all errors", "Ask after each error"}
+	policyValues := []string{ErrorPolicyStop, ErrorPolicyContinue, ErrorPolicyPrompt}
+	policySelect := widget.NewSelect(policyLabels, nil)
+	for i, policy := range policyValues {
+		if policy == settings.ErrorPolicy {
+			policySelect.SetSelected(policyLabels[i])
+		}
+	}
+
+	// Sensitive value options
+	patternsEntry := widget.NewEntry()
+	patternsEntry.SetText(strings.Join(settings.SensitivePatterns, ", "))
+	patternsEntry.SetPlaceHolder("Comma-separated name globs, e.g. *TOKEN*, *KEY*")
+	redactCheck := widget.NewCheck("Redact sensitive values on export", nil)
+	redactCheck.SetChecked(settings.RedactOnExport)
+	portableCheck := widget.NewCheck("Make user profile paths portable on export (%USERPROFILE%)", nil)
+	portableCheck.SetChecked(settings.PortableExport)
+
+	// Automatic backup options
+	scheduleLabels := []string{"Off", "Daily", "Weekly"}
+	scheduleValues := []string{BackupScheduleOff, BackupScheduleDaily, BackupScheduleWeekly}
+	scheduleSelect := widget.NewSelect(scheduleLabels, nil)
+	for i, schedule := range scheduleValues {
+		if schedule == settings.Backup.Schedule {
+			scheduleSelect.SetSelected(scheduleLabels[i])
+		}
+	}
+	backupDirEntry := widget.NewEntry()
+	backupDirEntry.SetText(settings.Backup.Directory)
+	defaultBackupDir := `%APPDATA%\SystemVariableManager\backups`
+	if base, err := appDataDir(); err == nil {
+		defaultBackupDir = filepath.Join(base, backupFolderName)
+	}
+	backupDirEntry.SetPlaceHolder("Default: " + defaultBackupDir)
+	keepEntry := widget.NewEntry()
+	keepEntry.SetText(strconv.Itoa(settings.Backup.Keep))
+
+	// Fleet apply options
+	fleetParallelismEntry := widget.NewEntry()
+	fleetParallelismEntry.SetText(strconv.Itoa(settings.FleetParallelism))
+
+	// Read-only mode takes effect at the next start so it cannot be switched off mid-session by accident
+	readOnlyCheck := widget.NewCheck("Read-only audit mode (takes effect after restart)", nil)
+	readOnlyCheck.SetChecked(settings.ReadOnly)
+
+	// Typed confirmation options
+	typedConfirmCheck := widget.NewCheck("Type the variable name to confirm dangerous changes", nil)
+	typedConfirmCheck.SetChecked(settings.TypedConfirmation)
+	importWizardCheck := widget.NewCheck("Review conflicting values one by one before applying a config", nil)
+	importWizardCheck.SetChecked(settings.ImportWizard)
+	requireStagingCheck := widget.NewCheck("Stage configs for review before they are written to the registry", nil)
+	requireStagingCheck.SetChecked(settings.RequireStaging)
+	confirmNamesEntry := widget.NewEntry()
+	confirmNamesEntry.SetText(strings.Join(settings.ConfirmNames, ", "))
+	confirmNamesEntry.SetPlaceHolder("Comma-separated names, e.g. PATH, TEMP, ComSpec")
+
+	protectedEntry := widget.NewEntry()
+	protectedEntry.SetText(strings.Join(settings.ProtectedVariables, ", "))
+	protectedEntry.SetPlaceHolder("Comma-separated names, e.g. Path, PATHEXT, ComSpec")
+
+	watchedEntry := widget.NewEntry()
+	watchedEntry.SetText(strings.Join(settings.WatchedVariables, ", "))
+	watchedEntry.SetPlaceHolder("Comma-separated names or globs, e.g. Path, JAVA_HOME")
+
+	normalizePathsCheck := widget.NewCheck("Normalize path values (slashes, quotes, doubled backslashes) at import and in the editors", nil)
+	normalizePathsCheck.SetChecked(settings.NormalizePaths)
+
+	listVariablesEntry := widget.NewMultiLineEntry()
+	listVariablesLines := make([]string, len(settings.ListVariables))
+	for i, rule := range settings.ListVariables {
+		listVariablesLines[i] = rule.String()
+	}
+	listVariablesEntry.SetText(strings.Join(listVariablesLines, "\n"))
+	listVariablesEntry.SetPlaceHolder("One per line: NAME SEPARATOR RULE, e.g. PATHEXT ; extension")
+
+	templateIndexEntry := widget.NewEntry()
+	templateIndexEntry.SetText(settings.TemplateIndexURL)
+	templateIndexEntry.SetPlaceHolder(defaultTemplateIndexURL)
+
+	// Listener options take effect at the next start
+	listenerCheck := widget.NewCheck("Accept apply requests over HTTP (takes effect after restart)", nil)
+	listenerCheck.SetChecked(settings.Listener.Enabled)
+	listenerAddressEntry := widget.NewEntry()
+	listenerAddressEntry.SetText(settings.Listener.Address)
+	listenerAddressEntry.SetPlaceHolder(defaultListenerAddress)
+	listenerTokenEntry := widget.NewPasswordEntry()
+	listenerTokenEntry.SetText(settings.Listener.Token)
+	generateTokenButton := widget.NewButton("Generate", func() {
+		token, err := generateListenerToken()
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+		listenerTokenEntry.SetText(token)
+		parent.Clipboard().SetContent(token)
+		dialog.ShowInformation("Token Generated", "A new token was generated and copied to the clipboard. Save the settings to use it.", parent)
+	})
+	listenerProfilesEntry := widget.NewEntry()
+	listenerProfilesEntry.SetText(strings.Join(settings.Listener.AllowedProfiles, ", "))
+	listenerProfilesEntry.SetPlaceHolder("Comma-separated profile names, empty allows all")
+	listenerClientsEntry := widget.NewEntry()
+	listenerClientsEntry.SetText(strings.Join(settings.Listener.AllowedClients, ", "))
+	listenerClientsEntry.SetPlaceHolder("Comma-separated IPs or CIDR ranges, empty allows all")
+	listenerApprovalCheck := widget.NewCheck("Hold requests until I approve them in Pending Approvals", nil)
+	listenerApprovalCheck.SetChecked(settings.Listener.RequireApproval)
+
+	// Enforcement starts with the application, the window and profile are read at every check
+	enforcementCheck := widget.NewCheck("Reapply a profile when the environment drifts from it (takes effect after restart)", nil)
+	enforcementCheck.SetChecked(settings.Enforcement.Enabled)
+	enforcementProfileEntry := widget.NewEntry()
+	enforcementProfileEntry.SetText(settings.Enforcement.Profile)
+	enforcementProfileEntry.SetPlaceHolder("Profile name")
+	enforcementIntervalEntry := widget.NewEntry()
+	enforcementIntervalEntry.SetText(strconv.Itoa(settings.Enforcement.IntervalMinutes))
+	windowStartEntry := widget.NewEntry()
+	windowStartEntry.SetText(settings.Enforcement.WindowStart)
+	windowStartEntry.SetPlaceHolder("HH:MM, e.g. 22:00, empty corrects at any time")
+	windowEndEntry := widget.NewEntry()
+	windowEndEntry.SetText(settings.Enforcement.WindowEnd)
+	windowEndEntry.SetPlaceHolder("HH:MM, e.g. 06:00")
+	windowDaysEntry := widget.NewEntry()
+	windowDaysEntry.SetText(strings.Join(settings.Enforcement.WindowDays, ", "))
+	windowDaysEntry.SetPlaceHolder("Comma-separated weekdays, e.g. Mon, Tue, Wed, Thu, Fri; empty for every day")
+	outsideWindowLabels := []string{"Wait for the maintenance window", "Ask me before correcting"}
+	outsideWindowValues := []string{EnforcementDefer, EnforcementPrompt}
+	outsideWindowSelect := widget.NewSelect(outsideWindowLabels, nil)
+	outsideWindowSelect.SetSelectedIndex(0)
+	for i, v := range outsideWindowValues {
+		if v == settings.Enforcement.OutsideWindow {
+			outsideWindowSelect.SetSelectedIndex(i)
+		}
+	}
+	enforcementApprovalCheck := widget.NewCheck("Hold corrections until I approve them in Pending Approvals", nil)
+	enforcementApprovalCheck.SetChecked(settings.Enforcement.RequireApproval)
+
+	backupNowButton := widget.NewButton("Back Up Now", func() {
+		backup := settings.Backup
+		backup.Directory = strings.TrimSpace(backupDirEntry.Text)
+		go func() {
+			path, err := createBackup(backup, isAdmin)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("error creating backup: %v", err), parent)
+				return
+			}
+			dialog.ShowInformation("Backup Created", fmt.Sprintf("Environment snapshot written to:\n%s", path), parent)
+		}()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("Broadcast Mode", broadcastSelect),
+		widget.NewFormItem("Broadcast Timeout (ms)", timeoutEntry),
+		widget.NewFormItem("Retry Attempts", retryAttemptsEntry),
+		widget.NewFormItem("Retry Delay (ms)", retryDelayEntry),
+		widget.NewFormItem("On Variable Error", policySelect),
+		widget.NewFormItem("Sensitive Names", patternsEntry),
+		widget.NewFormItem("", redactCheck),
+		widget.NewFormItem("", portableCheck),
+		widget.NewFormItem("Automatic Backup", scheduleSelect),
+		widget.NewFormItem("Backup Directory", backupDirEntry),
+		widget.NewFormItem("Backups to Keep", keepEntry),
+		widget.NewFormItem("", backupNowButton),
+		widget.NewFormItem("Safety", readOnlyCheck),
+		widget.NewFormItem("", typedConfirmCheck),
+		widget.NewFormItem("", importWizardCheck),
+		widget.NewFormItem("", requireStagingCheck),
+		widget.NewFormItem("Confirm Names", confirmNamesEntry),
+		widget.NewFormItem("Protected Variables", protectedEntry),
+		widget.NewFormItem("Watched Variables", watchedEntry),
+		widget.NewFormItem("List Variables", listVariablesEntry),
+		widget.NewFormItem("", normalizePathsCheck),
+		widget.NewFormItem("Parallel Machines", fleetParallelismEntry),
+		widget.NewFormItem("Template Index URL", templateIndexEntry),
+		widget.NewFormItem("Listener", listenerCheck),
+		widget.NewFormItem("Listen Address", listenerAddressEntry),
+		widget.NewFormItem("Token", container.NewBorder(nil, nil, nil, generateTokenButton, listenerTokenEntry)),
+		widget.NewFormItem("Allowed Profiles", listenerProfilesEntry),
+		widget.NewFormItem("Allowed Clients", listenerClientsEntry),
+		widget.NewFormItem("", listenerApprovalCheck),
+		widget.NewFormItem("Enforcement", enforcementCheck),
+		widget.NewFormItem("Enforced Profile", enforcementProfileEntry),
+		widget.NewFormItem("Check Every (min)", enforcementIntervalEntry),
+		widget.NewFormItem("Window Start", windowStartEntry),
+		widget.NewFormItem("Window End", windowEndEntry),
+		widget.NewFormItem("Window Days", windowDaysEntry),
+		widget.NewFormItem("Outside the Window", outsideWindowSelect),
+		widget.NewFormItem("", enforcementApprovalCheck),
+	)
+
+	saveButton := widget.NewButton("Save", func() {
+		updated := *settings
+
+		if i := broadcastSelect.SelectedIndex(); i >= 0 {
+			updated.Broadcast.Mode = broadcastModes[i]
+		}
+
+		if i := policySelect.SelectedIndex(); i >= 0 {
+			updated.ErrorPolicy = policyValues[i]
+		}
+
+		updated.SensitivePatterns = splitList(patternsEntry.Text)
+		updated.RedactOnExport = redactCheck.Checked
+		updated.PortableExport = portableCheck.Checked
+
+		timeout, err := strconv.Atoi(timeoutEntry.Text)
+		if err != nil || timeout <= 0 {
+			dialog.ShowError(fmt.Errorf("invalid broadcast timeout: please enter a positive number of milliseconds"), parent)
+			return
+		}
+		updated.Broadcast.TimeoutMs = timeout
+
+		attempts, err := strconv.Atoi(retryAttemptsEntry.Text)
+		if err != nil || attempts < 1 || attempts > maxRetryAttempts {
+			dialog.ShowError(fmt.Errorf("invalid retry attempts: please enter a number from 1 to %d", maxRetryAttempts), parent)
+			return
+		}
+		delay, err := strconv.Atoi(retryDelayEntry.Text)
+		if err != nil || delay < 0 {
+			dialog.ShowError(fmt.Errorf("invalid retry delay: please enter a number of milliseconds"), parent)
+			return
+		}
+		updated.Retry = RetrySettings{Attempts: attempts, DelayMs: delay}
+
+		if i := scheduleSelect.SelectedIndex(); i >= 0 {
+			updated.Backup.Schedule = scheduleValues[i]
+		}
+		updated.Backup.Directory = strings.TrimSpace(backupDirEntry.Text)
+		keep, err := strconv.Atoi(keepEntry.Text)
+		if err != nil || keep <= 0 {
+			dialog.ShowError(fmt.Errorf("invalid backup retention: please enter a positive number of backups to keep"), parent)
+			return
+		}
+		updated.Backup.Keep = keep
+		parallelism, err := strconv.Atoi(fleetParallelismEntry.Text)
+		if err != nil || parallelism < 1 || parallelism > maxFleetParallelism {
+			dialog.ShowError(fmt.Errorf("invalid parallel machines: please enter a number from 1 to %d", maxFleetParallelism), parent)
+			return
+		}
+		updated.FleetParallelism = parallelism
+		updated.ReadOnly = readOnlyCheck.Checked
+		updated.TypedConfirmation = typedConfirmCheck.Checked
+		updated.ImportWizard = importWizardCheck.Checked
+		updated.RequireStaging = requireStagingCheck.Checked
+		updated.ConfirmNames = splitList(confirmNamesEntry.Text)
+		updated.ProtectedVariables = splitList(protectedEntry.Text)
+		updated.WatchedVariables = splitList(watchedEntry.Text)
+		updated.NormalizePaths = normalizePathsCheck.Checked
+		if updated.ListVariables, err = parseListVariableRules(listVariablesEntry.Text); err != nil {
+			dialog.ShowError(fmt.Errorf("invalid list variables: %v", err), parent)
+			return
+		}
+		updated.TemplateIndexURL = strings.TrimSpace(templateIndexEntry.Text)
+		if updated.TemplateIndexURL == "" {
+			updated.TemplateIndexURL = defaultTemplateIndexURL
+		}
+		if !isRemoteConfig(updated.TemplateIndexURL) {
+			dialog.ShowError(fmt.Errorf("invalid template index URL: please enter an http:// or https:// URL"), parent)
+			return
+		}
+
+		updated.Listener.Enabled = listenerCheck.Checked
+		updated.Listener.Address = strings.TrimSpace(listenerAddressEntry.Text)
+		if updated.Listener.Address == "" {
+			updated.Listener.Address = defaultListenerAddress
+		}
+		if _, _, err := net.SplitHostPort(updated.Listener.Address); err != nil {
+			dialog.ShowError(fmt.Errorf("invalid listen address: please enter host:port, e.g. %s", defaultListenerAddress), parent)
+			return
+		}
+		updated.Listener.Token = strings.TrimSpace(listenerTokenEntry.Text)
+		if updated.Listener.Enabled && updated.Listener.Token == "" {
+			dialog.ShowError(fmt.Errorf("the listener requires a token: click Generate to create one"), parent)
+			return
+		}
+		updated.Listener.AllowedProfiles = splitList(listenerProfilesEntry.Text)
+		updated.Listener.AllowedClients = splitList(listenerClientsEntry.Text)
+		updated.Listener.RequireApproval = listenerApprovalCheck.Checked
+		for _, client := range updated.Listener.AllowedClients {
+			if _, _, err := net.ParseCIDR(client); err != nil && net.ParseIP(client) == nil {
+				dialog.ShowError(fmt.Errorf("invalid allowed client %q: please enter an IP address or CIDR range", client), parent)
+				return
+			}
+		}
+
+		updated.Enforcement.Enabled = enforcementCheck.Checked
+		updated.Enforcement.Profile = strings.TrimSpace(enforcementProfileEntry.Text)
+		if updated.Enforcement.Enabled && updated.Enforcement.Profile == "" {
+			dialog.ShowError(fmt.Errorf("enforcement requires a profile: enter the name of a saved profile"), parent)
+			return
+		}
+		interval, err := strconv.Atoi(enforcementIntervalEntry.Text)
+		if err != nil || interval < 1 || interval > maxEnforcementInterval {
+			dialog.ShowError(fmt.Errorf("invalid enforcement interval: please enter a number of minutes from 1 to %d", maxEnforcementInterval), parent)
+			return
+		}
+		updated.Enforcement.IntervalMinutes = interval
+		updated.Enforcement.WindowStart = strings.TrimSpace(windowStartEntry.Text)
+		updated.Enforcement.WindowEnd = strings.TrimSpace(windowEndEntry.Text)
+		updated.Enforcement.WindowDays = splitList(windowDaysEntry.Text)
+		if _, err := parseMaintenanceWindow(updated.Enforcement); err != nil {
+			dialog.ShowError(fmt.Errorf("invalid maintenance window: %v", err), parent)
+			return
+		}
+		if i := outsideWindowSelect.SelectedIndex(); i >= 0 {
+			updated.Enforcement.OutsideWindow = outsideWindowValues[i]
+		}
+		updated.Enforcement.RequireApproval = enforcementApprovalCheck.Checked
+
+		if err := saveSettings(updated); err != nil {
+			dialog.ShowError(fmt.Errorf("error saving settings: %v", err), parent)
+			return
+		}
+		*settings = updated
+		dialog.ShowInformation("Settings", "Settings saved.", parent)
+	})
+
+	return container.NewVScroll(container.NewVBox(
+		form,
+		widget.NewSeparator(),
+		container.NewHBox(saveButton),
+	))
+}
+
+// splitList parses a comma-separated settings field into trimmed, non-empty items
+func splitList(text string) []string {
+	var items []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
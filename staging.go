@@ -0,0 +1,227 @@
+// staging.go
+// Two-phase apply - stages a fully resolved config for review and commits it to the registry only after explicit confirmation
+// The staged config lives in the application data directory next to a record of who staged it, from which config and when
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Files of the staging area in the application data directory
+const (
+	stagedChangesFileName = "staged.yaml"
+	stagedInfoFileName    = "staged.json"
+)
+
+// StagedInfo records where staged changes came from
+type StagedInfo struct {
+	Source   string    `json:"source"`    // Config the changes were staged from
+	StagedAt time.Time `json:"staged_at"` // When they were staged
+	StagedBy string    `json:"staged_by"` // Account that staged them
+	Host     string    `json:"host"`      // Machine they were staged on
+}
+
+// stagedChangesPath returns the location of the staged config
+func stagedChangesPath() (string, error) {
+	return appDataPath(stagedChangesFileName)
+}
+
+// isStagedChangesFile reports whether filePath is the staged config
+func isStagedChangesFile(filePath string) bool {
+	path, err := stagedChangesPath()
+	return err == nil && strings.EqualFold(path, filePath)
+}
+
+// keepSecretPlaceholders puts the secret prompts and plugin lookups of unresolved back into the resolved config,
+// so secrets are asked for again at the commit instead of being written to the staging area in plaintext
+// Secret parameters cannot be asked for again once the parameters are gone, so configs using them are refused
+func keepSecretPlaceholders(config, unresolved Config) (Config, error) {
+	secrets := newPlaceholderResolver(nil).withParams(unresolved.Params, nil)
+	keep := func(resolved, raw []Variable) ([]Variable, error) {
+		rawValues := map[string]string{}
+		for _, v := range raw {
+			if v.Operation == "set" && hasPlaceholders(v.Value) {
+				rawValues[strings.ToUpper(v.Name)] = v.Value
+			}
+		}
+		kept := make([]Variable, len(resolved))
+		for i, v := range resolved {
+			if value, ok := rawValues[strings.ToUpper(v.Name)]; ok && v.Operation == "set" {
+				if secrets.usesSecretParam(value) {
+					return nil, fmt.Errorf("%s uses a secret parameter, which cannot be staged without writing it to disk; apply the config directly", v.Name)
+				}
+				if strings.Contains(value, secretPromptPlaceholderPrefix) || strings.Contains(value, pluginPlaceholderPrefix) {
+					v.Value, v.Sensitive = value, true
+				}
+			}
+			kept[i] = v
+		}
+		return kept, nil
+	}
+	var err error
+	if config.UserVariables, err = keep(config.UserVariables, unresolved.UserVariables); err != nil {
+		return config, err
+	}
+	if config.SystemVariables, err = keep(config.SystemVariables, unresolved.SystemVariables); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// stageChanges writes a resolved config to the staging area, replacing anything staged before
+// Patterns, sync mode and inheritance are already resolved, so committing writes exactly what was reviewed;
+// secret placeholders are taken from unresolved and asked for again at the commit
+func stageChanges(config, unresolved Config, source string) error {
+	config, err := keepSecretPlaceholders(config, unresolved)
+	if err != nil {
+		return err
+	}
+	path, err := stagedChangesPath()
+	if err != nil {
+		return err
+	}
+	infoPath, err := appDataPath(stagedInfoFileName)
+	if err != nil {
+		return err
+	}
+	config.Extends, config.Groups, config.Params = nil, nil, nil
+	config.Mode, config.UserMode, config.SystemMode = "", "", ""
+	if err := saveConfigToFile(config, path); err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	data, err := json.MarshalIndent(StagedInfo{Source: source, StagedAt: time.Now(), StagedBy: os.Getenv("USERNAME"), Host: host}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode staging record: %w", err)
+	}
+	if err := ioutil.WriteFile(infoPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write staging record %s: %w", infoPath, err)
+	}
+	return nil
+}
+
+// loadStagedChanges reads the staged config and its record, ok is false when nothing is staged
+func loadStagedChanges() (config Config, info StagedInfo, ok bool, err error) {
+	path, err := stagedChangesPath()
+	if err != nil {
+		return config, info, false, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return config, info, false, nil
+	}
+	if config, err = loadConfig(path); err != nil {
+		return config, info, false, err
+	}
+	if infoPath, err := appDataPath(stagedInfoFileName); err == nil {
+		if data, err := ioutil.ReadFile(infoPath); err == nil {
+			if err := json.Unmarshal(data, &info); err != nil {
+				fmt.Printf("Warning: Could not read the staging record: %v\n", err)
+			}
+		}
+	}
+	return config, info, true, nil
+}
+
+// clearStagedChanges empties the staging area after a commit or discard
+func clearStagedChanges() error {
+	for _, name := range []string{stagedChangesFileName, stagedInfoFileName} {
+		path, err := appDataPath(name)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear staged changes: %w", err)
+		}
+	}
+	return nil
+}
+
+// describeStagedChanges lists the staged changes for review, masking sensitive values
+func describeStagedChanges(config Config, info StagedInfo, patterns []string) string {
+	var lines []string
+	if !info.StagedAt.IsZero() {
+		lines = append(lines, fmt.Sprintf("Staged from %s by %s on %s at %s.", info.Source, info.StagedBy, info.Host, info.StagedAt.Local().Format("2006-01-02 15:04:05")), "")
+	}
+	changes := configChanges(config)
+	if len(changes) == 0 {
+		lines = append(lines, "The staged config makes no changes.")
+	}
+	for _, c := range changes {
+		if c.Operation == "set" {
+			lines = append(lines, fmt.Sprintf("%s  %s = %s", c.Scope, c.Name, c.displayValue(patterns)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s  delete %s", c.Scope, c.Name))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showStagedChangesWindow shows the staged changes with the buttons to commit or discard them
+// commit applies the staged config through the regular apply, which asks again for dangerous changes and elevation
+func showStagedChangesWindow(app fyne.App, parent fyne.Window, settings *Settings, commit func(path string)) {
+	config, info, ok, err := loadStagedChanges()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("error loading staged changes: %v", err), parent)
+		return
+	}
+	window := app.NewWindow("Staged Changes")
+	window.Resize(fyne.NewSize(760, 480))
+	if !ok {
+		window.SetContent(container.NewBorder(nil, container.NewHBox(widget.NewButton("Close", window.Close)), nil, nil,
+			widget.NewLabel("Nothing is staged. Use 'Stage for Review' on the Config / Apply tab to stage a config.")))
+		window.Show()
+		return
+	}
+
+	changesLabel := widget.NewLabel(describeStagedChanges(config, info, settings.SensitivePatterns))
+	changesLabel.Wrapping = fyne.TextWrapBreak
+	commitButton := widget.NewButton("Commit to Registry", func() {
+		dialog.ShowConfirm("Commit Staged Changes", fmt.Sprintf("Write the %d staged change(s) to the registry?", len(configChanges(config))), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			path, err := stagedChangesPath()
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			window.Close()
+			commit(path)
+		}, window)
+	})
+	discardButton := widget.NewButton("Discard", func() {
+		dialog.ShowConfirm("Discard Staged Changes", "Discard the staged changes without applying them?", func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := clearStagedChanges(); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if err := appendHistory(HistoryEntry{Action: "discard", ConfigPath: info.Source, Metadata: config.Metadata, Success: true, Message: "staged changes discarded"}); err != nil {
+				fmt.Printf("Warning: Could not write audit log: %v\n", err)
+			}
+			window.Close()
+		}, window)
+	})
+	hideInReadOnly(commitButton, discardButton)
+
+	window.SetContent(container.NewBorder(
+		widget.NewLabel("These changes are staged and not yet written to the registry:"),
+		container.NewVBox(widget.NewSeparator(), container.NewHBox(commitButton, discardButton, widget.NewButton("Close", window.Close))),
+		nil, nil,
+		container.NewVScroll(changesLabel),
+	))
+	window.Show()
+}
@@ -0,0 +1,70 @@
+// storage.go
+// Application data locations - where history, settings and other persistent state are kept on disk
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// appDataFolderName is the directory created under the user's roaming profile for application state
+const appDataFolderName = "SystemVariableManager"
+
+// Portable mode keeps all state in a folder next to the executable, e.g. when running from a USB stick
+const (
+	portableFlag           = "--portable"   // Command line switch enabling portable mode
+	portableMarkerFile     = "portable.ini" // Enables portable mode when present next to the executable
+	portableDataFolderName = "data"         // State directory next to the executable in portable mode
+)
+
+// portableMode is set once at startup from the --portable flag or the marker file
+var portableMode bool
+
+// detectPortableMode reports whether portable mode is requested by the flag or by the marker file next to the executable
+func detectPortableMode(flag bool) bool {
+	if flag {
+		return true
+	}
+	dir, err := executableDir()
+	return err == nil && fileExists(filepath.Join(dir, portableMarkerFile))
+}
+
+// executableDir returns the directory containing the running executable
+func executableDir() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("cannot find executable path: %w", err)
+	}
+	return filepath.Dir(exePath), nil
+}
+
+// appDataDir returns the directory used for persistent application state, creating it if necessary
+func appDataDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine user config directory: %w", err)
+	}
+
+	dir := filepath.Join(base, appDataFolderName)
+	if portableMode {
+		exeDir, err := executableDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(exeDir, portableDataFolderName)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create application data directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// appDataPath returns the full path of a file inside the application data directory
+func appDataPath(name string) (string, error) {
+	dir, err := appDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
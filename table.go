@@ -0,0 +1,159 @@
+// table.go
+// Sortable table - a widget.Table wrapper with clickable, resizable column headers used by the browser views
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// tableColumn describes one column of a sortableTable
+type tableColumn struct {
+	Title    string               // Header text
+	Width    float32              // Initial width, columns can be resized by dragging the header edge
+	Cell     func(row int) string // Text of the cell for a data row
+	OnTapped func(row int)        // Optional action when a cell of this column is clicked, e.g. toggling a mark
+}
+
+// sortableTable displays rows in columns that can be sorted by clicking a header
+// Row indices passed to callbacks always refer to the underlying data, not the sorted position
+type sortableTable struct {
+	table      *widget.Table
+	columns    []tableColumn
+	rowCount   func() int
+	order      []int // Data row index for each displayed row
+	sortColumn int   // Column currently used for sorting, -1 for data order
+	descending bool
+	selected   int       // Selected data row, -1 when nothing is selected
+	OnSelected func(int) // Called with the data row index when a row is selected
+
+	// OnSecondaryTapped is called with the data row and absolute position when a cell is right-clicked
+	OnSecondaryTapped func(row int, pos fyne.Position)
+}
+
+// tableCell is a label that forwards right-clicks to its table
+type tableCell struct {
+	widget.Label
+	table *sortableTable
+	row   int // Data row currently displayed, -1 when unused
+}
+
+// newTableCell creates an ellipsis-truncated cell for t
+func newTableCell(t *sortableTable) *tableCell {
+	cell := &tableCell{table: t, row: -1}
+	cell.Truncation = fyne.TextTruncateEllipsis
+	cell.ExtendBaseWidget(cell)
+	return cell
+}
+
+// TappedSecondary opens the table's context action for the row of the cell
+func (c *tableCell) TappedSecondary(e *fyne.PointEvent) {
+	if c.row >= 0 && c.table.OnSecondaryTapped != nil {
+		c.table.OnSecondaryTapped(c.row, e.AbsolutePosition)
+	}
+}
+
+// newSortableTable creates a table over rowCount data rows with the given columns
+func newSortableTable(columns []tableColumn, rowCount func() int) *sortableTable {
+	t := &sortableTable{columns: columns, rowCount: rowCount, sortColumn: -1, selected: -1}
+
+	t.table = widget.NewTable(
+		func() (int, int) { return len(t.order), len(t.columns) },
+		func() fyne.CanvasObject {
+			return newTableCell(t)
+		},
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			c := cell.(*tableCell)
+			if id.Row < len(t.order) {
+				c.row = t.order[id.Row]
+				c.SetText(t.columns[id.Col].Cell(c.row))
+			} else {
+				c.row = -1
+			}
+		},
+	)
+	t.table.ShowHeaderRow = true
+	t.table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewButton("", nil)
+	}
+	t.table.UpdateHeader = func(id widget.TableCellID, header fyne.CanvasObject) {
+		button := header.(*widget.Button)
+		title := t.columns[id.Col].Title
+		if id.Col == t.sortColumn {
+			if t.descending {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		button.SetText(title)
+		col := id.Col
+		button.OnTapped = func() { t.sortBy(col) }
+	}
+	t.table.OnSelected = func(id widget.TableCellID) {
+		if id.Row < 0 || id.Row >= len(t.order) {
+			return
+		}
+		t.selected = t.order[id.Row]
+		if tapped := t.columns[id.Col].OnTapped; tapped != nil {
+			tapped(t.selected)
+			// Unselect the cell so clicking it again fires another tap
+			t.table.Unselect(id)
+			t.table.Refresh()
+		}
+		if t.OnSelected != nil {
+			t.OnSelected(t.selected)
+		}
+	}
+
+	for i, c := range columns {
+		t.table.SetColumnWidth(i, c.Width)
+	}
+	t.Refresh()
+	return t
+}
+
+// sortBy sorts by a column, toggling the direction when the column is already the sort column
+func (t *sortableTable) sortBy(col int) {
+	if t.sortColumn == col {
+		t.descending = !t.descending
+	} else {
+		t.sortColumn = col
+		t.descending = false
+	}
+	t.Refresh()
+}
+
+// Refresh rebuilds the display order from the data and redraws the table
+func (t *sortableTable) Refresh() {
+	n := t.rowCount()
+	t.order = make([]int, n)
+	for i := range t.order {
+		t.order[i] = i
+	}
+
+	if t.sortColumn >= 0 {
+		cell := t.columns[t.sortColumn].Cell
+		sort.SliceStable(t.order, func(i, j int) bool {
+			a, b := strings.ToUpper(cell(t.order[i])), strings.ToUpper(cell(t.order[j]))
+			if t.descending {
+				return a > b
+			}
+			return a < b
+		})
+	}
+
+	if t.selected >= n {
+		t.selected = -1
+	}
+	t.table.Refresh()
+}
+
+// clearSelection removes the row selection
+func (t *sortableTable) clearSelection() {
+	t.selected = -1
+	t.table.UnselectAll()
+}
@@ -0,0 +1,243 @@
+// tags.go
+// Variable tags - groups variables by free-form tags and an owner, set in configs or the Variables tab, to filter, export, apply and delete by tag
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// tagsFileName stores the tags and owners of variables
+const tagsFileName = "tags.json"
+
+// tagFilterPrefix and ownerFilterPrefix select variables by tag or owner in the Variables tab filter, e.g. "tag:java"
+const (
+	tagFilterPrefix   = "tag:"
+	ownerFilterPrefix = "owner:"
+)
+
+// VariableTag records the tags and owner of a variable
+type VariableTag struct {
+	Scope string   `json:"scope"`           // ScopeUser or ScopeSystem
+	Name  string   `json:"name"`            // Variable name
+	Tags  []string `json:"tags,omitempty"`  // Lower-case tags, sorted
+	Owner string   `json:"owner,omitempty"` // Person or team responsible for the variable
+}
+
+// tagsMu serializes access to the tags file
+var tagsMu sync.Mutex
+
+// readTagsFile reads the variable tags, the caller must hold tagsMu
+func readTagsFile() ([]VariableTag, error) {
+	path, err := appDataPath(tagsFileName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags file %s: %w", path, err)
+	}
+	var entries []VariableTag
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse tags file %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// writeTagsFile writes the variable tags, the caller must hold tagsMu
+func writeTagsFile(entries []VariableTag) error {
+	path, err := appDataPath(tagsFileName)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tags: %w", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tags file %s: %w", path, err)
+	}
+	return nil
+}
+
+// normalizeTags lower-cases, trims, de-duplicates and sorts tags
+func normalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// parseTagList splits a comma or space separated list of tags
+func parseTagList(text string) []string {
+	return normalizeTags(strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }))
+}
+
+// validateConfigTags rejects tags the filter syntax cannot express
+func validateConfigTags(config Config) error {
+	for _, v := range append(append([]Variable{}, config.UserVariables...), config.SystemVariables...) {
+		for _, t := range v.Tags {
+			if strings.TrimSpace(t) == "" || strings.ContainsAny(t, ",; \t") {
+				return fmt.Errorf("tag %q of %s must not be empty or contain spaces, commas or semicolons", t, v.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// updateVariableTags replaces the tags and owner of variables of a scope and forgets those of the deleted ones
+// Variables without tags and owner are dropped from the file
+func updateVariableTags(scope string, tagged []VariableTag, deleted []string) error {
+	if len(tagged) == 0 && len(deleted) == 0 {
+		return nil
+	}
+	tagsMu.Lock()
+	defer tagsMu.Unlock()
+
+	entries, err := readTagsFile()
+	if err != nil {
+		return err
+	}
+	drop := map[string]bool{}
+	for _, name := range deleted {
+		drop[managedKey(scope, name)] = true
+	}
+	for _, t := range tagged {
+		drop[managedKey(scope, t.Name)] = true
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if !drop[managedKey(e.Scope, e.Name)] {
+			kept = append(kept, e)
+		}
+	}
+	for _, t := range tagged {
+		t.Scope, t.Tags, t.Owner = scope, normalizeTags(t.Tags), strings.TrimSpace(t.Owner)
+		if len(t.Tags) > 0 || t.Owner != "" {
+			kept = append(kept, t)
+		}
+	}
+	return writeTagsFile(kept)
+}
+
+// tagIndex looks up the tags of variables by scope and name
+type tagIndex map[string]VariableTag
+
+// loadTagIndex reads the tags file into a lookup index
+func loadTagIndex() (tagIndex, error) {
+	tagsMu.Lock()
+	entries, err := readTagsFile()
+	tagsMu.Unlock()
+	index := tagIndex{}
+	for _, e := range entries {
+		index[managedKey(e.Scope, e.Name)] = e
+	}
+	return index, err
+}
+
+// lookup returns the tags of a variable
+func (index tagIndex) lookup(scope, name string) (VariableTag, bool) {
+	t, ok := index[managedKey(scope, name)]
+	return t, ok
+}
+
+// annotateTags copies the recorded tags and owner onto variables read from the registry, so exports carry them
+func annotateTags(variables []Variable, scope string, index tagIndex) {
+	for i := range variables {
+		if t, ok := index.lookup(scope, variables[i].Name); ok {
+			variables[i].Tags, variables[i].Owner = t.Tags, t.Owner
+		}
+	}
+}
+
+// hasAnyTag reports whether the variable carries one of the tags
+func (v Variable) hasAnyTag(tags []string) bool {
+	for _, want := range tags {
+		for _, t := range v.Tags {
+			if strings.EqualFold(t, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterConfigByTags keeps the entries of a config carrying at least one of the tags, all entries when tags is empty
+func filterConfigByTags(config Config, tags []string) Config {
+	if len(tags) == 0 {
+		return config
+	}
+	keep := func(variables []Variable) []Variable {
+		var result []Variable
+		for _, v := range variables {
+			if v.hasAnyTag(tags) {
+				result = append(result, v)
+			}
+		}
+		return result
+	}
+	config.UserVariables, config.SystemVariables = keep(config.UserVariables), keep(config.SystemVariables)
+	return config
+}
+
+// matchesTagFilter reports whether a variable matches a "tag:" or "owner:" filter, ok is false for other filters
+func (v Variable) matchesTagFilter(filter string) (matches, ok bool) {
+	lower := strings.ToLower(strings.TrimSpace(filter))
+	switch {
+	case strings.HasPrefix(lower, tagFilterPrefix):
+		return v.hasAnyTag(parseTagList(strings.TrimPrefix(lower, tagFilterPrefix))), true
+	case strings.HasPrefix(lower, ownerFilterPrefix):
+		owner := strings.TrimSpace(strings.TrimPrefix(lower, ownerFilterPrefix))
+		return owner != "" && strings.Contains(strings.ToLower(v.Owner), owner), true
+	}
+	return false, false
+}
+
+// showTagDialog edits the tags and owner of variables, the fields start from the first variable
+func showTagDialog(variables []ScopedVariable, parent fyne.Window, onDone func()) {
+	tagsEntry := widget.NewEntry()
+	tagsEntry.SetPlaceHolder("e.g. java, corp-proxy")
+	tagsEntry.SetText(strings.Join(variables[0].Tags, ", "))
+	ownerEntry := widget.NewEntry()
+	ownerEntry.SetPlaceHolder("Person or team responsible")
+	ownerEntry.SetText(variables[0].Owner)
+
+	dialog.ShowForm(fmt.Sprintf("Tag %d Variable(s)", len(variables)), "Save", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Tags", tagsEntry),
+		widget.NewFormItem("Owner", ownerEntry),
+	}, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		byScope := map[string][]VariableTag{}
+		for _, v := range variables {
+			byScope[v.Scope] = append(byScope[v.Scope], VariableTag{Name: v.Name, Tags: parseTagList(tagsEntry.Text), Owner: ownerEntry.Text})
+		}
+		for scope, tagged := range byScope {
+			if err := updateVariableTags(scope, tagged, nil); err != nil {
+				dialog.ShowError(fmt.Errorf("error saving tags: %v", err), parent)
+				return
+			}
+		}
+		onDone()
+	}, parent)
+}
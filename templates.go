@@ -0,0 +1,218 @@
+// templates.go
+// Community templates - browses a curated JSON index of environment templates and imports them as profiles
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// defaultTemplateIndexURL is the curated template index maintained in the project repository
+const defaultTemplateIndexURL = "https://raw.githubusercontent.com/LewdLillyVT/SystemVariableManager/main/templates/index.json"
+
+// maxTemplateSize bounds the size of an index or template download
+const maxTemplateSize = 1 << 20
+
+// TemplateEntry describes one community template in the index
+type TemplateEntry struct {
+	Name        string   `json:"name"`             // Suggested profile name
+	Description string   `json:"description"`      // What the template sets up
+	Author      string   `json:"author,omitempty"` // Who contributed it
+	URL         string   `json:"url"`              // Template config, absolute or relative to the index
+	Tags        []string `json:"tags,omitempty"`   // Keywords for filtering
+}
+
+// templateIndex is the JSON document listing the templates
+type templateIndex struct {
+	Templates []TemplateEntry `json:"templates"`
+}
+
+// fetchTemplateData downloads an index or template with the remote config timeout and a size limit
+func fetchTemplateData(rawURL string) ([]byte, error) {
+	client := &http.Client{Timeout: remoteFetchTimeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: server returned %s", rawURL, resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxTemplateSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	if len(data) > maxTemplateSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, maxTemplateSize)
+	}
+	return data, nil
+}
+
+// fetchTemplateIndex downloads the index and resolves template URLs relative to it
+func fetchTemplateIndex(indexURL string) ([]TemplateEntry, error) {
+	data, err := fetchTemplateData(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	var index templateIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid template index %s: %w", indexURL, err)
+	}
+
+	var templates []TemplateEntry
+	for _, t := range index.Templates {
+		if strings.TrimSpace(t.Name) == "" || strings.TrimSpace(t.URL) == "" {
+			continue
+		}
+		t.URL = resolveConfigReference(indexURL, t.URL)
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// importTemplate downloads a template, validates it and stores it as the named profile
+// Relative extends references are resolved against the template URL so the profile keeps working locally
+func importTemplate(t TemplateEntry, profile string) error {
+	target, err := profilePath(profile)
+	if err != nil {
+		return err
+	}
+	data, err := fetchTemplateData(t.URL)
+	if err != nil {
+		return err
+	}
+	config, err := parseConfig(data)
+	if err != nil {
+		return fmt.Errorf("template %s is not a valid config: %w", t.Name, err)
+	}
+
+	if len(config.Extends) == 0 {
+		if err := ioutil.WriteFile(target, data, 0644); err != nil {
+			return fmt.Errorf("failed to write profile %s: %w", profile, err)
+		}
+		return nil
+	}
+	for i, parent := range config.Extends {
+		config.Extends[i] = resolveConfigReference(t.URL, parent)
+	}
+	return saveProfile(profile, config)
+}
+
+// describe renders the template details shown next to the list
+func (t TemplateEntry) describe() string {
+	lines := []string{t.Name, "", t.Description}
+	if t.Author != "" {
+		lines = append(lines, "", fmt.Sprintf("Author: %s", t.Author))
+	}
+	if len(t.Tags) > 0 {
+		lines = append(lines, fmt.Sprintf("Tags: %s", strings.Join(t.Tags, ", ")))
+	}
+	lines = append(lines, fmt.Sprintf("Source: %s", t.URL))
+	return strings.Join(lines, "\n")
+}
+
+// showTemplateBrowser opens the community template window; imported reloads the profile list after an import
+func showTemplateBrowser(settings *Settings, imported func()) {
+	win := fyne.CurrentApp().NewWindow("Community Templates")
+
+	indexURL := strings.TrimSpace(settings.TemplateIndexURL)
+	if indexURL == "" {
+		indexURL = defaultTemplateIndexURL
+	}
+
+	var templates, shown []TemplateEntry
+	selected := -1
+	statusLabel := widget.NewLabel(fmt.Sprintf("Loading %s...", indexURL))
+	details := widget.NewLabel("Select a template to see its description.")
+	details.Wrapping = fyne.TextWrapWord
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("Profile name")
+
+	list := widget.NewList(
+		func() int { return len(shown) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(shown[id].Name)
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		details.SetText(shown[id].describe())
+		nameEntry.SetText(invalidProfileNameChars.ReplaceAllString(shown[id].Name, "_"))
+	}
+	list.OnUnselected = func(widget.ListItemID) { selected = -1 }
+
+	filterEntry := widget.NewEntry()
+	filterEntry.SetPlaceHolder("Filter by name, description or tag")
+	applyFilter := func() {
+		filter := strings.ToLower(strings.TrimSpace(filterEntry.Text))
+		shown = nil
+		for _, t := range templates {
+			text := strings.ToLower(t.Name + " " + t.Description + " " + strings.Join(t.Tags, " "))
+			if filter == "" || strings.Contains(text, filter) {
+				shown = append(shown, t)
+			}
+		}
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+	filterEntry.OnChanged = func(string) { applyFilter() }
+
+	go func() {
+		loaded, err := fetchTemplateIndex(indexURL)
+		if err != nil {
+			statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+			return
+		}
+		templates = loaded
+		applyFilter()
+		statusLabel.SetText(fmt.Sprintf("%d template(s) from %s", len(templates), indexURL))
+	}()
+
+	importButton := widget.NewButton("Import as Profile", func() {
+		if selected < 0 {
+			dialog.ShowInformation("Error", "Please select a template first.", win)
+			return
+		}
+		t := shown[selected]
+		name := strings.TrimSpace(nameEntry.Text)
+		doImport := func() {
+			go func() {
+				if err := importTemplate(t, name); err != nil {
+					dialog.ShowError(fmt.Errorf("error importing template: %v", err), win)
+					return
+				}
+				imported()
+				dialog.ShowInformation("Template Imported", fmt.Sprintf("Template %q was saved as profile %q. Review it with 'Use Profile' and 'Preview Changes' before applying.", t.Name, name), win)
+			}()
+		}
+		if path, err := profilePath(name); err == nil && fileExists(path) {
+			dialog.ShowConfirm("Replace Profile", fmt.Sprintf("Profile %q already exists. Replace it?", name), func(ok bool) {
+				if ok {
+					doImport()
+				}
+			}, win)
+			return
+		}
+		doImport()
+	})
+
+	win.SetContent(container.NewBorder(
+		container.NewVBox(statusLabel, filterEntry),
+		container.NewBorder(nil, nil, widget.NewLabel("Profile Name:"), importButton, nameEntry),
+		nil, nil,
+		container.NewHSplit(list, container.NewVScroll(details)),
+	))
+	win.Resize(fyne.NewSize(800, 500))
+	win.Show()
+}
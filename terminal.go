@@ -0,0 +1,114 @@
+// terminal.go
+// Windows Terminal profiles - fragments that open shells with a config's environment without changing the registry
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// windowsTerminalFragmentsApp is the fragment folder name shown as the source of the generated profiles
+const windowsTerminalFragmentsApp = "SystemVariableManager"
+
+// windowsTerminalFragment is the JSON document Windows Terminal loads from its Fragments directory
+type windowsTerminalFragment struct {
+	Profiles []windowsTerminalProfile `json:"profiles"`
+}
+
+// windowsTerminalProfile is a single profile of a fragment
+type windowsTerminalProfile struct {
+	Name        string `json:"name"`
+	Commandline string `json:"commandline"`
+	Icon        string `json:"icon,omitempty"`
+}
+
+// windowsTerminalExportFormat generates a fragment with PowerShell and Command Prompt profiles
+var windowsTerminalExportFormat = exportFormat{
+	Name:        "Windows Terminal profiles",
+	Description: "Profile fragment with PowerShell and Command Prompt tabs that start with the variables set. Save it in the suggested Fragments folder and restart Windows Terminal.",
+	Extension:   ".json",
+	Render:      renderWindowsTerminalFragment,
+	StartDir:    windowsTerminalFragmentsDir,
+}
+
+// windowsTerminalFragmentsDir returns (and creates) the per-user fragment folder of this application
+func windowsTerminalFragmentsDir() string {
+	base := os.Getenv("LOCALAPPDATA")
+	if base == "" {
+		return ""
+	}
+	dir := filepath.Join(base, "Microsoft", "Windows Terminal", "Fragments", windowsTerminalFragmentsApp)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ""
+	}
+	return dir
+}
+
+// renderWindowsTerminalFragment renders config as a Windows Terminal fragment
+func renderWindowsTerminalFragment(config Config) ([]byte, error) {
+	name := exportName(config, "Environment")
+	variables := exportVariables(config)
+
+	fragment := windowsTerminalFragment{Profiles: []windowsTerminalProfile{
+		{
+			Name:        fmt.Sprintf("%s (PowerShell)", name),
+			Commandline: "powershell.exe -NoExit -NoLogo -Command " + quoteCommandArgument(powerShellEnvironmentScript(variables)),
+			Icon:        "ms-appx:///ProfileIcons/{61c54bbd-c2c6-5271-96e7-009a87ff44bf}.png",
+		},
+		{
+			Name:        fmt.Sprintf("%s (Command Prompt)", name),
+			Commandline: "cmd.exe /k " + cmdEnvironmentScript(variables),
+			Icon:        "ms-appx:///ProfileIcons/{0caa0dad-35be-5f56-a8ff-afceeeaa6101}.png",
+		},
+	}}
+	return marshalExportJSON(fragment)
+}
+
+// powerShellEnvironmentScript returns PowerShell statements that apply variables to the session
+func powerShellEnvironmentScript(variables []Variable) string {
+	var statements []string
+	for _, v := range variables {
+		name := powerShellQuote(v.Name)
+		switch {
+		case v.Operation == "delete":
+			statements = append(statements, fmt.Sprintf("[Environment]::SetEnvironmentVariable(%s, $null)", name))
+		case v.isExpandable():
+			statements = append(statements, fmt.Sprintf("[Environment]::SetEnvironmentVariable(%s, [Environment]::ExpandEnvironmentVariables(%s))", name, powerShellQuote(v.Value)))
+		default:
+			statements = append(statements, fmt.Sprintf("[Environment]::SetEnvironmentVariable(%s, %s)", name, powerShellQuote(v.Value)))
+		}
+	}
+	if len(statements) == 0 {
+		return "$null"
+	}
+	return strings.Join(statements, "; ")
+}
+
+// cmdEnvironmentScript returns Command Prompt statements that apply variables to the session
+// %VAR% references are expanded by cmd.exe itself, matching REG_EXPAND_SZ values
+func cmdEnvironmentScript(variables []Variable) string {
+	var statements []string
+	for _, v := range variables {
+		if v.Operation == "delete" {
+			statements = append(statements, fmt.Sprintf(`set "%s="`, v.Name))
+			continue
+		}
+		statements = append(statements, fmt.Sprintf(`set "%s=%s"`, v.Name, strings.ReplaceAll(v.Value, `"`, `""`)))
+	}
+	if len(statements) == 0 {
+		return "rem"
+	}
+	return strings.Join(statements, " & ")
+}
+
+// powerShellQuote returns value as a single-quoted PowerShell string literal
+func powerShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// quoteCommandArgument quotes an argument for a Windows command line, escaping embedded double quotes
+func quoteCommandArgument(arg string) string {
+	return `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+}
@@ -0,0 +1,130 @@
+// toolchains.go
+// Toolchains tab - helpers that detect language installs and wire their variables and PATH entries safely
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// toolchainContext is what a toolchain helper card needs to read and change the environment
+type toolchainContext struct {
+	parent   fyne.Window
+	settings *Settings
+	isAdmin  bool
+	all      []ScopedVariable // Variables of both scopes when the tab was last refreshed
+	refresh  func()           // Rebuilds every card, called after a change
+}
+
+// lookup returns the variable of a scope as of the last refresh
+func (c toolchainContext) lookup(scope, name string) (ScopedVariable, bool) {
+	for _, v := range c.all {
+		if v.Scope == scope && strings.EqualFold(v.Name, name) {
+			return v, true
+		}
+	}
+	return ScopedVariable{}, false
+}
+
+// apply confirms and writes changes made by a helper, then refreshes the tab; it runs in the background
+func (c toolchainContext) apply(source string, changes []ScopedVariable) {
+	go func() {
+		if !confirmDangerousChanges(changes, *c.settings, c.parent) {
+			return
+		}
+		if err := applyDirectChanges(source, changes, c.isAdmin, *c.settings); err != nil {
+			dialog.ShowError(fmt.Errorf("error applying changes: %v", err), c.parent)
+		}
+		c.refresh()
+	}()
+}
+
+// pathChange returns the change that rewrites the Path of a scope: entries matching remove are dropped
+// and prepend is put in front; ok is false when the value would not change
+func (c toolchainContext) pathChange(scope string, remove func(entry string) bool, prepend ...string) (ScopedVariable, bool) {
+	v, exists := c.lookup(scope, "Path")
+	if !exists {
+		v = ScopedVariable{Scope: scope, Variable: Variable{Name: "Path", Type: TypeExpand}}
+	}
+	kept := append([]string{}, prepend...)
+	for _, entry := range pathEntries(v.Value) {
+		if !remove(entry) && !containsPathEntry(prepend, entry) {
+			kept = append(kept, entry)
+		}
+	}
+	value := strings.Join(kept, ";")
+	if value == v.Value {
+		return v, false
+	}
+	v.Value, v.Operation = value, "set"
+	return v, true
+}
+
+// containsPathEntry reports whether entries lists entry, comparing normalized paths
+func containsPathEntry(entries []string, entry string) bool {
+	key := normalizePathEntry(entry)
+	for _, e := range entries {
+		if normalizePathEntry(e) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isBelowDirectory reports whether a PATH entry is dir or one of its subdirectories
+func isBelowDirectory(entry, dir string) bool {
+	entryKey, dirKey := normalizePathEntry(entry), normalizePathEntry(dir)
+	return entryKey == dirKey || strings.HasPrefix(entryKey, dirKey+`\`)
+}
+
+// isDirectory reports whether path exists and is a directory
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// toolchainHelper builds the card of one toolchain
+type toolchainHelper struct {
+	Title string
+	Build func(ctx toolchainContext) fyne.CanvasObject
+}
+
+// toolchainHelpers are the cards shown on the Toolchains tab
+var toolchainHelpers = []toolchainHelper{
+	{Title: "Python", Build: newPythonHelper},
+	{Title: "Node.js", Build: newNodeHelper},
+	{Title: "Go", Build: newGoHelper},
+}
+
+// newToolchainsTab builds the Toolchains tab, rebuilt on Refresh and after every change
+func newToolchainsTab(parent fyne.Window, settings *Settings, isAdmin bool) fyne.CanvasObject {
+	cards := container.NewVBox()
+	var refresh func()
+	refresh = func() {
+		go func() {
+			all, err := readAllVariables()
+			cards.RemoveAll()
+			if err != nil {
+				cards.Add(widget.NewLabel(fmt.Sprintf("⚠️  Could not read environment variables: %v", err)))
+			}
+			ctx := toolchainContext{parent: parent, settings: settings, isAdmin: isAdmin, all: all, refresh: refresh}
+			for _, helper := range toolchainHelpers {
+				cards.Add(widget.NewCard(helper.Title, "", helper.Build(ctx)))
+			}
+			cards.Refresh()
+		}()
+	}
+	refresh()
+
+	return container.NewBorder(
+		container.NewHBox(widget.NewButton("Refresh", refresh)),
+		nil, nil, nil,
+		container.NewVScroll(cards),
+	)
+}
@@ -0,0 +1,202 @@
+// trash.go
+// Session trash - keeps the values of deleted variables so they can be restored until the trash is purged
+// The trash lives in memory and is emptied when the application exits
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// TrashedVariable is a deleted variable together with the value it had before deletion
+type TrashedVariable struct {
+	ID        int       // Identifies the entry within the session
+	Scope     string    // ScopeUser or ScopeSystem
+	Variable            // Name, value and type at the time of deletion
+	DeletedAt time.Time // When the variable was deleted
+}
+
+// variableTrash is a concurrency-safe list of deleted variables
+type variableTrash struct {
+	mu       sync.Mutex
+	items    []TrashedVariable
+	nextID   int
+	onChange func() // Called after the contents changed, used to refresh the Trash tab
+}
+
+// sessionTrash collects every variable deleted by this application instance
+var sessionTrash = &variableTrash{}
+
+// add moves a deleted variable into the trash
+func (t *variableTrash) add(scope string, v Variable) {
+	t.mu.Lock()
+	t.nextID++
+	v.Operation = "set"
+	t.items = append(t.items, TrashedVariable{ID: t.nextID, Scope: scope, Variable: v, DeletedAt: time.Now()})
+	onChange := t.onChange
+	t.mu.Unlock()
+
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// list returns the trashed variables, most recently deleted first
+func (t *variableTrash) list() []TrashedVariable {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	items := make([]TrashedVariable, len(t.items))
+	for i, item := range t.items {
+		items[len(items)-1-i] = item
+	}
+	return items
+}
+
+// remove drops entries from the trash by ID, used after restoring or purging them
+func (t *variableTrash) remove(ids ...int) {
+	t.mu.Lock()
+	kept := t.items[:0]
+	for _, item := range t.items {
+		drop := false
+		for _, id := range ids {
+			drop = drop || item.ID == id
+		}
+		if !drop {
+			kept = append(kept, item)
+		}
+	}
+	t.items = kept
+	onChange := t.onChange
+	t.mu.Unlock()
+
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// purge empties the trash
+func (t *variableTrash) purge() {
+	t.mu.Lock()
+	t.items = nil
+	onChange := t.onChange
+	t.mu.Unlock()
+
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// newTrashTab builds the Trash tab listing deleted variables with restore and purge actions
+func newTrashTab(parent fyne.Window, settings *Settings, isAdmin bool) fyne.CanvasObject {
+	var items []TrashedVariable
+	selected := -1
+
+	list := widget.NewList(
+		func() int { return len(items) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			t := items[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s  [%s] %s = %s", t.DeletedAt.Format("15:04:05"), strings.ToUpper(t.Scope), t.Name, t.displayValue(settings.SensitivePatterns)))
+		},
+	)
+
+	reload := func() {
+		items = sessionTrash.list()
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+	}
+	reload()
+
+	restoreButton := widget.NewButton("Restore", func() {
+		if selected < 0 {
+			dialog.ShowInformation("Error", "Please select a deleted variable first.", parent)
+			return
+		}
+		item := items[selected]
+		restore := func() {
+			go func() {
+				if err := applyDirectChanges("Trash restore", []ScopedVariable{{Scope: item.Scope, Variable: item.Variable}}, isAdmin, *settings); err != nil {
+					dialog.ShowError(fmt.Errorf("error restoring %s: %v", item.Name, err), parent)
+					return
+				}
+				sessionTrash.remove(item.ID)
+			}()
+		}
+
+		// Restoring over a variable that was re-created since the deletion replaces its value
+		current, err := loadCurrentValueIndex()
+		if _, exists := current.lookup(item.Scope, item.Name); err == nil && exists {
+			dialog.ShowConfirm("Variable Exists", fmt.Sprintf("%s exists again in the %s environment. Replace it with the deleted value?", item.Name, item.Scope), func(ok bool) {
+				if ok {
+					restore()
+				}
+			}, parent)
+			return
+		}
+		restore()
+	})
+
+	hideInReadOnly(restoreButton)
+
+	// Restoring a system variable asks for administrator approval
+	updateAdminState := func() {
+		markElevates(restoreButton, isAdmin, selected >= 0 && items[selected].Scope == ScopeSystem)
+	}
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		updateAdminState()
+	}
+	list.OnUnselected = func(widget.ListItemID) {
+		selected = -1
+		updateAdminState()
+	}
+	sessionTrash.mu.Lock()
+	sessionTrash.onChange = func() {
+		reload()
+		updateAdminState()
+	}
+	sessionTrash.mu.Unlock()
+
+	purgeButton := widget.NewButton("Purge", func() {
+		if selected < 0 {
+			dialog.ShowInformation("Error", "Please select a deleted variable first.", parent)
+			return
+		}
+		item := items[selected]
+		dialog.ShowConfirm("Purge Variable", fmt.Sprintf("Permanently discard the deleted value of %s?", item.Name), func(ok bool) {
+			if ok {
+				sessionTrash.remove(item.ID)
+			}
+		}, parent)
+	})
+
+	emptyButton := widget.NewButton("Empty Trash", func() {
+		if len(items) == 0 {
+			return
+		}
+		dialog.ShowConfirm("Empty Trash", fmt.Sprintf("Permanently discard all %d deleted value(s)?", len(items)), func(ok bool) {
+			if ok {
+				sessionTrash.purge()
+			}
+		}, parent)
+	})
+
+	return container.NewBorder(
+		widget.NewLabel("Variables deleted in this session. Restore writes the old value back; the trash is emptied when the application exits."),
+		container.NewHBox(restoreButton, purgeButton, emptyButton),
+		nil, nil,
+		list,
+	)
+}
@@ -0,0 +1,150 @@
+// verify.go
+// Apply verification - starts a hidden process with the fresh environment and checks it sees what the config applied
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// verifyMismatch is a variable whose value in the verification process differs from what the config applied
+type verifyMismatch struct {
+	Name     string
+	Scope    string
+	Expected string // Value the config wrote, expanded against the verification process
+	Actual   string // Value the verification process saw, empty when it is not set
+	Problem  string
+}
+
+// verificationEnvironment starts "cmd.exe /d /c set" hidden with the environment a newly started program gets and parses its output
+func verificationEnvironment() (map[string]string, error) {
+	env, err := freshEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("cmd.exe", "/d", "/c", "set")
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("verification process failed: %w", err)
+	}
+	return environmentMap(strings.Split(strings.ReplaceAll(string(output), "\r\n", "\n"), "\n")), nil
+}
+
+// verifyAppliedConfig checks every set and delete entry of an applied config in a new process
+// It returns the number of variables checked and the ones that do not match
+func verifyAppliedConfig(config Config) (int, []verifyMismatch, error) {
+	actual, err := verificationEnvironment()
+	if err != nil {
+		return 0, nil, err
+	}
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		return 0, nil, err
+	}
+	checked, mismatches := compareAppliedEnvironment(config, actual, current)
+	return checked, mismatches, nil
+}
+
+// compareAppliedEnvironment checks every set and delete entry of an applied config against the environment of a process
+func compareAppliedEnvironment(config Config, actual map[string]string, current currentValueIndex) (int, []verifyMismatch) {
+	// A user value replaces the system value, so a system entry is only checked when the user scope does not define the name
+	userSets := map[string]bool{}
+	for _, v := range config.UserVariables {
+		if v.Operation == "set" {
+			userSets[strings.ToUpper(v.Name)] = true
+		}
+	}
+
+	checked := 0
+	var mismatches []verifyMismatch
+	check := func(scope string, v Variable) {
+		key := strings.ToUpper(v.Name)
+		value, present := actual[key]
+		other := ScopeSystem
+		if scope == ScopeSystem {
+			other = ScopeUser
+		}
+		switch v.Operation {
+		case "set":
+			if scope == ScopeSystem && userSets[key] && !concatenatedVariables[key] {
+				return
+			}
+			checked++
+			expected := v.Value
+			if v.isExpandable() {
+				expected = expandReferences(expected, actual)
+			}
+			m := verifyMismatch{Name: v.Name, Scope: scope, Expected: expected, Actual: value}
+			switch {
+			case !present:
+				m.Problem = "not set in a new process"
+			case concatenatedVariables[key] && !strings.Contains(strings.ToUpper(value), strings.ToUpper(strings.Trim(expected, ";"))):
+				m.Problem = "the new process's value does not contain the applied entries"
+			case !concatenatedVariables[key] && value != expected:
+				m.Problem = "the new process sees a different value"
+			default:
+				// References that survive in the process value name defined variables that were never expanded
+				for _, ref := range referencePattern.FindAllStringSubmatch(value, -1) {
+					if _, defined := actual[strings.ToUpper(ref[1])]; defined {
+						m.Problem = fmt.Sprintf("%%%s%% is not expanded, the value is probably stored as REG_SZ instead of REG_EXPAND_SZ", ref[1])
+						break
+					}
+				}
+			}
+			if m.Problem != "" {
+				mismatches = append(mismatches, m)
+			}
+		case "delete":
+			// The other scope may still define the name, the process then rightly sees that value
+			if _, inherited := current.lookup(other, v.Name); inherited {
+				return
+			}
+			checked++
+			if present {
+				mismatches = append(mismatches, verifyMismatch{Name: v.Name, Scope: scope, Actual: value, Problem: "still set in a new process"})
+			}
+		}
+	}
+	for _, v := range config.UserVariables {
+		check(ScopeUser, v)
+	}
+	for _, v := range config.SystemVariables {
+		check(ScopeSystem, v)
+	}
+	return checked, mismatches
+}
+
+// describeVerifyMismatches renders mismatches for a dialog, masking sensitive values
+func describeVerifyMismatches(mismatches []verifyMismatch, config Config, patterns []string) string {
+	sensitive := map[string]bool{}
+	for _, v := range append(append([]Variable{}, config.UserVariables...), config.SystemVariables...) {
+		if v.isSensitive(patterns) {
+			sensitive[strings.ToUpper(v.Name)] = true
+		}
+	}
+	lines := make([]string, 0, len(mismatches))
+	for _, m := range mismatches {
+		expected, actual := m.Expected, m.Actual
+		if sensitive[strings.ToUpper(m.Name)] {
+			if expected != "" {
+				expected = maskedValue
+			}
+			if actual != "" {
+				actual = maskedValue
+			}
+		}
+		line := fmt.Sprintf("%s (%s): %s", m.Name, m.Scope, m.Problem)
+		if expected != "" {
+			line += "\n    expected: " + expected
+		}
+		if actual != "" {
+			line += "\n    actual:   " + actual
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
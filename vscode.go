@@ -0,0 +1,65 @@
+// vscode.go
+// VS Code exports - terminal environment settings and dev container environments from a config
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// vsCodeSettingsExportFormat renders terminal.integrated.env.windows for .vscode/settings.json
+var vsCodeSettingsExportFormat = exportFormat{
+	Name:        "VS Code settings.json",
+	Description: "terminal.integrated.env.windows block for a workspace .vscode/settings.json, so integrated terminals start with the variables set. %VAR% references become ${env:VAR} and deletions become null.",
+	Extension:   ".json",
+	Render:      renderVSCodeSettings,
+}
+
+// devContainerExportFormat renders containerEnv for devcontainer.json
+var devContainerExportFormat = exportFormat{
+	Name:        "Dev Container devcontainer.json",
+	Description: "containerEnv block for .devcontainer/devcontainer.json. %VAR% references become ${localEnv:VAR}; deletions are left out because containers start from a clean environment.",
+	Extension:   ".json",
+	Render:      renderDevContainer,
+}
+
+// vsCodeReferences rewrites %VAR% references of expandable values into VS Code's ${prefix:VAR} syntax
+func vsCodeReferences(v Variable, prefix string) string {
+	if !v.isExpandable() {
+		return v.Value
+	}
+	return expansionReference.ReplaceAllString(v.Value, "${"+prefix+":$1}")
+}
+
+// renderVSCodeSettings renders config as a VS Code settings document
+func renderVSCodeSettings(config Config) ([]byte, error) {
+	env := map[string]interface{}{}
+	for _, v := range exportVariables(config) {
+		if v.Operation == "delete" {
+			env[v.Name] = nil // null removes the variable from the terminal environment
+			continue
+		}
+		env[v.Name] = vsCodeReferences(v, "env")
+	}
+	return marshalExportJSON(map[string]interface{}{"terminal.integrated.env.windows": env})
+}
+
+// renderDevContainer renders config as a devcontainer.json fragment
+func renderDevContainer(config Config) ([]byte, error) {
+	env := map[string]string{}
+	for _, v := range exportVariables(config) {
+		if v.Operation == "set" {
+			env[v.Name] = vsCodeReferences(v, "localEnv")
+		}
+	}
+	return marshalExportJSON(map[string]interface{}{"containerEnv": env})
+}
+
+// marshalExportJSON encodes an export document as indented JSON with a trailing newline
+func marshalExportJSON(doc interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,156 @@
+// watch.go
+// Variable watch - alerts with a notification and an audit log entry when a watched variable changes in the registry
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// watchNotifyFilter selects the registry changes that wake the watcher: values written, created or removed
+const watchNotifyFilter = windows.REG_NOTIFY_CHANGE_NAME | windows.REG_NOTIFY_CHANGE_LAST_SET
+
+// watchToastValueLength limits how much of a value is shown in a notification, the log keeps the full value
+const watchToastValueLength = 80
+
+// watchedValue is the last seen state of a watched variable
+type watchedValue struct {
+	Name    string // Name as stored in the registry
+	Value   string
+	Present bool
+}
+
+// variableWatch tracks the watched variables of one environment scope
+type variableWatch struct {
+	scope    string
+	hive     registry.Key
+	path     string
+	settings *Settings
+	notify   func(title, content string)
+
+	mu       sync.Mutex
+	seen     map[string]watchedValue // Keyed by upper-cased name
+	patterns string                  // Watch list the seen values were recorded for
+}
+
+// startVariableWatch monitors the user and system Environment keys for changes to the watched variables
+// Watched names are read from settings on every change, so edits on the Settings tab apply without a restart
+func startVariableWatch(settings *Settings, notify func(title, content string)) {
+	for _, w := range []*variableWatch{
+		{scope: ScopeUser, hive: registry.CURRENT_USER, path: userEnvironmentPath},
+		{scope: ScopeSystem, hive: registry.LOCAL_MACHINE, path: systemEnvironmentPath},
+	} {
+		w.settings = settings
+		w.notify = notify
+		go w.run()
+	}
+}
+
+// run blocks on registry change notifications and compares the watched values after each one
+func (w *variableWatch) run() {
+	key, err := registry.OpenKey(w.hive, w.path, registry.QUERY_VALUE|registry.NOTIFY)
+	if err != nil {
+		fmt.Printf("Warning: Could not watch %s variables: %v\n", w.scope, err)
+		return
+	}
+	defer key.Close()
+
+	w.check(false)
+	for {
+		if err := windows.RegNotifyChangeKeyValue(windows.Handle(key), false, watchNotifyFilter, 0, false); err != nil {
+			fmt.Printf("Warning: Stopped watching %s variables: %v\n", w.scope, err)
+			return
+		}
+		w.check(true)
+	}
+}
+
+// check reads the watched variables and reports the ones that differ from the last seen state
+// The first check only records the current values
+func (w *variableWatch) check(report bool) {
+	patterns := w.settings.WatchedVariables
+	if len(patterns) == 0 {
+		w.mu.Lock()
+		w.seen, w.patterns = nil, ""
+		w.mu.Unlock()
+		return
+	}
+	variables, err := readVariablesFromRegistry(w.hive, w.path)
+	if err != nil {
+		fmt.Printf("Warning: Could not read watched %s variables: %v\n", w.scope, err)
+		return
+	}
+
+	current := map[string]watchedValue{}
+	for _, v := range variables {
+		if nameMatchesAny(v.Name, patterns) {
+			current[strings.ToUpper(v.Name)] = watchedValue{Name: v.Name, Value: v.Value, Present: true}
+		}
+	}
+
+	// A changed watch list starts over instead of reporting newly watched variables as created
+	joined := strings.ToUpper(strings.Join(patterns, ","))
+	w.mu.Lock()
+	previous := w.seen
+	samePatterns := w.patterns == joined
+	w.seen, w.patterns = current, joined
+	w.mu.Unlock()
+	if !report || previous == nil || !samePatterns {
+		return
+	}
+
+	for upper, now := range current {
+		if before, ok := previous[upper]; !ok || before.Value != now.Value {
+			w.report(now.Name, before, now)
+		}
+	}
+	for upper, before := range previous {
+		if _, ok := current[upper]; !ok {
+			w.report(before.Name, before, watchedValue{})
+		}
+	}
+}
+
+// report logs a change of a watched variable and raises a notification
+func (w *variableWatch) report(name string, before, after watchedValue) {
+	display := func(state watchedValue) string {
+		switch {
+		case !state.Present:
+			return "(not set)"
+		case Variable{Name: name}.isSensitive(w.settings.SensitivePatterns) && state.Value != "":
+			return maskedValue
+		default:
+			return state.Value
+		}
+	}
+	oldValue, newValue := display(before), display(after)
+
+	message := fmt.Sprintf("%s (%s) changed from %q to %q", name, w.scope, oldValue, newValue)
+	fmt.Printf("Watch: %s\n", message)
+	if err := appendHistory(HistoryEntry{Action: "watch", Success: true, Message: message}); err != nil {
+		fmt.Printf("Warning: Could not record watch event: %v\n", err)
+	}
+
+	w.notify(fmt.Sprintf("%s changed (%s)", name, w.scope), fmt.Sprintf("Old: %s\nNew: %s", shortenValue(oldValue), shortenValue(newValue)))
+}
+
+// shortenValue truncates a value for display in a notification
+func shortenValue(value string) string {
+	if utf8.RuneCountInString(value) <= watchToastValueLength {
+		return value
+	}
+	return string([]rune(value)[:watchToastValueLength-3]) + "..."
+}
+
+// sendWatchNotification shows a desktop notification for a watched variable change
+func sendWatchNotification(app fyne.App) func(title, content string) {
+	return func(title, content string) {
+		app.SendNotification(fyne.NewNotification(title, content))
+	}
+}
@@ -0,0 +1,170 @@
+// wizard.go
+// Import wizard - walks through every entry that would change an existing value and lets the user keep, replace or skip it
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Choices offered for each conflict
+const (
+	wizardReplace = "Replace" // Write the config's value
+	wizardKeep    = "Keep"    // Keep the current value
+	wizardSkip    = "Skip"    // Leave the variable alone this time, listed as undecided in the summary
+	wizardCancel  = "Cancel"  // Abort the import
+)
+
+// wizardConflict is a config entry that changes or deletes an existing variable
+type wizardConflict struct {
+	Scope    string
+	Index    int      // Position of the entry in its config section
+	Entry    Variable // What the config wants
+	Existing Variable // What the registry holds now
+}
+
+// wizardResult lists what the user decided
+type wizardResult struct {
+	Replaced []string
+	Kept     []string
+	Skipped  []string
+}
+
+// summary renders the decisions for the status line and the audit log
+func (r wizardResult) summary() string {
+	line := fmt.Sprintf("import wizard: %d replaced, %d kept, %d skipped", len(r.Replaced), len(r.Kept), len(r.Skipped))
+	if len(r.Skipped) > 0 {
+		line += fmt.Sprintf(" (%s)", strings.Join(r.Skipped, ", "))
+	}
+	return line
+}
+
+// wizardConflicts finds the entries of config that differ from the current environment
+// New variables and entries matching the current value are not conflicts and always apply
+func wizardConflicts(config Config, current currentValueIndex) []wizardConflict {
+	var conflicts []wizardConflict
+	collect := func(scope string, variables []Variable) {
+		for i, v := range variables {
+			existing, exists := current.lookup(scope, v.Name)
+			if !exists {
+				continue
+			}
+			differs := v.Operation == "delete" ||
+				(v.Operation == "set" && (existing.Value != v.Value || existing.typeLabel() != v.typeLabel()))
+			if differs {
+				conflicts = append(conflicts, wizardConflict{Scope: scope, Index: i, Entry: v, Existing: existing.Variable})
+			}
+		}
+	}
+	collect(ScopeUser, config.UserVariables)
+	collect(ScopeSystem, config.SystemVariables)
+	return conflicts
+}
+
+// askConflict shows one conflict and blocks until the user picks a choice; it must not run on the UI thread
+func askConflict(c wizardConflict, position, total int, sensitivePatterns []string, parent fyne.Window) (string, bool) {
+	sensitive := c.Entry.isSensitive(sensitivePatterns) || c.Existing.isSensitive(sensitivePatterns)
+	show := func(v Variable) string {
+		if sensitive && v.Value != "" {
+			return maskedValue
+		}
+		return formatValueDetails(v, nil)
+	}
+
+	proposed := show(c.Entry)
+	if c.Entry.Operation == "delete" {
+		proposed = previewDeleted
+	}
+	currentLabel := widget.NewLabel(show(c.Existing))
+	currentLabel.Wrapping = fyne.TextWrapBreak
+	proposedLabel := widget.NewLabel(proposed)
+	proposedLabel.Wrapping = fyne.TextWrapBreak
+	allCheck := widget.NewCheck("Apply this choice to all remaining conflicts", nil)
+
+	choice := make(chan string, 1)
+	var d *dialog.CustomDialog
+	choose := func(c string) func() {
+		return func() {
+			d.Hide()
+			choice <- c
+		}
+	}
+	content := container.NewBorder(
+		widget.NewLabelWithStyle(fmt.Sprintf("%s (%s): %s", c.Entry.Name, c.Scope, strings.ToUpper(c.Entry.Operation)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		allCheck, nil, nil,
+		container.NewGridWithColumns(2,
+			container.NewBorder(widget.NewLabelWithStyle("Current", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}), nil, nil, nil, container.NewVScroll(currentLabel)),
+			container.NewBorder(widget.NewLabelWithStyle("Config", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}), nil, nil, nil, container.NewVScroll(proposedLabel)),
+		),
+	)
+	d = dialog.NewCustomWithoutButtons(fmt.Sprintf("Conflict %d of %d", position, total), content, parent)
+	d.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("Cancel Import", choose(wizardCancel)),
+		widget.NewButton(wizardSkip, choose(wizardSkip)),
+		widget.NewButton("Keep Current", choose(wizardKeep)),
+		widget.NewButton(wizardReplace, choose(wizardReplace)),
+	})
+	d.Resize(fyne.NewSize(760, 420))
+	d.Show()
+
+	picked := <-choice
+	return picked, allCheck.Checked
+}
+
+// runImportWizard asks about every conflict of config and returns the config reduced to the chosen entries
+// It returns false when the user cancelled; it must not run on the UI thread
+func runImportWizard(config Config, settings Settings, parent fyne.Window) (Config, wizardResult, bool) {
+	var result wizardResult
+	current, err := loadCurrentValueIndex()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("error reading current values: %v", err), parent)
+		return config, result, false
+	}
+	conflicts := wizardConflicts(config, current)
+
+	drop := map[string]map[int]bool{ScopeUser: {}, ScopeSystem: {}}
+	remembered := ""
+	for i, c := range conflicts {
+		choice := remembered
+		if choice == "" {
+			var toAll bool
+			choice, toAll = askConflict(c, i+1, len(conflicts), settings.SensitivePatterns, parent)
+			if choice == wizardCancel {
+				return config, result, false
+			}
+			if toAll {
+				remembered = choice
+			}
+		}
+
+		label := fmt.Sprintf("%s (%s)", c.Entry.Name, c.Scope)
+		switch choice {
+		case wizardReplace:
+			result.Replaced = append(result.Replaced, label)
+		case wizardKeep:
+			result.Kept = append(result.Kept, label)
+			drop[c.Scope][c.Index] = true
+		case wizardSkip:
+			result.Skipped = append(result.Skipped, label)
+			drop[c.Scope][c.Index] = true
+		}
+	}
+
+	keep := func(scope string, variables []Variable) []Variable {
+		var kept []Variable
+		for i, v := range variables {
+			if !drop[scope][i] {
+				kept = append(kept, v)
+			}
+		}
+		return kept
+	}
+	config.UserVariables = keep(ScopeUser, config.UserVariables)
+	config.SystemVariables = keep(ScopeSystem, config.SystemVariables)
+	return config, result, true
+}